@@ -9,6 +9,7 @@ import (
 	"paperplay/config"
 	"paperplay/internal/api"
 	"paperplay/internal/cron"
+	"paperplay/internal/mailer"
 	"paperplay/internal/middleware"
 	"paperplay/internal/model"
 	"paperplay/internal/service"
@@ -77,6 +78,13 @@ func main() {
 		logger.GetSugar().Fatalf("Failed to create database indexes: %v", err)
 	}
 
+	// Build the full-text search index (requires the sqlite_fts5 build tag)
+	if enabled, err := db.SetupSearchIndex(); err != nil {
+		logger.GetSugar().Fatalf("Failed to set up search index: %v", err)
+	} else if !enabled {
+		logger.GetSugar().Warn("SQLite was built without FTS5 (build with -tags sqlite_fts5); search will use LIKE queries")
+	}
+
 	// Seed initial data (only for new databases)
 	if isNewDatabase {
 		logger.GetSugar().Info("Seeding initial data for new database...")
@@ -89,6 +97,10 @@ func main() {
 
 	// Initialize services
 	userService := service.NewUserService(db.DB)
+	questionService := service.NewQuestionService(db.DB)
+	questionStatsService := service.NewQuestionStatsService(db.DB)
+	searchService := service.NewSearchService(db.DB)
+	paperService := service.NewPaperService(db.DB)
 
 	// Initialize Ethereum service (optional)
 	var ethService *service.EthereumService
@@ -103,7 +115,10 @@ func main() {
 	}
 
 	// Initialize JWT service
-	jwtService := middleware.NewJWTService(cfg, db.DB)
+	jwtService, err := middleware.NewJWTService(cfg, db.DB, logger)
+	if err != nil {
+		logger.GetSugar().Fatalf("Failed to initialize JWT service: %v", err)
+	}
 
 	// Initialize metrics service
 	metricsService := middleware.NewMetricsService()
@@ -115,6 +130,22 @@ func main() {
 
 	// Initialize achievement service (after WebSocket hub)
 	achievementService := service.NewAchievementService(db.DB, logger.GetLogger(), wsHub, ethService)
+	wsHub.SetNotificationStore(achievementService)
+	reportService := service.NewReportService(db.DB, logger.GetLogger(), wsHub)
+
+	// Evaluate achievements as domain events are published; the cron job is the safety net
+	eventBus := service.NewEventBus(db.DB, &cfg.EventBus, achievementService.HandleEvent, logger.GetLogger())
+	eventBus.Start()
+	defer eventBus.Stop()
+
+	// Initialize mailer and account service
+	mail, err := mailer.New(&cfg.Mail)
+	if err != nil {
+		logger.GetSugar().Fatalf("Failed to initialize mailer: %v", err)
+	}
+	accountService := service.NewAccountService(db.DB, mail, cfg, logger.GetLogger())
+	wechatService := service.NewWeChatService(db.DB, &cfg.WeChat, ethService, logger.GetLogger())
+	guestService := service.NewGuestService(db.DB, cfg, ethService, wechatService, logger.GetLogger())
 
 	// Initialize cron job manager
 	jobManager := cron.NewJobManager(
@@ -123,6 +154,9 @@ func main() {
 		&cfg.Cron,
 		achievementService,
 		userService,
+		questionStatsService,
+		accountService,
+		guestService,
 		wsHub,
 	)
 
@@ -133,10 +167,19 @@ func main() {
 	defer jobManager.Stop()
 
 	// Initialize API handlers
-	userHandler := api.NewUserHandler(db.DB, jwtService, userService, ethService)
-	levelHandler := api.NewLevelHandler(db.DB)
+	loginThrottler := middleware.NewLoginThrottler(cfg, db.DB, logger, metricsService)
+	apiKeyService := middleware.NewAPIKeyService(cfg, db.DB, logger)
+	userHandler := api.NewUserHandler(db.DB, jwtService, userService, ethService, accountService, loginThrottler)
+	wechatHandler := api.NewWeChatHandler(wechatService, jwtService, accountService)
+	guestHandler := api.NewGuestHandler(guestService, jwtService, accountService, loginThrottler)
+	levelHandler := api.NewLevelHandler(db.DB, eventBus)
+	questionHandler := api.NewQuestionHandler(db.DB, questionService, questionStatsService)
+	reportHandler := api.NewReportHandler(reportService)
+	searchHandler := api.NewSearchHandler(searchService)
+	paperHandler := api.NewPaperHandler(paperService)
 	achievementHandler := api.NewAchievementHandler(db.DB, achievementService, metricsService, wsHub)
 	systemHandler := api.NewSystemHandler(db.DB, metricsService, wsHub)
+	apiKeyHandler := api.NewAPIKeyHandler(apiKeyService)
 
 	// Setup Gin router
 	if cfg.Server.Mode == "release" {
@@ -154,6 +197,9 @@ func main() {
 		router.Use(metricsService.HTTPMetrics())
 	}
 
+	// Public keys for verifying access tokens
+	router.GET("/.well-known/jwks.json", jwtService.JWKSHandler())
+
 	// Health check endpoint
 	router.GET("/health", systemHandler.GetHealth)
 
@@ -168,7 +214,7 @@ func main() {
 	})
 
 	// API routes
-	setupAPIRoutes(router, userHandler, levelHandler, achievementHandler, jwtService, wsHub)
+	setupAPIRoutes(router, userHandler, wechatHandler, guestHandler, levelHandler, questionHandler, reportHandler, searchHandler, paperHandler, achievementHandler, apiKeyHandler, jwtService, apiKeyService, wsHub)
 
 	// Create HTTP server
 	server := &http.Server{
@@ -187,6 +233,28 @@ func main() {
 		}
 	}()
 
+	// Reload the JWT signing keys on SIGHUP, rotating to a newly added key
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	go func() {
+		for range reload {
+			if err := jwtService.ReloadKeys(); err != nil {
+				logger.GetSugar().Errorf("Failed to reload JWT keys: %v", err)
+			}
+		}
+	}()
+
+	// Pick up sessions revoked by other instances
+	go func() {
+		ticker := time.NewTicker(time.Duration(cfg.JWT.RevocationRefresh) * time.Second)
+		defer ticker.Stop()
+		for range ticker.C {
+			if err := jwtService.ReloadRevokedSessions(); err != nil {
+				logger.GetSugar().Errorf("Failed to reload revoked sessions: %v", err)
+			}
+		}
+	}()
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
@@ -209,9 +277,17 @@ func main() {
 func setupAPIRoutes(
 	router *gin.Engine,
 	userHandler *api.UserHandler,
+	wechatHandler *api.WeChatHandler,
+	guestHandler *api.GuestHandler,
 	levelHandler *api.LevelHandler,
+	questionHandler *api.QuestionHandler,
+	reportHandler *api.ReportHandler,
+	searchHandler *api.SearchHandler,
+	paperHandler *api.PaperHandler,
 	achievementHandler *api.AchievementHandler,
+	apiKeyHandler *api.APIKeyHandler,
 	jwtService *middleware.JWTService,
+	apiKeyService *middleware.APIKeyService,
 	wsHub *websocket.Hub,
 ) {
 	// API v1 group
@@ -222,7 +298,12 @@ func setupAPIRoutes(
 	{
 		auth.POST("/register", userHandler.Register)
 		auth.POST("/login", userHandler.Login)
+		auth.POST("/wechat", wechatHandler.Login)
+		auth.POST("/guest", guestHandler.Login)
 		auth.POST("/refresh", userHandler.RefreshToken)
+		auth.POST("/verify-email", userHandler.VerifyEmail)
+		auth.POST("/password-reset", userHandler.RequestPasswordReset)
+		auth.POST("/password-reset/confirm", userHandler.ConfirmPasswordReset)
 	}
 
 	// Protected routes (authentication required)
@@ -237,6 +318,25 @@ func setupAPIRoutes(
 			users.GET("/progress", userHandler.GetUserProgress)
 			users.GET("/achievements", userHandler.GetUserAchievements)
 			users.POST("/logout", userHandler.Logout)
+			users.GET("/sessions", userHandler.ListSessions)
+			users.DELETE("/sessions/:session_id", userHandler.RevokeSession)
+			users.POST("/upgrade/email", guestHandler.UpgradeWithEmail)
+			users.POST("/upgrade/wechat", guestHandler.UpgradeWithWeChat)
+
+			// Account management, not open to guests
+			account := users.Group("")
+			account.Use(middleware.RequireRegistered())
+			{
+				account.POST("/verify-email/resend", userHandler.ResendVerification)
+				account.PUT("/password", userHandler.ChangePassword)
+				account.GET("/export", userHandler.ExportData)
+				account.DELETE("/me", userHandler.DeleteAccount)
+				account.POST("/me/cancel-deletion", userHandler.CancelDeletion)
+				account.GET("/identities", wechatHandler.ListIdentities)
+				account.POST("/identities/wechat", wechatHandler.LinkWeChat)
+				account.DELETE("/identities/wechat", wechatHandler.UnlinkWeChat)
+				account.POST("/identities/email", wechatHandler.AttachEmail)
+			}
 		}
 
 		// WebSocket connection info
@@ -250,6 +350,9 @@ func setupAPIRoutes(
 		{
 			achievements.GET("", achievementHandler.GetAllAchievements)
 			achievements.GET("/user", achievementHandler.GetUserAchievements)
+			achievements.GET("/progress", achievementHandler.GetAchievementProgress)
+			achievements.GET("/unread-count", achievementHandler.GetUnreadCount)
+			achievements.POST("/ack", achievementHandler.AcknowledgeAchievements)
 			achievements.POST("/evaluate", achievementHandler.EvaluateAchievements)
 			achievements.POST("/force-award", achievementHandler.ForceAwardAchievement)
 		}
@@ -273,6 +376,7 @@ func setupAPIRoutes(
 		{
 			papers.GET("/:paper_id", levelHandler.GetPaper)
 			papers.GET("/:paper_id/level", levelHandler.GetPaperLevel)
+			papers.GET("/:paper_id/cite", paperHandler.CitePaper)
 		}
 
 		levels := protected.Group("/levels")
@@ -288,6 +392,14 @@ func setupAPIRoutes(
 		{
 			questions.GET("", levelHandler.GetAllQuestions)
 			questions.GET("/:question_id", levelHandler.GetQuestion)
+			questions.POST("/:question_id/report", middleware.RequireRegistered(), reportHandler.CreateReport)
+		}
+
+		protected.GET("/search", searchHandler.Search)
+
+		answers := protected.Group("/answers")
+		{
+			answers.GET("/:answer_id", questionHandler.GetAnswer)
 		}
 
 		// Future stats endpoints (to be implemented later)
@@ -297,6 +409,86 @@ func setupAPIRoutes(
 		//     stats.GET("/progress", statsHandler.GetProgress)
 		// }
 	}
+
+	// Admin endpoints, open to administrators and to API keys with a matching scope
+	admin := v1.Group("/admin")
+	admin.Use(apiKeyService.Middleware(jwtService.AuthMiddleware()))
+	{
+		contentWrite := admin.Group("")
+		contentWrite.Use(middleware.RequireScope(model.ScopeContentWrite))
+		{
+			contentWrite.POST("/questions", questionHandler.CreateQuestion)
+			contentWrite.GET("/questions/:question_id/revisions", questionHandler.GetQuestionRevisions)
+			contentWrite.POST("/questions/:question_id/revisions", questionHandler.CreateQuestionRevision)
+			contentWrite.GET("/questions/:question_id/revisions/:revision_id", questionHandler.GetQuestionRevision)
+			contentWrite.PUT("/questions/:question_id/revisions/:revision_id", questionHandler.UpdateQuestionRevision)
+			contentWrite.POST("/questions/:question_id/revisions/:revision_id/submit", questionHandler.SubmitQuestionRevision)
+			contentWrite.GET("/questions/:question_id/diff", questionHandler.DiffQuestionRevisions)
+			contentWrite.POST("/papers/import", paperHandler.ImportPapers)
+			contentWrite.GET("/papers/export", paperHandler.ExportPapers)
+		}
+
+		statsRead := admin.Group("")
+		statsRead.Use(middleware.RequireScope(model.ScopeStatsRead))
+		{
+			statsRead.GET("/questions/stats", questionHandler.GetQuestionStatsList)
+			statsRead.GET("/questions/:question_id/stats", questionHandler.GetQuestionStats)
+		}
+
+		// Administrator role required, API keys are refused
+		adminOnly := admin.Group("")
+		adminOnly.Use(middleware.RequireAdmin())
+		{
+			adminQuestions := adminOnly.Group("/questions")
+			{
+				adminQuestions.POST("/stats/refresh", questionHandler.RefreshQuestionStats)
+				adminQuestions.POST("/:question_id/revisions/:revision_id/publish", questionHandler.PublishQuestionRevision)
+				adminQuestions.POST("/:question_id/revisions/:revision_id/reject", questionHandler.RejectQuestionRevision)
+			}
+
+			adminAnswers := adminOnly.Group("/answers")
+			{
+				adminAnswers.POST("/:answer_id/regrade", questionHandler.RegradeAnswer)
+			}
+
+			adminReports := adminOnly.Group("/reports")
+			{
+				adminReports.GET("", reportHandler.GetReports)
+				adminReports.GET("/:report_id", reportHandler.GetReport)
+				adminReports.POST("/:report_id/triage", reportHandler.TriageReport)
+				adminReports.POST("/:report_id/resolve", reportHandler.ResolveReport)
+				adminReports.POST("/:report_id/reject", reportHandler.RejectReport)
+			}
+
+			adminUsers := adminOnly.Group("/users")
+			{
+				adminUsers.POST("/:user_id/unlock", userHandler.UnlockUser)
+				adminUsers.DELETE("/:user_id/sessions", userHandler.RevokeUserSessions)
+				adminUsers.POST("/:user_id/achievements/:achievement_id/revoke", achievementHandler.RevokeAchievement)
+			}
+
+			apiKeys := adminOnly.Group("/api-keys")
+			{
+				apiKeys.POST("", apiKeyHandler.CreateAPIKey)
+				apiKeys.GET("", apiKeyHandler.ListAPIKeys)
+				apiKeys.DELETE("/:key_id", apiKeyHandler.RevokeAPIKey)
+			}
+
+			adminAchievements := adminOnly.Group("/achievements")
+			{
+				adminAchievements.GET("", achievementHandler.ListAdminAchievements)
+				adminAchievements.POST("", achievementHandler.CreateAchievement)
+				adminAchievements.POST("/dry-run", achievementHandler.DryRunAchievement)
+				adminAchievements.GET("/audit-log", achievementHandler.GetAchievementAuditLog)
+				adminAchievements.GET("/:achievement_id", achievementHandler.GetAdminAchievement)
+				adminAchievements.PUT("/:achievement_id", achievementHandler.UpdateAchievement)
+				adminAchievements.DELETE("/:achievement_id", achievementHandler.DeleteAchievement)
+				adminAchievements.POST("/:achievement_id/backfills", achievementHandler.StartBackfill)
+				adminAchievements.GET("/:achievement_id/backfills", achievementHandler.ListBackfills)
+				adminAchievements.GET("/:achievement_id/backfills/:backfill_id", achievementHandler.GetBackfill)
+			}
+		}
+	}
 }
 
 // CORS middleware for handling cross-origin requests
@@ -18,6 +18,13 @@ type Config struct {
 	Log        LogConfig        `mapstructure:"log"`
 	Prometheus PrometheusConfig `mapstructure:"prometheus"`
 	Cron       CronConfig       `mapstructure:"cron"`
+	Mail       MailConfig       `mapstructure:"mail"`
+	Auth       AuthConfig       `mapstructure:"auth"`
+	WeChat     WeChatConfig     `mapstructure:"wechat"`
+	Account    AccountConfig    `mapstructure:"account"`
+	APIKeys    APIKeyConfig     `mapstructure:"api_keys"`
+	Guest      GuestConfig      `mapstructure:"guest"`
+	EventBus   EventBusConfig   `mapstructure:"event_bus"`
 }
 
 type ServerConfig struct {
@@ -37,9 +44,12 @@ type DatabaseConfig struct {
 }
 
 type JWTConfig struct {
-	SecretKey            string `mapstructure:"secret_key"`
+	SecretKey            string `mapstructure:"secret_key"`             // signs email links, and access tokens when no keys_dir is set
+	KeysDir              string `mapstructure:"keys_dir"`               // directory of RSA or Ed25519 PEM keys named <kid>.pem
+	SigningKeyID         string `mapstructure:"signing_key_id"`         // kid to sign with; defaults to the greatest kid
 	AccessTokenDuration  int    `mapstructure:"access_token_duration"`  // minutes
 	RefreshTokenDuration int    `mapstructure:"refresh_token_duration"` // days
+	RevocationRefresh    int    `mapstructure:"revocation_refresh"`     // seconds between reloads of sessions revoked by other instances
 }
 
 type EthereumConfig struct {
@@ -50,6 +60,7 @@ type EthereumConfig struct {
 	PrivateKey      string `mapstructure:"private_key"`
 	GasLimit        uint64 `mapstructure:"gas_limit"`
 	GasPrice        int64  `mapstructure:"gas_price"`
+	BurnEnabled     bool   `mapstructure:"burn_enabled"` // The contract supports burning revoked achievement NFTs
 }
 
 type WebSocketConfig struct {
@@ -76,10 +87,81 @@ type CronConfig struct {
 	StatsUpdateSpec      string `mapstructure:"stats_update_spec"`
 	ReportGenerationSpec string `mapstructure:"report_generation_spec"`
 	AchievementCheckSpec string `mapstructure:"achievement_check_spec"`
+	AccountPurgeSpec     string `mapstructure:"account_purge_spec"`
+	GuestPurgeSpec       string `mapstructure:"guest_purge_spec"`
+	BackfillSpec         string `mapstructure:"backfill_spec"`
+}
+
+type MailConfig struct {
+	Driver         string `mapstructure:"driver"` // smtp or outbox
+	From           string `mapstructure:"from"`
+	SMTPHost       string `mapstructure:"smtp_host"`
+	SMTPPort       int    `mapstructure:"smtp_port"`
+	SMTPUsername   string `mapstructure:"smtp_username"`
+	SMTPPassword   string `mapstructure:"smtp_password"`
+	OutboxDir      string `mapstructure:"outbox_dir"`       // .eml files are written here by the outbox driver
+	AppBaseURL     string `mapstructure:"app_base_url"`     // Frontend URL used in email links
+	VerifyTokenTTL int    `mapstructure:"verify_token_ttl"` // hours
+	ResetTokenTTL  int    `mapstructure:"reset_token_ttl"`  // minutes
+}
+
+// AuthConfig controls login throttling. Failed attempts are counted per account and per
+// client IP; after BackoffAfter failures each further attempt on an account has to wait
+// twice as long, and reaching either maximum locks logins out for LockoutDuration.
+type AuthConfig struct {
+	MaxFailedAttempts      int `mapstructure:"max_failed_attempts"`        // per account, before lockout
+	MaxFailedAttemptsPerIP int `mapstructure:"max_failed_attempts_per_ip"` // per client IP, before lockout
+	BackoffAfter           int `mapstructure:"backoff_after"`              // failures per account allowed without delay
+	BackoffBase            int `mapstructure:"backoff_base"`               // seconds, doubled for each further failure
+	MaxBackoff             int `mapstructure:"max_backoff"`                // seconds
+	LockoutDuration        int `mapstructure:"lockout_duration"`           // minutes
+	FailureWindow          int `mapstructure:"failure_window"`             // minutes; older failures are forgotten
+	MaxGuestsPerIP         int `mapstructure:"max_guests_per_ip"`          // guest accounts per client IP within the failure window, before lockout
+}
+
+// WeChatConfig configures mini-program login through code2session
+type WeChatConfig struct {
+	Enabled         bool   `mapstructure:"enabled"`
+	AppID           string `mapstructure:"app_id"`
+	AppSecret       string `mapstructure:"app_secret"`
+	Code2SessionURL string `mapstructure:"code2session_url"` // Overridden in tests and development with a fake server
+	Timeout         int    `mapstructure:"timeout"`          // seconds
+}
+
+// AccountConfig controls self-service account deletion
+type AccountConfig struct {
+	DeletionGracePeriod int    `mapstructure:"deletion_grace_period"` // days before a deletion request is carried out
+	DeletionMode        string `mapstructure:"deletion_mode"`         // anonymize or delete
+}
+
+// APIKeyConfig controls API keys for service-to-service access
+type APIKeyConfig struct {
+	DefaultRateLimit int `mapstructure:"default_rate_limit"` // requests per minute for keys without their own limit
+}
+
+// GuestConfig controls play without registering
+type GuestConfig struct {
+	Enabled       bool `mapstructure:"enabled"`
+	RetentionDays int  `mapstructure:"retention_days"` // days without a sign-in before a guest account is purged
+}
+
+// EventBusConfig controls in-process delivery of domain events. Events are queued
+// per user on one of Workers queues; events that do not fit, fail or are left over
+// from a previous run are redelivered from the outbox table every PollInterval.
+type EventBusConfig struct {
+	Workers       int `mapstructure:"workers"`
+	QueueSize     int `mapstructure:"queue_size"`     // events queued per worker
+	PollInterval  int `mapstructure:"poll_interval"`  // seconds
+	Lease         int `mapstructure:"lease"`          // seconds before an undelivered or unfinished event is redelivered
+	MaxAttempts   int `mapstructure:"max_attempts"`   // deliveries before an event is given up on
+	RetentionDays int `mapstructure:"retention_days"` // days delivered events are kept
 }
 
 var globalConfig *Config
 
+// defaultJWTSecret is the development placeholder for jwt.secret_key; it is refused in release mode
+const defaultJWTSecret = "your-secret-key-change-in-production"
+
 // Load reads configuration from file and environment variables
 func Load(configPath string) (*Config, error) {
 	v := viper.New()
@@ -150,9 +232,12 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("database.conn_max_lifetime", 3600)
 
 	// JWT defaults
-	v.SetDefault("jwt.secret_key", "your-secret-key-change-in-production")
+	v.SetDefault("jwt.secret_key", defaultJWTSecret)
+	v.SetDefault("jwt.keys_dir", "")
+	v.SetDefault("jwt.signing_key_id", "")
 	v.SetDefault("jwt.access_token_duration", 15)
 	v.SetDefault("jwt.refresh_token_duration", 7)
+	v.SetDefault("jwt.revocation_refresh", 30)
 
 	// Ethereum defaults
 	v.SetDefault("ethereum.enabled", false)
@@ -160,6 +245,7 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("ethereum.chain_id", 11155111) // Sepolia testnet
 	v.SetDefault("ethereum.gas_limit", 100000)
 	v.SetDefault("ethereum.gas_price", 20000000000) // 20 Gwei
+	v.SetDefault("ethereum.burn_enabled", false)
 
 	// WebSocket defaults
 	v.SetDefault("websocket.read_buffer_size", 1024)
@@ -182,6 +268,57 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("cron.stats_update_spec", "0 2 * * *")        // Daily at 2 AM
 	v.SetDefault("cron.report_generation_spec", "0 3 * * 0")   // Weekly on Sunday at 3 AM
 	v.SetDefault("cron.achievement_check_spec", "*/5 * * * *") // Every 5 minutes
+	v.SetDefault("cron.account_purge_spec", "0 4 * * *")       // Daily at 4 AM
+	v.SetDefault("cron.guest_purge_spec", "30 4 * * *")        // Daily at 4:30 AM
+	v.SetDefault("cron.backfill_spec", "*/10 * * * *")         // Every 10 minutes
+
+	// Mail defaults
+	v.SetDefault("mail.driver", "outbox")
+	v.SetDefault("mail.from", "PaperPlay <no-reply@paperplay.local>")
+	v.SetDefault("mail.smtp_host", "")
+	v.SetDefault("mail.smtp_port", 587)
+	v.SetDefault("mail.smtp_username", "")
+	v.SetDefault("mail.smtp_password", "")
+	v.SetDefault("mail.outbox_dir", "./data/outbox")
+	v.SetDefault("mail.app_base_url", "http://localhost:8080")
+	v.SetDefault("mail.verify_token_ttl", 48)
+	v.SetDefault("mail.reset_token_ttl", 30)
+
+	// Auth defaults
+	v.SetDefault("auth.max_failed_attempts", 5)
+	v.SetDefault("auth.max_failed_attempts_per_ip", 20)
+	v.SetDefault("auth.backoff_after", 3)
+	v.SetDefault("auth.backoff_base", 1)
+	v.SetDefault("auth.max_backoff", 300)
+	v.SetDefault("auth.lockout_duration", 15)
+	v.SetDefault("auth.failure_window", 15)
+	v.SetDefault("auth.max_guests_per_ip", 10)
+
+	// WeChat defaults
+	v.SetDefault("wechat.enabled", false)
+	v.SetDefault("wechat.app_id", "")
+	v.SetDefault("wechat.app_secret", "")
+	v.SetDefault("wechat.code2session_url", "https://api.weixin.qq.com/sns/jscode2session")
+	v.SetDefault("wechat.timeout", 5)
+
+	// Account defaults
+	v.SetDefault("account.deletion_grace_period", 14)
+	v.SetDefault("account.deletion_mode", "anonymize")
+
+	// API key defaults
+	v.SetDefault("api_keys.default_rate_limit", 120)
+
+	// Guest defaults
+	v.SetDefault("guest.enabled", true)
+	v.SetDefault("guest.retention_days", 30)
+
+	// Event bus defaults
+	v.SetDefault("event_bus.workers", 4)
+	v.SetDefault("event_bus.queue_size", 256)
+	v.SetDefault("event_bus.poll_interval", 5)
+	v.SetDefault("event_bus.lease", 30)
+	v.SetDefault("event_bus.max_attempts", 5)
+	v.SetDefault("event_bus.retention_days", 7)
 }
 
 // validateConfig performs basic validation on the configuration
@@ -194,6 +331,20 @@ func validateConfig(config *Config) error {
 		return fmt.Errorf("JWT refresh token duration must be positive")
 	}
 
+	if config.JWT.RevocationRefresh <= 0 {
+		return fmt.Errorf("JWT revocation refresh interval must be positive")
+	}
+
+	if config.JWT.SecretKey == "" {
+		return fmt.Errorf("JWT secret key cannot be empty")
+	}
+	if config.Server.Mode == "release" && config.JWT.SecretKey == defaultJWTSecret {
+		return fmt.Errorf("JWT secret key must be changed from the default in release mode")
+	}
+	if config.JWT.SigningKeyID != "" && config.JWT.KeysDir == "" {
+		return fmt.Errorf("JWT signing key ID requires a keys directory")
+	}
+
 	if config.Database.DSN == "" {
 		return fmt.Errorf("database DSN cannot be empty")
 	}
@@ -207,5 +358,65 @@ func validateConfig(config *Config) error {
 		}
 	}
 
+	switch config.Mail.Driver {
+	case "smtp":
+		if config.Mail.SMTPHost == "" {
+			return fmt.Errorf("SMTP host must be set when the smtp mail driver is used")
+		}
+	case "outbox":
+	default:
+		return fmt.Errorf("unknown mail driver: %s", config.Mail.Driver)
+	}
+
+	if config.Mail.VerifyTokenTTL <= 0 || config.Mail.ResetTokenTTL <= 0 {
+		return fmt.Errorf("mail token TTLs must be positive")
+	}
+
+	if config.Auth.MaxFailedAttempts <= 0 || config.Auth.MaxFailedAttemptsPerIP <= 0 || config.Auth.MaxGuestsPerIP <= 0 {
+		return fmt.Errorf("auth failed attempt and guest limits must be positive")
+	}
+	if config.Auth.LockoutDuration <= 0 || config.Auth.FailureWindow <= 0 {
+		return fmt.Errorf("auth lockout duration and failure window must be positive")
+	}
+	if config.Auth.BackoffAfter < 0 || config.Auth.BackoffBase < 0 || config.Auth.MaxBackoff < 0 {
+		return fmt.Errorf("auth backoff settings cannot be negative")
+	}
+
+	switch config.Account.DeletionMode {
+	case "anonymize", "delete":
+	default:
+		return fmt.Errorf("unknown account deletion mode: %s", config.Account.DeletionMode)
+	}
+	if config.APIKeys.DefaultRateLimit <= 0 {
+		return fmt.Errorf("API key default rate limit must be positive")
+	}
+
+	if config.Account.DeletionGracePeriod < 0 {
+		return fmt.Errorf("account deletion grace period cannot be negative")
+	}
+
+	if config.Guest.RetentionDays <= 0 {
+		return fmt.Errorf("guest retention days must be positive")
+	}
+
+	if config.EventBus.Workers <= 0 || config.EventBus.QueueSize <= 0 {
+		return fmt.Errorf("event bus workers and queue size must be positive")
+	}
+	if config.EventBus.PollInterval <= 0 || config.EventBus.Lease <= 0 || config.EventBus.MaxAttempts <= 0 {
+		return fmt.Errorf("event bus poll interval, lease and max attempts must be positive")
+	}
+	if config.EventBus.RetentionDays < 0 {
+		return fmt.Errorf("event bus retention days cannot be negative")
+	}
+
+	if config.WeChat.Enabled {
+		if config.WeChat.AppID == "" || config.WeChat.AppSecret == "" {
+			return fmt.Errorf("wechat app ID and secret must be set when wechat login is enabled")
+		}
+		if config.WeChat.Code2SessionURL == "" {
+			return fmt.Errorf("wechat code2session URL must be set when wechat login is enabled")
+		}
+	}
+
 	return nil
 }
@@ -6,6 +6,7 @@ import (
 	"paperplay/internal/model"
 	"paperplay/internal/service"
 	"paperplay/internal/websocket"
+	"sort"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -48,7 +49,48 @@ type AchievementResponse struct {
 	Category    string         `json:"category"`
 	IsActive    bool           `json:"is_active"`
 	Rules       map[string]any `json:"rules"`
+	NFTEnabled  bool           `json:"nft_enabled"`
 	NFTMetadata map[string]any `json:"nft_metadata,omitempty"`
+	SeriesID    string         `json:"series_id,omitempty"`
+	Tier        int            `json:"tier,omitempty"` // Position within its series
+
+	// Set on seasonal achievements, which can only be earned within this window
+	StartsAt       *time.Time `json:"starts_at,omitempty"`
+	EndsAt         *time.Time `json:"ends_at,omitempty"`
+	LimitedEdition bool       `json:"limited_edition,omitempty"` // The season has ended
+
+	// How widely the achievement is held, once computed by the daily stats job
+	Rarity *AchievementRarity `json:"rarity,omitempty"`
+
+	// Set on series entries only: the tiers of the series, lowest first
+	Tiers []AchievementResponse `json:"tiers,omitempty"`
+}
+
+// AchievementRarity represents the holder statistics of an achievement
+type AchievementRarity struct {
+	Rarity           string     `json:"rarity"` // common, uncommon, rare, epic or legendary
+	HolderCount      int        `json:"holder_count"`
+	HolderPercent    float64    `json:"holder_percent"`         // Share of active users holding it, 0-100
+	FirstEarner      string     `json:"first_earner,omitempty"` // Display name
+	FirstEarnedAt    *time.Time `json:"first_earned_at,omitempty"`
+	MedianDaysToEarn *int       `json:"median_days_to_earn,omitempty"` // Days from sign-up
+	ComputedAt       time.Time  `json:"computed_at"`
+}
+
+// newAchievementRarity converts achievement statistics for API responses
+func newAchievementRarity(stats *model.AchievementStats) *AchievementRarity {
+	rarity := &AchievementRarity{
+		Rarity:           stats.Rarity,
+		HolderCount:      stats.HolderCount,
+		HolderPercent:    stats.HolderPercent,
+		FirstEarnedAt:    stats.FirstEarnedAt,
+		MedianDaysToEarn: stats.MedianDaysToEarn,
+		ComputedAt:       stats.ComputedAt,
+	}
+	if stats.FirstEarner != nil {
+		rarity.FirstEarner = stats.FirstEarner.DisplayName
+	}
+	return rarity
 }
 
 // UserAchievementResponse represents user achievement data for API responses
@@ -57,6 +99,8 @@ type UserAchievementResponse struct {
 	UserID        string              `json:"user_id"`
 	AchievementID string              `json:"achievement_id"`
 	EarnedAt      string              `json:"earned_at"`
+	NotifiedAt    *time.Time          `json:"notified_at,omitempty"` // First delivered as a notification
+	ViewedAt      *time.Time          `json:"viewed_at,omitempty"`   // Acknowledged by the user; unread while empty
 	EventData     map[string]any      `json:"event_data,omitempty"`
 	Achievement   *AchievementSummary `json:"achievement"`
 }
@@ -68,6 +112,86 @@ type AchievementSummary struct {
 	Description string `json:"description"`
 	Level       int    `json:"level"`
 	IconURL     string `json:"icon_url"`
+	SeriesID    string `json:"series_id,omitempty"`
+	Tier        int    `json:"tier,omitempty"`
+
+	EndsAt         *time.Time `json:"ends_at,omitempty"`         // End of a seasonal achievement's season
+	LimitedEdition bool       `json:"limited_edition,omitempty"` // The season has ended
+}
+
+// newAchievementSummary summarizes an achievement
+func newAchievementSummary(achievement *model.Achievement) *AchievementSummary {
+	summary := &AchievementSummary{
+		ID:          achievement.ID,
+		Name:        achievement.Name,
+		Description: achievement.Description,
+		Level:       achievement.Level,
+		IconURL:     achievement.IconURL,
+		Tier:        achievement.Tier,
+
+		EndsAt:         achievement.EndsAt,
+		LimitedEdition: achievement.IsLimitedEdition(time.Now()),
+	}
+	if achievement.SeriesID != nil {
+		summary.SeriesID = *achievement.SeriesID
+	}
+	return summary
+}
+
+// newAchievementResponse converts an achievement for API responses
+func newAchievementResponse(achievement *model.Achievement) AchievementResponse {
+	// Parse rule JSON
+	rule, _ := achievement.GetRule()
+	var rules map[string]any
+	if rule != nil {
+		if rule.Match != nil {
+			rules = map[string]any{"match": rule.Match}
+		} else {
+			rules = map[string]any{
+				"type":       rule.Type,
+				"conditions": rule.Conditions,
+			}
+		}
+	}
+
+	// Parse NFT metadata JSON if available
+	var nftMetadata map[string]any
+	if achievement.NFTEnabled && achievement.NFTMetadata != "" {
+		metadata, _ := achievement.GetNFTMetadata()
+		if metadata != nil {
+			nftMetadata = map[string]any{
+				"name":        metadata.Name,
+				"description": metadata.Description,
+				"image":       metadata.Image,
+				"attributes":  metadata.Attributes,
+			}
+		}
+	}
+
+	response := AchievementResponse{
+		ID:          achievement.ID,
+		Name:        achievement.Name,
+		Description: achievement.Description,
+		IconURL:     achievement.IconURL,
+		Level:       achievement.Level,
+		Category:    achievement.BadgeType,
+		IsActive:    achievement.IsActive,
+		Rules:       rules,
+		NFTEnabled:  achievement.NFTEnabled,
+		NFTMetadata: nftMetadata,
+		Tier:        achievement.Tier,
+
+		StartsAt:       achievement.StartsAt,
+		EndsAt:         achievement.EndsAt,
+		LimitedEdition: achievement.IsLimitedEdition(time.Now()),
+	}
+	if achievement.SeriesID != nil {
+		response.SeriesID = *achievement.SeriesID
+	}
+	if achievement.Stats != nil {
+		response.Rarity = newAchievementRarity(achievement.Stats)
+	}
+	return response
 }
 
 // GetAllAchievements handles GET /api/v1/achievements
@@ -83,44 +207,40 @@ func (h *AchievementHandler) GetAllAchievements(c *gin.Context) {
 		return
 	}
 
-	// Convert to response format
+	// Convert to response format; the tiers of a series share one entry
 	var response []AchievementResponse
+	seriesIndex := make(map[string]int)
 	for _, achievement := range achievements {
-		// Parse rule JSON
-		rule, _ := achievement.GetRule()
-		var rules map[string]any
-		if rule != nil {
-			rules = map[string]any{
-				"type":       rule.Type,
-				"conditions": rule.Conditions,
-			}
+		entry := newAchievementResponse(&achievement)
+		if !achievement.IsTiered() || achievement.Series == nil {
+			response = append(response, entry)
+			continue
 		}
 
-		// Parse NFT metadata JSON if available
-		var nftMetadata map[string]any
-		if achievement.NFTEnabled && achievement.NFTMetadata != "" {
-			metadata, _ := achievement.GetNFTMetadata()
-			if metadata != nil {
-				nftMetadata = map[string]any{
-					"name":        metadata.Name,
-					"description": metadata.Description,
-					"image":       metadata.Image,
-					"attributes":  metadata.Attributes,
-				}
-			}
+		i, ok := seriesIndex[achievement.Series.ID]
+		if !ok {
+			i = len(response)
+			seriesIndex[achievement.Series.ID] = i
+			response = append(response, AchievementResponse{
+				ID:          achievement.Series.ID,
+				Name:        achievement.Series.Name,
+				Description: achievement.Series.Description,
+				IsActive:    true,
+			})
 		}
+		response[i].Tiers = append(response[i].Tiers, entry)
+	}
 
-		response = append(response, AchievementResponse{
-			ID:          achievement.ID,
-			Name:        achievement.Name,
-			Description: achievement.Description,
-			IconURL:     achievement.IconURL,
-			Level:       achievement.Level,
-			Category:    achievement.BadgeType,
-			IsActive:    achievement.IsActive,
-			Rules:       rules,
-			NFTMetadata: nftMetadata,
-		})
+	// A series is shown with the icon, level and category of its lowest tier
+	for i := range response {
+		tiers := response[i].Tiers
+		if len(tiers) == 0 {
+			continue
+		}
+		sort.Slice(tiers, func(a, b int) bool { return tiers[a].Tier < tiers[b].Tier })
+		response[i].IconURL = tiers[0].IconURL
+		response[i].Level = tiers[0].Level
+		response[i].Category = tiers[0].Category
 	}
 
 	c.JSON(http.StatusOK, SuccessResponse{
@@ -158,19 +278,15 @@ func (h *AchievementHandler) GetUserAchievements(c *gin.Context) {
 			eventData, _ = event.GetData()
 		}
 
-		achievementSummary := &AchievementSummary{
-			ID:          userAchievement.Achievement.ID,
-			Name:        userAchievement.Achievement.Name,
-			Description: userAchievement.Achievement.Description,
-			Level:       userAchievement.Achievement.Level,
-			IconURL:     userAchievement.Achievement.IconURL,
-		}
+		achievementSummary := newAchievementSummary(userAchievement.Achievement)
 
 		response = append(response, UserAchievementResponse{
 			ID:            userAchievement.ID,
 			UserID:        userAchievement.UserID,
 			AchievementID: userAchievement.AchievementID,
 			EarnedAt:      userAchievement.EarnedAt.Format("2006-01-02T15:04:05Z"),
+			NotifiedAt:    userAchievement.NotifiedAt,
+			ViewedAt:      userAchievement.ViewedAt,
 			EventData:     eventData,
 			Achievement:   achievementSummary,
 		})
@@ -182,6 +298,51 @@ func (h *AchievementHandler) GetUserAchievements(c *gin.Context) {
 	})
 }
 
+// AchievementProgressResponse is the user's progress towards one achievement
+type AchievementProgressResponse struct {
+	Achievement *AchievementSummary         `json:"achievement"`
+	Earned      bool                        `json:"earned"`
+	EarnedAt    *time.Time                  `json:"earned_at,omitempty"`
+	Locked      bool                        `json:"locked"`   // The tier below has not been earned yet
+	Revoked     bool                        `json:"revoked"`  // Taken back by an administrator
+	Progress    float64                     `json:"progress"` // 0.0-1.0
+	Conditions  []service.ConditionProgress `json:"conditions"`
+}
+
+// GetAchievementProgress handles GET /api/v1/achievements/progress
+func (h *AchievementHandler) GetAchievementProgress(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	progress, err := h.achievementService.GetAchievementProgress(userID)
+	if err != nil {
+		h.metricsService.RecordError("database_error", "achievement_progress")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "failed_to_get_achievement_progress",
+			Message: "Failed to retrieve achievement progress",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	response := make([]AchievementProgressResponse, 0, len(progress))
+	for _, p := range progress {
+		response = append(response, AchievementProgressResponse{
+			Achievement: newAchievementSummary(&p.Achievement),
+			Earned:      p.Earned,
+			Locked:      p.Locked,
+			Revoked:     p.Revoked,
+			EarnedAt:    p.EarnedAt,
+			Progress:    p.Progress,
+			Conditions:  p.Conditions,
+		})
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    response,
+	})
+}
+
 // EvaluateAchievements handles POST /api/v1/achievements/evaluate
 func (h *AchievementHandler) EvaluateAchievements(c *gin.Context) {
 	userID := middleware.MustGetCurrentUserID(c)
@@ -204,6 +365,69 @@ func (h *AchievementHandler) EvaluateAchievements(c *gin.Context) {
 	})
 }
 
+// AcknowledgeRequest marks achievement notifications as seen
+type AcknowledgeRequest struct {
+	IDs []string `json:"ids" validate:"required,min=1,max=100"` // User achievement IDs, as in the notifications
+}
+
+// GetUnreadCount handles GET /api/v1/achievements/unread-count
+func (h *AchievementHandler) GetUnreadCount(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	unread, err := h.achievementService.UnreadNotificationCount(userID)
+	if err != nil {
+		h.metricsService.RecordError("database_error", "achievements")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to count unread achievements",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    map[string]int64{"unread": unread},
+	})
+}
+
+// AcknowledgeAchievements handles POST /api/v1/achievements/ack
+func (h *AchievementHandler) AcknowledgeAchievements(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	var req AcknowledgeRequest
+	if !h.bindAchievementRequest(c, &req) {
+		return
+	}
+
+	acknowledged, err := h.achievementService.AcknowledgeNotifications(userID, req.IDs)
+	if err != nil {
+		h.metricsService.RecordError("database_error", "achievements")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to acknowledge achievements",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	unread, err := h.achievementService.UnreadNotificationCount(userID)
+	if err != nil {
+		h.metricsService.RecordError("database_error", "achievements")
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to count unread achievements",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    map[string]int64{"acknowledged": acknowledged, "unread": unread},
+	})
+}
+
 // ForceAwardRequest represents request for force awarding an achievement
 type ForceAwardRequest struct {
 	AchievementID string `json:"achievement_id" validate:"required"`
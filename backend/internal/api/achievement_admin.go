@@ -0,0 +1,375 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"paperplay/internal/middleware"
+	"paperplay/internal/model"
+	"paperplay/internal/service"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultDryRunLimit is the number of qualifying users a dry run lists by default
+const defaultDryRunLimit = 100
+
+// AchievementRequest creates or replaces an achievement
+type AchievementRequest struct {
+	ID          string                     `json:"id" validate:"omitempty,max=64"` // Create only; generated if omitted
+	Name        string                     `json:"name" validate:"required,max=100"`
+	Description string                     `json:"description" validate:"required,max=500"`
+	IconURL     string                     `json:"icon_url" validate:"omitempty,url"`
+	Level       int                        `json:"level" validate:"min=1,max=5"`
+	Category    string                     `json:"category" validate:"required,max=50"`
+	Rules       *model.AchievementRule     `json:"rules" validate:"required"`
+	NFTEnabled  bool                       `json:"nft_enabled"`
+	NFTMetadata *model.NFTMetadataTemplate `json:"nft_metadata"`
+	IsActive    bool                       `json:"is_active"` // New achievements are drafts unless set
+	SeriesID    string                     `json:"series_id" validate:"omitempty,max=64"`
+	Tier        int                        `json:"tier" validate:"min=0,max=100"`
+	StartsAt    *time.Time                 `json:"starts_at"` // Seasonal achievements only
+	EndsAt      *time.Time                 `json:"ends_at"`
+
+	// Notify users awarded by the backfill that runs when the achievement is
+	// activated or its rule changes
+	NotifyBackfill bool `json:"notify_backfill"`
+}
+
+// input converts the request for the achievement service
+func (r *AchievementRequest) input(c *gin.Context) *service.AchievementInput {
+	input := &service.AchievementInput{
+		ID:          r.ID,
+		Name:        r.Name,
+		Description: r.Description,
+		IconURL:     r.IconURL,
+		Level:       r.Level,
+		BadgeType:   r.Category,
+		Rule:        r.Rules,
+		NFTEnabled:  r.NFTEnabled,
+		NFTMetadata: r.NFTMetadata,
+		IsActive:    r.IsActive,
+		Tier:        r.Tier,
+		StartsAt:    r.StartsAt,
+		EndsAt:      r.EndsAt,
+		Backfill:    backfillOptions(c, r.NotifyBackfill),
+	}
+	if r.SeriesID != "" {
+		input.SeriesID = &r.SeriesID
+	}
+	return input
+}
+
+// backfillOptions describes a backfill started by the current administrator
+func backfillOptions(c *gin.Context, notify bool) service.BackfillOptions {
+	adminID, _ := middleware.GetCurrentUserID(c)
+	return service.BackfillOptions{Notify: notify, TriggeredBy: adminID}
+}
+
+// BackfillRequest starts a backfill of an achievement
+type BackfillRequest struct {
+	Notify bool `json:"notify"` // Send real-time notifications for the awards
+}
+
+// DryRunRequest asks who would qualify for a rule now. Either rules or
+// achievement_id is required; with both, the draft rules replace the achievement's.
+type DryRunRequest struct {
+	Rules         *model.AchievementRule `json:"rules"`
+	AchievementID string                 `json:"achievement_id"`
+	Limit         int                    `json:"limit" validate:"omitempty,min=1,max=1000"` // Users listed, default 100
+}
+
+// RevokeAchievementRequest takes an achievement back from a user
+type RevokeAchievementRequest struct {
+	Reason string `json:"reason" validate:"required,max=500"`
+}
+
+// writeAchievementAdminError maps achievement administration errors to HTTP responses
+func (h *AchievementHandler) writeAchievementAdminError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	code := "database_error"
+
+	switch {
+	case errors.Is(err, model.ErrInvalidRule), errors.Is(err, model.ErrUnknownRuleType):
+		status, code = http.StatusBadRequest, "invalid_rule"
+	case errors.Is(err, service.ErrInvalidTier):
+		status, code = http.StatusBadRequest, "invalid_tier"
+	case errors.Is(err, service.ErrInvalidSeason):
+		status, code = http.StatusBadRequest, "invalid_season"
+	case errors.Is(err, service.ErrSeriesNotFound):
+		status, code = http.StatusBadRequest, "series_not_found"
+	case errors.Is(err, service.ErrAchievementNotFound):
+		status, code = http.StatusNotFound, "achievement_not_found"
+	case errors.Is(err, service.ErrDuplicateTier):
+		status, code = http.StatusConflict, "duplicate_tier"
+	case errors.Is(err, service.ErrAchievementEarned):
+		status, code = http.StatusConflict, "achievement_earned"
+	case errors.Is(err, service.ErrBackfillNotFound):
+		status, code = http.StatusNotFound, "backfill_not_found"
+	case errors.Is(err, service.ErrAchievementInactive):
+		status, code = http.StatusConflict, "achievement_inactive"
+	case errors.Is(err, service.ErrBackfillRunning):
+		status, code = http.StatusConflict, "backfill_running"
+	case errors.Is(err, service.ErrUserAchievementNotFound):
+		status, code = http.StatusNotFound, "user_achievement_not_found"
+	case errors.Is(err, service.ErrAchievementRevoked):
+		status, code = http.StatusConflict, "achievement_revoked"
+	default:
+		h.metricsService.RecordError(code, "achievements")
+	}
+
+	c.JSON(status, ErrorResponse{
+		Error:   code,
+		Message: message,
+		Details: err.Error(),
+	})
+}
+
+// bindAchievementRequest parses and validates a request body
+func (h *AchievementHandler) bindAchievementRequest(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return false
+	}
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Validation failed",
+			Details: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// ListAdminAchievements handles GET /api/v1/admin/achievements
+func (h *AchievementHandler) ListAdminAchievements(c *gin.Context) {
+	achievements, err := h.achievementService.ListAchievements()
+	if err != nil {
+		h.writeAchievementAdminError(c, err, "Failed to list achievements")
+		return
+	}
+
+	response := make([]AchievementResponse, 0, len(achievements))
+	for i := range achievements {
+		response = append(response, newAchievementResponse(&achievements[i]))
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    response,
+	})
+}
+
+// GetAdminAchievement handles GET /api/v1/admin/achievements/{achievement_id}
+func (h *AchievementHandler) GetAdminAchievement(c *gin.Context) {
+	achievement, err := h.achievementService.GetAchievement(c.Param("achievement_id"))
+	if err != nil {
+		h.writeAchievementAdminError(c, err, "Failed to get achievement")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    newAchievementResponse(achievement),
+	})
+}
+
+// CreateAchievement handles POST /api/v1/admin/achievements
+func (h *AchievementHandler) CreateAchievement(c *gin.Context) {
+	var req AchievementRequest
+	if !h.bindAchievementRequest(c, &req) {
+		return
+	}
+
+	achievement, err := h.achievementService.CreateAchievement(req.input(c))
+	if err != nil {
+		h.writeAchievementAdminError(c, err, "Failed to create achievement")
+		return
+	}
+
+	c.JSON(http.StatusCreated, SuccessResponse{
+		Success: true,
+		Message: "Achievement created",
+		Data:    newAchievementResponse(achievement),
+	})
+}
+
+// UpdateAchievement handles PUT /api/v1/admin/achievements/{achievement_id}
+func (h *AchievementHandler) UpdateAchievement(c *gin.Context) {
+	var req AchievementRequest
+	if !h.bindAchievementRequest(c, &req) {
+		return
+	}
+
+	achievement, err := h.achievementService.UpdateAchievement(c.Param("achievement_id"), req.input(c))
+	if err != nil {
+		h.writeAchievementAdminError(c, err, "Failed to update achievement")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Achievement updated",
+		Data:    newAchievementResponse(achievement),
+	})
+}
+
+// DeleteAchievement handles DELETE /api/v1/admin/achievements/{achievement_id}
+func (h *AchievementHandler) DeleteAchievement(c *gin.Context) {
+	if err := h.achievementService.DeleteAchievement(c.Param("achievement_id")); err != nil {
+		h.writeAchievementAdminError(c, err, "Failed to delete achievement")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Achievement deleted",
+	})
+}
+
+// DryRunAchievement handles POST /api/v1/admin/achievements/dry-run
+func (h *AchievementHandler) DryRunAchievement(c *gin.Context) {
+	var req DryRunRequest
+	if !h.bindAchievementRequest(c, &req) {
+		return
+	}
+	if req.Rules == nil && req.AchievementID == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Either rules or achievement_id is required",
+		})
+		return
+	}
+	if req.Limit == 0 {
+		req.Limit = defaultDryRunLimit
+	}
+
+	result, err := h.achievementService.DryRunRule(req.Rules, req.AchievementID, req.Limit)
+	if err != nil {
+		h.writeAchievementAdminError(c, err, "Failed to simulate achievement")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    result,
+	})
+}
+
+// StartBackfill handles POST /api/v1/admin/achievements/{achievement_id}/backfills
+func (h *AchievementHandler) StartBackfill(c *gin.Context) {
+	var req BackfillRequest
+	// The body is optional
+	if c.Request.ContentLength > 0 && !h.bindAchievementRequest(c, &req) {
+		return
+	}
+
+	backfill, err := h.achievementService.StartBackfill(c.Param("achievement_id"), backfillOptions(c, req.Notify))
+	if err != nil {
+		h.writeAchievementAdminError(c, err, "Failed to start backfill")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, SuccessResponse{
+		Success: true,
+		Message: "Backfill started",
+		Data:    backfill,
+	})
+}
+
+// ListBackfills handles GET /api/v1/admin/achievements/{achievement_id}/backfills
+func (h *AchievementHandler) ListBackfills(c *gin.Context) {
+	backfills, err := h.achievementService.ListBackfills(c.Param("achievement_id"))
+	if err != nil {
+		h.writeAchievementAdminError(c, err, "Failed to list backfills")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    backfills,
+	})
+}
+
+// GetBackfill handles GET /api/v1/admin/achievements/{achievement_id}/backfills/{backfill_id}
+func (h *AchievementHandler) GetBackfill(c *gin.Context) {
+	backfill, err := h.achievementService.GetBackfill(c.Param("achievement_id"), c.Param("backfill_id"))
+	if err != nil {
+		h.writeAchievementAdminError(c, err, "Failed to get backfill")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data:    backfill,
+	})
+}
+
+// RevokeAchievement handles POST /api/v1/admin/users/{user_id}/achievements/{achievement_id}/revoke
+func (h *AchievementHandler) RevokeAchievement(c *gin.Context) {
+	var req RevokeAchievementRequest
+	if !h.bindAchievementRequest(c, &req) {
+		return
+	}
+
+	adminID, _ := middleware.GetCurrentUserID(c)
+	userAchievement, err := h.achievementService.RevokeAchievement(c.Param("user_id"), c.Param("achievement_id"), adminID, req.Reason)
+	if err != nil {
+		h.writeAchievementAdminError(c, err, "Failed to revoke achievement")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Achievement revoked",
+		Data:    userAchievement,
+	})
+}
+
+// GetAchievementAuditLog handles GET /api/v1/admin/achievements/audit-log
+func (h *AchievementHandler) GetAchievementAuditLog(c *gin.Context) {
+	page := 1
+	pageSize := 20
+
+	if p := c.Query("page"); p != "" {
+		if val, err := strconv.Atoi(p); err == nil && val > 0 {
+			page = val
+		}
+	}
+
+	if ps := c.Query("page_size"); ps != "" {
+		if val, err := strconv.Atoi(ps); err == nil && val > 0 && val <= 100 {
+			pageSize = val
+		}
+	}
+
+	entries, total, err := h.achievementService.ListAchievementAuditLog(service.AchievementAuditFilter{
+		UserID:        c.Query("user_id"),
+		AchievementID: c.Query("achievement_id"),
+		Action:        c.Query("action"),
+		Limit:         pageSize,
+		Offset:        (page - 1) * pageSize,
+	})
+	if err != nil {
+		h.writeAchievementAdminError(c, err, "Failed to retrieve achievement audit log")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Data: map[string]any{
+			"total":       total,
+			"page":        page,
+			"page_size":   pageSize,
+			"total_pages": int(math.Ceil(float64(total) / float64(pageSize))),
+			"entries":     entries,
+		},
+	})
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"paperplay/config"
@@ -9,10 +10,13 @@ import (
 	"paperplay/internal/model"
 	"paperplay/internal/service"
 	"paperplay/internal/websocket"
+	"strings"
 	"sync"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	gorillaws "github.com/gorilla/websocket"
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/zap"
 	"gorm.io/driver/sqlite"
@@ -29,8 +33,13 @@ func setupAchievementTestDB() *gorm.DB {
 
 	// Auto migrate the schema - include all required models
 	db.AutoMigrate(
+		&model.AchievementSeries{},
 		&model.Achievement{},
 		&model.UserAchievement{},
+		&model.UserAchievementProgress{},
+		&model.AchievementBackfill{},
+		&model.AchievementAuditLog{},
+		&model.AchievementStats{},
 		&model.Event{},
 		&model.User{},
 		&model.Subject{},
@@ -40,6 +49,7 @@ func setupAchievementTestDB() *gorm.DB {
 		&model.UserProgress{},
 		&model.UserAttempts{},
 		&model.RefreshToken{},
+		&model.RevokedSession{},
 		&model.NFTAsset{},
 		&model.RoadmapNode{},
 	)
@@ -96,6 +106,7 @@ func setupAchievementTestRouter(handler *AchievementHandler, systemHandler *Syst
 		{
 			achievements.GET("", handler.GetAllAchievements)
 			achievements.GET("/user", handler.GetUserAchievements)
+			achievements.GET("/progress", handler.GetAchievementProgress)
 			achievements.POST("/evaluate", handler.EvaluateAchievements)
 		}
 
@@ -163,6 +174,75 @@ func TestAchievementHandler_GetAllAchievements(t *testing.T) {
 	assert.True(t, ok)
 	// Since we're using real service, we might get all achievements
 	assert.GreaterOrEqual(t, len(data), 0)
+	assert.NotContains(t, w.Body.String(), `"rarity"`)
+
+	// Rarity is shown once computed
+	db.Create(&model.UserAchievement{UserID: "test-user-id", AchievementID: "test-achievement-1", EarnedAt: time.Now()})
+	db.Create(&model.UserAttempts{UserID: "test-user-id", StatDate: time.Now().Format("2006-01-02"), AttemptsTotal: 1})
+	_, err = achievementService.RefreshAchievementStats()
+	assert.NoError(t, err)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/achievements", nil))
+	var withRarity struct {
+		Data []AchievementResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &withRarity))
+	if assert.Len(t, withRarity.Data, 1) && assert.NotNil(t, withRarity.Data[0].Rarity) {
+		rarity := withRarity.Data[0].Rarity
+		assert.Equal(t, model.RarityCommon, rarity.Rarity)
+		assert.Equal(t, 1, rarity.HolderCount)
+		assert.Equal(t, float64(100), rarity.HolderPercent)
+		assert.Equal(t, "Test User", rarity.FirstEarner)
+	}
+}
+
+func TestAchievementHandler_GetAllAchievements_GroupsSeries(t *testing.T) {
+	db := setupAchievementTestDB()
+	seedAchievementTestData(db)
+	seriesID := "test-series"
+	db.Create(&model.AchievementSeries{ID: seriesID, Name: "闯关达人", Description: "完成越来越多的关卡"})
+	for _, tier := range []int{2, 1} {
+		db.Create(&model.Achievement{
+			ID:          fmt.Sprintf("test-series-%d", tier),
+			Name:        fmt.Sprintf("闯关达人 %d", tier),
+			Description: "完成关卡",
+			Level:       tier,
+			BadgeType:   "learning",
+			RuleJSON:    `{"match":{"source":"events","agg":"count","filter":{"event_type":"level_completed"},"op":">=","value":1}}`,
+			IsActive:    true,
+			SeriesID:    &seriesID,
+			Tier:        tier,
+		})
+	}
+
+	achievementService, metricsService, wsHub := createTestAchievementServices(db)
+	handler := NewAchievementHandler(db, achievementService, metricsService, wsHub)
+	router := setupAchievementTestRouter(handler, NewSystemHandler(db, metricsService, wsHub))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/achievements", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data []AchievementResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+
+	var series *AchievementResponse
+	for i := range response.Data {
+		assert.NotEqual(t, "test-series-1", response.Data[i].ID)
+		if response.Data[i].ID == seriesID {
+			series = &response.Data[i]
+		}
+	}
+	if assert.NotNil(t, series) && assert.Len(t, series.Tiers, 2) {
+		assert.Equal(t, "闯关达人", series.Name)
+		assert.Equal(t, "test-series-1", series.Tiers[0].ID)
+		assert.Equal(t, 2, series.Tiers[1].Tier)
+		assert.Equal(t, 1, series.Level)
+	}
 }
 
 func TestAchievementHandler_GetUserAchievements(t *testing.T) {
@@ -187,6 +267,54 @@ func TestAchievementHandler_GetUserAchievements(t *testing.T) {
 	assert.True(t, response.Success)
 }
 
+func TestAchievementHandler_GetAchievementProgress(t *testing.T) {
+	db := setupAchievementTestDB()
+	seedAchievementTestData(db)
+	db.Create(&model.Achievement{
+		ID:          "test-achievement-2",
+		Name:        "七日坚持",
+		Description: "最近 7 天中有 7 天答题",
+		BadgeType:   "streak",
+		RuleJSON:    `{"match":{"source":"attempts","field":"stat_date","agg":"distinct","window":"rolling","days":7,"op":">=","value":7}}`,
+		IsActive:    true,
+	})
+	for i := 0; i < 5; i++ {
+		db.Create(&model.UserAttempts{UserID: "test-user-id", StatDate: time.Now().AddDate(0, 0, -i).Format("2006-01-02"), AttemptsTotal: 1})
+	}
+	db.Create(&model.UserAchievement{UserID: "test-user-id", AchievementID: "test-achievement-1", EarnedAt: time.Now()})
+
+	achievementService, metricsService, wsHub := createTestAchievementServices(db)
+	handler := NewAchievementHandler(db, achievementService, metricsService, wsHub)
+	router := setupAchievementTestRouter(handler, NewSystemHandler(db, metricsService, wsHub))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/achievements/progress", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response struct {
+		Data []AchievementProgressResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	progress := make(map[string]AchievementProgressResponse)
+	for _, p := range response.Data {
+		progress[p.Achievement.ID] = p
+	}
+
+	earned := progress["test-achievement-1"]
+	assert.True(t, earned.Earned)
+	assert.Equal(t, 1.0, earned.Progress)
+
+	// Five of seven days
+	streak := progress["test-achievement-2"]
+	assert.False(t, streak.Earned)
+	assert.InDelta(t, 5.0/7, streak.Progress, 0.001)
+	if assert.Len(t, streak.Conditions, 1) {
+		assert.Equal(t, 5.0, streak.Conditions[0].Current)
+		assert.Equal(t, 7.0, streak.Conditions[0].Target)
+	}
+}
+
 func TestAchievementHandler_EvaluateAchievements(t *testing.T) {
 	db := setupAchievementTestDB()
 	seedAchievementTestData(db)
@@ -339,3 +467,274 @@ func TestSystemHandler_GetPrometheusMetrics_Unavailable(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "metrics_unavailable", response.Error)
 }
+
+func TestAchievementHandler_Admin(t *testing.T) {
+	db := setupAchievementTestDB()
+	seedAchievementTestData(db)
+	achievementService, metricsService, wsHub := createTestAchievementServices(db)
+	handler := NewAchievementHandler(db, achievementService, metricsService, wsHub)
+	// Backfills run in the background on the in-memory database's only connection
+	sqlDB, _ := db.DB()
+	sqlDB.SetMaxOpenConns(1)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", "admin-user-id")
+		c.Next()
+	})
+	admin := router.Group("/api/v1/admin/achievements")
+	admin.GET("", handler.ListAdminAchievements)
+	admin.POST("", handler.CreateAchievement)
+	admin.POST("/dry-run", handler.DryRunAchievement)
+	admin.GET("/audit-log", handler.GetAchievementAuditLog)
+	admin.GET("/:achievement_id", handler.GetAdminAchievement)
+	admin.PUT("/:achievement_id", handler.UpdateAchievement)
+	admin.DELETE("/:achievement_id", handler.DeleteAchievement)
+	admin.POST("/:achievement_id/backfills", handler.StartBackfill)
+	admin.GET("/:achievement_id/backfills", handler.ListBackfills)
+	admin.GET("/:achievement_id/backfills/:backfill_id", handler.GetBackfill)
+	router.POST("/api/v1/admin/users/:user_id/achievements/:achievement_id/revoke", handler.RevokeAchievement)
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	db.Create(&model.UserAttempts{UserID: "test-user-id", StatDate: time.Now().Format("2006-01-02"), AttemptsTotal: 12})
+	draft := `{"id":"twelve-answers","name":"十二问","description":"累计作答 12 题","level":1,"category":"learning",
+		"rules":{"match":{"source":"attempts","field":"attempts_total","agg":"sum","op":">=","value":12}}}`
+
+	// Rules are validated before anything is stored
+	w := do(http.MethodPost, "/api/v1/admin/achievements", strings.Replace(draft, "attempts_total", "nope", 1))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_rule")
+
+	// Seasons must end after they start
+	seasonal := strings.Replace(draft, `"category"`, `"starts_at":"2025-06-08T00:00:00Z","ends_at":"2025-06-01T00:00:00Z","category"`, 1)
+	w = do(http.MethodPost, "/api/v1/admin/achievements", seasonal)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid_season")
+
+	w = do(http.MethodPost, "/api/v1/admin/achievements", draft)
+	assert.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+	// Drafts are listed for administrators only
+	w = do(http.MethodGet, "/api/v1/admin/achievements/twelve-answers", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var created struct {
+		Data AchievementResponse `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.False(t, created.Data.IsActive)
+	active, err := achievementService.GetAllAchievements()
+	assert.NoError(t, err)
+	for _, a := range active {
+		assert.NotEqual(t, "twelve-answers", a.ID)
+	}
+
+	w = do(http.MethodPost, "/api/v1/admin/achievements/dry-run", `{"achievement_id":"twelve-answers"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var dryRun struct {
+		Data service.DryRunResult `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &dryRun))
+	assert.Equal(t, 1, dryRun.Data.QualifyingCount)
+	if assert.Len(t, dryRun.Data.Users, 1) {
+		assert.Equal(t, "test-user-id", dryRun.Data.Users[0].UserID)
+	}
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/admin/achievements/dry-run", `{}`).Code)
+
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPut, "/api/v1/admin/achievements/missing", draft).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/api/v1/admin/achievements/twelve-answers", "").Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/api/v1/admin/achievements/twelve-answers", "").Code)
+
+	// Activating an achievement backfills it for users who already qualify
+	assert.Equal(t, http.StatusCreated, do(http.MethodPost, "/api/v1/admin/achievements", draft).Code)
+	w = do(http.MethodPut, "/api/v1/admin/achievements/twelve-answers", strings.Replace(draft, `"category"`, `"is_active":true,"category"`, 1))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var backfills struct {
+		Data []model.AchievementBackfill `json:"data"`
+	}
+	assert.Eventually(t, func() bool {
+		w := do(http.MethodGet, "/api/v1/admin/achievements/twelve-answers/backfills", "")
+		return json.Unmarshal(w.Body.Bytes(), &backfills) == nil &&
+			len(backfills.Data) == 1 && backfills.Data[0].Status == model.BackfillCompleted
+	}, 2*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "admin-user-id", backfills.Data[0].TriggeredBy)
+	assert.Equal(t, 1, backfills.Data[0].AwardedCount)
+
+	w = do(http.MethodGet, "/api/v1/admin/achievements/twelve-answers/backfills/"+backfills.Data[0].ID, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusNotFound, do(http.MethodGet, "/api/v1/admin/achievements/twelve-answers/backfills/missing", "").Code)
+
+	// Backfills can also be started by hand
+	assert.Equal(t, http.StatusNotFound, do(http.MethodPost, "/api/v1/admin/achievements/missing/backfills", "").Code)
+	w = do(http.MethodPost, "/api/v1/admin/achievements/test-achievement-1/backfills", `{"notify":true}`)
+	assert.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+	var started struct {
+		Data model.AchievementBackfill `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &started))
+	assert.True(t, started.Data.Notify)
+	assert.Eventually(t, func() bool {
+		backfill, err := achievementService.GetBackfill("test-achievement-1", started.Data.ID)
+		return err == nil && backfill.IsFinished()
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Earned achievements cannot be deleted
+	assert.Equal(t, http.StatusConflict, do(http.MethodDelete, "/api/v1/admin/achievements/twelve-answers", "").Code)
+
+	// Revoking needs a reason, and can only be done once
+	revoke := "/api/v1/admin/users/test-user-id/achievements/twelve-answers/revoke"
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, revoke, `{}`).Code)
+	w = do(http.MethodPost, revoke, `{"reason":"scripted answers"}`)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var revoked struct {
+		Data model.UserAchievement `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &revoked))
+	assert.True(t, revoked.Data.IsRevoked())
+	assert.Equal(t, "admin-user-id", *revoked.Data.RevokedBy)
+	assert.Equal(t, http.StatusConflict, do(http.MethodPost, revoke, `{"reason":"again"}`).Code)
+	assert.Equal(t, http.StatusNotFound,
+		do(http.MethodPost, "/api/v1/admin/users/nobody/achievements/twelve-answers/revoke", `{"reason":"x"}`).Code)
+
+	w = do(http.MethodGet, "/api/v1/admin/achievements/audit-log?achievement_id=twelve-answers&page_size=1", "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var audit struct {
+		Data struct {
+			Total      int64                       `json:"total"`
+			TotalPages int                         `json:"total_pages"`
+			Entries    []model.AchievementAuditLog `json:"entries"`
+		} `json:"data"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &audit))
+	assert.Equal(t, int64(2), audit.Data.Total)
+	assert.Equal(t, 2, audit.Data.TotalPages)
+	if assert.Len(t, audit.Data.Entries, 1) {
+		assert.Equal(t, model.AuditActionRevoke, audit.Data.Entries[0].Action)
+	}
+
+	w = do(http.MethodGet, "/api/v1/admin/achievements/audit-log?action=award&achievement_id=twelve-answers", "")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &audit))
+	if assert.Len(t, audit.Data.Entries, 1) {
+		assert.Equal(t, model.AuditSourceBackfill, audit.Data.Entries[0].Source)
+		assert.Equal(t, "admin-user-id", *audit.Data.Entries[0].ActorID)
+	}
+}
+
+func TestAchievementHandler_NotificationAcks(t *testing.T) {
+	db := setupAchievementTestDB()
+	seedAchievementTestData(db)
+	achievementService, metricsService, wsHub := createTestAchievementServices(db)
+	handler := NewAchievementHandler(db, achievementService, metricsService, wsHub)
+	wsHub.SetNotificationStore(achievementService)
+	go wsHub.Run()
+
+	var user model.User
+	assert.NoError(t, db.First(&user, "id = ?", "test-user-id").Error)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &user)
+		c.Set("user_id", user.ID)
+		c.Next()
+	})
+	router.GET("/ws", func(c *gin.Context) { wsHub.ServeWS(c, nil) })
+	router.GET("/api/v1/achievements/unread-count", handler.GetUnreadCount)
+	router.POST("/api/v1/achievements/ack", handler.AcknowledgeAchievements)
+	server := httptest.NewServer(router)
+	defer server.Close()
+
+	do := func(method, path, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	unreadCount := func() int64 {
+		var response struct {
+			Data map[string]int64 `json:"data"`
+		}
+		w := do(http.MethodGet, "/api/v1/achievements/unread-count", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data["unread"]
+	}
+
+	// Awarded while offline: unread and not delivered
+	assert.NoError(t, achievementService.ForceAwardAchievement(user.ID, "test-achievement-1"))
+	var offline model.UserAchievement
+	assert.NoError(t, db.First(&offline, "user_id = ?", user.ID).Error)
+	assert.Nil(t, offline.NotifiedAt)
+	assert.Equal(t, int64(1), unreadCount())
+
+	// Messages may share a frame, one per line
+	conn, _, err := gorillaws.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	var queued []websocket.Message
+	next := func(messageType string) map[string]any {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		for {
+			for len(queued) > 0 {
+				msg := queued[0]
+				queued = queued[1:]
+				if msg.Type == messageType {
+					data, _ := msg.Data.(map[string]any)
+					return data
+				}
+			}
+			_, frame, err := conn.ReadMessage()
+			if !assert.NoError(t, err) {
+				return nil
+			}
+			for _, line := range strings.Split(string(frame), "\n") {
+				var msg websocket.Message
+				assert.NoError(t, json.Unmarshal([]byte(line), &msg))
+				queued = append(queued, msg)
+			}
+		}
+	}
+
+	// Redelivered on connect and marked delivered
+	redelivered := next("notification")
+	assert.Equal(t, offline.ID, redelivered["id"])
+	assert.Eventually(t, func() bool {
+		db.First(&offline, "id = ?", offline.ID)
+		return offline.NotifiedAt != nil
+	}, 2*time.Second, 10*time.Millisecond)
+
+	// Acknowledged over the socket
+	assert.NoError(t, conn.WriteJSON(websocket.AckMessage{Type: "ack", IDs: []string{offline.ID}}))
+	ack := next("ack")
+	assert.Equal(t, float64(1), ack["acknowledged"])
+	assert.Equal(t, float64(0), ack["unread"])
+	assert.Zero(t, unreadCount())
+
+	// Awarded while connected: delivered at once, then acknowledged over REST
+	second := &model.Achievement{ID: "second", Name: "第二", Description: "第二个成就", Level: 1, BadgeType: "learning", RuleJSON: `{}`, IsActive: true}
+	assert.NoError(t, db.Create(second).Error)
+	assert.NoError(t, achievementService.ForceAwardAchievement(user.ID, second.ID))
+	live := next("notification")
+	var online model.UserAchievement
+	assert.NoError(t, db.First(&online, "user_id = ? AND achievement_id = ?", user.ID, second.ID).Error)
+	assert.Equal(t, online.ID, live["id"])
+	assert.NotNil(t, online.NotifiedAt)
+	assert.Nil(t, online.ViewedAt)
+	assert.Equal(t, int64(1), unreadCount())
+
+	assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/achievements/ack", `{"ids":[]}`).Code)
+	w := do(http.MethodPost, "/api/v1/achievements/ack", fmt.Sprintf(`{"ids":[%q]}`, online.ID))
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Body.String(), `"acknowledged":1`)
+	assert.Zero(t, unreadCount())
+}
@@ -0,0 +1,129 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"paperplay/internal/middleware"
+	"paperplay/internal/model"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// APIKeyHandler handles administration of API keys
+type APIKeyHandler struct {
+	apiKeyService *middleware.APIKeyService
+	validator     *validator.Validate
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService *middleware.APIKeyService) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		validator:     validator.New(),
+	}
+}
+
+// CreateAPIKeyRequest represents a new API key
+type CreateAPIKeyRequest struct {
+	Name          string   `json:"name" validate:"required,max=100"`
+	Scopes        []string `json:"scopes" validate:"required,min=1,dive,required"`
+	ExpiresInDays int      `json:"expires_in_days" validate:"omitempty,min=1,max=3650"` // Omit for a key that never expires
+	RateLimit     int      `json:"rate_limit" validate:"omitempty,min=1,max=100000"`    // Requests per minute
+}
+
+// CreateAPIKeyResponse returns a new key together with its secret, which is shown only once
+type CreateAPIKeyResponse struct {
+	Key    *model.APIKey `json:"api_key"`
+	Secret string        `json:"key"`
+}
+
+// writeAPIKeyError maps API key errors to HTTP responses
+func writeAPIKeyError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	code := "database_error"
+
+	switch {
+	case errors.Is(err, middleware.ErrUnknownScope):
+		status, code = http.StatusBadRequest, "unknown_scope"
+	case errors.Is(err, middleware.ErrAPIKeyNotFound):
+		status, code = http.StatusNotFound, "api_key_not_found"
+	}
+
+	c.JSON(status, ErrorResponse{
+		Error:   code,
+		Message: message,
+		Details: err.Error(),
+	})
+}
+
+// CreateAPIKey handles POST /api/v1/admin/api-keys
+func (h *APIKeyHandler) CreateAPIKey(c *gin.Context) {
+	var req CreateAPIKeyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Validation failed",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	input := middleware.CreateAPIKeyInput{
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		RateLimit: req.RateLimit,
+	}
+	if req.ExpiresInDays > 0 {
+		expiresAt := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		input.ExpiresAt = &expiresAt
+	}
+
+	key, secret, err := h.apiKeyService.Create(middleware.MustGetCurrentUserID(c), input)
+	if err != nil {
+		writeAPIKeyError(c, err, "Failed to create API key")
+		return
+	}
+
+	c.JSON(http.StatusCreated, SuccessResponse{
+		Success: true,
+		Message: "API key created, store the key now as it is not shown again",
+		Data:    CreateAPIKeyResponse{Key: key, Secret: secret},
+	})
+}
+
+// ListAPIKeys handles GET /api/v1/admin/api-keys
+func (h *APIKeyHandler) ListAPIKeys(c *gin.Context) {
+	keys, err := h.apiKeyService.List()
+	if err != nil {
+		writeAPIKeyError(c, err, "Failed to list API keys")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"api_keys": keys,
+		"count":    len(keys),
+	})
+}
+
+// RevokeAPIKey handles DELETE /api/v1/admin/api-keys/{key_id}
+func (h *APIKeyHandler) RevokeAPIKey(c *gin.Context) {
+	if err := h.apiKeyService.Revoke(middleware.MustGetCurrentUserID(c), c.Param("key_id")); err != nil {
+		writeAPIKeyError(c, err, "Failed to revoke API key")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "API key revoked",
+	})
+}
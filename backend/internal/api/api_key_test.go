@@ -0,0 +1,152 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"paperplay/config"
+	"paperplay/internal/middleware"
+	"paperplay/internal/model"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyHandler(t *testing.T) {
+	db := setupTestDB()
+	require.NoError(t, db.AutoMigrate(&model.APIKey{}))
+	jwtService, _, _ := createTestServices(db)
+	apiKeyService := middleware.NewAPIKeyService(&config.Config{
+		APIKeys: config.APIKeyConfig{DefaultRateLimit: 100},
+	}, db, nil)
+	handler := NewAPIKeyHandler(apiKeyService)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	ok := func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"user_id": middleware.MustGetCurrentUserID(c)}) }
+	admin := router.Group("/api/v1/admin", apiKeyService.Middleware(jwtService.AuthMiddleware()))
+	admin.POST("/questions", middleware.RequireScope(model.ScopeContentWrite), ok)
+	admin.GET("/questions/stats", middleware.RequireScope(model.ScopeStatsRead), ok)
+	adminOnly := admin.Group("", middleware.RequireAdmin())
+	adminOnly.POST("/api-keys", handler.CreateAPIKey)
+	adminOnly.GET("/api-keys", handler.ListAPIKeys)
+	adminOnly.DELETE("/api-keys/:key_id", handler.RevokeAPIKey)
+
+	adminUser := &model.User{Email: "admin@example.com", DisplayName: "Admin", Role: model.RoleAdmin}
+	db.Create(adminUser)
+	user := &model.User{Email: "test@example.com", DisplayName: "Test User"}
+	db.Create(user)
+	adminToken, _, err := jwtService.GenerateTokenPair(adminUser, middleware.DeviceInfo{})
+	require.NoError(t, err)
+	userToken, _, err := jwtService.GenerateTokenPair(user, middleware.DeviceInfo{})
+	require.NoError(t, err)
+
+	do := func(method, path string, headers map[string]string, body any) *httptest.ResponseRecorder {
+		var payload []byte
+		if body != nil {
+			payload, _ = json.Marshal(body)
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	bearer := func(token string) map[string]string { return map[string]string{"Authorization": "Bearer " + token} }
+	withKey := func(key string) map[string]string { return map[string]string{middleware.APIKeyHeader: key} }
+	create := func(body CreateAPIKeyRequest) (string, string) {
+		w := do(http.MethodPost, "/api/v1/admin/api-keys", bearer(adminToken), body)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var response struct {
+			Data struct {
+				Key    model.APIKey `json:"api_key"`
+				Secret string       `json:"key"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data.Key.ID, response.Data.Secret
+	}
+
+	t.Run("Only administrators manage keys", func(t *testing.T) {
+		body := CreateAPIKeyRequest{Name: "importer", Scopes: []string{model.ScopeContentWrite}}
+		assert.Equal(t, http.StatusForbidden, do(http.MethodPost, "/api/v1/admin/api-keys", bearer(userToken), body).Code)
+
+		body.Scopes = []string{"everything"}
+		assert.Equal(t, http.StatusBadRequest, do(http.MethodPost, "/api/v1/admin/api-keys", bearer(adminToken), body).Code)
+	})
+
+	t.Run("Keys are stored hashed and only reach routes of their scopes", func(t *testing.T) {
+		keyID, secret := create(CreateAPIKeyRequest{Name: "importer", Scopes: []string{model.ScopeContentWrite}})
+		assert.Contains(t, secret, "pp_")
+
+		var stored model.APIKey
+		require.NoError(t, db.First(&stored, "id = ?", keyID).Error)
+		assert.NotContains(t, stored.KeyHash, secret)
+		assert.True(t, len(stored.Prefix) < len(secret))
+		assert.Nil(t, stored.LastUsedAt)
+
+		w := do(http.MethodPost, "/api/v1/admin/questions", withKey(secret), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), adminUser.ID)
+
+		require.NoError(t, db.First(&stored, "id = ?", keyID).Error)
+		assert.NotNil(t, stored.LastUsedAt)
+
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/v1/admin/questions/stats", withKey(secret), nil).Code)
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/v1/admin/api-keys", withKey(secret), nil).Code)
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodPost, "/api/v1/admin/questions", withKey(secret+"x"), nil).Code)
+
+		// Bearer tokens still work, for administrators only
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/admin/questions/stats", bearer(adminToken), nil).Code)
+		assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/v1/admin/questions/stats", bearer(userToken), nil).Code)
+	})
+
+	t.Run("Revoked and expired keys are refused", func(t *testing.T) {
+		keyID, secret := create(CreateAPIKeyRequest{Name: "stats", Scopes: []string{model.ScopeStatsRead}})
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/admin/questions/stats", withKey(secret), nil).Code)
+
+		assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/api/v1/admin/api-keys/"+keyID, bearer(adminToken), nil).Code)
+		assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/v1/admin/api-keys/"+keyID, bearer(adminToken), nil).Code)
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/admin/questions/stats", withKey(secret), nil).Code)
+
+		keyID, secret = create(CreateAPIKeyRequest{Name: "expiring", Scopes: []string{model.ScopeStatsRead}, ExpiresInDays: 1})
+		db.Model(&model.APIKey{}).Where("id = ?", keyID).Update("expires_at", time.Now().Add(-time.Minute))
+		w := do(http.MethodGet, "/api/v1/admin/questions/stats", withKey(secret), nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "expired")
+
+		w = do(http.MethodGet, "/api/v1/admin/api-keys", bearer(adminToken), nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), "key_hash")
+	})
+
+	t.Run("Each key has its own rate limit", func(t *testing.T) {
+		_, limited := create(CreateAPIKeyRequest{Name: "limited", Scopes: []string{model.ScopeStatsRead}, RateLimit: 2})
+		_, other := create(CreateAPIKeyRequest{Name: "other", Scopes: []string{model.ScopeStatsRead}})
+
+		for i := 0; i < 2; i++ {
+			assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/admin/questions/stats", withKey(limited), nil).Code)
+		}
+		w := do(http.MethodGet, "/api/v1/admin/questions/stats", withKey(limited), nil)
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.NotEmpty(t, w.Header().Get("Retry-After"))
+
+		assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/admin/questions/stats", withKey(other), nil).Code)
+	})
+
+	t.Run("Keys stop working when their creator loses the administrator role", func(t *testing.T) {
+		_, secret := create(CreateAPIKeyRequest{Name: "demoted", Scopes: []string{model.ScopeStatsRead}})
+		db.Model(adminUser).Update("role", model.RoleUser)
+		defer db.Model(adminUser).Update("role", model.RoleAdmin)
+
+		assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/admin/questions/stats", withKey(secret), nil).Code)
+	})
+}
@@ -0,0 +1,262 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"paperplay/internal/middleware"
+	"paperplay/internal/model"
+	"paperplay/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// GuestHandler handles guest play and upgrading guests to registered accounts
+type GuestHandler struct {
+	guestService   *service.GuestService
+	jwtService     *middleware.JWTService
+	accountService *service.AccountService
+	loginThrottler *middleware.LoginThrottler
+	validator      *validator.Validate
+}
+
+// NewGuestHandler creates a new guest handler; accountService and loginThrottler may be nil
+func NewGuestHandler(
+	guestService *service.GuestService,
+	jwtService *middleware.JWTService,
+	accountService *service.AccountService,
+	loginThrottler *middleware.LoginThrottler,
+) *GuestHandler {
+	return &GuestHandler{
+		guestService:   guestService,
+		jwtService:     jwtService,
+		accountService: accountService,
+		loginThrottler: loginThrottler,
+		validator:      validator.New(),
+	}
+}
+
+// GuestLoginRequest represents starting a guest session
+type GuestLoginRequest struct {
+	DisplayName string `json:"display_name" validate:"omitempty,max=50"`
+	DeviceName  string `json:"device_name" validate:"omitempty,max=100"` // Optional label shown in the session list
+}
+
+// UpgradeEmailRequest represents upgrading a guest with an email address and password.
+// For the address of an existing account, the password is that account's password.
+type UpgradeEmailRequest struct {
+	Email       string `json:"email" validate:"required,email"`
+	Password    string `json:"password" validate:"required,min=6"`
+	DisplayName string `json:"display_name" validate:"omitempty,max=50"`
+	DeviceName  string `json:"device_name" validate:"omitempty,max=100"`
+}
+
+// UpgradeWeChatRequest represents upgrading a guest with a wx.login code
+type UpgradeWeChatRequest struct {
+	Code        string `json:"code" validate:"required,max=128"`
+	DisplayName string `json:"display_name" validate:"omitempty,max=50"`
+	DeviceName  string `json:"device_name" validate:"omitempty,max=100"`
+}
+
+// UpgradeResponse is the authentication of the account a guest continues with
+type UpgradeResponse struct {
+	AuthResponse
+	Merged bool `json:"merged"` // The guest's data was merged into an existing account
+}
+
+// writeGuestThrottledError responds to a guest session refused because the client IP
+// has started too many recently
+func writeGuestThrottledError(c *gin.Context, err error) {
+	var throttled *middleware.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to check guest sessions",
+		})
+		return
+	}
+
+	retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+	c.JSON(http.StatusTooManyRequests, ErrorResponse{
+		Error:   "too_many_guests",
+		Message: "Too many guest sessions from this address, please wait before trying again",
+		Details: fmt.Sprintf("retry after %d seconds", retryAfter),
+	})
+}
+
+// writeGuestError maps guest service errors to HTTP responses
+func writeGuestError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	code := "database_error"
+
+	switch {
+	case errors.Is(err, service.ErrGuestsDisabled):
+		status, code = http.StatusServiceUnavailable, "guests_disabled"
+	case errors.Is(err, service.ErrNotGuest):
+		status, code = http.StatusConflict, "not_guest"
+	case errors.Is(err, service.ErrInvalidCredentials):
+		status, code = http.StatusUnauthorized, "invalid_credentials"
+	case errors.Is(err, service.ErrEmailTaken):
+		status, code = http.StatusConflict, "user_exists"
+	case errors.Is(err, service.ErrWeChatDisabled):
+		status, code = http.StatusServiceUnavailable, "wechat_disabled"
+	case errors.Is(err, service.ErrInvalidWeChatCode):
+		status, code = http.StatusUnauthorized, "invalid_wechat_code"
+	case errors.Is(err, service.ErrWeChatUnavailable):
+		status, code = http.StatusBadGateway, "wechat_unavailable"
+	case errors.Is(err, service.ErrUserNotFound):
+		status, code = http.StatusNotFound, "user_not_found"
+	}
+
+	c.JSON(status, ErrorResponse{
+		Error:   code,
+		Message: message,
+		Details: err.Error(),
+	})
+}
+
+// bindAndValidate binds a JSON body and validates it, writing the error response on failure
+func (h *GuestHandler) bindAndValidate(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return false
+	}
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Validation failed",
+			Details: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// authResponse starts a session for a user on the requesting device
+func (h *GuestHandler) authResponse(c *gin.Context, user *model.User, deviceName string) (*AuthResponse, bool) {
+	accessToken, refreshToken, err := h.jwtService.GenerateTokenPair(user, middleware.DeviceFromRequest(c, deviceName))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "token_generation_error",
+			Message: "Failed to generate authentication tokens",
+		})
+		return nil, false
+	}
+
+	return &AuthResponse{
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    15 * 60, // 15 minutes in seconds
+	}, true
+}
+
+// Login handles POST /api/v1/auth/guest
+func (h *GuestHandler) Login(c *gin.Context) {
+	var req GuestLoginRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	// Guests need no credentials, so their creation is throttled per client IP
+	ip := c.ClientIP()
+	if h.loginThrottler != nil {
+		if err := h.loginThrottler.CheckGuest(ip); err != nil {
+			writeGuestThrottledError(c, err)
+			return
+		}
+	}
+
+	user, err := h.guestService.CreateGuest(req.DisplayName)
+	if err != nil {
+		writeGuestError(c, err, "Failed to start guest session")
+		return
+	}
+	if h.loginThrottler != nil {
+		_ = h.loginThrottler.RecordGuest(user.ID, ip)
+	}
+
+	response, ok := h.authResponse(c, user, req.DeviceName)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusCreated, response)
+}
+
+// UpgradeWithEmail handles POST /api/v1/users/upgrade/email
+func (h *GuestHandler) UpgradeWithEmail(c *gin.Context) {
+	guestID := middleware.MustGetCurrentUserID(c)
+
+	var req UpgradeEmailRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	// Upgrading into an existing account checks its password, so it is throttled like a login
+	ip := c.ClientIP()
+	if h.loginThrottler != nil {
+		if err := h.loginThrottler.Check(req.Email, ip); err != nil {
+			writeLoginThrottledError(c, err)
+			return
+		}
+	}
+
+	result, err := h.guestService.UpgradeWithEmail(guestID, req.Email, req.Password, req.DisplayName)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCredentials) && h.loginThrottler != nil {
+			_ = h.loginThrottler.RecordFailure("", req.Email, ip)
+		}
+		writeGuestError(c, err, "Failed to upgrade guest account")
+		return
+	}
+
+	if result.Merged {
+		if h.loginThrottler != nil {
+			_ = h.loginThrottler.RecordSuccess(result.User.ID, result.User.Email, ip)
+		}
+	} else if h.accountService != nil {
+		// A failed send is logged; the user can request a new link
+		_ = h.accountService.SendVerificationEmail(result.User)
+	}
+
+	h.writeUpgrade(c, result, req.DeviceName)
+}
+
+// UpgradeWithWeChat handles POST /api/v1/users/upgrade/wechat
+func (h *GuestHandler) UpgradeWithWeChat(c *gin.Context) {
+	guestID := middleware.MustGetCurrentUserID(c)
+
+	var req UpgradeWeChatRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	result, err := h.guestService.UpgradeWithWeChat(c.Request.Context(), guestID, req.Code, req.DisplayName)
+	if err != nil {
+		writeGuestError(c, err, "Failed to upgrade guest account")
+		return
+	}
+
+	h.writeUpgrade(c, result, req.DeviceName)
+}
+
+// writeUpgrade responds with tokens for the account the guest continues with
+func (h *GuestHandler) writeUpgrade(c *gin.Context, result *service.UpgradeResult, deviceName string) {
+	response, ok := h.authResponse(c, result.User, deviceName)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, UpgradeResponse{
+		AuthResponse: *response,
+		Merged:       result.Merged,
+	})
+}
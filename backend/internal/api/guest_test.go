@@ -0,0 +1,144 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"paperplay/config"
+	"paperplay/internal/middleware"
+	"paperplay/internal/model"
+	"paperplay/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+func TestGuestHandler(t *testing.T) {
+	db := setupTestDB()
+	require.NoError(t, db.AutoMigrate(&model.AnswerRecord{}, &model.OutboxEvent{}, &model.AchievementAuditLog{}, &model.QuestionReport{}, &model.ExternalIdentity{}))
+	jwtService, userService, ethService := createTestServices(db)
+	guestService := service.NewGuestService(db, &config.Config{
+		Guest: config.GuestConfig{Enabled: true, RetentionDays: 30},
+	}, nil, nil, zap.NewNop())
+	guestHandler := NewGuestHandler(guestService, jwtService, nil, nil)
+	userHandler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/auth/guest", guestHandler.Login)
+	users := router.Group("/api/v1/users", jwtService.AuthMiddleware())
+	users.GET("/profile", userHandler.GetProfile)
+	users.POST("/upgrade/email", guestHandler.UpgradeWithEmail)
+	users.GET("/sessions", middleware.RequireRegistered(), userHandler.ListSessions)
+
+	do := func(method, path, token string, body any) *httptest.ResponseRecorder {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodPost, "/api/v1/auth/guest", "", GuestLoginRequest{DisplayName: "Player"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var guestAuth AuthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &guestAuth))
+	assert.Equal(t, model.RoleGuest, guestAuth.User.Role)
+
+	claims, err := jwtService.ValidateAccessToken(guestAuth.AccessToken)
+	require.NoError(t, err)
+	assert.True(t, claims.Guest)
+
+	// Guests can play but not manage an account
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/users/profile", guestAuth.AccessToken, nil).Code)
+	assert.Equal(t, http.StatusForbidden, do(http.MethodGet, "/api/v1/users/sessions", guestAuth.AccessToken, nil).Code)
+
+	w = do(http.MethodPost, "/api/v1/users/upgrade/email", guestAuth.AccessToken, UpgradeEmailRequest{
+		Email: "player@example.com", Password: "password123",
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var upgraded UpgradeResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &upgraded))
+	assert.False(t, upgraded.Merged)
+	assert.Equal(t, guestAuth.User.ID, upgraded.User.ID)
+	assert.Equal(t, model.RoleUser, upgraded.User.Role)
+
+	claims, err = jwtService.ValidateAccessToken(upgraded.AccessToken)
+	require.NoError(t, err)
+	assert.False(t, claims.Guest)
+
+	// The guest tokens stop working; the new ones reach account endpoints
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/users/profile", guestAuth.AccessToken, nil).Code)
+	assert.Equal(t, http.StatusOK, do(http.MethodGet, "/api/v1/users/sessions", upgraded.AccessToken, nil).Code)
+
+	// Registered users cannot upgrade again
+	w = do(http.MethodPost, "/api/v1/users/upgrade/email", upgraded.AccessToken, UpgradeEmailRequest{
+		Email: "other@example.com", Password: "password123",
+	})
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	// A second guest signing in to the same account merges into it
+	w = do(http.MethodPost, "/api/v1/auth/guest", "", GuestLoginRequest{})
+	require.Equal(t, http.StatusCreated, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &guestAuth))
+
+	w = do(http.MethodPost, "/api/v1/users/upgrade/email", guestAuth.AccessToken, UpgradeEmailRequest{
+		Email: "player@example.com", Password: "wrong-password",
+	})
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = do(http.MethodPost, "/api/v1/users/upgrade/email", guestAuth.AccessToken, UpgradeEmailRequest{
+		Email: "player@example.com", Password: "password123",
+	})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &upgraded))
+	assert.True(t, upgraded.Merged)
+	assert.NotEqual(t, guestAuth.User.ID, upgraded.User.ID)
+	assert.Equal(t, "player@example.com", upgraded.User.Email)
+}
+
+func TestGuestHandler_Throttling(t *testing.T) {
+	db := setupTestDB()
+	jwtService, _, _ := createTestServices(db)
+	guestService := service.NewGuestService(db, &config.Config{
+		Guest: config.GuestConfig{Enabled: true, RetentionDays: 30},
+	}, nil, nil, zap.NewNop())
+	throttler := middleware.NewLoginThrottler(&config.Config{Auth: config.AuthConfig{
+		MaxFailedAttempts: 5, MaxFailedAttemptsPerIP: 20, MaxGuestsPerIP: 2, LockoutDuration: 15, FailureWindow: 15,
+	}}, db, nil, nil)
+	guestHandler := NewGuestHandler(guestService, jwtService, nil, throttler)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.POST("/api/v1/auth/guest", guestHandler.Login)
+
+	start := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/guest", bytes.NewReader([]byte("{}")))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusCreated, start().Code)
+	assert.Equal(t, http.StatusCreated, start().Code)
+
+	// The address reached its limit, so further guests are refused until the lockout ends
+	w := start()
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	assert.Contains(t, w.Body.String(), "too_many_guests")
+
+	var guests int64
+	db.Model(&model.User{}).Where("role = ?", model.RoleGuest).Count(&guests)
+	assert.Equal(t, int64(2), guests)
+}
@@ -1,11 +1,14 @@
 package api
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
 	"math"
 	"net/http"
 	"paperplay/internal/middleware"
 	"paperplay/internal/model"
+	"paperplay/internal/service"
 	"strconv"
 	"time"
 
@@ -16,28 +19,45 @@ import (
 
 // LevelHandler handles level system HTTP requests
 type LevelHandler struct {
-	db        *gorm.DB
-	validator *validator.Validate
+	db              *gorm.DB
+	questionService *service.QuestionService
+	paperService    *service.PaperService
+	eventBus        *service.EventBus // Optional; without it achievements wait for the cron job
+	validator       *validator.Validate
 }
 
 // NewLevelHandler creates a new level handler
-func NewLevelHandler(db *gorm.DB) *LevelHandler {
+func NewLevelHandler(db *gorm.DB, eventBus *service.EventBus) *LevelHandler {
 	return &LevelHandler{
-		db:        db,
-		validator: validator.New(),
+		db:              db,
+		questionService: service.NewQuestionService(db),
+		paperService:    service.NewPaperService(db),
+		eventBus:        eventBus,
+		validator:       validator.New(),
+	}
+}
+
+// publish sends a domain event to the event bus. The request has already succeeded,
+// so a failure is only logged by the bus; the achievement check job catches up.
+func (h *LevelHandler) publish(userID, eventType string, data model.EventData) {
+	if h.eventBus != nil {
+		_ = h.eventBus.Publish(userID, eventType, data)
 	}
 }
 
 // SubmitAnswerRequest represents answer submission request
 type SubmitAnswerRequest struct {
 	QuestionID string `json:"question_id" validate:"required,uuid"`
-	AnswerJSON any    `json:"answer_json" validate:"required"`
+	AnswerJSON any    `json:"answer_json" validate:"required_unless=Skipped true"`
 	DurationMS int    `json:"duration_ms" validate:"min=0"`
+	Skipped    bool   `json:"skipped"` // Learner moved on without answering
 }
 
 // SubmitAnswerResponse represents answer submission response
 type SubmitAnswerResponse struct {
 	QuestionID  string `json:"question_id"`
+	AnswerID    string `json:"answer_id"`
+	RevisionID  string `json:"revision_id"`
 	IsCorrect   bool   `json:"is_correct"`
 	Score       int    `json:"score"`
 	TotalScore  int    `json:"total_score"`
@@ -136,9 +156,9 @@ func (h *LevelHandler) GetSubjectPapers(c *gin.Context) {
 func (h *LevelHandler) GetPaper(c *gin.Context) {
 	paperID := c.Param("paper_id")
 
-	var paper model.Paper
-	if err := h.db.Preload("Subject").First(&paper, "id = ?", paperID).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	paper, err := h.paperService.GetPaper(paperID)
+	if err != nil {
+		if errors.Is(err, service.ErrPaperNotFound) {
 			c.JSON(http.StatusNotFound, ErrorResponse{
 				Error:   "paper_not_found",
 				Message: "Paper not found",
@@ -286,7 +306,8 @@ func (h *LevelHandler) GetLevelQuestions(c *gin.Context) {
 	}
 
 	var questions []model.Question
-	if err := h.db.Where("level_id = ?", levelID).
+	if err := h.db.Scopes(model.PublishedQuestions).
+		Where("level_id = ?", levelID).
 		Order("created_at ASC").
 		Find(&questions).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
@@ -323,7 +344,7 @@ func (h *LevelHandler) GetQuestion(c *gin.Context) {
 	questionID := c.Param("question_id")
 
 	var question model.Question
-	if err := h.db.First(&question, "id = ?", questionID).Error; err != nil {
+	if err := h.db.Scopes(model.PublishedQuestions).First(&question, "id = ?", questionID).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, ErrorResponse{
 				Error:   "question_not_found",
@@ -397,7 +418,7 @@ func (h *LevelHandler) GetAllQuestions(c *gin.Context) {
 
 	// Get total count
 	var total int64
-	if err := h.db.Model(&model.Question{}).Count(&total).Error; err != nil {
+	if err := h.db.Model(&model.Question{}).Scopes(model.PublishedQuestions).Count(&total).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "database_error",
 			Message: "Failed to count questions",
@@ -408,7 +429,7 @@ func (h *LevelHandler) GetAllQuestions(c *gin.Context) {
 
 	// Get questions with pagination and sorting
 	var questions []model.Question
-	if err := h.db.
+	if err := h.db.Scopes(model.PublishedQuestions).
 		Order(fmt.Sprintf("%s %s", sortBy, sortOrder)).
 		Offset(offset).
 		Limit(pageSize).
@@ -571,6 +592,8 @@ func (h *LevelHandler) StartLevel(c *gin.Context) {
 		}
 	}
 
+	h.publish(userID, model.EventSessionStarted, model.EventData{"level_id": levelID})
+
 	c.JSON(http.StatusOK, SuccessResponse{
 		Success: true,
 		Message: "Level started",
@@ -609,7 +632,8 @@ func (h *LevelHandler) SubmitAnswer(c *gin.Context) {
 
 	// Check if question exists and belongs to the level
 	var question model.Question
-	if err := h.db.Where("id = ? AND level_id = ?", req.QuestionID, levelID).First(&question).Error; err != nil {
+	if err := h.db.Scopes(model.PublishedQuestions).
+		Where("id = ? AND level_id = ?", req.QuestionID, levelID).First(&question).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, ErrorResponse{
 				Error:   "question_not_found",
@@ -625,37 +649,89 @@ func (h *LevelHandler) SubmitAnswer(c *gin.Context) {
 		return
 	}
 
-	// Convert answer to string for validation
-	userAnswer := ""
-	switch v := req.AnswerJSON.(type) {
-	case string:
-		userAnswer = v
-	case float64:
-		userAnswer = string(rune(int(v)))
-	case int:
-		userAnswer = string(rune(v))
-	default:
-		// For complex answers, convert to JSON string
-		userAnswer = c.GetString("answer_json")
-	}
-
-	// Check if answer is correct
-	isCorrect, err := question.IsCorrectAnswer(userAnswer)
+	// Grade against the published revision so the answer can be explained later
+	revision, err := h.questionService.EnsureBaseRevision(&question)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
-			Error:   "answer_validation_error",
-			Message: "Failed to validate answer",
+			Error:   "database_error",
+			Message: "Failed to resolve question revision",
 			Details: err.Error(),
 		})
 		return
 	}
+	gradedQuestion := revision.AsQuestion()
+
+	// Skipped questions are recorded for analytics but never graded
+	isCorrect := false
+	if !req.Skipped {
+		// Convert answer to string for validation
+		userAnswer := service.AnswerValue(req.AnswerJSON)
+
+		// Check if answer is correct
+		isCorrect, err = gradedQuestion.IsCorrectAnswer(userAnswer)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "answer_validation_error",
+				Message: "Failed to validate answer",
+				Details: err.Error(),
+			})
+			return
+		}
+	}
 
 	// Calculate score
 	score := 0
 	if isCorrect {
-		score = question.Score
+		score = gradedQuestion.Score
+	}
+
+	// Only the first answer to a question counts as a first try
+	var previousAnswers int64
+	if err := h.db.Model(&model.AnswerRecord{}).
+		Where("user_id = ? AND question_id = ?", userID, question.ID).
+		Count(&previousAnswers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to check previous answers",
+			Details: err.Error(),
+		})
+		return
 	}
 
+	// Record the answer against the revision the learner saw
+	var answerJSON []byte
+	if !req.Skipped {
+		answerJSON, _ = json.Marshal(req.AnswerJSON)
+	}
+	record := &model.AnswerRecord{
+		UserID:     userID,
+		LevelID:    levelID,
+		QuestionID: question.ID,
+		RevisionID: revision.ID,
+		AnswerJSON: string(answerJSON),
+		IsCorrect:  isCorrect,
+		Score:      score,
+		DurationMS: req.DurationMS,
+		Skipped:    req.Skipped,
+	}
+	if err := h.questionService.RecordAnswer(record); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to record answer",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	h.publish(userID, model.EventQuestionAnswered, model.EventData{
+		"level_id":    levelID,
+		"question_id": question.ID,
+		"correct":     isCorrect,
+		"first_try":   previousAnswers == 0,
+		"duration_ms": req.DurationMS,
+		"skipped":     req.Skipped,
+	})
+
 	// Get current total score for the level
 	var currentProgress model.UserProgress
 	totalScore := score
@@ -664,7 +740,7 @@ func (h *LevelHandler) SubmitAnswer(c *gin.Context) {
 	}
 
 	// Get answer explanation if available
-	answer, _ := question.GetAnswer()
+	answer, _ := gradedQuestion.GetAnswer()
 	explanation := ""
 	if answer != nil {
 		explanation = answer.Explanation
@@ -675,6 +751,8 @@ func (h *LevelHandler) SubmitAnswer(c *gin.Context) {
 		Message: "Answer submitted",
 		Data: SubmitAnswerResponse{
 			QuestionID:  req.QuestionID,
+			AnswerID:    record.ID,
+			RevisionID:  revision.ID,
 			IsCorrect:   isCorrect,
 			Score:       score,
 			TotalScore:  totalScore,
@@ -718,7 +796,8 @@ func (h *LevelHandler) CompleteLevel(c *gin.Context) {
 
 	// Calculate total possible score for the level
 	var totalPossibleScore int
-	h.db.Model(&model.Question{}).Where("level_id = ?", levelID).Select("COALESCE(SUM(score), 0)").Scan(&totalPossibleScore)
+	h.db.Model(&model.Question{}).Scopes(model.PublishedQuestions).
+		Where("level_id = ?", levelID).Select("COALESCE(SUM(score), 0)").Scan(&totalPossibleScore)
 
 	// Calculate stars based on score percentage
 	stars := 0
@@ -748,6 +827,12 @@ func (h *LevelHandler) CompleteLevel(c *gin.Context) {
 		return
 	}
 
+	h.publish(userID, model.EventLevelCompleted, model.EventData{
+		"level_id": levelID,
+		"score":    progress.Score,
+		"stars":    stars,
+	})
+
 	c.JSON(http.StatusOK, SuccessResponse{
 		Success: true,
 		Message: "Level completed",
@@ -5,11 +5,14 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"paperplay/config"
 	"paperplay/internal/model"
+	"paperplay/internal/service"
 	"testing"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -21,11 +24,14 @@ func setupLevelTestDB() *gorm.DB {
 	db.AutoMigrate(
 		&model.Subject{},
 		&model.Paper{},
+		&model.Author{},
 		&model.Level{},
 		&model.Question{},
 		&model.RoadmapNode{},
 		&model.UserProgress{},
 		&model.User{},
+		&model.QuestionRevision{},
+		&model.AnswerRecord{},
 	)
 
 	return db
@@ -136,7 +142,7 @@ func TestLevelHandler_GetAllSubjects(t *testing.T) {
 	db := setupLevelTestDB()
 	seedLevelTestData(db)
 
-	handler := NewLevelHandler(db)
+	handler := NewLevelHandler(db, nil)
 	router := setupLevelTestRouter(handler)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/subjects", nil)
@@ -161,7 +167,7 @@ func TestLevelHandler_GetSubject(t *testing.T) {
 	db := setupLevelTestDB()
 	seedLevelTestData(db)
 
-	handler := NewLevelHandler(db)
+	handler := NewLevelHandler(db, nil)
 	router := setupLevelTestRouter(handler)
 
 	tests := []struct {
@@ -210,7 +216,7 @@ func TestLevelHandler_GetSubjectPapers(t *testing.T) {
 	db := setupLevelTestDB()
 	seedLevelTestData(db)
 
-	handler := NewLevelHandler(db)
+	handler := NewLevelHandler(db, nil)
 	router := setupLevelTestRouter(handler)
 
 	tests := []struct {
@@ -259,7 +265,7 @@ func TestLevelHandler_GetPaper(t *testing.T) {
 	db := setupLevelTestDB()
 	seedLevelTestData(db)
 
-	handler := NewLevelHandler(db)
+	handler := NewLevelHandler(db, nil)
 	router := setupLevelTestRouter(handler)
 
 	tests := []struct {
@@ -308,7 +314,7 @@ func TestLevelHandler_GetPaperLevel(t *testing.T) {
 	db := setupLevelTestDB()
 	seedLevelTestData(db)
 
-	handler := NewLevelHandler(db)
+	handler := NewLevelHandler(db, nil)
 	router := setupLevelTestRouter(handler)
 
 	tests := []struct {
@@ -357,7 +363,7 @@ func TestLevelHandler_StartLevel(t *testing.T) {
 	db := setupLevelTestDB()
 	seedLevelTestData(db)
 
-	handler := NewLevelHandler(db)
+	handler := NewLevelHandler(db, nil)
 	router := setupLevelTestRouter(handler)
 
 	tests := []struct {
@@ -416,7 +422,7 @@ func TestLevelHandler_SubmitAnswer(t *testing.T) {
 	}
 	db.Create(progress)
 
-	handler := NewLevelHandler(db)
+	handler := NewLevelHandler(db, nil)
 	router := setupLevelTestRouter(handler)
 
 	tests := []struct {
@@ -447,6 +453,26 @@ func TestLevelHandler_SubmitAnswer(t *testing.T) {
 			expectedStatus: http.StatusNotFound,
 			expectedError:  "question_not_found",
 		},
+		{
+			name:    "Skipped question",
+			levelID: "550e8400-e29b-41d4-a716-446655440003",
+			payload: SubmitAnswerRequest{
+				QuestionID: "550e8400-e29b-41d4-a716-446655440004",
+				DurationMS: 5000,
+				Skipped:    true,
+			},
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:    "Missing answer",
+			levelID: "550e8400-e29b-41d4-a716-446655440003",
+			payload: SubmitAnswerRequest{
+				QuestionID: "550e8400-e29b-41d4-a716-446655440004",
+				DurationMS: 5000,
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectedError:  "validation_error",
+		},
 		{
 			name:    "Missing question ID",
 			levelID: "550e8400-e29b-41d4-a716-446655440003",
@@ -499,7 +525,7 @@ func TestLevelHandler_CompleteLevel(t *testing.T) {
 	}
 	db.Create(progress)
 
-	handler := NewLevelHandler(db)
+	handler := NewLevelHandler(db, nil)
 	router := setupLevelTestRouter(handler)
 
 	tests := []struct {
@@ -544,11 +570,88 @@ func TestLevelHandler_CompleteLevel(t *testing.T) {
 	}
 }
 
+func TestLevelHandler_PublishesEvents(t *testing.T) {
+	db := setupLevelTestDB()
+	db.AutoMigrate(&model.Event{}, &model.OutboxEvent{})
+	seedLevelTestData(db)
+
+	// The bus is not started, so published events stay in the outbox
+	bus := service.NewEventBus(db, &config.EventBusConfig{Workers: 1, QueueSize: 8, PollInterval: 5, Lease: 30, MaxAttempts: 5},
+		func(*model.OutboxEvent) error { return nil }, zap.NewNop())
+	router := setupLevelTestRouter(NewLevelHandler(db, bus))
+
+	levelID := "550e8400-e29b-41d4-a716-446655440003"
+	post := func(path string, body any) {
+		payload, _ := json.Marshal(body)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/levels/"+levelID+path, bytes.NewBuffer(payload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, path)
+	}
+	answer := SubmitAnswerRequest{QuestionID: "550e8400-e29b-41d4-a716-446655440004", AnswerJSON: "Option A", DurationMS: 2000}
+	post("/start", nil)
+	post("/submit", answer)
+	post("/submit", answer)
+	post("/complete", nil)
+
+	var outbox []model.OutboxEvent
+	assert.NoError(t, db.Order("created_at").Find(&outbox).Error)
+	var types []string
+	for _, e := range outbox {
+		types = append(types, e.EventType)
+		assert.Equal(t, model.OutboxPending, e.Status)
+	}
+	assert.Equal(t, []string{model.EventSessionStarted, model.EventQuestionAnswered, model.EventQuestionAnswered, model.EventLevelCompleted}, types)
+
+	// Only the first answer to a question is a first try
+	first, _ := outbox[1].GetData()
+	second, _ := outbox[2].GetData()
+	assert.Equal(t, true, first["first_try"])
+	assert.Equal(t, false, second["first_try"])
+	assert.Equal(t, true, first["correct"])
+
+	var logged int64
+	db.Model(&model.Event{}).Where("level_id = ?", levelID).Count(&logged)
+	assert.Equal(t, int64(4), logged)
+}
+
+func TestLevelHandler_SubmitFailsWithoutPreviousAnswers(t *testing.T) {
+	db := setupLevelTestDB()
+	db.AutoMigrate(&model.Event{}, &model.OutboxEvent{})
+	seedLevelTestData(db)
+
+	bus := service.NewEventBus(db, &config.EventBusConfig{Workers: 1, QueueSize: 8, PollInterval: 5, Lease: 30, MaxAttempts: 5},
+		func(*model.OutboxEvent) error { return nil }, zap.NewNop())
+	router := setupLevelTestRouter(NewLevelHandler(db, bus))
+
+	levelID := "550e8400-e29b-41d4-a716-446655440003"
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/levels/"+levelID+"/start", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Without previous answers to check, the answer is neither graded nor published
+	assert.NoError(t, db.Migrator().DropTable(&model.AnswerRecord{}))
+	answer := SubmitAnswerRequest{QuestionID: "550e8400-e29b-41d4-a716-446655440004", AnswerJSON: "Option A", DurationMS: 2000}
+	payload, _ := json.Marshal(answer)
+	req = httptest.NewRequest(http.MethodPost, "/api/v1/levels/"+levelID+"/submit", bytes.NewBuffer(payload))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "Failed to check previous answers")
+
+	var answered int64
+	db.Model(&model.OutboxEvent{}).Where("event_type = ?", model.EventQuestionAnswered).Count(&answered)
+	assert.Zero(t, answered)
+}
+
 func TestLevelHandler_GetLevelQuestions(t *testing.T) {
 	db := setupLevelTestDB()
 	seedLevelTestData(db)
 
-	handler := NewLevelHandler(db)
+	handler := NewLevelHandler(db, nil)
 	router := setupLevelTestRouter(handler)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/levels/550e8400-e29b-41d4-a716-446655440003/questions", nil)
@@ -567,7 +670,7 @@ func TestLevelHandler_GetQuestion(t *testing.T) {
 	db := setupLevelTestDB()
 	seedLevelTestData(db)
 
-	handler := NewLevelHandler(db)
+	handler := NewLevelHandler(db, nil)
 	router := setupLevelTestRouter(handler)
 
 	tests := []struct {
@@ -616,7 +719,7 @@ func TestLevelHandler_GetSubjectRoadmap(t *testing.T) {
 	db := setupLevelTestDB()
 	seedLevelTestData(db)
 
-	handler := NewLevelHandler(db)
+	handler := NewLevelHandler(db, nil)
 	router := setupLevelTestRouter(handler)
 
 	tests := []struct {
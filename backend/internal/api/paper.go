@@ -0,0 +1,146 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"paperplay/internal/service"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// citationContentTypes maps citation formats to their MIME types
+var citationContentTypes = map[string]string{
+	service.CitationFormatBibTeX: "application/x-bibtex; charset=utf-8",
+	service.CitationFormatRIS:    "application/x-research-info-systems; charset=utf-8",
+}
+
+// citationExtensions maps citation formats to file extensions
+var citationExtensions = map[string]string{
+	service.CitationFormatBibTeX: ".bib",
+	service.CitationFormatRIS:    ".ris",
+}
+
+// PaperHandler handles paper citation HTTP requests
+type PaperHandler struct {
+	paperService *service.PaperService
+	validator    *validator.Validate
+}
+
+// NewPaperHandler creates a new paper handler
+func NewPaperHandler(paperService *service.PaperService) *PaperHandler {
+	return &PaperHandler{
+		paperService: paperService,
+		validator:    validator.New(),
+	}
+}
+
+// ImportPapersRequest represents a BibTeX or RIS import into a subject
+type ImportPapersRequest struct {
+	SubjectID string `json:"subject_id" validate:"required"`
+	Format    string `json:"format" validate:"required,oneof=bibtex ris"`
+	Content   string `json:"content" validate:"required,max=5000000"`
+}
+
+// writePaperError maps paper service errors to HTTP responses
+func writePaperError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	code := "database_error"
+
+	switch {
+	case errors.Is(err, service.ErrPaperNotFound):
+		status, code = http.StatusNotFound, "paper_not_found"
+	case errors.Is(err, service.ErrSubjectNotFound):
+		status, code = http.StatusNotFound, "subject_not_found"
+	case errors.Is(err, service.ErrPaperSubjectConflict):
+		status, code = http.StatusConflict, "paper_subject_conflict"
+	case errors.Is(err, service.ErrUnsupportedCitationFormat):
+		status, code = http.StatusBadRequest, "unsupported_format"
+	case errors.Is(err, service.ErrCitationParse):
+		status, code = http.StatusBadRequest, "invalid_citation"
+	}
+
+	c.JSON(status, ErrorResponse{
+		Error:   code,
+		Message: message,
+		Details: err.Error(),
+	})
+}
+
+// citationFormat reads the format query parameter, defaulting to BibTeX
+func citationFormat(c *gin.Context) string {
+	return strings.ToLower(c.DefaultQuery("format", service.CitationFormatBibTeX))
+}
+
+// writeCitation writes a citation file, as an attachment if download=true
+func writeCitation(c *gin.Context, format, filename, body string) {
+	if c.Query("download") == "true" {
+		c.Header("Content-Disposition", `attachment; filename="`+filename+citationExtensions[format]+`"`)
+	}
+	c.Data(http.StatusOK, citationContentTypes[format], []byte(body))
+}
+
+// CitePaper handles GET /api/v1/papers/{paper_id}/cite?format=bibtex|ris
+func (h *PaperHandler) CitePaper(c *gin.Context) {
+	format := citationFormat(c)
+
+	paper, citation, err := h.paperService.Cite(c.Param("paper_id"), format)
+	if err != nil {
+		writePaperError(c, err, "Failed to cite paper")
+		return
+	}
+
+	writeCitation(c, format, service.CitationKey(paper), citation)
+}
+
+// ImportPapers handles POST /api/v1/admin/papers/import
+func (h *PaperHandler) ImportPapers(c *gin.Context) {
+	var req ImportPapersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request format",
+			Details: err.Error(),
+		})
+		return
+	}
+	if err := h.validator.Struct(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Request validation failed",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.paperService.Import(req.Format, req.Content, req.SubjectID)
+	if err != nil {
+		writePaperError(c, err, "Failed to import papers")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Papers imported successfully",
+		Data:    result,
+	})
+}
+
+// ExportPapers handles GET /api/v1/admin/papers/export?format=&subject_id=
+func (h *PaperHandler) ExportPapers(c *gin.Context) {
+	format := citationFormat(c)
+	subjectID := c.Query("subject_id")
+
+	body, err := h.paperService.Export(format, subjectID)
+	if err != nil {
+		writePaperError(c, err, "Failed to export papers")
+		return
+	}
+
+	filename := "papers"
+	if subjectID != "" {
+		filename = "papers-" + subjectID
+	}
+	writeCitation(c, format, filename, body)
+}
@@ -0,0 +1,447 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"paperplay/internal/middleware"
+	"paperplay/internal/model"
+	"paperplay/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"gorm.io/gorm"
+)
+
+// QuestionHandler handles question authoring and answer review HTTP requests
+type QuestionHandler struct {
+	db              *gorm.DB
+	questionService *service.QuestionService
+	statsService    *service.QuestionStatsService
+	validator       *validator.Validate
+}
+
+// NewQuestionHandler creates a new question handler
+func NewQuestionHandler(db *gorm.DB, questionService *service.QuestionService, statsService *service.QuestionStatsService) *QuestionHandler {
+	return &QuestionHandler{
+		db:              db,
+		questionService: questionService,
+		statsService:    statsService,
+		validator:       validator.New(),
+	}
+}
+
+// CreateQuestionRequest represents a request to author a new question
+type CreateQuestionRequest struct {
+	LevelID     string `json:"level_id" validate:"required"`
+	Subtitle    string `json:"subtitle"`
+	Stem        string `json:"stem" validate:"required"`
+	ContentJSON string `json:"content_json" validate:"required"`
+	AnswerJSON  string `json:"answer_json" validate:"required"`
+	Score       int    `json:"score" validate:"min=0"`
+	ChangeNote  string `json:"change_note"`
+}
+
+// ReviewRevisionRequest represents a reviewer decision on a revision
+type ReviewRevisionRequest struct {
+	Note string `json:"note"`
+}
+
+// writeQuestionError maps question service errors to HTTP responses
+func writeQuestionError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	code := "database_error"
+
+	switch {
+	case errors.Is(err, service.ErrQuestionNotFound):
+		status, code = http.StatusNotFound, "question_not_found"
+	case errors.Is(err, service.ErrRevisionNotFound):
+		status, code = http.StatusNotFound, "revision_not_found"
+	case errors.Is(err, service.ErrAnswerRecordNotFound):
+		status, code = http.StatusNotFound, "answer_not_found"
+	case errors.Is(err, service.ErrRevisionNotEditable):
+		status, code = http.StatusConflict, "revision_not_editable"
+	case errors.Is(err, service.ErrRevisionInvalidStatus):
+		status, code = http.StatusConflict, "invalid_revision_status"
+	case errors.Is(err, service.ErrPendingRevisionExists):
+		status, code = http.StatusConflict, "pending_revision_exists"
+	case errors.Is(err, service.ErrRevisionContentInvalid):
+		status, code = http.StatusBadRequest, "invalid_revision_content"
+	}
+
+	c.JSON(status, ErrorResponse{
+		Error:   code,
+		Message: message,
+		Details: err.Error(),
+	})
+}
+
+// CreateQuestion handles POST /api/v1/admin/questions
+func (h *QuestionHandler) CreateQuestion(c *gin.Context) {
+	user := middleware.MustGetCurrentUser(c)
+
+	var req CreateQuestionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Validation failed",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	// Check if level exists
+	var level model.Level
+	if err := h.db.First(&level, "id = ?", req.LevelID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "level_not_found",
+				Message: "Level not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to verify level",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	question, revision, err := h.questionService.CreateDraftQuestion(req.LevelID, &service.RevisionInput{
+		Subtitle:    &req.Subtitle,
+		Stem:        &req.Stem,
+		ContentJSON: &req.ContentJSON,
+		AnswerJSON:  &req.AnswerJSON,
+		Score:       &req.Score,
+		ChangeNote:  req.ChangeNote,
+	}, user.ID)
+	if err != nil {
+		writeQuestionError(c, err, "Failed to create question")
+		return
+	}
+
+	c.JSON(http.StatusCreated, SuccessResponse{
+		Success: true,
+		Message: "Draft question created",
+		Data: map[string]any{
+			"question": question,
+			"revision": revision,
+		},
+	})
+}
+
+// GetQuestionRevisions handles GET /api/v1/admin/questions/{question_id}/revisions
+func (h *QuestionHandler) GetQuestionRevisions(c *gin.Context) {
+	revisions, err := h.questionService.GetRevisions(c.Param("question_id"))
+	if err != nil {
+		writeQuestionError(c, err, "Failed to retrieve revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Revisions retrieved successfully",
+		Data:    revisions,
+	})
+}
+
+// GetQuestionRevision handles GET /api/v1/admin/questions/{question_id}/revisions/{revision_id}
+func (h *QuestionHandler) GetQuestionRevision(c *gin.Context) {
+	revision, err := h.questionService.GetRevision(c.Param("question_id"), c.Param("revision_id"))
+	if err != nil {
+		writeQuestionError(c, err, "Failed to retrieve revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Revision retrieved successfully",
+		Data:    revision,
+	})
+}
+
+// CreateQuestionRevision handles POST /api/v1/admin/questions/{question_id}/revisions
+func (h *QuestionHandler) CreateQuestionRevision(c *gin.Context) {
+	user := middleware.MustGetCurrentUser(c)
+
+	var req service.RevisionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	revision, err := h.questionService.CreateRevision(c.Param("question_id"), &req, user.ID)
+	if err != nil {
+		writeQuestionError(c, err, "Failed to create revision")
+		return
+	}
+
+	c.JSON(http.StatusCreated, SuccessResponse{
+		Success: true,
+		Message: "Draft revision created",
+		Data:    revision,
+	})
+}
+
+// UpdateQuestionRevision handles PUT /api/v1/admin/questions/{question_id}/revisions/{revision_id}
+func (h *QuestionHandler) UpdateQuestionRevision(c *gin.Context) {
+	var req service.RevisionInput
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	revision, err := h.questionService.UpdateDraft(c.Param("question_id"), c.Param("revision_id"), &req)
+	if err != nil {
+		writeQuestionError(c, err, "Failed to update revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Draft revision updated",
+		Data:    revision,
+	})
+}
+
+// SubmitQuestionRevision handles POST /api/v1/admin/questions/{question_id}/revisions/{revision_id}/submit
+func (h *QuestionHandler) SubmitQuestionRevision(c *gin.Context) {
+	revision, err := h.questionService.SubmitForReview(c.Param("question_id"), c.Param("revision_id"))
+	if err != nil {
+		writeQuestionError(c, err, "Failed to submit revision for review")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Revision submitted for review",
+		Data:    revision,
+	})
+}
+
+// PublishQuestionRevision handles POST /api/v1/admin/questions/{question_id}/revisions/{revision_id}/publish
+func (h *QuestionHandler) PublishQuestionRevision(c *gin.Context) {
+	user := middleware.MustGetCurrentUser(c)
+
+	var req ReviewRevisionRequest
+	// The review note is optional, so an empty body is accepted
+	_ = c.ShouldBindJSON(&req)
+
+	revision, err := h.questionService.PublishRevision(c.Param("question_id"), c.Param("revision_id"), user.ID, req.Note)
+	if err != nil {
+		writeQuestionError(c, err, "Failed to publish revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Revision published",
+		Data:    revision,
+	})
+}
+
+// RejectQuestionRevision handles POST /api/v1/admin/questions/{question_id}/revisions/{revision_id}/reject
+func (h *QuestionHandler) RejectQuestionRevision(c *gin.Context) {
+	user := middleware.MustGetCurrentUser(c)
+
+	var req ReviewRevisionRequest
+	_ = c.ShouldBindJSON(&req)
+
+	revision, err := h.questionService.RejectRevision(c.Param("question_id"), c.Param("revision_id"), user.ID, req.Note)
+	if err != nil {
+		writeQuestionError(c, err, "Failed to reject revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Revision sent back to draft",
+		Data:    revision,
+	})
+}
+
+// DiffQuestionRevisions handles GET /api/v1/admin/questions/{question_id}/diff?from=&to=
+func (h *QuestionHandler) DiffQuestionRevisions(c *gin.Context) {
+	from, to := c.Query("from"), c.Query("to")
+	if from == "" || to == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Both 'from' and 'to' revision IDs are required",
+		})
+		return
+	}
+
+	diff, err := h.questionService.DiffRevisions(c.Param("question_id"), from, to)
+	if err != nil {
+		writeQuestionError(c, err, "Failed to diff revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Revision diff computed",
+		Data:    diff,
+	})
+}
+
+// RegradeAnswer handles POST /api/v1/admin/answers/{answer_id}/regrade
+func (h *QuestionHandler) RegradeAnswer(c *gin.Context) {
+	record, changed, err := h.questionService.RegradeAnswer(c.Param("answer_id"))
+	if err != nil {
+		writeQuestionError(c, err, "Failed to regrade answer")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Answer regraded",
+		Data: map[string]any{
+			"answer":  record,
+			"changed": changed,
+		},
+	})
+}
+
+// GetAnswer handles GET /api/v1/answers/{answer_id}
+func (h *QuestionHandler) GetAnswer(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	record, err := h.questionService.GetAnswerRecord(userID, c.Param("answer_id"))
+	if err != nil {
+		writeQuestionError(c, err, "Failed to retrieve answer")
+		return
+	}
+
+	// Explain the answer against the revision the learner actually saw
+	response := map[string]any{
+		"id":          record.ID,
+		"question_id": record.QuestionID,
+		"revision_id": record.RevisionID,
+		"answer_json": record.AnswerJSON,
+		"is_correct":  record.IsCorrect,
+		"score":       record.Score,
+		"duration_ms": record.DurationMS,
+		"created_at":  record.CreatedAt,
+	}
+	if record.Revision != nil {
+		response["revision_number"] = record.Revision.Number
+		response["stem"] = record.Revision.Stem
+		response["content_json"] = record.Revision.ContentJSON
+		if answer, err := record.Revision.AsQuestion().GetAnswer(); err == nil {
+			response["explanation"] = answer.Explanation
+		}
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Answer retrieved successfully",
+		Data:    response,
+	})
+}
+
+// GetQuestionStatsList handles GET /api/v1/admin/questions/stats
+func (h *QuestionHandler) GetQuestionStatsList(c *gin.Context) {
+	page := 1
+	pageSize := 20
+
+	if p := c.Query("page"); p != "" {
+		if val, err := strconv.Atoi(p); err == nil && val > 0 {
+			page = val
+		}
+	}
+
+	if ps := c.Query("page_size"); ps != "" {
+		if val, err := strconv.Atoi(ps); err == nil && val > 0 && val <= 100 {
+			pageSize = val
+		}
+	}
+
+	filter := service.QuestionStatsFilter{
+		LevelID:     c.Query("level_id"),
+		FlaggedOnly: c.Query("flagged") == "true",
+		Limit:       pageSize,
+		Offset:      (page - 1) * pageSize,
+	}
+	if mr := c.Query("min_responses"); mr != "" {
+		if val, err := strconv.Atoi(mr); err == nil && val > 0 {
+			filter.MinResponses = val
+		}
+	}
+
+	stats, total, err := h.statsService.ListQuestionStats(filter)
+	if err != nil {
+		writeQuestionError(c, err, "Failed to retrieve question statistics")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Question statistics retrieved successfully",
+		Data: map[string]any{
+			"total":       total,
+			"page":        page,
+			"page_size":   pageSize,
+			"total_pages": int(math.Ceil(float64(total) / float64(pageSize))),
+			"stats":       stats,
+		},
+	})
+}
+
+// GetQuestionStats handles GET /api/v1/admin/questions/{question_id}/stats
+func (h *QuestionHandler) GetQuestionStats(c *gin.Context) {
+	stats, err := h.statsService.GetQuestionStats(c.Param("question_id"))
+	if err != nil {
+		writeQuestionError(c, err, "Failed to retrieve question statistics")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Question statistics retrieved successfully",
+		Data:    stats,
+	})
+}
+
+// RefreshQuestionStats handles POST /api/v1/admin/questions/stats/refresh?level_id=
+func (h *QuestionHandler) RefreshQuestionStats(c *gin.Context) {
+	var (
+		count int
+		err   error
+	)
+	if levelID := c.Query("level_id"); levelID != "" {
+		count, err = h.statsService.RefreshLevel(levelID)
+	} else {
+		count, err = h.statsService.RefreshAll()
+	}
+	if err != nil {
+		writeQuestionError(c, err, "Failed to refresh question statistics")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Question statistics refreshed",
+		Data: map[string]any{
+			"questions_updated": count,
+		},
+	})
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"errors"
+	"math"
+	"net/http"
+	"paperplay/internal/middleware"
+	"paperplay/internal/service"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// ReportHandler handles learner question reports and the moderation queue
+type ReportHandler struct {
+	reportService *service.ReportService
+	validator     *validator.Validate
+}
+
+// NewReportHandler creates a new report handler
+func NewReportHandler(reportService *service.ReportService) *ReportHandler {
+	return &ReportHandler{
+		reportService: reportService,
+		validator:     validator.New(),
+	}
+}
+
+// CreateReportRequest represents a learner report on a question
+type CreateReportRequest struct {
+	Category    string `json:"category" validate:"required,oneof=wrong_answer typo ambiguous offensive"`
+	Description string `json:"description" validate:"max=2000"`
+}
+
+// ModerateReportRequest represents an admin decision on a report
+type ModerateReportRequest struct {
+	Note string `json:"note" validate:"max=2000"`
+}
+
+// writeReportError maps report service errors to HTTP responses
+func writeReportError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	code := "database_error"
+
+	switch {
+	case errors.Is(err, service.ErrQuestionNotFound):
+		status, code = http.StatusNotFound, "question_not_found"
+	case errors.Is(err, service.ErrReportNotFound):
+		status, code = http.StatusNotFound, "report_not_found"
+	case errors.Is(err, service.ErrReportClosed):
+		status, code = http.StatusConflict, "report_closed"
+	case errors.Is(err, service.ErrDuplicateReport):
+		status, code = http.StatusConflict, "duplicate_report"
+	case errors.Is(err, service.ErrInvalidReportCategory):
+		status, code = http.StatusBadRequest, "invalid_category"
+	}
+
+	c.JSON(status, ErrorResponse{
+		Error:   code,
+		Message: message,
+		Details: err.Error(),
+	})
+}
+
+// CreateReport handles POST /api/v1/questions/{question_id}/report
+func (h *ReportHandler) CreateReport(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	var req CreateReportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Validation failed",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	report, _, err := h.reportService.CreateReport(userID, c.Param("question_id"), req.Category, req.Description)
+	if err != nil {
+		writeReportError(c, err, "Failed to report question")
+		return
+	}
+
+	c.JSON(http.StatusCreated, SuccessResponse{
+		Success: true,
+		Message: "Thank you, your report has been submitted",
+		Data:    report,
+	})
+}
+
+// GetReports handles GET /api/v1/admin/reports
+func (h *ReportHandler) GetReports(c *gin.Context) {
+	page := 1
+	pageSize := 20
+
+	if p := c.Query("page"); p != "" {
+		if val, err := strconv.Atoi(p); err == nil && val > 0 {
+			page = val
+		}
+	}
+
+	if ps := c.Query("page_size"); ps != "" {
+		if val, err := strconv.Atoi(ps); err == nil && val > 0 && val <= 100 {
+			pageSize = val
+		}
+	}
+
+	reports, total, err := h.reportService.ListReports(service.ReportFilter{
+		Status:     c.Query("status"),
+		Category:   c.Query("category"),
+		QuestionID: c.Query("question_id"),
+		Limit:      pageSize,
+		Offset:     (page - 1) * pageSize,
+	})
+	if err != nil {
+		writeReportError(c, err, "Failed to retrieve reports")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Reports retrieved successfully",
+		Data: map[string]any{
+			"total":       total,
+			"page":        page,
+			"page_size":   pageSize,
+			"total_pages": int(math.Ceil(float64(total) / float64(pageSize))),
+			"reports":     reports,
+		},
+	})
+}
+
+// GetReport handles GET /api/v1/admin/reports/{report_id}
+func (h *ReportHandler) GetReport(c *gin.Context) {
+	report, err := h.reportService.GetReport(c.Param("report_id"))
+	if err != nil {
+		writeReportError(c, err, "Failed to retrieve report")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Report retrieved successfully",
+		Data:    report,
+	})
+}
+
+// TriageReport handles POST /api/v1/admin/reports/{report_id}/triage
+func (h *ReportHandler) TriageReport(c *gin.Context) {
+	req, ok := h.bindModerateRequest(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.TriageReport(c.Param("report_id"), middleware.MustGetCurrentUserID(c), req.Note)
+	if err != nil {
+		writeReportError(c, err, "Failed to triage report")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Report triaged",
+		Data:    report,
+	})
+}
+
+// ResolveReport handles POST /api/v1/admin/reports/{report_id}/resolve
+func (h *ReportHandler) ResolveReport(c *gin.Context) {
+	req, ok := h.bindModerateRequest(c)
+	if !ok {
+		return
+	}
+
+	reports, err := h.reportService.ResolveReport(c.Param("report_id"), middleware.MustGetCurrentUserID(c), req.Note)
+	if err != nil {
+		writeReportError(c, err, "Failed to resolve report")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Report resolved and reporters notified",
+		Data: map[string]any{
+			"resolved_count": len(reports),
+			"reports":        reports,
+		},
+	})
+}
+
+// RejectReport handles POST /api/v1/admin/reports/{report_id}/reject
+func (h *ReportHandler) RejectReport(c *gin.Context) {
+	req, ok := h.bindModerateRequest(c)
+	if !ok {
+		return
+	}
+
+	report, err := h.reportService.RejectReport(c.Param("report_id"), middleware.MustGetCurrentUserID(c), req.Note)
+	if err != nil {
+		writeReportError(c, err, "Failed to reject report")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Report rejected",
+		Data:    report,
+	})
+}
+
+// bindModerateRequest binds the optional moderation note, writing an error response on failure
+func (h *ReportHandler) bindModerateRequest(c *gin.Context) (*ModerateReportRequest, bool) {
+	var req ModerateReportRequest
+	// The note is optional, so an empty body is accepted
+	_ = c.ShouldBindJSON(&req)
+
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Validation failed",
+			Details: err.Error(),
+		})
+		return nil, false
+	}
+	return &req, true
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"net/http"
+	"paperplay/internal/service"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSearchQueryLength is the longest accepted search query in characters
+const maxSearchQueryLength = 200
+
+// SearchHandler handles full-text search HTTP requests
+type SearchHandler struct {
+	searchService *service.SearchService
+}
+
+// NewSearchHandler creates a new search handler
+func NewSearchHandler(searchService *service.SearchService) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+	}
+}
+
+// Search handles GET /api/v1/search?q=&types=&limit=
+func (h *SearchHandler) Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Search query 'q' is required",
+		})
+		return
+	}
+	if utf8.RuneCountInString(query) > maxSearchQueryLength {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Search query is too long",
+		})
+		return
+	}
+
+	var types []string
+	if t := c.Query("types"); t != "" {
+		for _, name := range strings.Split(t, ",") {
+			switch name = strings.TrimSpace(name); name {
+			case service.SearchTypePaper, service.SearchTypeQuestion, service.SearchTypeSubject:
+				types = append(types, name)
+			default:
+				c.JSON(http.StatusBadRequest, ErrorResponse{
+					Error:   "invalid_request",
+					Message: "Unknown search type: " + name,
+				})
+				return
+			}
+		}
+	}
+
+	limit := 10
+	if l := c.Query("limit"); l != "" {
+		if val, err := strconv.Atoi(l); err == nil && val > 0 && val <= 50 {
+			limit = val
+		}
+	}
+
+	results, err := h.searchService.Search(query, types, limit)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "search_error",
+			Message: "Failed to search",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Search completed",
+		Data:    results,
+	})
+}
@@ -1,10 +1,14 @@
 package api
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"paperplay/internal/middleware"
 	"paperplay/internal/model"
 	"paperplay/internal/service"
+	"strconv"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -14,11 +18,13 @@ import (
 
 // UserHandler handles user-related HTTP requests
 type UserHandler struct {
-	db          *gorm.DB
-	jwtService  *middleware.JWTService
-	userService *service.UserService
-	ethService  *service.EthereumService
-	validator   *validator.Validate
+	db             *gorm.DB
+	jwtService     *middleware.JWTService
+	userService    *service.UserService
+	ethService     *service.EthereumService
+	accountService *service.AccountService
+	loginThrottler *middleware.LoginThrottler
+	validator      *validator.Validate
 }
 
 // NewUserHandler creates a new user handler
@@ -27,13 +33,17 @@ func NewUserHandler(
 	jwtService *middleware.JWTService,
 	userService *service.UserService,
 	ethService *service.EthereumService,
+	accountService *service.AccountService,
+	loginThrottler *middleware.LoginThrottler,
 ) *UserHandler {
 	return &UserHandler{
-		db:          db,
-		jwtService:  jwtService,
-		userService: userService,
-		ethService:  ethService,
-		validator:   validator.New(),
+		db:             db,
+		jwtService:     jwtService,
+		userService:    userService,
+		ethService:     ethService,
+		accountService: accountService,
+		loginThrottler: loginThrottler,
+		validator:      validator.New(),
 	}
 }
 
@@ -42,12 +52,14 @@ type RegisterRequest struct {
 	Email       string `json:"email" validate:"required,email"`
 	Password    string `json:"password" validate:"required,min=6"`
 	DisplayName string `json:"display_name" validate:"required,min=2,max=50"`
+	DeviceName  string `json:"device_name" validate:"omitempty,max=100"` // Optional label shown in the session list
 }
 
 // LoginRequest represents user login request
 type LoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Email      string `json:"email" validate:"required,email"`
+	Password   string `json:"password" validate:"required"`
+	DeviceName string `json:"device_name" validate:"omitempty,max=100"` // Optional label shown in the session list
 }
 
 // RefreshTokenRequest represents token refresh request
@@ -55,6 +67,36 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+// VerifyEmailRequest represents an email verification request
+type VerifyEmailRequest struct {
+	Token string `json:"token" validate:"required"`
+}
+
+// PasswordResetRequest represents a request for a password reset email
+type PasswordResetRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// DeleteAccountRequest represents a request to delete the current account
+type DeleteAccountRequest struct {
+	Password string `json:"password"`                                         // Required for accounts with email login
+	Confirm  bool   `json:"confirm" validate:"required"`                      // Must be true
+	Wallet   string `json:"wallet" validate:"omitempty,oneof=export forfeit"` // Required when the account has a custodial wallet
+}
+
+// ConfirmPasswordResetRequest represents setting a new password with a reset token
+type ConfirmPasswordResetRequest struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=6"`
+}
+
+// ChangePasswordRequest represents a password change by a signed-in user
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required"`
+	NewPassword     string `json:"new_password" validate:"required,min=6"`
+	DeviceName      string `json:"device_name" validate:"omitempty,max=100"` // Label for the new session
+}
+
 // AuthResponse represents authentication response
 type AuthResponse struct {
 	User         *model.User `json:"user"`
@@ -100,6 +142,15 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// Placeholder domains are reserved for WeChat and guest accounts
+	if model.IsReservedEmail(req.Email) {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "This email domain cannot be used",
+		})
+		return
+	}
+
 	// Check if user already exists
 	var existingUser model.User
 	if err := h.db.Where("email = ?", req.Email).First(&existingUser).Error; err == nil {
@@ -149,8 +200,13 @@ func (h *UserHandler) Register(c *gin.Context) {
 		return
 	}
 
+	// A failed send is logged; the user can request a new link
+	if h.accountService != nil {
+		_ = h.accountService.SendVerificationEmail(user)
+	}
+
 	// Generate tokens
-	accessToken, refreshToken, err := h.jwtService.GenerateTokenPair(user)
+	accessToken, refreshToken, err := h.jwtService.GenerateTokenPair(user, middleware.DeviceFromRequest(c, req.DeviceName))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "token_generation_error",
@@ -193,9 +249,19 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// Refuse the attempt before checking the password while the account or IP backs off
+	ip := c.ClientIP()
+	if h.loginThrottler != nil {
+		if err := h.loginThrottler.Check(req.Email, ip); err != nil {
+			writeLoginThrottledError(c, err)
+			return
+		}
+	}
+
 	// Find user by email
 	var user model.User
 	if err := h.db.Where("email = ?", strings.ToLower(req.Email)).First(&user).Error; err != nil {
+		h.recordLoginFailure("", req.Email, ip)
 		c.JSON(http.StatusUnauthorized, ErrorResponse{
 			Error:   "invalid_credentials",
 			Message: "Invalid email or password",
@@ -205,6 +271,7 @@ func (h *UserHandler) Login(c *gin.Context) {
 
 	// Check password
 	if !user.CheckPassword(req.Password) {
+		h.recordLoginFailure(user.ID, req.Email, ip)
 		c.JSON(http.StatusUnauthorized, ErrorResponse{
 			Error:   "invalid_credentials",
 			Message: "Invalid email or password",
@@ -212,8 +279,13 @@ func (h *UserHandler) Login(c *gin.Context) {
 		return
 	}
 
+	// A failure to clear the counter must not block a correct login
+	if h.loginThrottler != nil {
+		_ = h.loginThrottler.RecordSuccess(user.ID, user.Email, ip)
+	}
+
 	// Generate tokens
-	accessToken, refreshToken, err := h.jwtService.GenerateTokenPair(&user)
+	accessToken, refreshToken, err := h.jwtService.GenerateTokenPair(&user, middleware.DeviceFromRequest(c, req.DeviceName))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "token_generation_error",
@@ -256,29 +328,163 @@ func (h *UserHandler) RefreshToken(c *gin.Context) {
 		return
 	}
 
-	// Generate new access token
-	accessToken, err := h.jwtService.RefreshAccessToken(req.RefreshToken)
+	// Exchange the refresh token for a new pair; the old one stops working
+	accessToken, refreshToken, err := h.jwtService.RotateRefreshToken(req.RefreshToken, middleware.DeviceFromRequest(c, ""))
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{
-			Error:   "invalid_refresh_token",
-			Message: "Invalid or expired refresh token",
-		})
+		switch {
+		case errors.Is(err, middleware.ErrRefreshTokenReused):
+			c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Error:   "refresh_token_reused",
+				Message: "Refresh token has already been used, please log in again",
+			})
+		case errors.Is(err, middleware.ErrRefreshTokenNotFound), errors.Is(err, middleware.ErrRefreshTokenExpired):
+			c.JSON(http.StatusUnauthorized, ErrorResponse{
+				Error:   "invalid_refresh_token",
+				Message: "Invalid or expired refresh token",
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, ErrorResponse{
+				Error:   "token_generation_error",
+				Message: "Failed to refresh authentication tokens",
+			})
+		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"access_token": accessToken,
-		"token_type":   "Bearer",
-		"expires_in":   15 * 60, // 15 minutes in seconds
+		"access_token":  accessToken,
+		"refresh_token": refreshToken,
+		"token_type":    "Bearer",
+		"expires_in":    15 * 60, // 15 minutes in seconds
+	})
+}
+
+// recordLoginFailure counts a failed login; the response is the same whether or not that succeeds
+func (h *UserHandler) recordLoginFailure(userID, email, ip string) {
+	if h.loginThrottler != nil {
+		_ = h.loginThrottler.RecordFailure(userID, email, ip)
+	}
+}
+
+// writeLoginThrottledError responds to a login attempt refused by throttling
+func writeLoginThrottledError(c *gin.Context, err error) {
+	var throttled *middleware.LoginThrottledError
+	if !errors.As(err, &throttled) {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to check login attempts",
+		})
+		return
+	}
+
+	retryAfter := int(math.Ceil(throttled.RetryAfter.Seconds()))
+	c.Header("Retry-After", strconv.Itoa(retryAfter))
+
+	response := ErrorResponse{
+		Error:   "too_many_attempts",
+		Message: "Too many failed login attempts, please wait before trying again",
+		Details: fmt.Sprintf("retry after %d seconds", retryAfter),
+	}
+	if throttled.Locked {
+		response.Error = "login_locked"
+		response.Message = "Login temporarily locked after too many failed attempts"
+	}
+	c.JSON(http.StatusTooManyRequests, response)
+}
+
+// UnlockUserRequest represents an administrator clearing failed logins
+type UnlockUserRequest struct {
+	IP string `json:"ip" validate:"omitempty,ip"` // Also clear this client IP
+}
+
+// UnlockUser clears the failed login attempts of a user, lifting any lockout (admin only)
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	var req UnlockUserRequest
+	// The body is optional
+	if c.Request.ContentLength > 0 && !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	var user model.User
+	if err := h.db.First(&user, "id = ?", c.Param("user_id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "user_not_found",
+				Message: "User not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to get user",
+		})
+		return
+	}
+
+	adminID := middleware.MustGetCurrentUserID(c)
+	if err := h.loginThrottler.Unlock(adminID, user.Email, req.IP); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to unlock user",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Login attempts cleared",
+	})
+}
+
+// RevokeUserSessions signs a user out on every device, including access tokens
+// already issued (admin only)
+func (h *UserHandler) RevokeUserSessions(c *gin.Context) {
+	var user model.User
+	if err := h.db.First(&user, "id = ?", c.Param("user_id")).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "user_not_found",
+				Message: "User not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to get user",
+		})
+		return
+	}
+
+	adminID := middleware.MustGetCurrentUserID(c)
+	if err := h.jwtService.RevokeUserSessions(adminID, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to revoke sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "All sessions revoked",
 	})
 }
 
-// Logout handles user logout
+// Logout ends the current session, or every session with ?all=true
 func (h *UserHandler) Logout(c *gin.Context) {
 	userID := middleware.MustGetCurrentUserID(c)
-
-	// Revoke all refresh tokens for this user
-	if err := h.jwtService.RevokeAllRefreshTokens(userID); err != nil {
+	sessionID, hasSession := middleware.GetCurrentSessionID(c)
+
+	// Tokens issued before sessions were tracked cannot name their session,
+	// so they fall back to signing out everywhere
+	var err error
+	if c.Query("all") == "true" || !hasSession {
+		err = h.jwtService.RevokeAllSessions(userID)
+	} else {
+		err = h.jwtService.RevokeSession(userID, sessionID)
+	}
+	// A session already revoked from another device is as good as logged out
+	if err != nil && !errors.Is(err, middleware.ErrSessionNotFound) {
 		c.JSON(http.StatusInternalServerError, ErrorResponse{
 			Error:   "logout_error",
 			Message: "Failed to logout user",
@@ -292,6 +498,285 @@ func (h *UserHandler) Logout(c *gin.Context) {
 	})
 }
 
+// ListSessions lists the devices the current user is signed in on
+func (h *UserHandler) ListSessions(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+	sessionID, _ := middleware.GetCurrentSessionID(c)
+
+	sessions, err := h.jwtService.ListSessions(userID, sessionID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to get sessions",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Sessions loaded successfully",
+		Data:    sessions,
+	})
+}
+
+// RevokeSession signs the current user out of one session
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	if err := h.jwtService.RevokeSession(userID, c.Param("session_id")); err != nil {
+		if errors.Is(err, middleware.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{
+				Error:   "session_not_found",
+				Message: "Session not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "database_error",
+			Message: "Failed to revoke session",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Session revoked",
+	})
+}
+
+// writeAccountError maps account service errors to HTTP responses
+func writeAccountError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	code := "account_error"
+
+	switch {
+	case errors.Is(err, service.ErrInvalidAccountToken):
+		status, code = http.StatusBadRequest, "invalid_token"
+	case errors.Is(err, service.ErrAccountTokenExpired):
+		status, code = http.StatusBadRequest, "token_expired"
+	case errors.Is(err, service.ErrEmailAlreadyVerified):
+		status, code = http.StatusConflict, "email_already_verified"
+	case errors.Is(err, service.ErrInvalidPassword):
+		status, code = http.StatusUnauthorized, "invalid_password"
+	case errors.Is(err, service.ErrNoEmailLogin):
+		status, code = http.StatusConflict, "no_email_login"
+	case errors.Is(err, service.ErrWalletActionRequired):
+		status, code = http.StatusConflict, "wallet_action_required"
+	case errors.Is(err, service.ErrInvalidWalletDecision):
+		status, code = http.StatusBadRequest, "validation_error"
+	case errors.Is(err, service.ErrDeletionPending):
+		status, code = http.StatusConflict, "deletion_pending"
+	case errors.Is(err, service.ErrNoDeletionPending):
+		status, code = http.StatusNotFound, "no_deletion_pending"
+	case errors.Is(err, service.ErrUserNotFound):
+		status, code = http.StatusNotFound, "user_not_found"
+	}
+
+	c.JSON(status, ErrorResponse{
+		Error:   code,
+		Message: message,
+		Details: err.Error(),
+	})
+}
+
+// bindAndValidate binds a JSON body and validates it, writing the error response on failure
+func (h *UserHandler) bindAndValidate(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return false
+	}
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Validation failed",
+			Details: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// VerifyEmail handles POST /api/v1/auth/verify-email
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	var req VerifyEmailRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	user, err := h.accountService.VerifyEmail(req.Token)
+	if err != nil {
+		writeAccountError(c, err, "Failed to verify email address")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Email address verified",
+		Data:    user,
+	})
+}
+
+// ResendVerification handles POST /api/v1/users/verify-email/resend
+func (h *UserHandler) ResendVerification(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	if err := h.accountService.ResendVerification(userID); err != nil {
+		writeAccountError(c, err, "Failed to send verification email")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Verification email sent",
+	})
+}
+
+// RequestPasswordReset handles POST /api/v1/auth/password-reset
+func (h *UserHandler) RequestPasswordReset(c *gin.Context) {
+	var req PasswordResetRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.accountService.RequestPasswordReset(req.Email); err != nil {
+		writeAccountError(c, err, "Failed to request password reset")
+		return
+	}
+
+	// Same response whether or not the account exists
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "If an account exists for this email, a password reset link has been sent",
+	})
+}
+
+// ConfirmPasswordReset handles POST /api/v1/auth/password-reset/confirm
+func (h *UserHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req ConfirmPasswordResetRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	if err := h.accountService.ResetPassword(req.Token, req.NewPassword); err != nil {
+		writeAccountError(c, err, "Failed to reset password")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Password has been reset, please log in again",
+	})
+}
+
+// ChangePassword handles PUT /api/v1/users/password. Every session is signed out,
+// and the device making the request gets a new token pair.
+func (h *UserHandler) ChangePassword(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	var req ChangePasswordRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	user, err := h.accountService.ChangePassword(userID, req.CurrentPassword, req.NewPassword)
+	if err != nil {
+		writeAccountError(c, err, "Failed to change password")
+		return
+	}
+
+	accessToken, refreshToken, err := h.jwtService.GenerateTokenPair(user, middleware.DeviceFromRequest(c, req.DeviceName))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "token_generation_error",
+			Message: "Failed to generate authentication tokens",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, AuthResponse{
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    15 * 60, // 15 minutes in seconds
+	})
+}
+
+// ExportData handles GET /api/v1/users/export
+func (h *UserHandler) ExportData(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_format",
+			Message: "Format must be json or zip",
+		})
+		return
+	}
+
+	export, err := h.accountService.ExportUserData(userID)
+	if err != nil {
+		writeAccountError(c, err, "Failed to export account data")
+		return
+	}
+
+	filename := fmt.Sprintf("paperplay-export-%s.%s", export.ExportedAt.Format("2006-01-02"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "json" {
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Status(http.StatusOK)
+	if err := service.WriteExportZip(c.Writer, export); err != nil {
+		// Headers are already sent, so the client sees a truncated archive
+		_ = c.Error(err)
+	}
+}
+
+// DeleteAccount handles DELETE /api/v1/users/me
+func (h *UserHandler) DeleteAccount(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	var req DeleteAccountRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	result, err := h.accountService.RequestDeletion(userID, req.Password, req.Wallet)
+	if err != nil {
+		writeAccountError(c, err, "Failed to delete account")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, SuccessResponse{
+		Success: true,
+		Message: "Account scheduled for deletion, log in before then to cancel",
+		Data:    result,
+	})
+}
+
+// CancelDeletion handles POST /api/v1/users/me/cancel-deletion
+func (h *UserHandler) CancelDeletion(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	if err := h.accountService.CancelDeletion(userID); err != nil {
+		writeAccountError(c, err, "Failed to cancel account deletion")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Account deletion cancelled",
+	})
+}
+
 // GetProfile returns current user profile
 func (h *UserHandler) GetProfile(c *gin.Context) {
 	user := middleware.MustGetCurrentUser(c)
@@ -2,16 +2,26 @@ package api
 
 import (
 	"bytes"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
 	"encoding/json"
+	"encoding/pem"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"paperplay/config"
 	"paperplay/internal/middleware"
 	"paperplay/internal/model"
 	"paperplay/internal/service"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -24,12 +34,15 @@ func setupTestDB() *gorm.DB {
 	db.AutoMigrate(
 		&model.User{},
 		&model.RefreshToken{},
+		&model.RevokedSession{},
 		&model.UserProgress{},
 		&model.UserAttempts{},
 		&model.Achievement{},
 		&model.UserAchievement{},
+		&model.UserAchievementProgress{},
 		&model.Event{},
 		&model.NFTAsset{},
+		&model.LoginThrottle{},
 	)
 
 	return db
@@ -67,13 +80,13 @@ func setupTestRouter(handler *UserHandler) *gin.Engine {
 
 func createTestServices(db *gorm.DB) (*middleware.JWTService, *service.UserService, *service.EthereumService) {
 	// Create JWT service
-	jwtService := middleware.NewJWTService(&config.Config{
+	jwtService, _ := middleware.NewJWTService(&config.Config{
 		JWT: config.JWTConfig{
 			SecretKey:            "test-secret",
 			AccessTokenDuration:  15,
 			RefreshTokenDuration: 7,
 		},
-	}, db)
+	}, db, nil)
 
 	// Create user service
 	userService := service.NewUserService(db)
@@ -91,7 +104,7 @@ func TestUserHandler_Register(t *testing.T) {
 	db := setupTestDB()
 	jwtService, userService, ethService := createTestServices(db)
 
-	handler := NewUserHandler(db, jwtService, userService, ethService)
+	handler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
 	router := setupTestRouter(handler)
 
 	tests := []struct {
@@ -165,7 +178,7 @@ func TestUserHandler_Login(t *testing.T) {
 	db := setupTestDB()
 	jwtService, userService, ethService := createTestServices(db)
 
-	handler := NewUserHandler(db, jwtService, userService, ethService)
+	handler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
 	router := setupTestRouter(handler)
 
 	// Create a test user first
@@ -238,11 +251,326 @@ func TestUserHandler_Login(t *testing.T) {
 	}
 }
 
+func TestUserHandler_RefreshToken(t *testing.T) {
+	db := setupTestDB()
+	jwtService, userService, ethService := createTestServices(db)
+
+	handler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
+	router := setupTestRouter(handler)
+
+	user := &model.User{Email: "test@example.com", DisplayName: "Test User"}
+	user.SetPassword("password123")
+	db.Create(user)
+
+	refresh := func(token string) *httptest.ResponseRecorder {
+		jsonPayload, _ := json.Marshal(RefreshTokenRequest{RefreshToken: token})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewBuffer(jsonPayload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	_, first, err := jwtService.GenerateTokenPair(user, middleware.DeviceInfo{})
+	assert.NoError(t, err)
+	_, otherDevice, err := jwtService.GenerateTokenPair(user, middleware.DeviceInfo{})
+	assert.NoError(t, err)
+
+	// Each refresh returns a new refresh token from the same family
+	w := refresh(first)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var rotated struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rotated))
+	assert.NotEmpty(t, rotated.AccessToken)
+	assert.NotEqual(t, first, rotated.RefreshToken)
+
+	var firstRow, secondRow model.RefreshToken
+	db.First(&firstRow, "token = ?", first)
+	db.First(&secondRow, "token = ?", rotated.RefreshToken)
+	assert.True(t, firstRow.IsRotated())
+	assert.Equal(t, firstRow.FamilyID, secondRow.FamilyID)
+
+	// Replaying the rotated token revokes the whole family
+	w = refresh(first)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	var response ErrorResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "refresh_token_reused", response.Error)
+
+	w = refresh(rotated.RefreshToken)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "invalid_refresh_token", response.Error)
+
+	// Other logins are unaffected
+	w = refresh(otherDevice)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestUserHandler_Sessions(t *testing.T) {
+	db := setupTestDB()
+	jwtService, userService, ethService := createTestServices(db)
+	handler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	v1 := router.Group("/api/v1")
+	v1.POST("/auth/login", handler.Login)
+	users := v1.Group("/users", jwtService.AuthMiddleware())
+	users.GET("/sessions", handler.ListSessions)
+	users.DELETE("/sessions/:session_id", handler.RevokeSession)
+	users.POST("/logout", handler.Logout)
+
+	user := &model.User{Email: "test@example.com", DisplayName: "Test User"}
+	user.SetPassword("password123")
+	db.Create(user)
+
+	login := func(deviceName, userAgent string) AuthResponse {
+		jsonPayload, _ := json.Marshal(LoginRequest{Email: user.Email, Password: "password123", DeviceName: deviceName})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBuffer(jsonPayload))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response AuthResponse
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+	do := func(method, path, accessToken string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	listSessions := func(accessToken string) []middleware.Session {
+		w := do(http.MethodGet, "/api/v1/users/sessions", accessToken)
+		assert.Equal(t, http.StatusOK, w.Code)
+		var response struct {
+			Success bool                 `json:"success"`
+			Data    []middleware.Session `json:"data"`
+		}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, response.Success)
+		return response.Data
+	}
+
+	laptop := login("Laptop", "Mozilla/5.0 (X11; Linux x86_64)")
+	phone := login("Phone", "PaperPlay/1.0 (iOS)")
+	tablet := login("", "PaperPlay/1.0 (iPadOS)")
+
+	// Refreshing keeps the session and its device name
+	_, _, err := jwtService.RotateRefreshToken(phone.RefreshToken, middleware.DeviceInfo{IP: "203.0.113.7"})
+	assert.NoError(t, err)
+
+	sessions := listSessions(laptop.AccessToken)
+	assert.Len(t, sessions, 3)
+	byName := map[string]middleware.Session{}
+	for _, session := range sessions {
+		byName[session.DeviceName] = session
+	}
+	assert.True(t, byName["Laptop"].Current)
+	assert.False(t, byName["Phone"].Current)
+	assert.Equal(t, "Mozilla/5.0 (X11; Linux x86_64)", byName["Laptop"].UserAgent)
+	assert.Equal(t, "PaperPlay/1.0 (iOS)", byName["Phone"].UserAgent)
+	assert.Equal(t, "203.0.113.7", byName["Phone"].IPAddress)
+	assert.Equal(t, "PaperPlay/1.0 (iPadOS)", byName[""].UserAgent)
+	// The most recently refreshed session comes first
+	assert.Equal(t, "Phone", sessions[0].DeviceName)
+
+	// Revoking a session signs that device out
+	w := do(http.MethodDelete, "/api/v1/users/sessions/"+byName["Phone"].ID, laptop.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	_, _, err = jwtService.RotateRefreshToken(phone.RefreshToken, middleware.DeviceInfo{})
+	assert.ErrorIs(t, err, middleware.ErrRefreshTokenNotFound)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/users/sessions", phone.AccessToken).Code)
+
+	w = do(http.MethodDelete, "/api/v1/users/sessions/"+byName["Phone"].ID, laptop.AccessToken)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Logout ends only the current session by default
+	other, _, _ := createTestServices(db)
+	w = do(http.MethodPost, "/api/v1/users/logout", tablet.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/users/sessions", tablet.AccessToken).Code)
+
+	// The revocation is stored, so a restarted or second instance rejects the token too
+	restarted, _, _ := createTestServices(db)
+	claims, err := restarted.ValidateAccessToken(tablet.AccessToken)
+	assert.NoError(t, err)
+	var stored model.User
+	assert.NoError(t, db.First(&stored, "id = ?", user.ID).Error)
+	assert.ErrorIs(t, restarted.CheckRevocation(claims, &stored), middleware.ErrAccessTokenRevoked)
+
+	// An instance already running picks the revocation up on its next reload
+	assert.NoError(t, other.CheckRevocation(claims, &stored))
+	assert.NoError(t, other.ReloadRevokedSessions())
+	assert.ErrorIs(t, other.CheckRevocation(claims, &stored), middleware.ErrAccessTokenRevoked)
+
+	sessions = listSessions(laptop.AccessToken)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, "Laptop", sessions[0].DeviceName)
+
+	// ...and everywhere on request, rejecting the access tokens already issued
+	phone = login("Phone", "PaperPlay/1.0 (iOS)")
+	w = do(http.MethodPost, "/api/v1/users/logout?all=true", laptop.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/users/sessions", laptop.AccessToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/users/sessions", phone.AccessToken).Code)
+
+	fresh := login("Laptop", "Mozilla/5.0 (X11; Linux x86_64)")
+	assert.Len(t, listSessions(fresh.AccessToken), 1)
+}
+
+func TestUserHandler_RevokeUserSessions(t *testing.T) {
+	db := setupTestDB()
+	jwtService, userService, ethService := createTestServices(db)
+	handler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	protected := router.Group("/api/v1", jwtService.AuthMiddleware())
+	protected.GET("/users/profile", handler.GetProfile)
+	protected.DELETE("/admin/users/:user_id/sessions", middleware.RequireAdmin(), handler.RevokeUserSessions)
+
+	admin := &model.User{Email: "admin@example.com", DisplayName: "Admin", Role: model.RoleAdmin}
+	db.Create(admin)
+	user := &model.User{Email: "test@example.com", DisplayName: "Test User"}
+	db.Create(user)
+
+	adminToken, _, err := jwtService.GenerateTokenPair(admin, middleware.DeviceInfo{})
+	assert.NoError(t, err)
+	userToken, userRefresh, err := jwtService.GenerateTokenPair(user, middleware.DeviceInfo{})
+	assert.NoError(t, err)
+
+	do := func(method, path, accessToken string) int {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+accessToken)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusForbidden, do(http.MethodDelete, "/api/v1/admin/users/"+admin.ID+"/sessions", userToken))
+	assert.Equal(t, http.StatusNotFound, do(http.MethodDelete, "/api/v1/admin/users/missing/sessions", adminToken))
+	assert.Equal(t, http.StatusOK, do(http.MethodDelete, "/api/v1/admin/users/"+user.ID+"/sessions", adminToken))
+
+	// Both the access token and the refresh token stop working at once
+	assert.Equal(t, http.StatusUnauthorized, do(http.MethodGet, "/api/v1/users/profile", userToken))
+	_, _, err = jwtService.RotateRefreshToken(userRefresh, middleware.DeviceInfo{})
+	assert.ErrorIs(t, err, middleware.ErrRefreshTokenNotFound)
+}
+
+func TestUserHandler_LoginThrottling(t *testing.T) {
+	db := setupTestDB()
+	jwtService, userService, ethService := createTestServices(db)
+
+	newRouter := func(auth config.AuthConfig) *gin.Engine {
+		throttler := middleware.NewLoginThrottler(&config.Config{Auth: auth}, db, nil, nil)
+		handler := NewUserHandler(db, jwtService, userService, ethService, nil, throttler)
+
+		gin.SetMode(gin.TestMode)
+		router := gin.New()
+		router.POST("/api/v1/auth/login", handler.Login)
+		router.POST("/api/v1/admin/users/:user_id/unlock", func(c *gin.Context) {
+			c.Set("user_id", "admin-id")
+			c.Next()
+		}, handler.UnlockUser)
+		return router
+	}
+
+	user := &model.User{ID: "user-1", Email: "test@example.com", DisplayName: "Test User"}
+	user.SetPassword("password123")
+	db.Create(user)
+
+	login := func(router *gin.Engine, email, password string) *httptest.ResponseRecorder {
+		jsonPayload, _ := json.Marshal(LoginRequest{Email: email, Password: password})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBuffer(jsonPayload))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	errorCode := func(w *httptest.ResponseRecorder) string {
+		var response ErrorResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return response.Error
+	}
+
+	t.Run("backoff", func(t *testing.T) {
+		router := newRouter(config.AuthConfig{
+			MaxFailedAttempts: 10, MaxFailedAttemptsPerIP: 100,
+			BackoffAfter: 2, BackoffBase: 60, MaxBackoff: 600,
+			LockoutDuration: 15, FailureWindow: 15,
+		})
+
+		assert.Equal(t, http.StatusUnauthorized, login(router, "backoff@example.com", "wrong").Code)
+		assert.Equal(t, http.StatusUnauthorized, login(router, "backoff@example.com", "wrong").Code)
+		assert.Equal(t, http.StatusUnauthorized, login(router, "backoff@example.com", "wrong").Code)
+
+		// The third failure starts the backoff
+		w := login(router, "backoff@example.com", "wrong")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "too_many_attempts", errorCode(w))
+		assert.Equal(t, "60", w.Header().Get("Retry-After"))
+
+		// Other accounts are unaffected
+		assert.Equal(t, http.StatusOK, login(router, "test@example.com", "password123").Code)
+	})
+
+	t.Run("lockout and unlock", func(t *testing.T) {
+		db.Where("1 = 1").Delete(&model.LoginThrottle{})
+		router := newRouter(config.AuthConfig{
+			MaxFailedAttempts: 3, MaxFailedAttemptsPerIP: 5,
+			LockoutDuration: 15, FailureWindow: 15,
+		})
+
+		// A successful login clears the account's failures
+		assert.Equal(t, http.StatusUnauthorized, login(router, "test@example.com", "wrong").Code)
+		assert.Equal(t, http.StatusOK, login(router, "test@example.com", "password123").Code)
+
+		for i := 0; i < 3; i++ {
+			assert.Equal(t, http.StatusUnauthorized, login(router, "test@example.com", "wrong").Code)
+		}
+
+		// Even the right password is refused while locked
+		w := login(router, "test@example.com", "password123")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "login_locked", errorCode(w))
+		assert.Equal(t, "900", w.Header().Get("Retry-After"))
+
+		// Guessing other accounts from the same IP locks the IP out
+		assert.Equal(t, http.StatusUnauthorized, login(router, "nobody@example.com", "wrong").Code)
+		w = login(router, "other@example.com", "wrong")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "login_locked", errorCode(w))
+
+		// Unlocking the user and the IP lets the user back in
+		jsonPayload, _ := json.Marshal(UnlockUserRequest{IP: "192.0.2.1"})
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/user-1/unlock", bytes.NewBuffer(jsonPayload))
+		req.Header.Set("Content-Type", "application/json")
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		assert.Equal(t, http.StatusOK, login(router, "test@example.com", "password123").Code)
+
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/admin/users/missing/unlock", nil)
+		w = httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
 func TestUserHandler_GetProfile(t *testing.T) {
 	db := setupTestDB()
 	jwtService, userService, ethService := createTestServices(db)
 
-	handler := NewUserHandler(db, jwtService, userService, ethService)
+	handler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
 	router := setupTestRouter(handler)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/profile", nil)
@@ -262,7 +590,7 @@ func TestUserHandler_UpdateProfile(t *testing.T) {
 	db := setupTestDB()
 	jwtService, userService, ethService := createTestServices(db)
 
-	handler := NewUserHandler(db, jwtService, userService, ethService)
+	handler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
 	router := setupTestRouter(handler)
 
 	// Create a test user
@@ -298,7 +626,7 @@ func TestUserHandler_GetUserProgress(t *testing.T) {
 	db := setupTestDB()
 	jwtService, userService, ethService := createTestServices(db)
 
-	handler := NewUserHandler(db, jwtService, userService, ethService)
+	handler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
 	router := setupTestRouter(handler)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/progress", nil)
@@ -317,7 +645,7 @@ func TestUserHandler_GetUserAchievements(t *testing.T) {
 	db := setupTestDB()
 	jwtService, userService, ethService := createTestServices(db)
 
-	handler := NewUserHandler(db, jwtService, userService, ethService)
+	handler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
 	router := setupTestRouter(handler)
 
 	req := httptest.NewRequest(http.MethodGet, "/api/v1/users/achievements", nil)
@@ -336,7 +664,7 @@ func TestUserHandler_Logout(t *testing.T) {
 	db := setupTestDB()
 	jwtService, userService, ethService := createTestServices(db)
 
-	handler := NewUserHandler(db, jwtService, userService, ethService)
+	handler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
 	router := setupTestRouter(handler)
 
 	req := httptest.NewRequest(http.MethodPost, "/api/v1/users/logout", nil)
@@ -351,3 +679,123 @@ func TestUserHandler_Logout(t *testing.T) {
 	assert.True(t, response.Success)
 	assert.Equal(t, "Successfully logged out", response.Message)
 }
+
+// writeKeyFile stores a key as <kid>.pem, or <kid>.pub.pem when only the public key is kept
+func writeKeyFile(t *testing.T, dir, kid string, key any) {
+	var block *pem.Block
+	switch key.(type) {
+	case ed25519.PublicKey, *rsa.PublicKey:
+		der, err := x509.MarshalPKIXPublicKey(key)
+		assert.NoError(t, err)
+		block = &pem.Block{Type: "PUBLIC KEY", Bytes: der}
+		kid += ".pub"
+	default:
+		der, err := x509.MarshalPKCS8PrivateKey(key)
+		assert.NoError(t, err)
+		block = &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	}
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, kid+".pem"), pem.EncodeToMemory(block), 0o600))
+}
+
+func TestUserHandler_AsymmetricTokens(t *testing.T) {
+	db := setupTestDB()
+	_, userService, ethService := createTestServices(db)
+
+	dir := t.TempDir()
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	writeKeyFile(t, dir, "2026-01-01", rsaKey)
+
+	jwtService, err := middleware.NewJWTService(&config.Config{
+		JWT: config.JWTConfig{
+			SecretKey:            "test-secret",
+			KeysDir:              dir,
+			AccessTokenDuration:  15,
+			RefreshTokenDuration: 7,
+		},
+	}, db, nil)
+	assert.NoError(t, err)
+	handler := NewUserHandler(db, jwtService, userService, ethService, nil, nil)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/.well-known/jwks.json", jwtService.JWKSHandler())
+	router.GET("/api/v1/users/sessions", jwtService.AuthMiddleware(), handler.ListSessions)
+
+	user := &model.User{Email: "test@example.com", DisplayName: "Test User"}
+	db.Create(user)
+
+	get := func(path, accessToken string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if accessToken != "" {
+			req.Header.Set("Authorization", "Bearer "+accessToken)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	jwks := func() []middleware.JWK {
+		w := get("/.well-known/jwks.json", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		var set middleware.JWKSet
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &set))
+		return set.Keys
+	}
+
+	oldToken, _, err := jwtService.GenerateTokenPair(user, middleware.DeviceInfo{})
+	assert.NoError(t, err)
+	parsed, _, err := jwt.NewParser().ParseUnverified(oldToken, &middleware.JWTClaims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "RS256", parsed.Method.Alg())
+	assert.Equal(t, "2026-01-01", parsed.Header["kid"])
+	assert.Equal(t, http.StatusOK, get("/api/v1/users/sessions", oldToken).Code)
+
+	keys := jwks()
+	assert.Len(t, keys, 1)
+	assert.Equal(t, "RSA", keys[0].KeyType)
+	assert.Equal(t, "AQAB", keys[0].E)
+
+	// HS256 tokens signed with the secret are no longer accepted
+	legacy, err := jwt.NewWithClaims(jwt.SigningMethodHS256, middleware.JWTClaims{
+		UserID:           user.ID,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute))},
+	}).SignedString([]byte("test-secret"))
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusUnauthorized, get("/api/v1/users/sessions", legacy).Code)
+
+	// A newer key takes over signing after a reload; the old one still verifies
+	edPublic, edKey, err := ed25519.GenerateKey(rand.Reader)
+	assert.NoError(t, err)
+	writeKeyFile(t, dir, "2026-06-01", edKey)
+	assert.NoError(t, jwtService.ReloadKeys())
+
+	newToken, _, err := jwtService.GenerateTokenPair(user, middleware.DeviceInfo{})
+	assert.NoError(t, err)
+	parsed, _, err = jwt.NewParser().ParseUnverified(newToken, &middleware.JWTClaims{})
+	assert.NoError(t, err)
+	assert.Equal(t, "EdDSA", parsed.Method.Alg())
+	assert.Equal(t, http.StatusOK, get("/api/v1/users/sessions", newToken).Code)
+	assert.Equal(t, http.StatusOK, get("/api/v1/users/sessions", oldToken).Code)
+
+	keys = jwks()
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "OKP", keys[1].KeyType)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(edPublic), keys[1].X)
+
+	// Retiring the RSA private key leaves its tokens valid through the public key
+	assert.NoError(t, os.Remove(filepath.Join(dir, "2026-01-01.pem")))
+	writeKeyFile(t, dir, "2026-01-01", &rsaKey.PublicKey)
+	assert.NoError(t, jwtService.ReloadKeys())
+	assert.Equal(t, http.StatusOK, get("/api/v1/users/sessions", oldToken).Code)
+	assert.Len(t, jwks(), 2)
+
+	// Tokens signed with a key that was removed are rejected
+	assert.NoError(t, os.Remove(filepath.Join(dir, "2026-01-01.pub.pem")))
+	assert.NoError(t, jwtService.ReloadKeys())
+	assert.Equal(t, http.StatusUnauthorized, get("/api/v1/users/sessions", oldToken).Code)
+
+	// A broken key file keeps the keys already loaded
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "broken.pem"), []byte("not a key"), 0o600))
+	assert.Error(t, jwtService.ReloadKeys())
+	assert.Equal(t, http.StatusOK, get("/api/v1/users/sessions", newToken).Code)
+}
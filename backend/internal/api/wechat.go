@@ -0,0 +1,223 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"paperplay/internal/middleware"
+	"paperplay/internal/service"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// WeChatHandler handles WeChat mini-program login and linking of login methods
+type WeChatHandler struct {
+	wechatService  *service.WeChatService
+	jwtService     *middleware.JWTService
+	accountService *service.AccountService
+	validator      *validator.Validate
+}
+
+// NewWeChatHandler creates a new WeChat handler; accountService may be nil
+func NewWeChatHandler(
+	wechatService *service.WeChatService,
+	jwtService *middleware.JWTService,
+	accountService *service.AccountService,
+) *WeChatHandler {
+	return &WeChatHandler{
+		wechatService:  wechatService,
+		jwtService:     jwtService,
+		accountService: accountService,
+		validator:      validator.New(),
+	}
+}
+
+// WeChatLoginRequest represents a login with a wx.login code
+type WeChatLoginRequest struct {
+	Code        string `json:"code" validate:"required,max=128"`
+	DisplayName string `json:"display_name" validate:"omitempty,max=50"` // Used when the account is created
+	DeviceName  string `json:"device_name" validate:"omitempty,max=100"` // Optional label shown in the session list
+}
+
+// LinkWeChatRequest represents linking a WeChat account with a wx.login code
+type LinkWeChatRequest struct {
+	Code string `json:"code" validate:"required,max=128"`
+}
+
+// AttachEmailRequest represents adding email and password login to a WeChat account
+type AttachEmailRequest struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,min=6"`
+}
+
+// writeWeChatError maps WeChat service errors to HTTP responses
+func writeWeChatError(c *gin.Context, err error, message string) {
+	status := http.StatusInternalServerError
+	code := "database_error"
+
+	switch {
+	case errors.Is(err, service.ErrWeChatDisabled):
+		status, code = http.StatusServiceUnavailable, "wechat_disabled"
+	case errors.Is(err, service.ErrInvalidWeChatCode):
+		status, code = http.StatusUnauthorized, "invalid_wechat_code"
+	case errors.Is(err, service.ErrWeChatUnavailable):
+		status, code = http.StatusBadGateway, "wechat_unavailable"
+	case errors.Is(err, service.ErrIdentityAlreadyLinked):
+		status, code = http.StatusConflict, "identity_already_linked"
+	case errors.Is(err, service.ErrProviderAlreadyLinked):
+		status, code = http.StatusConflict, "provider_already_linked"
+	case errors.Is(err, service.ErrIdentityNotLinked):
+		status, code = http.StatusNotFound, "identity_not_linked"
+	case errors.Is(err, service.ErrLastLoginMethod):
+		status, code = http.StatusConflict, "last_login_method"
+	case errors.Is(err, service.ErrEmailLoginExists):
+		status, code = http.StatusConflict, "email_login_exists"
+	case errors.Is(err, service.ErrEmailTaken):
+		status, code = http.StatusConflict, "user_exists"
+	case errors.Is(err, service.ErrUserNotFound):
+		status, code = http.StatusNotFound, "user_not_found"
+	}
+
+	c.JSON(status, ErrorResponse{
+		Error:   code,
+		Message: message,
+		Details: err.Error(),
+	})
+}
+
+// bindAndValidate binds a JSON body and validates it, writing the error response on failure
+func (h *WeChatHandler) bindAndValidate(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "invalid_request",
+			Message: "Invalid request body",
+			Details: err.Error(),
+		})
+		return false
+	}
+	if err := h.validator.Struct(req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error:   "validation_error",
+			Message: "Validation failed",
+			Details: err.Error(),
+		})
+		return false
+	}
+	return true
+}
+
+// Login handles POST /api/v1/auth/wechat
+func (h *WeChatHandler) Login(c *gin.Context) {
+	var req WeChatLoginRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	user, created, err := h.wechatService.Login(c.Request.Context(), req.Code, req.DisplayName)
+	if err != nil {
+		writeWeChatError(c, err, "WeChat login failed")
+		return
+	}
+
+	accessToken, refreshToken, err := h.jwtService.GenerateTokenPair(user, middleware.DeviceFromRequest(c, req.DeviceName))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{
+			Error:   "token_generation_error",
+			Message: "Failed to generate authentication tokens",
+		})
+		return
+	}
+
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, AuthResponse{
+		User:         user,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    15 * 60, // 15 minutes in seconds
+	})
+}
+
+// ListIdentities handles GET /api/v1/users/identities
+func (h *WeChatHandler) ListIdentities(c *gin.Context) {
+	user := middleware.MustGetCurrentUser(c)
+
+	identities, err := h.wechatService.ListIdentities(user.ID)
+	if err != nil {
+		writeWeChatError(c, err, "Failed to get linked accounts")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"identities":      identities,
+		"count":           len(identities),
+		"has_email_login": user.HasEmailLogin(),
+	})
+}
+
+// LinkWeChat handles POST /api/v1/users/identities/wechat
+func (h *WeChatHandler) LinkWeChat(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	var req LinkWeChatRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	identity, err := h.wechatService.LinkWeChat(c.Request.Context(), userID, req.Code)
+	if err != nil {
+		writeWeChatError(c, err, "Failed to link WeChat account")
+		return
+	}
+
+	c.JSON(http.StatusCreated, SuccessResponse{
+		Success: true,
+		Message: "WeChat account linked",
+		Data:    identity,
+	})
+}
+
+// UnlinkWeChat handles DELETE /api/v1/users/identities/wechat
+func (h *WeChatHandler) UnlinkWeChat(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	if err := h.wechatService.UnlinkWeChat(userID); err != nil {
+		writeWeChatError(c, err, "Failed to unlink WeChat account")
+		return
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "WeChat account unlinked",
+	})
+}
+
+// AttachEmail handles POST /api/v1/users/identities/email
+func (h *WeChatHandler) AttachEmail(c *gin.Context) {
+	userID := middleware.MustGetCurrentUserID(c)
+
+	var req AttachEmailRequest
+	if !h.bindAndValidate(c, &req) {
+		return
+	}
+
+	user, err := h.wechatService.AttachEmail(userID, req.Email, req.Password)
+	if err != nil {
+		writeWeChatError(c, err, "Failed to set up email login")
+		return
+	}
+
+	// A failed send is logged; the user can request a new link
+	if h.accountService != nil {
+		_ = h.accountService.SendVerificationEmail(user)
+	}
+
+	c.JSON(http.StatusOK, SuccessResponse{
+		Success: true,
+		Message: "Email login set up, please confirm your email address",
+		Data:    user,
+	})
+}
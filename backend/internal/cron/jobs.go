@@ -21,6 +21,9 @@ type JobManager struct {
 	config             *config.CronConfig
 	achievementService *service.AchievementService
 	userService        *service.UserService
+	statsService       *service.QuestionStatsService
+	accountService     *service.AccountService
+	guestService       *service.GuestService
 	wsHub              *websocket.Hub
 }
 
@@ -31,6 +34,9 @@ func NewJobManager(
 	config *config.CronConfig,
 	achievementService *service.AchievementService,
 	userService *service.UserService,
+	statsService *service.QuestionStatsService,
+	accountService *service.AccountService,
+	guestService *service.GuestService,
 	wsHub *websocket.Hub,
 ) *JobManager {
 	c := cron.New(cron.WithChain(cron.Recover(cron.DefaultLogger)))
@@ -42,6 +48,9 @@ func NewJobManager(
 		config:             config,
 		achievementService: achievementService,
 		userService:        userService,
+		statsService:       statsService,
+		accountService:     accountService,
+		guestService:       guestService,
 		wsHub:              wsHub,
 	}
 }
@@ -58,6 +67,16 @@ func (jm *JobManager) Start() error {
 		return fmt.Errorf("failed to add daily stats update job: %w", err)
 	}
 
+	// Question statistics refresh job, runs alongside the daily stats update
+	if _, err := jm.cron.AddFunc(jm.config.StatsUpdateSpec, jm.questionStatsUpdate); err != nil {
+		return fmt.Errorf("failed to add question stats update job: %w", err)
+	}
+
+	// Achievement rarity refresh job, runs alongside the daily stats update
+	if _, err := jm.cron.AddFunc(jm.config.StatsUpdateSpec, jm.achievementStatsUpdate); err != nil {
+		return fmt.Errorf("failed to add achievement stats update job: %w", err)
+	}
+
 	// Weekly report generation job
 	if _, err := jm.cron.AddFunc(jm.config.ReportGenerationSpec, jm.weeklyReportGeneration); err != nil {
 		return fmt.Errorf("failed to add weekly report generation job: %w", err)
@@ -68,6 +87,21 @@ func (jm *JobManager) Start() error {
 		return fmt.Errorf("failed to add achievement check job: %w", err)
 	}
 
+	// Purge of accounts whose deletion grace period has passed
+	if _, err := jm.cron.AddFunc(jm.config.AccountPurgeSpec, jm.accountPurge); err != nil {
+		return fmt.Errorf("failed to add account purge job: %w", err)
+	}
+
+	// Purge of guest accounts nobody has used for the retention period
+	if _, err := jm.cron.AddFunc(jm.config.GuestPurgeSpec, jm.guestPurge); err != nil {
+		return fmt.Errorf("failed to add guest purge job: %w", err)
+	}
+
+	// Resumption of achievement backfills that are queued or were interrupted
+	if _, err := jm.cron.AddFunc(jm.config.BackfillSpec, jm.achievementBackfill); err != nil {
+		return fmt.Errorf("failed to add achievement backfill job: %w", err)
+	}
+
 	// Start the cron scheduler
 	jm.cron.Start()
 	jm.logger.Info("Cron jobs started successfully")
@@ -129,6 +163,95 @@ func (jm *JobManager) dailyStatsUpdate() {
 	)
 }
 
+// questionStatsUpdate recomputes psychometric statistics for all answered questions
+func (jm *JobManager) questionStatsUpdate() {
+	jm.logger.Info("Starting question stats update job")
+	startTime := time.Now()
+
+	count, err := jm.statsService.RefreshAll()
+	if err != nil {
+		jm.logger.Error("Failed to update question stats", zap.Error(err))
+	}
+
+	var flagged int64
+	jm.db.Model(&model.QuestionStats{}).Where("suspected_wrong_key = ?", true).Count(&flagged)
+
+	duration := time.Since(startTime)
+	jm.logger.Info("Question stats update job completed",
+		zap.Duration("duration", duration),
+		zap.Int("question_count", count),
+		zap.Int64("flagged_count", flagged),
+	)
+}
+
+// achievementStatsUpdate recomputes the holder statistics and rarity of all achievements
+func (jm *JobManager) achievementStatsUpdate() {
+	jm.logger.Info("Starting achievement stats update job")
+	startTime := time.Now()
+
+	count, err := jm.achievementService.RefreshAchievementStats()
+	if err != nil {
+		jm.logger.Error("Failed to update achievement stats", zap.Error(err))
+	}
+
+	duration := time.Since(startTime)
+	jm.logger.Info("Achievement stats update job completed",
+		zap.Duration("duration", duration),
+		zap.Int("achievement_count", count),
+	)
+}
+
+// accountPurge anonymizes or deletes accounts whose deletion is due
+func (jm *JobManager) accountPurge() {
+	jm.logger.Info("Starting account purge job")
+	startTime := time.Now()
+
+	count, err := jm.accountService.PurgeDueAccounts()
+	if err != nil {
+		jm.logger.Error("Failed to purge accounts", zap.Error(err))
+	}
+
+	duration := time.Since(startTime)
+	jm.logger.Info("Account purge job completed",
+		zap.Duration("duration", duration),
+		zap.Int("purged_count", count),
+	)
+}
+
+// guestPurge deletes abandoned guest accounts
+func (jm *JobManager) guestPurge() {
+	jm.logger.Info("Starting guest purge job")
+	startTime := time.Now()
+
+	count, err := jm.guestService.PurgeAbandonedGuests()
+	if err != nil {
+		jm.logger.Error("Failed to purge guests", zap.Error(err))
+	}
+
+	duration := time.Since(startTime)
+	jm.logger.Info("Guest purge job completed",
+		zap.Duration("duration", duration),
+		zap.Int("purged_count", count),
+	)
+}
+
+// achievementBackfill resumes achievement backfills left unfinished, e.g. by a restart
+func (jm *JobManager) achievementBackfill() {
+	jm.logger.Info("Starting achievement backfill job")
+	startTime := time.Now()
+
+	count, err := jm.achievementService.ResumeBackfills()
+	if err != nil {
+		jm.logger.Error("Failed to resume achievement backfills", zap.Error(err))
+	}
+
+	duration := time.Since(startTime)
+	jm.logger.Info("Achievement backfill job completed",
+		zap.Duration("duration", duration),
+		zap.Int("backfill_count", count),
+	)
+}
+
 // weeklyReportGeneration generates weekly learning reports
 func (jm *JobManager) weeklyReportGeneration() {
 	jm.logger.Info("Starting weekly report generation job")
@@ -170,11 +293,15 @@ func (jm *JobManager) weeklyReportGeneration() {
 	)
 }
 
-// achievementCheck checks and awards achievements for active users
+// achievementCheck checks and awards achievements for active users. The event bus
+// evaluates users as they play; this job catches anything it failed to deliver. It
+// also announces seasonal achievements whose season started or ended.
 func (jm *JobManager) achievementCheck() {
 	jm.logger.Info("Starting achievement check job")
 	startTime := time.Now()
 
+	jm.announceSeasons()
+
 	// Get users with recent activity (last 24 hours)
 	yesterday := time.Now().AddDate(0, 0, -1).Format("2006-01-02")
 	today := time.Now().Format("2006-01-02")
@@ -212,6 +339,26 @@ func (jm *JobManager) achievementCheck() {
 	)
 }
 
+// announceSeasons records season starts and ends, and announces them to all connected users
+func (jm *JobManager) announceSeasons() {
+	transitions, err := jm.achievementService.AdvanceSeasons(time.Now())
+	if err != nil {
+		jm.logger.Error("Failed to advance achievement seasons", zap.Error(err))
+	}
+
+	for i := range transitions {
+		transition := &transitions[i]
+		if jm.wsHub != nil {
+			jm.wsHub.BroadcastToAll("notification", transition.Notification())
+		}
+
+		jm.logger.Info("Announced achievement season",
+			zap.String("achievement_id", transition.Achievement.ID),
+			zap.String("season_state", transition.To),
+		)
+	}
+}
+
 // updateUserStreak updates a user's learning streak
 func (jm *JobManager) updateUserStreak(userID string) error {
 	// Get user's recent attempts to calculate streak
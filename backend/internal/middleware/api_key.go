@@ -0,0 +1,321 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"paperplay/config"
+	"paperplay/internal/model"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// APIKeyHeader carries the API key of a request
+const APIKeyHeader = "X-API-Key"
+
+// apiKeyPrefix starts every API key, so leaked keys are easy to recognize
+const apiKeyPrefix = "pp_"
+
+// lastUsedInterval limits how often the last-used time of a key is written
+const lastUsedInterval = time.Minute
+
+// API key errors
+var (
+	ErrAPIKeyInvalid  = errors.New("invalid API key")
+	ErrAPIKeyExpired  = errors.New("API key has expired")
+	ErrAPIKeyRevoked  = errors.New("API key has been revoked")
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	ErrUnknownScope   = errors.New("unknown API key scope")
+)
+
+// API key security events
+const (
+	SecurityEventAPIKeyCreated  = "api_key_created"
+	SecurityEventAPIKeyRevoked  = "api_key_revoked"
+	SecurityEventAPIKeyRejected = "api_key_rejected"
+	SecurityEventAPIKeyLimited  = "api_key_rate_limited"
+)
+
+// CreateAPIKeyInput describes a new API key
+type CreateAPIKeyInput struct {
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time // Nil for a key that never expires
+	RateLimit int        // Requests per minute, 0 for the default
+}
+
+// rateWindow counts the requests of one key in the current minute
+type rateWindow struct {
+	start time.Time
+	count int
+}
+
+// APIKeyService issues API keys and authenticates requests made with them.
+// Each key has its own per-minute rate limit, and every request is written to the audit log.
+type APIKeyService struct {
+	db               *gorm.DB
+	logger           *LoggerService
+	defaultRateLimit int
+	now              func() time.Time
+
+	windows   map[string]*rateWindow
+	windowsMu sync.Mutex
+}
+
+// NewAPIKeyService creates a new API key service; logger may be nil
+func NewAPIKeyService(config *config.Config, db *gorm.DB, logger *LoggerService) *APIKeyService {
+	return &APIKeyService{
+		db:               db,
+		logger:           logger,
+		defaultRateLimit: config.APIKeys.DefaultRateLimit,
+		now:              time.Now,
+		windows:          make(map[string]*rateWindow),
+	}
+}
+
+// hashAPIKey returns the hash a key is stored and looked up by. Keys are random, so a
+// plain SHA-256 is enough.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create issues a new key acting for the given administrator. The key itself is
+// returned only here.
+func (s *APIKeyService) Create(adminID string, input CreateAPIKeyInput) (*model.APIKey, string, error) {
+	for _, scope := range input.Scopes {
+		if !slices.Contains(model.APIKeyScopes, scope) {
+			return nil, "", fmt.Errorf("%w: %s", ErrUnknownScope, scope)
+		}
+	}
+
+	random := make([]byte, 24)
+	if _, err := rand.Read(random); err != nil {
+		return nil, "", fmt.Errorf("failed to generate API key: %w", err)
+	}
+	secret := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	scopes := slices.Clone(input.Scopes)
+	slices.Sort(scopes)
+	key := &model.APIKey{
+		Name:      strings.TrimSpace(input.Name),
+		Prefix:    secret[:len(apiKeyPrefix)+8],
+		KeyHash:   hashAPIKey(secret),
+		Scopes:    slices.Compact(scopes),
+		RateLimit: input.RateLimit,
+		CreatedBy: adminID,
+		ExpiresAt: input.ExpiresAt,
+	}
+	if err := s.db.Create(key).Error; err != nil {
+		return nil, "", fmt.Errorf("failed to store API key: %w", err)
+	}
+
+	s.logSecurityEvent(SecurityEventAPIKeyCreated, adminID, "API key created", map[string]any{
+		"api_key_id": key.ID, "name": key.Name, "scopes": key.Scopes,
+	})
+	return key, secret, nil
+}
+
+// List returns all keys, newest first
+func (s *APIKeyService) List() ([]model.APIKey, error) {
+	var keys []model.APIKey
+	if err := s.db.Order("created_at DESC").Find(&keys).Error; err != nil {
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// Revoke disables a key for good
+func (s *APIKeyService) Revoke(adminID, keyID string) error {
+	result := s.db.Model(&model.APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", keyID).
+		Update("revoked_at", s.now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke API key: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrAPIKeyNotFound
+	}
+
+	s.logSecurityEvent(SecurityEventAPIKeyRevoked, adminID, "API key revoked", map[string]any{"api_key_id": keyID})
+	return nil
+}
+
+// Authenticate looks up a key and the administrator it acts for
+func (s *APIKeyService) Authenticate(secret, ip string) (*model.APIKey, *model.User, error) {
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		return nil, nil, ErrAPIKeyInvalid
+	}
+
+	var key model.APIKey
+	if err := s.db.Preload("Creator").Where("key_hash = ?", hashAPIKey(secret)).First(&key).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, nil, ErrAPIKeyInvalid
+		}
+		return nil, nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	now := s.now()
+	switch {
+	case key.RevokedAt != nil:
+		return &key, nil, ErrAPIKeyRevoked
+	case key.IsExpired(now):
+		return &key, nil, ErrAPIKeyExpired
+	case key.Creator == nil || !key.Creator.IsAdmin():
+		// Keys stop working once their creator is no longer an administrator
+		return &key, nil, ErrAPIKeyRevoked
+	}
+
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= lastUsedInterval || key.LastUsedIP != ip {
+		if err := s.db.Model(&key).UpdateColumns(map[string]any{
+			"last_used_at": now,
+			"last_used_ip": ip,
+		}).Error; err != nil {
+			return nil, nil, fmt.Errorf("failed to record API key use: %w", err)
+		}
+	}
+	return &key, key.Creator, nil
+}
+
+// allow counts a request against the key's rate limit, returning how long to wait when
+// the limit is reached
+func (s *APIKeyService) allow(key *model.APIKey) (bool, time.Duration) {
+	limit := key.RateLimit
+	if limit <= 0 {
+		limit = s.defaultRateLimit
+	}
+	now := s.now()
+
+	s.windowsMu.Lock()
+	defer s.windowsMu.Unlock()
+
+	window, ok := s.windows[key.ID]
+	if !ok || now.Sub(window.start) >= time.Minute {
+		window = &rateWindow{start: now}
+		s.windows[key.ID] = window
+	}
+	if window.count >= limit {
+		return false, window.start.Add(time.Minute).Sub(now)
+	}
+	window.count++
+	return true, 0
+}
+
+// Middleware authenticates requests carrying an X-API-Key header and hands all other
+// requests to the JWT bearer middleware. A key acts for the administrator who created it,
+// but only on routes that accept one of its scopes through RequireScope.
+func (s *APIKeyService) Middleware(jwtAuth gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		secret := c.GetHeader(APIKeyHeader)
+		if secret == "" {
+			jwtAuth(c)
+			return
+		}
+
+		key, user, err := s.Authenticate(secret, c.ClientIP())
+		if err != nil {
+			status, message := http.StatusUnauthorized, "Invalid API key"
+			switch {
+			case errors.Is(err, ErrAPIKeyExpired):
+				message = "API key has expired"
+			case errors.Is(err, ErrAPIKeyRevoked):
+				message = "API key has been revoked"
+			case !errors.Is(err, ErrAPIKeyInvalid):
+				status, message = http.StatusInternalServerError, "Failed to check API key"
+			}
+			if key != nil {
+				s.logSecurityEvent(SecurityEventAPIKeyRejected, key.CreatedBy, message, map[string]any{
+					"api_key_id": key.ID, "ip": c.ClientIP(),
+				})
+			}
+			c.JSON(status, gin.H{"error": message})
+			c.Abort()
+			return
+		}
+
+		if ok, retryAfter := s.allow(key); !ok {
+			s.logSecurityEvent(SecurityEventAPIKeyLimited, key.CreatedBy, "API key rate limit reached", map[string]any{
+				"api_key_id": key.ID, "ip": c.ClientIP(),
+			})
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "API key rate limit exceeded"})
+			c.Abort()
+			return
+		}
+
+		c.Set("user_id", user.ID)
+		c.Set("user_email", user.Email)
+		c.Set("user", user)
+		c.Set("api_key", key)
+
+		c.Next()
+
+		if s.logger != nil {
+			s.logger.LogUserAction(user.ID, "api_key_request", c.FullPath(), map[string]any{
+				"api_key_id": key.ID,
+				"method":     c.Request.Method,
+				"path":       c.Request.URL.Path,
+				"status":     c.Writer.Status(),
+				"ip":         c.ClientIP(),
+			})
+		}
+	}
+}
+
+// logSecurityEvent forwards a security event to the logger, if configured
+func (s *APIKeyService) logSecurityEvent(eventType, userID, description string, metadata map[string]any) {
+	if s.logger != nil {
+		s.logger.LogSecurityEvent(eventType, userID, description, metadata)
+	}
+}
+
+// GetCurrentAPIKey extracts the API key a request was authenticated with
+func GetCurrentAPIKey(c *gin.Context) (*model.APIKey, bool) {
+	keyInterface, exists := c.Get("api_key")
+	if !exists {
+		return nil, false
+	}
+
+	key, ok := keyInterface.(*model.APIKey)
+	return key, ok
+}
+
+// RequireScope lets through API keys granted the scope and administrators signed in
+// with a bearer token. It must be chained after APIKeyService.Middleware.
+func RequireScope(scope string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if key, isKey := GetCurrentAPIKey(c); isKey {
+			if !key.HasScope(scope) {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error": "API key lacks the " + scope + " scope",
+				})
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+
+		user, exists := GetCurrentUser(c)
+		if !exists || !user.IsAdmin() {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Administrator privileges required",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
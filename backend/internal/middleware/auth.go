@@ -1,65 +1,234 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"paperplay/config"
 	"paperplay/internal/model"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // JWTClaims represents JWT token claims
 type JWTClaims struct {
-	UserID string `json:"user_id"`
-	Email  string `json:"email"`
+	UserID       string `json:"user_id"`
+	Email        string `json:"email"`
+	SessionID    string `json:"sid,omitempty"`   // Refresh token family the access token was issued for
+	TokenVersion int    `json:"ver"`             // User's token version at issue time
+	Guest        bool   `json:"guest,omitempty"` // Issued to a guest, who may only play
 	jwt.RegisteredClaims
 }
 
+// Refresh token errors
+var (
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	ErrRefreshTokenExpired  = errors.New("refresh token has expired")
+	ErrRefreshTokenReused   = errors.New("refresh token has already been used")
+	ErrSessionNotFound      = errors.New("session not found")
+	ErrAccessTokenRevoked   = errors.New("access token has been revoked")
+)
+
+// Security event types logged by the JWT service
+const (
+	SecurityEventRefreshTokenReuse = "refresh_token_reuse" // A rotated refresh token was presented again
+	SecurityEventSessionsRevoked   = "sessions_revoked"    // An administrator signed a user out everywhere
+)
+
+// maxUserAgentLength bounds the user agent stored with a session
+const maxUserAgentLength = 512
+
+// DeviceInfo describes the client a session is used from
+type DeviceInfo struct {
+	Name      string
+	UserAgent string
+	IP        string
+}
+
+// DeviceFromRequest collects the device information of a request; name is the
+// optional label supplied by the client
+func DeviceFromRequest(c *gin.Context, name string) DeviceInfo {
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	return DeviceInfo{
+		Name:      strings.TrimSpace(name),
+		UserAgent: userAgent,
+		IP:        c.ClientIP(),
+	}
+}
+
+// Session describes a signed-in device: the live refresh token of a token family
+type Session struct {
+	ID         string    `json:"id"`
+	DeviceName string    `json:"device_name"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
 // JWTService handles JWT operations
 type JWTService struct {
 	secretKey            string
+	keysDir              string
+	signingKeyID         string
 	accessTokenDuration  time.Duration
 	refreshTokenDuration time.Duration
 	db                   *gorm.DB
+	logger               *LoggerService
+
+	// Asymmetric signing keys; nil when tokens are signed with the HS256 secret
+	keys   *keyRing
+	keysMu sync.RWMutex
+
+	// Revoked sessions by the expiry of their denial, mirrored from the revoked_sessions
+	// table so requests are checked without a query
+	revoked   map[string]time.Time
+	revokedMu sync.RWMutex
 }
 
-// NewJWTService creates a new JWT service
-func NewJWTService(config *config.Config, db *gorm.DB) *JWTService {
-	return &JWTService{
+// NewJWTService creates a new JWT service; logger may be nil. Access tokens are signed
+// with the keys in jwt.keys_dir, or with the HS256 secret when no directory is set.
+func NewJWTService(config *config.Config, db *gorm.DB, logger *LoggerService) (*JWTService, error) {
+	j := &JWTService{
 		secretKey:            config.JWT.SecretKey,
+		keysDir:              config.JWT.KeysDir,
+		signingKeyID:         config.JWT.SigningKeyID,
 		accessTokenDuration:  time.Duration(config.JWT.AccessTokenDuration) * time.Minute,
 		refreshTokenDuration: time.Duration(config.JWT.RefreshTokenDuration) * 24 * time.Hour,
 		db:                   db,
+		logger:               logger,
 	}
+	if err := j.ReloadKeys(); err != nil {
+		return nil, err
+	}
+	if err := j.ReloadRevokedSessions(); err != nil {
+		return nil, err
+	}
+	return j, nil
 }
 
-// GenerateTokenPair generates both access and refresh tokens for a user
-func (j *JWTService) GenerateTokenPair(user *model.User) (accessToken, refreshToken string, err error) {
-	// Generate access token
-	accessToken, err = j.GenerateAccessToken(user)
+// ReloadKeys reads the signing keys again, so a newly added key takes over signing
+// while tokens signed with the older keys still verify. On error the current keys stay.
+func (j *JWTService) ReloadKeys() error {
+	if j.keysDir == "" {
+		return nil
+	}
+	ring, err := loadKeyRing(j.keysDir, j.signingKeyID)
 	if err != nil {
-		return "", "", fmt.Errorf("failed to generate access token: %w", err)
+		return fmt.Errorf("failed to load JWT signing keys: %w", err)
 	}
 
+	j.keysMu.Lock()
+	j.keys = ring
+	j.keysMu.Unlock()
+
+	if j.logger != nil {
+		j.logger.GetSugar().Infof("Loaded %d JWT keys, signing with %q (%s)",
+			len(ring.keys), ring.active.id, ring.active.method.Alg())
+	}
+	return nil
+}
+
+// ReloadRevokedSessions reads the revoked sessions again, picking up those revoked
+// by other instances. On error the current set stays.
+func (j *JWTService) ReloadRevokedSessions() error {
+	var sessions []model.RevokedSession
+	if err := j.db.Where("expires_at > ?", time.Now()).Find(&sessions).Error; err != nil {
+		return fmt.Errorf("failed to load revoked sessions: %w", err)
+	}
+
+	now := time.Now()
+	j.revokedMu.Lock()
+	defer j.revokedMu.Unlock()
+	revoked := make(map[string]time.Time, len(sessions))
+	for _, session := range sessions {
+		revoked[session.SessionID] = session.ExpiresAt
+	}
+	// Keep sessions revoked here while the query ran; revocations are never undone
+	for id, until := range j.revoked {
+		if until.After(now) && until.After(revoked[id]) {
+			revoked[id] = until
+		}
+	}
+	j.revoked = revoked
+	return nil
+}
+
+// keyRing returns the current signing keys, or nil in HS256 mode
+func (j *JWTService) keyRing() *keyRing {
+	j.keysMu.RLock()
+	defer j.keysMu.RUnlock()
+	return j.keys
+}
+
+// signToken signs claims with the active key, naming it in the kid header
+func (j *JWTService) signToken(claims jwt.Claims) (string, error) {
+	ring := j.keyRing()
+	if ring == nil {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(j.secretKey))
+	}
+
+	token := jwt.NewWithClaims(ring.active.method, claims)
+	token.Header["kid"] = ring.active.id
+	return token.SignedString(ring.active.private)
+}
+
+// verificationKey picks the key a token is verified with from its kid header
+func (j *JWTService) verificationKey(token *jwt.Token) (any, error) {
+	ring := j.keyRing()
+	if ring == nil {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(j.secretKey), nil
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	key, ok := ring.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", errUnknownKeyID, kid)
+	}
+	if token.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return key.public, nil
+}
+
+// GenerateTokenPair starts a new session for a user on the given device
+func (j *JWTService) GenerateTokenPair(user *model.User, device DeviceInfo) (accessToken, refreshToken string, err error) {
 	// Generate refresh token
-	refreshToken, err = j.GenerateRefreshToken(user)
+	stored, err := j.GenerateRefreshToken(user, device)
 	if err != nil {
 		return "", "", fmt.Errorf("failed to generate refresh token: %w", err)
 	}
 
-	return accessToken, refreshToken, nil
+	// Generate access token
+	accessToken, err = j.GenerateAccessToken(user, stored.FamilyID)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to generate access token: %w", err)
+	}
+
+	return accessToken, stored.Token, nil
 }
 
-// GenerateAccessToken generates a new access token for the user
-func (j *JWTService) GenerateAccessToken(user *model.User) (string, error) {
+// GenerateAccessToken generates a new access token for the user within a session
+func (j *JWTService) GenerateAccessToken(user *model.User, sessionID string) (string, error) {
 	claims := JWTClaims{
-		UserID: user.ID,
-		Email:  user.Email,
+		UserID:       user.ID,
+		Email:        user.Email,
+		SessionID:    sessionID,
+		TokenVersion: user.TokenVersion,
+		Guest:        user.IsGuest(),
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(j.accessTokenDuration)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -69,38 +238,37 @@ func (j *JWTService) GenerateAccessToken(user *model.User) (string, error) {
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(j.secretKey))
+	return j.signToken(claims)
 }
 
-// GenerateRefreshToken generates and stores a new refresh token for the user
-func (j *JWTService) GenerateRefreshToken(user *model.User) (string, error) {
+// GenerateRefreshToken generates and stores the first refresh token of a new session
+func (j *JWTService) GenerateRefreshToken(user *model.User, device DeviceInfo) (*model.RefreshToken, error) {
 	// Clean up expired refresh tokens for this user
 	if err := j.CleanupExpiredRefreshTokens(user.ID); err != nil {
-		return "", fmt.Errorf("failed to cleanup expired tokens: %w", err)
+		return nil, fmt.Errorf("failed to cleanup expired tokens: %w", err)
 	}
 
 	// Create new refresh token
+	now := time.Now()
 	refreshToken := &model.RefreshToken{
-		UserID:    user.ID,
-		ExpiresAt: time.Now().Add(j.refreshTokenDuration),
+		UserID:     user.ID,
+		DeviceName: device.Name,
+		UserAgent:  device.UserAgent,
+		IPAddress:  device.IP,
+		LastUsedAt: &now,
+		ExpiresAt:  now.Add(j.refreshTokenDuration),
 	}
 
 	if err := j.db.Create(refreshToken).Error; err != nil {
-		return "", fmt.Errorf("failed to store refresh token: %w", err)
+		return nil, fmt.Errorf("failed to store refresh token: %w", err)
 	}
 
-	return refreshToken.Token, nil
+	return refreshToken, nil
 }
 
 // ValidateAccessToken validates and parses an access token
 func (j *JWTService) ValidateAccessToken(tokenString string) (*JWTClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (any, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(j.secretKey), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, j.verificationKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse token: %w", err)
 	}
@@ -112,17 +280,69 @@ func (j *JWTService) ValidateAccessToken(tokenString string) (*JWTClaims, error)
 	return nil, fmt.Errorf("invalid token")
 }
 
+// CheckRevocation rejects an access token whose token version is older than the user's
+// or whose session was revoked. The version comes with the user the middleware loads
+// anyway; revoked sessions are stored so they hold across restarts and instances.
+func (j *JWTService) CheckRevocation(claims *JWTClaims, user *model.User) error {
+	if claims.TokenVersion != user.TokenVersion {
+		return ErrAccessTokenRevoked
+	}
+	if claims.SessionID == "" {
+		return nil
+	}
+
+	j.revokedMu.RLock()
+	expiresAt, revoked := j.revoked[claims.SessionID]
+	j.revokedMu.RUnlock()
+	if revoked && expiresAt.After(time.Now()) {
+		return ErrAccessTokenRevoked
+	}
+	return nil
+}
+
+// denySession rejects the access tokens already issued for a revoked session until
+// the last of them expires, and drops the denials that have run out
+func (j *JWTService) denySession(tx *gorm.DB, sessionID string) error {
+	now := time.Now()
+	if err := tx.Where("expires_at <= ?", now).Delete(&model.RevokedSession{}).Error; err != nil {
+		return fmt.Errorf("failed to prune revoked sessions: %w", err)
+	}
+	expiresAt := now.Add(j.accessTokenDuration)
+	if err := tx.Clauses(clause.OnConflict{UpdateAll: true}).Create(&model.RevokedSession{
+		SessionID: sessionID,
+		ExpiresAt: expiresAt,
+	}).Error; err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	j.revokedMu.Lock()
+	defer j.revokedMu.Unlock()
+	if j.revoked == nil {
+		j.revoked = make(map[string]time.Time)
+	}
+	for id, until := range j.revoked {
+		if !until.After(now) {
+			delete(j.revoked, id)
+		}
+	}
+	j.revoked[sessionID] = expiresAt
+	return nil
+}
+
 // ValidateRefreshToken validates a refresh token and returns the associated user
 func (j *JWTService) ValidateRefreshToken(tokenString string) (*model.User, error) {
 	var refreshToken model.RefreshToken
 	if err := j.db.Where("token = ?", tokenString).First(&refreshToken).Error; err != nil {
-		return nil, fmt.Errorf("refresh token not found: %w", err)
+		return nil, ErrRefreshTokenNotFound
 	}
 
 	if refreshToken.IsExpired() {
 		// Clean up expired token
 		j.db.Delete(&refreshToken)
-		return nil, fmt.Errorf("refresh token has expired")
+		return nil, ErrRefreshTokenExpired
+	}
+	if refreshToken.IsRotated() {
+		return nil, ErrRefreshTokenReused
 	}
 
 	// Get associated user
@@ -134,14 +354,172 @@ func (j *JWTService) ValidateRefreshToken(tokenString string) (*model.User, erro
 	return &user, nil
 }
 
-// RefreshAccessToken generates a new access token using a refresh token
-func (j *JWTService) RefreshAccessToken(refreshTokenString string) (string, error) {
-	user, err := j.ValidateRefreshToken(refreshTokenString)
+// RotateRefreshToken exchanges a refresh token for a new access and refresh token pair.
+// The presented token is marked as rotated; presenting it again revokes its whole family,
+// since either the legitimate client or an attacker holds a copy of it.
+// The new token keeps the session's device name and records the device it was used from.
+func (j *JWTService) RotateRefreshToken(refreshTokenString string, device DeviceInfo) (accessToken, refreshToken string, err error) {
+	var user model.User
+	var reused *model.RefreshToken
+	var sessionID string
+
+	err = j.db.Transaction(func(tx *gorm.DB) error {
+		var current model.RefreshToken
+		if err := tx.Where("token = ?", refreshTokenString).First(&current).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrRefreshTokenNotFound
+			}
+			return fmt.Errorf("failed to get refresh token: %w", err)
+		}
+
+		if current.IsRotated() {
+			reused = &current
+			return nil
+		}
+		if current.IsExpired() {
+			return ErrRefreshTokenExpired
+		}
+
+		// Guard against a concurrent rotation of the same token
+		now := time.Now()
+		result := tx.Model(&model.RefreshToken{}).
+			Where("token = ? AND rotated_at IS NULL", current.Token).
+			Update("rotated_at", now)
+		if result.Error != nil {
+			return fmt.Errorf("failed to rotate refresh token: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			reused = &current
+			return nil
+		}
+
+		if err := tx.First(&user, "id = ?", current.UserID).Error; err != nil {
+			return fmt.Errorf("user not found: %w", err)
+		}
+
+		next := &model.RefreshToken{
+			UserID:     current.UserID,
+			FamilyID:   current.FamilyID,
+			DeviceName: current.DeviceName,
+			UserAgent:  current.UserAgent,
+			IPAddress:  current.IPAddress,
+			LastUsedAt: &now,
+			ExpiresAt:  now.Add(j.refreshTokenDuration),
+		}
+		if device.Name != "" {
+			next.DeviceName = device.Name
+		}
+		if device.UserAgent != "" {
+			next.UserAgent = device.UserAgent
+		}
+		if device.IP != "" {
+			next.IPAddress = device.IP
+		}
+		if err := tx.Create(next).Error; err != nil {
+			return fmt.Errorf("failed to store refresh token: %w", err)
+		}
+		refreshToken = next.Token
+		sessionID = next.FamilyID
+		return nil
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if reused != nil {
+		if err := j.RevokeTokenFamily(reused.FamilyID); err != nil {
+			return "", "", err
+		}
+		j.logSecurityEvent(SecurityEventRefreshTokenReuse, reused.UserID,
+			"Rotated refresh token presented again, token family revoked",
+			map[string]any{"family_id": reused.FamilyID, "rotated_at": reused.RotatedAt})
+		return "", "", ErrRefreshTokenReused
+	}
+
+	accessToken, err = j.GenerateAccessToken(&user, sessionID)
 	if err != nil {
-		return "", err
+		return "", "", fmt.Errorf("failed to generate access token: %w", err)
+	}
+	return accessToken, refreshToken, nil
+}
+
+// RevokeTokenFamily revokes every refresh token descended from the same login
+func (j *JWTService) RevokeTokenFamily(familyID string) error {
+	return j.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("family_id = ?", familyID).Delete(&model.RefreshToken{}).Error; err != nil {
+			return fmt.Errorf("failed to revoke token family: %w", err)
+		}
+		return j.denySession(tx, familyID)
+	})
+}
+
+// ListSessions returns the active sessions of a user, most recently used first.
+// currentSessionID marks the session the request was made from.
+func (j *JWTService) ListSessions(userID, currentSessionID string) ([]Session, error) {
+	var tokens []model.RefreshToken
+	if err := j.db.Where("user_id = ? AND rotated_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_used_at DESC").
+		Find(&tokens).Error; err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	sessions := make([]Session, 0, len(tokens))
+	for _, token := range tokens {
+		lastUsed := token.CreatedAt
+		if token.LastUsedAt != nil {
+			lastUsed = *token.LastUsedAt
+		}
+		sessions = append(sessions, Session{
+			ID:         token.FamilyID,
+			DeviceName: token.DeviceName,
+			UserAgent:  token.UserAgent,
+			IPAddress:  token.IPAddress,
+			LastUsedAt: lastUsed,
+			ExpiresAt:  token.ExpiresAt,
+			Current:    token.FamilyID == currentSessionID,
+		})
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs one of the user's sessions out by revoking its token family
+// and the access tokens already issued for it
+func (j *JWTService) RevokeSession(userID, sessionID string) error {
+	return j.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("user_id = ? AND family_id = ?", userID, sessionID).Delete(&model.RefreshToken{})
+		if result.Error != nil {
+			return fmt.Errorf("failed to revoke session: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return ErrSessionNotFound
+		}
+		return j.denySession(tx, sessionID)
+	})
+}
+
+// RevokeAllSessions signs a user out everywhere, including access tokens already issued
+func (j *JWTService) RevokeAllSessions(userID string) error {
+	if err := model.RevokeAllTokens(j.db, userID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserSessions signs a user out everywhere on behalf of an administrator
+func (j *JWTService) RevokeUserSessions(adminID, userID string) error {
+	if err := j.RevokeAllSessions(userID); err != nil {
+		return err
 	}
+	j.logSecurityEvent(SecurityEventSessionsRevoked, userID,
+		"All sessions revoked by an administrator", map[string]any{"admin_id": adminID})
+	return nil
+}
 
-	return j.GenerateAccessToken(user)
+// logSecurityEvent forwards a security event to the logger, if configured
+func (j *JWTService) logSecurityEvent(eventType, userID, description string, metadata map[string]any) {
+	if j.logger != nil {
+		j.logger.LogSecurityEvent(eventType, userID, description, metadata)
+	}
 }
 
 // RevokeRefreshToken revokes a refresh token
@@ -201,10 +579,19 @@ func (j *JWTService) AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		if err := j.CheckRevocation(claims, &user); err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"error": "Token has been revoked",
+			})
+			c.Abort()
+			return
+		}
+
 		// Store user information in context
 		c.Set("user_id", user.ID)
 		c.Set("user_email", user.Email)
 		c.Set("user", &user)
+		c.Set("session_id", claims.SessionID)
 
 		c.Next()
 	}
@@ -239,11 +626,59 @@ func (j *JWTService) OptionalAuthMiddleware() gin.HandlerFunc {
 			c.Next()
 			return
 		}
+		if err := j.CheckRevocation(claims, &user); err != nil {
+			c.Next()
+			return
+		}
 
 		// Store user information in context
 		c.Set("user_id", user.ID)
 		c.Set("user_email", user.Email)
 		c.Set("user", &user)
+		c.Set("session_id", claims.SessionID)
+
+		c.Next()
+	}
+}
+
+// RequireAdmin creates a middleware that only lets administrators through.
+// It must be chained after AuthMiddleware. Requests made with an API key are
+// refused; routes open to API keys use RequireScope instead.
+func RequireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, isKey := GetCurrentAPIKey(c); isKey {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "API keys cannot access this endpoint",
+			})
+			c.Abort()
+			return
+		}
+
+		user, exists := GetCurrentUser(c)
+		if !exists || !user.IsAdmin() {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Administrator privileges required",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// RequireRegistered creates a middleware that refuses guests, who can play but not
+// manage an account until they upgrade. It must be chained after AuthMiddleware.
+func RequireRegistered() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := GetCurrentUser(c)
+		if !exists || user.IsGuest() {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "Guest accounts must be upgraded to use this endpoint",
+			})
+			c.Abort()
+			return
+		}
 
 		c.Next()
 	}
@@ -271,6 +706,13 @@ func GetCurrentUserID(c *gin.Context) (string, bool) {
 	return userIDStr, ok
 }
 
+// GetCurrentSessionID extracts the session of the current access token from Gin context.
+// Tokens issued before sessions were tracked carry no session.
+func GetCurrentSessionID(c *gin.Context) (string, bool) {
+	sessionID := c.GetString("session_id")
+	return sessionID, sessionID != ""
+}
+
 // MustGetCurrentUser extracts the current user from context or panics
 func MustGetCurrentUser(c *gin.Context) *model.User {
 	user, exists := GetCurrentUser(c)
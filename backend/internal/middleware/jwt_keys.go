@@ -0,0 +1,203 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// minRSAKeyBits is the smallest RSA modulus accepted for signing keys
+const minRSAKeyBits = 2048
+
+// publicKeySuffix marks key files that hold only a public key. Such keys verify tokens
+// signed before the private key was retired, but never sign new ones.
+const publicKeySuffix = ".pub"
+
+// signingKey is one key of the key ring, identified by the file it was loaded from
+type signingKey struct {
+	id      string
+	method  jwt.SigningMethod
+	private crypto.Signer // Nil for verification-only keys
+	public  crypto.PublicKey
+}
+
+// keyRing holds every key tokens may be verified with and the one new tokens are signed with
+type keyRing struct {
+	keys   map[string]*signingKey
+	active *signingKey
+}
+
+// loadKeyRing loads every *.pem file in dir. The file name without extension is the
+// key ID. activeID selects the signing key; when empty, the private key with the
+// greatest ID is used, so keys named by date rotate by adding a newer file.
+func loadKeyRing(dir, activeID string) (*keyRing, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list key files: %w", err)
+	}
+
+	ring := &keyRing{keys: make(map[string]*signingKey)}
+	var signers []string
+	for _, path := range paths {
+		key, err := loadKeyFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, exists := ring.keys[key.id]; exists {
+			return nil, fmt.Errorf("duplicate key ID %q in %s", key.id, dir)
+		}
+		ring.keys[key.id] = key
+		if key.private != nil {
+			signers = append(signers, key.id)
+		}
+	}
+	if len(signers) == 0 {
+		return nil, fmt.Errorf("no private signing key found in %s", dir)
+	}
+
+	if activeID == "" {
+		sort.Strings(signers)
+		activeID = signers[len(signers)-1]
+	}
+	active, ok := ring.keys[activeID]
+	if !ok {
+		return nil, fmt.Errorf("signing key %q not found in %s", activeID, dir)
+	}
+	if active.private == nil {
+		return nil, fmt.Errorf("signing key %q has no private key", activeID)
+	}
+	ring.active = active
+	return ring, nil
+}
+
+// loadKeyFile parses a PEM encoded RSA or Ed25519 key, private or public
+func loadKeyFile(path string) (*signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read key file: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no PEM data in %s", path)
+	}
+
+	id := strings.TrimSuffix(filepath.Base(path), ".pem")
+	key := &signingKey{id: strings.TrimSuffix(id, publicKeySuffix)}
+
+	var parsed any
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q in %s", block.Type, path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse key %s: %w", path, err)
+	}
+
+	switch k := parsed.(type) {
+	case *rsa.PrivateKey:
+		key.private, key.public = k, &k.PublicKey
+	case ed25519.PrivateKey:
+		key.private, key.public = k, k.Public()
+	case *rsa.PublicKey, ed25519.PublicKey:
+		key.public = k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T in %s, use RSA or Ed25519", parsed, path)
+	}
+
+	switch k := key.public.(type) {
+	case *rsa.PublicKey:
+		if k.N.BitLen() < minRSAKeyBits {
+			return nil, fmt.Errorf("RSA key %s is shorter than %d bits", path, minRSAKeyBits)
+		}
+		key.method = jwt.SigningMethodRS256
+	case ed25519.PublicKey:
+		key.method = jwt.SigningMethodEdDSA
+	}
+	return key, nil
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"` // Ed25519 keys
+	X         string `json:"x,omitempty"`   // Ed25519 keys
+	N         string `json:"n,omitempty"`   // RSA keys
+	E         string `json:"e,omitempty"`   // RSA keys
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// jwk converts the public half of a key to JWK format
+func (k *signingKey) jwk() JWK {
+	encode := base64.RawURLEncoding.EncodeToString
+	jwk := JWK{KeyID: k.id, Use: "sig", Algorithm: k.method.Alg()}
+
+	switch pub := k.public.(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.N = encode(pub.N.Bytes())
+		jwk.E = encode(big.NewInt(int64(pub.E)).Bytes())
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = encode(pub)
+	}
+	return jwk
+}
+
+// errUnknownKeyID is returned for tokens signed with a key that is not in the key ring
+var errUnknownKeyID = errors.New("unknown signing key")
+
+// JWKS returns the public keys access tokens are verified with. It is empty when
+// tokens are signed with the shared HS256 secret.
+func (j *JWTService) JWKS() JWKSet {
+	set := JWKSet{Keys: []JWK{}}
+
+	ring := j.keyRing()
+	if ring == nil {
+		return set
+	}
+	ids := make([]string, 0, len(ring.keys))
+	for id := range ring.keys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		set.Keys = append(set.Keys, ring.keys[id].jwk())
+	}
+	return set
+}
+
+// JWKSHandler serves GET /.well-known/jwks.json
+func (j *JWTService) JWKSHandler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, j.JWKS())
+	}
+}
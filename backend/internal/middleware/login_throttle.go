@@ -0,0 +1,288 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"paperplay/config"
+	"paperplay/internal/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ErrLoginThrottled is wrapped by LoginThrottledError
+var ErrLoginThrottled = errors.New("too many failed login attempts")
+
+// Login security events
+const (
+	SecurityEventLoginSucceeded = "login_succeeded"
+	SecurityEventLoginFailed    = "login_failed"
+	SecurityEventLoginThrottled = "login_throttled"
+	SecurityEventLoginLocked    = "login_locked"
+	SecurityEventLoginUnlocked  = "login_unlocked"
+)
+
+// LoginThrottledError reports that an account or client IP has to wait before the next login attempt
+type LoginThrottledError struct {
+	Scope      string        // model.ThrottleScopeAccount or model.ThrottleScopeIP
+	Locked     bool          // Locked out, rather than backing off between attempts
+	RetryAfter time.Duration // Time until the next attempt is allowed
+}
+
+func (e *LoginThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("%s locked out after too many failed login attempts, retry in %s", e.Scope, e.RetryAfter)
+	}
+	return fmt.Sprintf("too many failed login attempts for %s, retry in %s", e.Scope, e.RetryAfter)
+}
+
+func (e *LoginThrottledError) Unwrap() error {
+	return ErrLoginThrottled
+}
+
+// LoginThrottler limits password guessing. Failed logins are counted per account and per
+// client IP: after a few failures each attempt on the account has to wait exponentially
+// longer, and reaching either limit locks logins out for a while. Outcomes are recorded as metrics and
+// security events.
+type LoginThrottler struct {
+	db      *gorm.DB
+	cfg     config.AuthConfig
+	logger  *LoggerService
+	metrics *MetricsService
+	now     func() time.Time
+}
+
+// NewLoginThrottler creates a new login throttler; logger and metrics may be nil
+func NewLoginThrottler(config *config.Config, db *gorm.DB, logger *LoggerService, metrics *MetricsService) *LoginThrottler {
+	return &LoginThrottler{
+		db:      db,
+		cfg:     config.Auth,
+		logger:  logger,
+		metrics: metrics,
+		now:     time.Now,
+	}
+}
+
+// throttleKey identifies a throttle row
+type throttleKey struct {
+	scope      string
+	identifier string
+}
+
+// keys returns the throttles that apply to a login attempt
+func (t *LoginThrottler) keys(email, ip string) []throttleKey {
+	keys := []throttleKey{{model.ThrottleScopeAccount, strings.ToLower(strings.TrimSpace(email))}}
+	if ip != "" {
+		keys = append(keys, throttleKey{model.ThrottleScopeIP, ip})
+	}
+	return keys
+}
+
+// maxFailures returns the lockout threshold of a scope
+func (t *LoginThrottler) maxFailures(scope string) int {
+	switch scope {
+	case model.ThrottleScopeIP:
+		return t.cfg.MaxFailedAttemptsPerIP
+	case model.ThrottleScopeGuest:
+		return t.cfg.MaxGuestsPerIP
+	}
+	return t.cfg.MaxFailedAttempts
+}
+
+// backoff returns how long to wait after the given number of consecutive failures.
+// Client IPs are only locked out: many users may share one address.
+func (t *LoginThrottler) backoff(scope string, failures int) time.Duration {
+	if scope != model.ThrottleScopeAccount || failures <= t.cfg.BackoffAfter || t.cfg.BackoffBase == 0 {
+		return 0
+	}
+	maxBackoff := time.Duration(t.cfg.MaxBackoff) * time.Second
+	delay := time.Duration(t.cfg.BackoffBase) * time.Second
+	for i := t.cfg.BackoffAfter + 1; i < failures; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return min(delay, maxBackoff)
+}
+
+// isStale reports whether a throttle's failures no longer count: the last one is older than
+// the failure window, or a lockout has run out
+func (t *LoginThrottler) isStale(throttle *model.LoginThrottle, now time.Time) bool {
+	if throttle.LockedUntil != nil {
+		return !throttle.IsLocked(now)
+	}
+	window := time.Duration(t.cfg.FailureWindow) * time.Minute
+	return throttle.LastFailureAt == nil || now.Sub(*throttle.LastFailureAt) > window
+}
+
+// find loads a throttle, returning nil if there is none
+func (t *LoginThrottler) find(tx *gorm.DB, key throttleKey) (*model.LoginThrottle, error) {
+	var throttle model.LoginThrottle
+	err := tx.Where("scope = ? AND identifier = ?", key.scope, key.identifier).First(&throttle).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get login throttle: %w", err)
+	}
+	return &throttle, nil
+}
+
+// Check returns a *LoginThrottledError if the account or client IP may not attempt a login yet.
+// It must be called before the password is verified.
+func (t *LoginThrottler) Check(email, ip string) error {
+	return t.check(t.keys(email, ip), email, ip)
+}
+
+// CheckGuest returns a *LoginThrottledError if the client IP has created too many guest
+// accounts recently. It must be called before the guest account is created.
+func (t *LoginThrottler) CheckGuest(ip string) error {
+	if ip == "" {
+		return nil
+	}
+	return t.check([]throttleKey{{model.ThrottleScopeGuest, ip}}, "", ip)
+}
+
+// check returns a *LoginThrottledError for the first of the throttles that blocks an attempt
+func (t *LoginThrottler) check(keys []throttleKey, email, ip string) error {
+	now := t.now()
+	for _, key := range keys {
+		throttle, err := t.find(t.db, key)
+		if err != nil {
+			return err
+		}
+		if throttle == nil || t.isStale(throttle, now) {
+			continue
+		}
+
+		var throttled *LoginThrottledError
+		if throttle.IsLocked(now) {
+			throttled = &LoginThrottledError{Scope: key.scope, Locked: true, RetryAfter: throttle.LockedUntil.Sub(now)}
+		} else if next := throttle.LastFailureAt.Add(t.backoff(key.scope, throttle.Failures)); now.Before(next) {
+			throttled = &LoginThrottledError{Scope: key.scope, RetryAfter: next.Sub(now)}
+		}
+		if throttled != nil {
+			t.recordLogin(false)
+			t.logSecurityEvent(SecurityEventLoginThrottled, "", "Login attempt rejected by throttling", map[string]any{
+				"email": strings.ToLower(email), "ip": ip, "scope": key.scope,
+				"locked": throttled.Locked, "retry_after_seconds": int(throttled.RetryAfter.Seconds()),
+			})
+			return throttled
+		}
+	}
+	return nil
+}
+
+// RecordFailure counts a failed login against the account and the client IP, locking them
+// out once they reach their limit. userID is empty for unknown accounts.
+func (t *LoginThrottler) RecordFailure(userID, email, ip string) error {
+	now := t.now()
+	t.recordLogin(false)
+	t.logSecurityEvent(SecurityEventLoginFailed, userID, "Failed login attempt", map[string]any{
+		"email": strings.ToLower(email), "ip": ip,
+	})
+
+	for _, key := range t.keys(email, ip) {
+		if err := t.count(key, userID, now); err != nil {
+			return err
+		}
+	}
+	return t.pruneStale(now)
+}
+
+// RecordGuest counts a guest account created from the client IP, locking guest creation
+// out for the IP once it reaches its limit
+func (t *LoginThrottler) RecordGuest(userID, ip string) error {
+	if ip == "" {
+		return nil
+	}
+	now := t.now()
+	if err := t.count(throttleKey{model.ThrottleScopeGuest, ip}, userID, now); err != nil {
+		return err
+	}
+	return t.pruneStale(now)
+}
+
+// count adds an attempt to a throttle and locks it out once it reaches the limit of its scope
+func (t *LoginThrottler) count(key throttleKey, userID string, now time.Time) error {
+	throttle, err := t.find(t.db, key)
+	if err != nil {
+		return err
+	}
+	if throttle == nil {
+		throttle = &model.LoginThrottle{Scope: key.scope, Identifier: key.identifier}
+	} else if t.isStale(throttle, now) {
+		throttle.Failures = 0
+		throttle.LockedUntil = nil
+	}
+
+	throttle.Failures++
+	throttle.LastFailureAt = &now
+	if throttle.Failures >= t.maxFailures(key.scope) && throttle.LockedUntil == nil {
+		lockedUntil := now.Add(time.Duration(t.cfg.LockoutDuration) * time.Minute)
+		throttle.LockedUntil = &lockedUntil
+		t.logSecurityEvent(SecurityEventLoginLocked, userID, "Logins locked out after too many failed attempts", map[string]any{
+			"scope": key.scope, "identifier": key.identifier, "failures": throttle.Failures, "locked_until": lockedUntil,
+		})
+	}
+
+	if err := t.db.Save(throttle).Error; err != nil {
+		return fmt.Errorf("failed to save login throttle: %w", err)
+	}
+	return nil
+}
+
+// pruneStale deletes throttles whose failures no longer count, so guesses against
+// made-up email addresses do not pile up
+func (t *LoginThrottler) pruneStale(now time.Time) error {
+	cutoff := now.Add(-time.Duration(t.cfg.FailureWindow) * time.Minute)
+	if err := t.db.Where("(locked_until IS NULL AND last_failure_at < ?) OR locked_until <= ?", cutoff, now).
+		Delete(&model.LoginThrottle{}).Error; err != nil {
+		return fmt.Errorf("failed to prune login throttles: %w", err)
+	}
+	return nil
+}
+
+// RecordSuccess clears the account's failed logins. The client IP keeps its count, so one
+// valid account cannot be used to keep guessing passwords of others.
+func (t *LoginThrottler) RecordSuccess(userID, email, ip string) error {
+	t.recordLogin(true)
+	t.logSecurityEvent(SecurityEventLoginSucceeded, userID, "Successful login", map[string]any{"ip": ip})
+
+	key := t.keys(email, "")[0]
+	if err := t.db.Where("scope = ? AND identifier = ?", key.scope, key.identifier).
+		Delete(&model.LoginThrottle{}).Error; err != nil {
+		return fmt.Errorf("failed to clear login throttle: %w", err)
+	}
+	return nil
+}
+
+// Unlock clears the failed logins of an account and, if given, a client IP
+func (t *LoginThrottler) Unlock(adminID, email, ip string) error {
+	for _, key := range t.keys(email, ip) {
+		if err := t.db.Where("scope = ? AND identifier = ?", key.scope, key.identifier).
+			Delete(&model.LoginThrottle{}).Error; err != nil {
+			return fmt.Errorf("failed to clear login throttle: %w", err)
+		}
+	}
+	t.logSecurityEvent(SecurityEventLoginUnlocked, adminID, "Login throttle cleared by administrator", map[string]any{
+		"email": strings.ToLower(email), "ip": ip,
+	})
+	return nil
+}
+
+// recordLogin records a login outcome in the metrics, if configured
+func (t *LoginThrottler) recordLogin(success bool) {
+	if t.metrics != nil {
+		t.metrics.RecordUserLogin(success)
+	}
+}
+
+// logSecurityEvent forwards a security event to the logger, if configured
+func (t *LoginThrottler) logSecurityEvent(eventType, userID, description string, metadata map[string]any) {
+	if t.logger != nil {
+		t.logger.LogSecurityEvent(eventType, userID, description, metadata)
+	}
+}
@@ -10,22 +10,95 @@ import (
 
 // Achievement represents an achievement definition
 type Achievement struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:text"`
+	Name        string     `json:"name" gorm:"not null;type:text"`
+	Description string     `json:"description" gorm:"not null;type:text"`
+	Level       int        `json:"level" gorm:"not null;default:1"`            // Achievement level (Bronze=1, Silver=2, Gold=3)
+	IconURL     string     `json:"icon_url" gorm:"type:text"`                  // Icon image URL
+	BadgeType   string     `json:"badge_type" gorm:"not null;type:text"`       // Category: "learning", "streak", "speed", etc.
+	RuleJSON    string     `json:"rule_json" gorm:"not null;type:text"`        // Achievement trigger rules as JSON
+	NFTEnabled  bool       `json:"nft_enabled" gorm:"default:false"`           // Whether this achievement generates NFT
+	NFTMetadata string     `json:"nft_metadata" gorm:"type:text"`              // NFT metadata template as JSON
+	IsActive    bool       `json:"is_active" gorm:"default:true"`              // Whether this achievement is active
+	SeriesID    *string    `json:"series_id,omitempty" gorm:"type:text;index"` // Series this achievement is a tier of
+	Tier        int        `json:"tier,omitempty" gorm:"not null;default:0"`   // 1-based tier within the series, 0 if standalone
+	StartsAt    *time.Time `json:"starts_at,omitempty" gorm:"type:datetime"`   // Seasonal: hidden and not earned before this time
+	EndsAt      *time.Time `json:"ends_at,omitempty" gorm:"type:datetime"`     // Seasonal: no longer earned from this time on
+	SeasonState string     `json:"season_state,omitempty" gorm:"type:text"`    // Season state last seen by the achievement check job
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"not null"`
+
+	// Associations
+	Series           *AchievementSeries `json:"series,omitempty" gorm:"foreignKey:SeriesID"`
+	UserAchievements []UserAchievement  `json:"user_achievements,omitempty" gorm:"foreignKey:AchievementID;constraint:OnDelete:CASCADE"`
+	NFTAssets        []NFTAsset         `json:"nft_assets,omitempty" gorm:"foreignKey:AchievementID"`
+	Stats            *AchievementStats  `json:"stats,omitempty" gorm:"foreignKey:AchievementID"`
+}
+
+// IsTiered checks if the achievement is a tier of a series
+func (a *Achievement) IsTiered() bool {
+	return a.SeriesID != nil && a.Tier > 0
+}
+
+// Season states of a seasonal achievement
+const (
+	SeasonUpcoming = "upcoming"
+	SeasonOpen     = "open"
+	SeasonEnded    = "ended"
+)
+
+// IsSeasonal checks if the achievement can only be earned within a time window
+func (a *Achievement) IsSeasonal() bool {
+	return a.StartsAt != nil || a.EndsAt != nil
+}
+
+// SeasonStateAt returns the state of a seasonal achievement's window at a time,
+// or an empty string if the achievement is not seasonal
+func (a *Achievement) SeasonStateAt(t time.Time) string {
+	switch {
+	case !a.IsSeasonal():
+		return ""
+	case a.StartsAt != nil && t.Before(*a.StartsAt):
+		return SeasonUpcoming
+	case a.EndsAt != nil && !t.Before(*a.EndsAt):
+		return SeasonEnded
+	default:
+		return SeasonOpen
+	}
+}
+
+// IsAvailableAt checks if the achievement can be earned at a time: it is not
+// seasonal, or its season is open
+func (a *Achievement) IsAvailableAt(t time.Time) bool {
+	state := a.SeasonStateAt(t)
+	return state == "" || state == SeasonOpen
+}
+
+// IsLimitedEdition checks if the achievement's season has ended. It can no longer
+// be earned; users who earned it keep it.
+func (a *Achievement) IsLimitedEdition(t time.Time) bool {
+	return a.SeasonStateAt(t) == SeasonEnded
+}
+
+// AchievementSeries groups achievements into tiers, e.g. bronze to diamond. Tier N+1
+// of a series can only be earned after tier N, and supersedes it once earned.
+type AchievementSeries struct {
 	ID          string    `json:"id" gorm:"primaryKey;type:text"`
 	Name        string    `json:"name" gorm:"not null;type:text"`
-	Description string    `json:"description" gorm:"not null;type:text"`
-	Level       int       `json:"level" gorm:"not null;default:1"`      // Achievement level (Bronze=1, Silver=2, Gold=3)
-	IconURL     string    `json:"icon_url" gorm:"type:text"`            // Icon image URL
-	BadgeType   string    `json:"badge_type" gorm:"not null;type:text"` // Category: "learning", "streak", "speed", etc.
-	RuleJSON    string    `json:"rule_json" gorm:"not null;type:text"`  // Achievement trigger rules as JSON
-	NFTEnabled  bool      `json:"nft_enabled" gorm:"default:false"`     // Whether this achievement generates NFT
-	NFTMetadata string    `json:"nft_metadata" gorm:"type:text"`        // NFT metadata template as JSON
-	IsActive    bool      `json:"is_active" gorm:"default:true"`        // Whether this achievement is active
+	Description string    `json:"description" gorm:"type:text"`
 	CreatedAt   time.Time `json:"created_at" gorm:"not null"`
 	UpdatedAt   time.Time `json:"updated_at" gorm:"not null"`
 
 	// Associations
-	UserAchievements []UserAchievement `json:"user_achievements,omitempty" gorm:"foreignKey:AchievementID;constraint:OnDelete:CASCADE"`
-	NFTAssets        []NFTAsset        `json:"nft_assets,omitempty" gorm:"foreignKey:AchievementID"`
+	Tiers []Achievement `json:"tiers,omitempty" gorm:"foreignKey:SeriesID"`
+}
+
+// BeforeCreate generates UUID for new achievement series
+func (as *AchievementSeries) BeforeCreate(tx *gorm.DB) error {
+	if as.ID == "" {
+		as.ID = uuid.New().String()
+	}
+	return nil
 }
 
 // BeforeCreate generates UUID for new achievement
@@ -36,14 +109,17 @@ func (a *Achievement) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
-// AchievementRule represents the structure of achievement rule JSON
+// AchievementRule represents the structure of achievement rule JSON. Rules are
+// written as a Match tree; Type and Conditions are the legacy format that only
+// looks at a single day of user_attempts, kept so existing rows keep working.
 type AchievementRule struct {
-	Type       string          `json:"type"`       // "streak", "score", "speed", "total", etc.
-	Conditions []ConditionRule `json:"conditions"` // Multiple conditions (AND logic)
-	Metadata   map[string]any  `json:"metadata"`   // Additional rule data
+	Match      *RuleNode       `json:"match,omitempty"`      // Declarative rule, see RuleNode
+	Type       string          `json:"type,omitempty"`       // Legacy: "first_try", "streak", "speed_accuracy", "endurance", "memory"
+	Conditions []ConditionRule `json:"conditions,omitempty"` // Legacy: multiple conditions (AND logic)
+	Metadata   map[string]any  `json:"metadata,omitempty"`   // Additional rule data
 }
 
-// ConditionRule represents a single condition within an achievement rule
+// ConditionRule represents a single condition within a legacy achievement rule
 type ConditionRule struct {
 	Field    string `json:"field"`    // Field to check (e.g., "streak_days", "correct_rate")
 	Operator string `json:"operator"` // ">=", ">", "<=", "<", "=", "!="
@@ -112,10 +188,15 @@ type UserAchievement struct {
 	UserID        string     `json:"user_id" gorm:"not null;type:text;index"`
 	AchievementID string     `json:"achievement_id" gorm:"not null;type:text;index"`
 	EarnedAt      time.Time  `json:"earned_at" gorm:"not null"`
-	Progress      float64    `json:"progress" gorm:"default:1.0"`      // Progress towards achievement (0.0-1.0)
-	MetaJSON      string     `json:"meta_json" gorm:"type:text"`       // Additional metadata about earning
-	NotifiedAt    *time.Time `json:"notified_at" gorm:"type:datetime"` // When user was notified
-	ViewedAt      *time.Time `json:"viewed_at" gorm:"type:datetime"`   // When user viewed the achievement
+	Progress      float64    `json:"progress" gorm:"default:1.0"`                  // Progress towards achievement (0.0-1.0)
+	MetaJSON      string     `json:"meta_json" gorm:"type:text"`                   // Additional metadata about earning
+	NotifiedAt    *time.Time `json:"notified_at" gorm:"type:datetime"`             // When user was notified
+	ViewedAt      *time.Time `json:"viewed_at" gorm:"type:datetime"`               // When user viewed the achievement
+	SupersededAt  *time.Time `json:"superseded_at,omitempty" gorm:"type:datetime"` // When a higher tier of the series was earned
+	SupersededBy  *string    `json:"superseded_by,omitempty" gorm:"type:text"`     // User achievement of that higher tier
+	RevokedAt     *time.Time `json:"revoked_at,omitempty" gorm:"type:datetime"`    // Tombstone: taken back by an administrator
+	RevokedBy     *string    `json:"revoked_by,omitempty" gorm:"type:text"`        // Administrator who revoked it
+	RevokeReason  string     `json:"revoke_reason,omitempty" gorm:"type:text"`
 
 	// Associations
 	User        *User        `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
@@ -141,12 +222,32 @@ func (ua *UserAchievement) MarkNotified() {
 	ua.NotifiedAt = &now
 }
 
+// IsSuperseded checks if a higher tier of the same series has been earned
+func (ua *UserAchievement) IsSuperseded() bool {
+	return ua.SupersededAt != nil
+}
+
+// IsRevoked checks if the achievement was taken back. Revoked achievements are kept
+// as tombstones and are not awarded again by rules.
+func (ua *UserAchievement) IsRevoked() bool {
+	return ua.RevokedAt != nil
+}
+
 // MarkViewed marks the achievement as viewed by user
 func (ua *UserAchievement) MarkViewed() {
 	now := time.Now()
 	ua.ViewedAt = &now
 }
 
+// UserAchievementProgress records the highest progress milestone a user has been
+// notified about for an achievement they have not earned yet
+type UserAchievementProgress struct {
+	UserID        string    `json:"user_id" gorm:"primaryKey;type:text"`
+	AchievementID string    `json:"achievement_id" gorm:"primaryKey;type:text"`
+	Milestone     int       `json:"milestone" gorm:"not null;default:0"` // Percent, e.g. 50 or 90
+	UpdatedAt     time.Time `json:"updated_at" gorm:"not null"`
+}
+
 // Event represents user events for achievement tracking
 type Event struct {
 	ID         string    `json:"id" gorm:"primaryKey;type:text"`
@@ -198,16 +299,143 @@ func (e *Event) SetData(data EventData) error {
 
 // Event type constants
 const (
-	EventQuestionAnswered  = "question_answered"
-	EventLevelCompleted    = "level_completed"
-	EventLevelStarted      = "level_started"
-	EventLevelFailed       = "level_failed"
-	EventSessionStarted    = "session_started"
-	EventSessionEnded      = "session_ended"
-	EventStreakUpdated     = "streak_updated"
-	EventAchievementEarned = "achievement_earned"
+	EventQuestionAnswered    = "question_answered"
+	EventLevelCompleted      = "level_completed"
+	EventLevelStarted        = "level_started"
+	EventLevelFailed         = "level_failed"
+	EventSessionStarted      = "session_started"
+	EventSessionEnded        = "session_ended"
+	EventStreakUpdated       = "streak_updated"
+	EventAchievementEarned   = "achievement_earned"
+	EventAchievementUpgraded = "achievement_upgraded"
+	EventAchievementRevoked  = "achievement_revoked"
 )
 
+// Outbox event statuses
+const (
+	OutboxPending    = "pending"    // Waiting for delivery
+	OutboxProcessing = "processing" // Claimed by a worker until AvailableAt
+	OutboxDone       = "done"
+	OutboxFailed     = "failed" // Gave up after the maximum number of attempts
+)
+
+// OutboxEvent is an event waiting to be delivered to the event bus consumers. It is
+// written together with the logged Event, so an event is delivered at least once even
+// if the server stops before a worker gets to it.
+type OutboxEvent struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:text"`
+	EventID     string     `json:"event_id" gorm:"not null;type:text;index"` // The logged Event
+	UserID      string     `json:"user_id" gorm:"not null;type:text;index"`
+	EventType   string     `json:"event_type" gorm:"not null;type:text"`
+	DataJSON    string     `json:"data_json" gorm:"type:text"`
+	Status      string     `json:"status" gorm:"not null;type:text;default:'pending'"`
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	AvailableAt time.Time  `json:"available_at" gorm:"not null"`    // Redelivered from then on if still pending or processing
+	AppliedAt   *time.Time `json:"applied_at" gorm:"type:datetime"` // When the event was counted in the user's statistics
+	LastError   string     `json:"last_error,omitempty" gorm:"type:text"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null"`
+	ProcessedAt *time.Time `json:"processed_at" gorm:"type:datetime"`
+}
+
+// BeforeCreate generates UUID for new outbox event
+func (e *OutboxEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// GetData parses and returns event data
+func (e *OutboxEvent) GetData() (EventData, error) {
+	if e.DataJSON == "" {
+		return EventData{}, nil
+	}
+	var data EventData
+	if err := json.Unmarshal([]byte(e.DataJSON), &data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// Achievement audit log actions
+const (
+	AuditActionAward  = "award"
+	AuditActionRevoke = "revoke"
+)
+
+// Achievement audit log sources: what caused an award or revocation
+const (
+	AuditSourceRule     = "rule"     // Rule evaluation after activity
+	AuditSourceForce    = "force"    // ForceAwardAchievement
+	AuditSourceBackfill = "backfill" // Retroactive backfill
+	AuditSourceAdmin    = "admin"    // Administrator action
+)
+
+// AchievementAuditLog records every award and revocation of an achievement. Entries
+// are never updated or deleted.
+type AchievementAuditLog struct {
+	ID                string    `json:"id" gorm:"primaryKey;type:text"`
+	UserID            string    `json:"user_id" gorm:"not null;type:text;index"`
+	AchievementID     string    `json:"achievement_id" gorm:"not null;type:text;index"`
+	UserAchievementID string    `json:"user_achievement_id" gorm:"not null;type:text"`
+	Action            string    `json:"action" gorm:"not null;type:text"`
+	Source            string    `json:"source" gorm:"not null;type:text"`
+	ActorID           *string   `json:"actor_id,omitempty" gorm:"type:text"` // User who caused it, if not the system
+	Reason            string    `json:"reason,omitempty" gorm:"type:text"`
+	CreatedAt         time.Time `json:"created_at" gorm:"not null;index"`
+}
+
+// BeforeCreate generates UUID for new audit log entry
+func (l *AchievementAuditLog) BeforeCreate(tx *gorm.DB) error {
+	if l.ID == "" {
+		l.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Achievement backfill statuses
+const (
+	BackfillPending   = "pending"
+	BackfillRunning   = "running" // Claimed by a runner until LeaseUntil
+	BackfillCompleted = "completed"
+	BackfillFailed    = "failed"
+)
+
+// AchievementBackfill awards an achievement to every user who already qualifies,
+// not only to users active since it was activated. Users are processed in ID order
+// and LastUserID is checkpointed after each batch, so an interrupted backfill
+// resumes where it stopped.
+type AchievementBackfill struct {
+	ID             string     `json:"id" gorm:"primaryKey;type:text"`
+	AchievementID  string     `json:"achievement_id" gorm:"not null;type:text;index"`
+	Status         string     `json:"status" gorm:"not null;type:text;default:'pending'"`
+	Notify         bool       `json:"notify" gorm:"not null;default:false"`    // Send real-time notifications for awards
+	TriggeredBy    string     `json:"triggered_by,omitempty" gorm:"type:text"` // Administrator who started it
+	TotalUsers     int        `json:"total_users" gorm:"not null;default:0"`
+	ProcessedUsers int        `json:"processed_users" gorm:"not null;default:0"`
+	AwardedCount   int        `json:"awarded_count" gorm:"not null;default:0"`
+	LastUserID     string     `json:"last_user_id,omitempty" gorm:"type:text"` // Checkpoint: users up to this ID are done
+	LeaseUntil     *time.Time `json:"lease_until,omitempty" gorm:"type:datetime"`
+	LastError      string     `json:"last_error,omitempty" gorm:"type:text"`
+	StartedAt      *time.Time `json:"started_at" gorm:"type:datetime"`
+	CompletedAt    *time.Time `json:"completed_at" gorm:"type:datetime"`
+	CreatedAt      time.Time  `json:"created_at" gorm:"not null"`
+	UpdatedAt      time.Time  `json:"updated_at" gorm:"not null"`
+}
+
+// BeforeCreate generates UUID for new achievement backfill
+func (b *AchievementBackfill) BeforeCreate(tx *gorm.DB) error {
+	if b.ID == "" {
+		b.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// IsFinished checks if the backfill has stopped for good
+func (b *AchievementBackfill) IsFinished() bool {
+	return b.Status == BackfillCompleted || b.Status == BackfillFailed
+}
+
 // NFTAsset represents an NFT asset owned by a user
 type NFTAsset struct {
 	ID              string    `json:"id" gorm:"primaryKey;type:text"`
@@ -217,7 +445,9 @@ type NFTAsset struct {
 	TokenID         string    `json:"token_id" gorm:"not null;type:text"`
 	MetadataURI     string    `json:"metadata_uri" gorm:"not null;type:text"`
 	MintTxHash      string    `json:"mint_tx_hash" gorm:"type:text"`
-	Status          string    `json:"status" gorm:"not null;default:'pending'"` // pending, minted, failed
+	Status          string    `json:"status" gorm:"not null;default:'pending'"`  // pending, minted, failed, burned, revoked
+	UpgradedToID    *string   `json:"upgraded_to_id,omitempty" gorm:"type:text"` // NFT of the higher tier that replaced this one
+	BurnTxHash      string    `json:"burn_tx_hash,omitempty" gorm:"type:text"`   // Set when a revoked NFT was burned
 	CreatedAt       time.Time `json:"created_at" gorm:"not null"`
 	UpdatedAt       time.Time `json:"updated_at" gorm:"not null"`
 
@@ -239,6 +469,8 @@ const (
 	NFTStatusPending = "pending"
 	NFTStatusMinted  = "minted"
 	NFTStatusFailed  = "failed"
+	NFTStatusBurned  = "burned"  // Revoked and burned on chain
+	NFTStatusRevoked = "revoked" // Revoked but not burned; the token is no longer honored
 )
 
 // IsPending checks if the NFT is still pending
@@ -263,3 +495,45 @@ func (nft *NFTAsset) MarkFailed() {
 	nft.Status = NFTStatusFailed
 	nft.UpdatedAt = time.Now()
 }
+
+// Achievement rarities, from the most to the least held
+const (
+	RarityCommon    = "common"
+	RarityUncommon  = "uncommon"
+	RarityRare      = "rare"
+	RarityEpic      = "epic"
+	RarityLegendary = "legendary"
+)
+
+// AchievementStats holds aggregate statistics of an achievement, recomputed daily
+type AchievementStats struct {
+	AchievementID    string     `json:"achievement_id" gorm:"primaryKey;type:text"`
+	HolderCount      int        `json:"holder_count" gorm:"not null;default:0"`   // Users holding it, revoked awards excluded
+	ActiveUsers      int        `json:"active_users" gorm:"not null;default:0"`   // Registered users who practised recently
+	ActiveHolders    int        `json:"active_holders" gorm:"not null;default:0"` // Active users holding it
+	HolderPercent    float64    `json:"holder_percent" gorm:"default:0"`          // Share of active users holding it, 0-100
+	Rarity           string     `json:"rarity" gorm:"type:text"`                  // Empty while nobody is active
+	FirstEarnerID    *string    `json:"first_earner_id,omitempty" gorm:"type:text"`
+	FirstEarnedAt    *time.Time `json:"first_earned_at,omitempty" gorm:"type:datetime"`
+	MedianDaysToEarn *int       `json:"median_days_to_earn,omitempty"` // Days from sign-up to earning it, NULL without holders
+	ComputedAt       time.Time  `json:"computed_at" gorm:"not null"`
+
+	// Associations
+	FirstEarner *User `json:"first_earner,omitempty" gorm:"foreignKey:FirstEarnerID"`
+}
+
+// RarityFor returns the rarity of an achievement held by the given share of active users
+func RarityFor(holderPercent float64) string {
+	switch {
+	case holderPercent >= 50:
+		return RarityCommon
+	case holderPercent >= 20:
+		return RarityUncommon
+	case holderPercent >= 5:
+		return RarityRare
+	case holderPercent >= 1:
+		return RarityEpic
+	default:
+		return RarityLegendary
+	}
+}
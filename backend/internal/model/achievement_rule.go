@@ -0,0 +1,278 @@
+package model
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Rule data sources
+const (
+	RuleSourceAttempts = "attempts" // Daily statistics in user_attempts
+	RuleSourceEvents   = "events"   // Event log
+	RuleSourceProgress = "progress" // Per-level results in user_progresses
+)
+
+// Rule aggregations
+const (
+	RuleAggSum      = "sum"
+	RuleAggMax      = "max"
+	RuleAggCount    = "count"
+	RuleAggDistinct = "distinct"
+)
+
+// Rule windows
+const (
+	RuleWindowLifetime = "lifetime"
+	RuleWindowToday    = "today"
+	RuleWindowRolling  = "rolling" // Today and the Days-1 days before it
+)
+
+// maxRuleDepth bounds the nesting of rule groups
+const maxRuleDepth = 8
+
+// Rule errors
+var (
+	ErrInvalidRule     = errors.New("invalid achievement rule")
+	ErrUnknownRuleType = errors.New("unknown legacy achievement rule type")
+)
+
+// RuleNode is one node of a declarative achievement rule. A node is either a
+// group (exactly one of All, Any or Not) or a metric leaf. For example
+//
+//	{"all": [
+//	  {"source": "progress", "agg": "count", "filter": {"passed": true, "min_stars": 3}, "op": ">=", "value": 10},
+//	  {"not": {"source": "events", "agg": "count", "filter": {"event_type": "level_failed"}, "window": "today", "op": ">", "value": 0}}
+//	]}
+type RuleNode struct {
+	All []*RuleNode `json:"all,omitempty"` // True if every child is true
+	Any []*RuleNode `json:"any,omitempty"` // True if at least one child is true
+	Not *RuleNode   `json:"not,omitempty"` // True if the child is false
+
+	*RuleMetric
+}
+
+// RuleMetric compares an aggregate over one data source with a threshold
+type RuleMetric struct {
+	Source   string      `json:"source"`           // attempts, events or progress
+	Field    string      `json:"field,omitempty"`  // Column aggregated by sum, max and distinct
+	Agg      string      `json:"agg"`              // sum, max, count or distinct
+	Window   string      `json:"window,omitempty"` // lifetime (default), today or rolling
+	Days     int         `json:"days,omitempty"`   // Length of a rolling window
+	Filter   *RuleFilter `json:"filter,omitempty"` // Restricts the rows aggregated
+	Operator string      `json:"op"`               // ">=", ">", "<=", "<", "=", "!="
+	Value    float64     `json:"value"`
+}
+
+// RuleFilter restricts the rows a metric aggregates; each filter applies to
+// the sources listed next to it
+type RuleFilter struct {
+	EventType string `json:"event_type,omitempty"` // events
+	SubjectID string `json:"subject_id,omitempty"` // events, progress
+	LevelID   string `json:"level_id,omitempty"`   // events, progress
+	Passed    bool   `json:"passed,omitempty"`     // progress: only passed levels
+	MinStars  int    `json:"min_stars,omitempty"`  // progress: only levels with at least this many stars
+}
+
+// ruleSourceFields lists the fields of each source and whether they are numeric.
+// Numeric fields can be summed and maxed, every field can be counted distinct.
+var ruleSourceFields = map[string]map[string]bool{
+	RuleSourceAttempts: {
+		"stat_date":                  false,
+		"attempts_total":             true,
+		"attempts_correct":           true,
+		"attempts_first_try_correct": true,
+		"correct_rate":               true,
+		"first_try_correct_rate":     true,
+		"giveup_count":               true,
+		"skip_rate":                  true,
+		"avg_duration_ms":            true,
+		"total_time_ms":              true,
+		"sessions_count":             true,
+		"streak_days":                true,
+		"review_due_count":           true,
+		"retention_score":            true,
+	},
+	RuleSourceEvents: {
+		"event_type":  false,
+		"level_id":    false,
+		"question_id": false,
+	},
+	RuleSourceProgress: {
+		"level_id": false,
+		"score":    true,
+		"stars":    true,
+	},
+}
+
+// ruleOperators are the supported comparison operators
+var ruleOperators = map[string]bool{">=": true, ">": true, "<=": true, "<": true, "=": true, "==": true, "!=": true}
+
+// Root returns the rule as a tree, converting the legacy format
+func (r *AchievementRule) Root() (*RuleNode, error) {
+	if r.Match != nil {
+		return r.Match, nil
+	}
+	return legacyRuleNode(r)
+}
+
+// Validate checks that the rule can be evaluated
+func (r *AchievementRule) Validate() error {
+	root, err := r.Root()
+	if err != nil {
+		return err
+	}
+	return root.validate("match", 1)
+}
+
+// validate checks a node and its children; path locates the node in error messages
+func (n *RuleNode) validate(path string, depth int) error {
+	if n == nil {
+		return fmt.Errorf("%w: %s is empty", ErrInvalidRule, path)
+	}
+	if depth > maxRuleDepth {
+		return fmt.Errorf("%w: %s is nested deeper than %d levels", ErrInvalidRule, path, maxRuleDepth)
+	}
+
+	kinds := 0
+	for _, set := range []bool{n.All != nil, n.Any != nil, n.Not != nil, n.RuleMetric != nil} {
+		if set {
+			kinds++
+		}
+	}
+	if kinds != 1 {
+		return fmt.Errorf("%w: %s must be exactly one of all, any, not or a metric", ErrInvalidRule, path)
+	}
+
+	switch {
+	case n.All != nil, n.Any != nil:
+		name, children := "all", n.All
+		if n.Any != nil {
+			name, children = "any", n.Any
+		}
+		if len(children) == 0 {
+			return fmt.Errorf("%w: %s.%s has no conditions", ErrInvalidRule, path, name)
+		}
+		for i, child := range children {
+			if err := child.validate(fmt.Sprintf("%s.%s[%d]", path, name, i), depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	case n.Not != nil:
+		return n.Not.validate(path+".not", depth+1)
+	default:
+		return n.RuleMetric.validate(path)
+	}
+}
+
+// validate checks a metric leaf
+func (m *RuleMetric) validate(path string) error {
+	fields, ok := ruleSourceFields[m.Source]
+	if !ok {
+		return fmt.Errorf("%w: %s has unknown source %q", ErrInvalidRule, path, m.Source)
+	}
+
+	switch m.Agg {
+	case RuleAggCount:
+		if m.Field != "" {
+			return fmt.Errorf("%w: %s counts rows and takes no field", ErrInvalidRule, path)
+		}
+	case RuleAggSum, RuleAggMax, RuleAggDistinct:
+		numeric, ok := fields[m.Field]
+		if !ok {
+			return fmt.Errorf("%w: %s has unknown %s field %q", ErrInvalidRule, path, m.Source, m.Field)
+		}
+		if !numeric && m.Agg != RuleAggDistinct {
+			return fmt.Errorf("%w: %s cannot %s the non-numeric field %q", ErrInvalidRule, path, m.Agg, m.Field)
+		}
+	default:
+		return fmt.Errorf("%w: %s has unknown aggregation %q", ErrInvalidRule, path, m.Agg)
+	}
+
+	switch m.Window {
+	case "", RuleWindowLifetime, RuleWindowToday:
+		if m.Days != 0 {
+			return fmt.Errorf("%w: %s sets days without a rolling window", ErrInvalidRule, path)
+		}
+	case RuleWindowRolling:
+		if m.Days < 1 || m.Days > 366 {
+			return fmt.Errorf("%w: %s needs a rolling window of 1 to 366 days", ErrInvalidRule, path)
+		}
+	default:
+		return fmt.Errorf("%w: %s has unknown window %q", ErrInvalidRule, path, m.Window)
+	}
+
+	if f := m.Filter; f != nil {
+		if f.EventType != "" && m.Source != RuleSourceEvents {
+			return fmt.Errorf("%w: %s can only filter events by event_type", ErrInvalidRule, path)
+		}
+		if (f.SubjectID != "" || f.LevelID != "") && m.Source == RuleSourceAttempts {
+			return fmt.Errorf("%w: %s cannot filter daily attempts by subject or level", ErrInvalidRule, path)
+		}
+		if (f.Passed || f.MinStars != 0) && m.Source != RuleSourceProgress {
+			return fmt.Errorf("%w: %s can only filter progress by passed or min_stars", ErrInvalidRule, path)
+		}
+		if f.MinStars < 0 || f.MinStars > 3 {
+			return fmt.Errorf("%w: %s min_stars must be between 0 and 3", ErrInvalidRule, path)
+		}
+	}
+
+	if !ruleOperators[m.Operator] {
+		return fmt.Errorf("%w: %s has unknown operator %q", ErrInvalidRule, path, m.Operator)
+	}
+	return nil
+}
+
+// legacyRuleNode expresses a legacy rule as a tree. Legacy rules compared fields
+// of today's user_attempts row, or of the latest row for streaks, and failed
+// when there was no such row.
+func legacyRuleNode(r *AchievementRule) (*RuleNode, error) {
+	window := RuleWindowToday
+	var extra []*RuleNode
+	switch r.Type {
+	case "first_try", "endurance", "memory":
+	case "streak":
+		// A streak once reached stays reached, so the lifetime maximum matches the latest row
+		window = RuleWindowLifetime
+	case "speed_accuracy":
+		extra = append(extra, &RuleNode{RuleMetric: &RuleMetric{
+			Source: RuleSourceAttempts, Field: "attempts_total", Agg: RuleAggMax,
+			Window: RuleWindowToday, Operator: ">=", Value: 10,
+		}})
+	default:
+		return nil, fmt.Errorf("%w: %q", ErrUnknownRuleType, r.Type)
+	}
+
+	root := &RuleNode{All: []*RuleNode{{RuleMetric: &RuleMetric{
+		Source: RuleSourceAttempts, Agg: RuleAggCount, Window: window, Operator: ">=", Value: 1,
+	}}}}
+	root.All = append(root.All, extra...)
+	for _, c := range r.Conditions {
+		value, err := legacyConditionValue(c.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: condition on %q: %v", ErrInvalidRule, c.Field, err)
+		}
+		root.All = append(root.All, &RuleNode{RuleMetric: &RuleMetric{
+			Source: RuleSourceAttempts, Field: c.Field, Agg: RuleAggMax,
+			Window: window, Operator: c.Operator, Value: value,
+		}})
+	}
+	return root, nil
+}
+
+// legacyConditionValue converts a legacy condition value to a number
+func legacyConditionValue(v any) (float64, error) {
+	switch val := v.(type) {
+	case float64:
+		return val, nil
+	case int:
+		return float64(val), nil
+	case int64:
+		return float64(val), nil
+	case string:
+		return strconv.ParseFloat(val, 64)
+	default:
+		return 0, fmt.Errorf("value %v is not a number", v)
+	}
+}
@@ -92,19 +92,34 @@ func (d *Database) ValidateSchema() error {
 
 	// List of required tables and their critical columns
 	requiredSchema := map[string][]string{
-		"subjects":          {"id", "name", "description", "created_at", "updated_at"},
-		"papers":            {"id", "subject_id", "title", "paper_author", "created_at", "updated_at"},
-		"levels":            {"id", "paper_id", "name", "pass_condition", "created_at", "updated_at"},
-		"questions":         {"id", "level_id", "stem", "content_json", "answer_json", "created_at"},
-		"roadmap_nodes":     {"id", "subject_id", "level_id", "path", "sort_order"},
-		"users":             {"id", "email", "password_hash", "display_name", "created_at", "updated_at"},
-		"refresh_tokens":    {"token", "user_id", "expires_at", "created_at"},
-		"user_progresses":   {"id", "user_id", "level_id", "status", "score", "created_at", "updated_at"},
-		"user_attempts":     {"stat_date", "user_id", "attempts_total", "attempts_correct", "attempts_first_try_correct", "updated_at"},
-		"achievements":      {"id", "name", "description", "level", "badge_type", "is_active"},
-		"user_achievements": {"id", "user_id", "achievement_id", "earned_at", "progress"},
-		"events":            {"id", "user_id", "event_type", "data_json", "created_at"},
-		"nft_assets":        {"id", "user_id", "token_id", "metadata_uri", "status"},
+		"subjects":                    {"id", "name", "description", "created_at", "updated_at"},
+		"papers":                      {"id", "subject_id", "title", "paper_author", "created_at", "updated_at", "doi", "arxiv_id", "pub_year", "citation_count"},
+		"paper_authors":               {"id", "paper_id", "position", "name", "normalized_name"},
+		"levels":                      {"id", "paper_id", "name", "pass_condition", "created_at", "updated_at"},
+		"questions":                   {"id", "level_id", "stem", "content_json", "answer_json", "created_at", "status", "published_revision_id"},
+		"roadmap_nodes":               {"id", "subject_id", "level_id", "path", "sort_order"},
+		"users":                       {"id", "email", "password_hash", "display_name", "role", "email_verified_at", "token_version", "deletion_scheduled_at", "anonymized_at", "created_at", "updated_at"},
+		"refresh_tokens":              {"token", "user_id", "family_id", "rotated_at", "last_used_at", "expires_at", "created_at"},
+		"revoked_sessions":            {"session_id", "expires_at"},
+		"user_progresses":             {"id", "user_id", "level_id", "status", "score", "created_at", "updated_at"},
+		"user_attempts":               {"stat_date", "user_id", "attempts_total", "attempts_correct", "attempts_first_try_correct", "updated_at"},
+		"achievement_series":          {"id", "name"},
+		"achievements":                {"id", "name", "description", "level", "badge_type", "is_active", "series_id", "tier", "starts_at", "ends_at", "season_state"},
+		"user_achievements":           {"id", "user_id", "achievement_id", "earned_at", "progress", "superseded_at", "revoked_at"},
+		"user_achievement_progresses": {"user_id", "achievement_id", "milestone"},
+		"events":                      {"id", "user_id", "event_type", "data_json", "created_at"},
+		"outbox_events":               {"id", "event_id", "user_id", "event_type", "status", "attempts", "available_at", "applied_at"},
+		"achievement_backfills":       {"id", "achievement_id", "status", "notify", "last_user_id", "lease_until"},
+		"achievement_audit_logs":      {"id", "user_id", "achievement_id", "action", "source", "created_at"},
+		"achievement_stats":           {"achievement_id", "holder_count", "holder_percent", "rarity", "computed_at"},
+		"nft_assets":                  {"id", "user_id", "token_id", "metadata_uri", "status", "upgraded_to_id"},
+		"question_revisions":          {"id", "question_id", "number", "status", "stem", "content_json", "answer_json", "score"},
+		"answer_records":              {"id", "user_id", "question_id", "revision_id", "is_correct", "skipped", "created_at"},
+		"question_stats":              {"question_id", "revision_id", "level_id", "p_value", "point_biserial", "suspected_wrong_key"},
+		"question_reports":            {"id", "question_id", "user_id", "category", "status", "created_at"},
+		"login_throttles":             {"scope", "identifier", "failures", "last_failure_at", "locked_until"},
+		"external_identities":         {"id", "user_id", "provider", "subject", "union_id", "created_at"},
+		"api_keys":                    {"id", "name", "prefix", "key_hash", "scopes", "rate_limit", "created_by", "expires_at", "last_used_at", "revoked_at"},
 	}
 
 	for tableName, columns := range requiredSchema {
@@ -141,17 +156,32 @@ func (d *Database) Migrate() error {
 	if err := d.DB.AutoMigrate(
 		&Subject{},
 		&Paper{},
+		&Author{},
 		&Level{},
 		&Question{},
 		&RoadmapNode{},
 		&User{},
 		&RefreshToken{},
+		&RevokedSession{},
 		&UserProgress{},
 		&UserAttempts{},
+		&AchievementSeries{},
 		&Achievement{},
 		&UserAchievement{},
+		&UserAchievementProgress{},
 		&Event{},
+		&OutboxEvent{},
+		&AchievementBackfill{},
+		&AchievementAuditLog{},
+		&AchievementStats{},
 		&NFTAsset{},
+		&QuestionRevision{},
+		&AnswerRecord{},
+		&QuestionStats{},
+		&QuestionReport{},
+		&LoginThrottle{},
+		&ExternalIdentity{},
+		&APIKey{},
 	); err != nil {
 		return fmt.Errorf("failed to migrate database: %w", err)
 	}
@@ -178,11 +208,16 @@ func (d *Database) CreateIndexes() error {
 
 	indexes := []string{
 		"CREATE INDEX IF NOT EXISTS idx_events_user_type_created ON events(user_id, event_type, created_at)",
+		"CREATE INDEX IF NOT EXISTS idx_outbox_events_status_available ON outbox_events(status, available_at)",
+		"CREATE INDEX IF NOT EXISTS idx_achievement_backfills_status ON achievement_backfills(status, lease_until)",
+		"CREATE INDEX IF NOT EXISTS idx_user_achievements_unread ON user_achievements(user_id, viewed_at)",
 		"CREATE INDEX IF NOT EXISTS idx_user_attempts_date_user ON user_attempts(stat_date, user_id)",
 		"CREATE INDEX IF NOT EXISTS idx_user_progress_user_status ON user_progresses(user_id, status)",
 		"CREATE INDEX IF NOT EXISTS idx_roadmap_nodes_subject_path ON roadmap_nodes(subject_id, path)",
 		"CREATE INDEX IF NOT EXISTS idx_achievements_active ON achievements(is_active)",
+		"CREATE UNIQUE INDEX IF NOT EXISTS idx_achievements_series_tier ON achievements(series_id, tier) WHERE series_id IS NOT NULL",
 		"CREATE INDEX IF NOT EXISTS idx_nft_assets_status ON nft_assets(status)",
+		"CREATE INDEX IF NOT EXISTS idx_answer_records_question_created ON answer_records(question_id, created_at)",
 	}
 
 	for _, index := range indexes {
@@ -235,7 +270,7 @@ func (d *Database) seedAchievements() error {
 			Description: "第一次作答即答对任意一道题目",
 			Level:       1,
 			BadgeType:   "learning",
-			RuleJSON:    `{"type":"first_try","conditions":[{"field":"attempts_first_try_correct","operator":">=","value":1}]}`,
+			RuleJSON:    `{"match":{"source":"attempts","field":"attempts_first_try_correct","agg":"sum","op":">=","value":1}}`,
 			NFTEnabled:  true,
 			NFTMetadata: `{"name":"First Victory Badge","description":"Awarded for getting first question right on first try","image":"","attributes":[{"trait_type":"Achievement Type","value":"Learning"},{"trait_type":"Level","value":"Bronze"}]}`,
 			IsActive:    true,
@@ -245,7 +280,7 @@ func (d *Database) seedAchievements() error {
 			Description: "连续 7 天都有学习记录",
 			Level:       2,
 			BadgeType:   "streak",
-			RuleJSON:    `{"type":"streak","conditions":[{"field":"streak_days","operator":">=","value":7}]}`,
+			RuleJSON:    `{"match":{"source":"attempts","field":"streak_days","agg":"max","op":">=","value":7}}`,
 			NFTEnabled:  true,
 			NFTMetadata: `{"name":"Streak Master Badge","description":"Awarded for 7-day learning streak","image":"","attributes":[{"trait_type":"Achievement Type","value":"Consistency"},{"trait_type":"Level","value":"Silver"}]}`,
 			IsActive:    true,
@@ -255,7 +290,7 @@ func (d *Database) seedAchievements() error {
 			Description: "平均每题用时 ≤ 30秒 且正确率 ≥ 90%",
 			Level:       3,
 			BadgeType:   "speed",
-			RuleJSON:    `{"type":"speed_accuracy","conditions":[{"field":"avg_duration_ms","operator":"<=","value":30000},{"field":"correct_rate","operator":">=","value":0.9}]}`,
+			RuleJSON:    `{"match":{"all":[{"source":"attempts","field":"attempts_total","agg":"max","window":"today","op":">=","value":10},{"source":"attempts","field":"avg_duration_ms","agg":"max","window":"today","op":"<=","value":30000},{"source":"attempts","field":"correct_rate","agg":"max","window":"today","op":">=","value":0.9}]}}`,
 			NFTEnabled:  true,
 			NFTMetadata: `{"name":"Speed & Accuracy Master","description":"Awarded for high speed and accuracy","image":"","attributes":[{"trait_type":"Achievement Type","value":"Performance"},{"trait_type":"Level","value":"Gold"}]}`,
 			IsActive:    true,
@@ -265,7 +300,7 @@ func (d *Database) seedAchievements() error {
 			Description: "单日学习总时长 ≥ 1 小时",
 			Level:       2,
 			BadgeType:   "endurance",
-			RuleJSON:    `{"type":"endurance","conditions":[{"field":"total_time_ms","operator":">=","value":3600000}]}`,
+			RuleJSON:    `{"match":{"source":"attempts","field":"total_time_ms","agg":"sum","window":"today","op":">=","value":3600000}}`,
 			NFTEnabled:  true,
 			NFTMetadata: `{"name":"Endurance Warrior Badge","description":"Awarded for studying 1+ hours in a day","image":"","attributes":[{"trait_type":"Achievement Type","value":"Dedication"},{"trait_type":"Level","value":"Silver"}]}`,
 			IsActive:    true,
@@ -275,7 +310,7 @@ func (d *Database) seedAchievements() error {
 			Description: "保留度 ≥ 85% 且当天无需复习推荐",
 			Level:       3,
 			BadgeType:   "memory",
-			RuleJSON:    `{"type":"memory","conditions":[{"field":"retention_score","operator":">=","value":0.85},{"field":"review_due_count","operator":"=","value":0}]}`,
+			RuleJSON:    `{"match":{"all":[{"source":"attempts","agg":"count","window":"today","op":">=","value":1},{"source":"attempts","field":"retention_score","agg":"max","window":"today","op":">=","value":0.85},{"source":"attempts","field":"review_due_count","agg":"max","window":"today","op":"=","value":0}]}}`,
 			NFTEnabled:  true,
 			NFTMetadata: `{"name":"Memory Master Badge","description":"Awarded for excellent retention without review","image":"","attributes":[{"trait_type":"Achievement Type","value":"Mastery"},{"trait_type":"Level","value":"Gold"}]}`,
 			IsActive:    true,
@@ -105,8 +105,25 @@ type Question struct {
 	CreatedBy   string    `json:"created_by" gorm:"type:text"`            // Creator/generator
 	CreatedAt   time.Time `json:"created_at" gorm:"not null"`
 
+	// Revisioning: the columns above always mirror the published revision
+	Status              string  `json:"status" gorm:"not null;type:text;default:'published';index"` // draft, published, hidden
+	PublishedRevisionID *string `json:"published_revision_id" gorm:"type:text"`                     // NULL until the first revision snapshot
+
 	// Associations
-	Level *Level `json:"level,omitempty" gorm:"foreignKey:LevelID;constraint:OnDelete:CASCADE"`
+	Level     *Level             `json:"level,omitempty" gorm:"foreignKey:LevelID;constraint:OnDelete:CASCADE"`
+	Revisions []QuestionRevision `json:"revisions,omitempty" gorm:"foreignKey:QuestionID;constraint:OnDelete:CASCADE"`
+}
+
+// Question status constants
+const (
+	QuestionStatusDraft     = "draft"     // Never published, invisible to learners
+	QuestionStatusPublished = "published" // Has a published revision
+	QuestionStatusHidden    = "hidden"    // Published but withdrawn while learner reports are open
+)
+
+// PublishedQuestions is a query scope limiting questions to those learners may see
+func PublishedQuestions(db *gorm.DB) *gorm.DB {
+	return db.Where("questions.status = ?", QuestionStatusPublished)
 }
 
 // BeforeCreate generates UUID for new question
@@ -121,6 +138,7 @@ func (q *Question) BeforeCreate(tx *gorm.DB) error {
 type QuestionContent struct {
 	Type        string         `json:"type"`        // "multiple_choice", "text", "code", etc.
 	Text        string         `json:"text"`        // Question text
+	Question    string         `json:"question"`    // Question text as written by the question generator
 	Options     []string       `json:"options"`     // For multiple choice
 	Code        string         `json:"code"`        // For code questions
 	Images      []string       `json:"images"`      // Image URLs
@@ -151,6 +169,7 @@ func (q *Question) SetContent(content *QuestionContent) error {
 type QuestionAnswer struct {
 	Type           string         `json:"type"`            // "single", "multiple", "text", "code"
 	CorrectOptions []string       `json:"correct_options"` // For multiple choice
+	CorrectOption  string         `json:"correct_option"`  // Legacy single choice key written by the question generator
 	CorrectText    string         `json:"correct_text"`    // For text answers
 	CorrectCode    string         `json:"correct_code"`    // For code answers
 	Explanation    string         `json:"explanation"`     // Answer explanation
@@ -0,0 +1,107 @@
+package model
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// LoginThrottle tracks recent failed logins for an account or a client IP
+type LoginThrottle struct {
+	Scope         string     `json:"scope" gorm:"primaryKey;type:text"`      // account or ip
+	Identifier    string     `json:"identifier" gorm:"primaryKey;type:text"` // Lowercased email or IP address
+	Failures      int        `json:"failures" gorm:"not null;default:0"`
+	LastFailureAt *time.Time `json:"last_failure_at"`
+	LockedUntil   *time.Time `json:"locked_until" gorm:"index"`
+	UpdatedAt     time.Time  `json:"updated_at" gorm:"not null"`
+}
+
+// Login throttle scopes
+const (
+	ThrottleScopeAccount = "account"
+	ThrottleScopeIP      = "ip"
+	ThrottleScopeGuest   = "guest_ip" // Guest accounts created from a client IP
+)
+
+// IsLocked checks if logins are locked out at the given time
+func (t *LoginThrottle) IsLocked(now time.Time) bool {
+	return t.LockedUntil != nil && now.Before(*t.LockedUntil)
+}
+
+// ExternalIdentity links a user to an account at an external login provider
+type ExternalIdentity struct {
+	ID        string    `json:"id" gorm:"primaryKey;type:text"`
+	UserID    string    `json:"user_id" gorm:"not null;type:text;index"`
+	Provider  string    `json:"provider" gorm:"not null;type:text;uniqueIndex:idx_external_identities_provider_subject"`
+	Subject   string    `json:"-" gorm:"not null;type:text;uniqueIndex:idx_external_identities_provider_subject"` // Provider's user ID, e.g. the WeChat openid
+	UnionID   string    `json:"-" gorm:"type:text;index"`                                                         // WeChat unionid, shared across the developer's apps
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at" gorm:"not null"`
+
+	// Associations
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+}
+
+// External identity providers
+const (
+	ProviderWeChat = "wechat"
+)
+
+// BeforeCreate generates UUID for new external identity
+func (e *ExternalIdentity) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == "" {
+		e.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// APIKey lets a service call the API without a user login. Only a hash of the key is
+// stored; requests made with it act on behalf of the administrator who created it,
+// limited to the key's scopes.
+type APIKey struct {
+	ID         string     `json:"id" gorm:"primaryKey;type:text"`
+	Name       string     `json:"name" gorm:"not null;type:text"`
+	Prefix     string     `json:"prefix" gorm:"not null;type:text"`                 // Start of the key, to recognize it in lists
+	KeyHash    string     `json:"-" gorm:"not null;type:text;uniqueIndex"`          // SHA-256 of the key
+	Scopes     []string   `json:"scopes" gorm:"not null;type:text;serializer:json"` // Stored as a JSON array
+	RateLimit  int        `json:"rate_limit" gorm:"not null;default:0"`             // Requests per minute, 0 for the default
+	CreatedBy  string     `json:"created_by" gorm:"not null;type:text;index"`       // Administrator the key acts for
+	ExpiresAt  *time.Time `json:"expires_at"`                                       // Nil for keys that never expire
+	LastUsedAt *time.Time `json:"last_used_at"`
+	LastUsedIP string     `json:"last_used_ip" gorm:"type:text"`
+	RevokedAt  *time.Time `json:"revoked_at"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"not null"`
+	UpdatedAt  time.Time  `json:"updated_at" gorm:"not null"`
+
+	// Associations
+	Creator *User `json:"-" gorm:"foreignKey:CreatedBy;constraint:OnDelete:CASCADE"`
+}
+
+// API key scopes
+const (
+	ScopeContentWrite = "content:write" // Author questions and revisions, import and export papers
+	ScopeStatsRead    = "stats:read"    // Read question statistics
+)
+
+// APIKeyScopes lists the scopes an API key can be granted
+var APIKeyScopes = []string{ScopeContentWrite, ScopeStatsRead}
+
+// BeforeCreate generates UUID for new API key
+func (k *APIKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == "" {
+		k.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// HasScope checks if the key was granted a scope
+func (k *APIKey) HasScope(scope string) bool {
+	return slices.Contains(k.Scopes, scope)
+}
+
+// IsExpired checks if the key has expired at the given time
+func (k *APIKey) IsExpired(now time.Time) bool {
+	return k.ExpiresAt != nil && !now.Before(*k.ExpiresAt)
+}
@@ -1,9 +1,11 @@
 package model
 
 import (
+	"regexp"
 	"strconv"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/google/uuid"
 	"gorm.io/gorm"
@@ -20,9 +22,37 @@ type Paper struct {
 	CreatedAt          time.Time `json:"created_at" gorm:"not null"`
 	UpdatedAt          time.Time `json:"updated_at" gorm:"not null"`
 
+	// Structured metadata; the legacy string columns above are kept in sync for the agent
+	Abstract      string `json:"abstract" gorm:"type:text"`
+	DOI           string `json:"doi" gorm:"type:text;index"`
+	ArxivID       string `json:"arxiv_id" gorm:"type:text;index"`
+	Venue         string `json:"venue" gorm:"type:text"`              // Journal or conference
+	URL           string `json:"url" gorm:"type:text"`                // Landing page or PDF
+	EntryType     string `json:"entry_type" gorm:"type:text"`         // BibTeX entry type: article, inproceedings, misc...
+	PubYear       int    `json:"pub_year" gorm:"not null;default:0"`  // 0 if unknown
+	PubMonth      int    `json:"pub_month" gorm:"not null;default:0"` // 1-12, 0 if unknown
+	CitationCount int    `json:"citation_count" gorm:"not null;default:0"`
+
 	// Associations
 	Subject *Subject `json:"subject,omitempty" gorm:"foreignKey:SubjectID;constraint:OnDelete:CASCADE"`
 	Level   *Level   `json:"level,omitempty" gorm:"foreignKey:PaperID;constraint:OnDelete:CASCADE"`
+	Authors []Author `json:"authors,omitempty" gorm:"foreignKey:PaperID;constraint:OnDelete:CASCADE"`
+}
+
+// Author represents one author of a paper, in byline order
+type Author struct {
+	ID             string `json:"id" gorm:"primaryKey;type:text"`
+	PaperID        string `json:"paper_id" gorm:"not null;type:text;uniqueIndex:idx_paper_authors_position"`
+	Position       int    `json:"position" gorm:"not null;uniqueIndex:idx_paper_authors_position"` // 1-based
+	Name           string `json:"name" gorm:"not null;type:text"`                                  // Display name
+	GivenName      string `json:"given_name" gorm:"type:text"`
+	FamilyName     string `json:"family_name" gorm:"type:text"`
+	NormalizedName string `json:"-" gorm:"type:text;index"` // Lowercase "given family" for matching
+}
+
+// TableName keeps authors next to papers in the schema
+func (Author) TableName() string {
+	return "paper_authors"
 }
 
 // BeforeCreate generates UUID for new paper
@@ -33,6 +63,26 @@ func (p *Paper) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// BeforeSave keeps the structured and legacy metadata columns consistent
+func (p *Paper) BeforeSave(tx *gorm.DB) error {
+	p.NormalizeMetadata()
+	return nil
+}
+
+// AfterFind fills the structured fields of papers written directly to the database by the agent
+func (p *Paper) AfterFind(tx *gorm.DB) error {
+	p.NormalizeMetadata()
+	return nil
+}
+
+// BeforeCreate generates UUID for new author
+func (a *Author) BeforeCreate(tx *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = uuid.New().String()
+	}
+	return nil
+}
+
 // GetAuthors returns authors as a slice
 func (p *Paper) GetAuthors() []string {
 	if p.PaperAuthor == "" {
@@ -59,3 +109,152 @@ func (p *Paper) GetCitationCountInt() int {
 	}
 	return count
 }
+
+// NormalizeMetadata fills the structured fields from the legacy strings and vice versa
+func (p *Paper) NormalizeMetadata() {
+	if p.PubYear == 0 {
+		p.PubYear, p.PubMonth = ParsePubYM(p.PaperPubYM)
+	}
+	if ym := FormatPubYM(p.PubYear, p.PubMonth); ym != "" && (p.PaperPubYM == "" || p.PaperPubYM == "None") {
+		p.PaperPubYM = ym
+	}
+
+	if p.CitationCount == 0 {
+		p.CitationCount = p.GetCitationCountInt()
+	}
+	if p.PaperCitationCount == "" {
+		p.PaperCitationCount = strconv.Itoa(p.CitationCount)
+	}
+
+	if p.PaperAuthor == "" && len(p.Authors) > 0 {
+		names := make([]string, len(p.Authors))
+		for i, a := range p.Authors {
+			names[i] = a.Name
+		}
+		p.SetAuthors(names)
+	}
+
+	if p.ArxivID == "" {
+		p.ArxivID = arxivIDFromPaperID(p.ID)
+	}
+	if p.URL == "" && p.ArxivID != "" {
+		p.URL = "https://arxiv.org/abs/" + p.ArxivID
+	}
+	p.DOI = NormalizeDOI(p.DOI)
+}
+
+// BuildAuthors parses the legacy author string into ordered authors
+func (p *Paper) BuildAuthors() []Author {
+	var authors []Author
+	for _, raw := range p.GetAuthors() {
+		if raw == "" {
+			continue
+		}
+		author := ParseAuthorName(raw)
+		author.PaperID = p.ID
+		author.Position = len(authors) + 1
+		authors = append(authors, author)
+	}
+	return authors
+}
+
+var (
+	pubYMPattern = regexp.MustCompile(`^\s*(\d{4})(?:\s*[-/.]?\s*(\d{1,2}))?`)
+	doiPrefix    = regexp.MustCompile(`(?i)^(?:https?://(?:dx\.)?doi\.org/|doi:\s*)`)
+	// Agent-generated papers are stored as paper_<arXiv ID>
+	agentPaperID = regexp.MustCompile(`^paper_(\d{4}\.\d{4,5}(?:v\d+)?)$`)
+)
+
+// ParsePubYM parses a free-form publication date such as "2017", "2017-06" or "201706"
+func ParsePubYM(s string) (year, month int) {
+	m := pubYMPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, 0
+	}
+	year, _ = strconv.Atoi(m[1])
+	if m[2] != "" {
+		if mo, _ := strconv.Atoi(m[2]); mo >= 1 && mo <= 12 {
+			month = mo
+		}
+	}
+	return year, month
+}
+
+// FormatPubYM formats a publication date as "YYYY" or "YYYY-MM"
+func FormatPubYM(year, month int) string {
+	if year == 0 {
+		return ""
+	}
+	if month == 0 {
+		return strconv.Itoa(year)
+	}
+	return strconv.Itoa(year) + "-" + twoDigits(month)
+}
+
+// NormalizeDOI strips resolver prefixes and lowercases a DOI
+func NormalizeDOI(doi string) string {
+	return strings.ToLower(strings.TrimSpace(doiPrefix.ReplaceAllString(strings.TrimSpace(doi), "")))
+}
+
+// ParseAuthorName splits a name written as "Family, Given", "Given Family" or in CJK order
+func ParseAuthorName(raw string) Author {
+	name := strings.Join(strings.Fields(raw), " ")
+	author := Author{Name: name}
+
+	switch {
+	case strings.Contains(name, ","):
+		parts := strings.SplitN(name, ",", 2)
+		author.FamilyName = strings.TrimSpace(parts[0])
+		author.GivenName = strings.TrimSpace(parts[1])
+		author.Name = strings.TrimSpace(author.GivenName + " " + author.FamilyName)
+	case strings.Contains(name, " "):
+		i := strings.LastIndex(name, " ")
+		author.GivenName, author.FamilyName = name[:i], name[i+1:]
+	case isCJK(name):
+		// Chinese, Japanese and Korean names put the family name first
+		runes := []rune(name)
+		author.FamilyName, author.GivenName = string(runes[:1]), string(runes[1:])
+	default:
+		author.FamilyName = name
+	}
+
+	author.NormalizedName = NormalizeAuthorName(author.GivenName + " " + author.FamilyName)
+	return author
+}
+
+// NormalizeAuthorName lowercases a name and strips punctuation for matching
+func NormalizeAuthorName(name string) string {
+	cleaned := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsSpace(r) || r == '-' {
+			return unicode.ToLower(r)
+		}
+		return ' '
+	}, name)
+	return strings.Join(strings.Fields(cleaned), " ")
+}
+
+// isCJK reports whether a name is written in a CJK script
+func isCJK(s string) bool {
+	for _, r := range s {
+		if unicode.In(r, unicode.Han, unicode.Hangul, unicode.Hiragana, unicode.Katakana) {
+			return true
+		}
+	}
+	return false
+}
+
+// arxivIDFromPaperID extracts the arXiv ID from agent-generated paper IDs
+func arxivIDFromPaperID(id string) string {
+	if m := agentPaperID.FindStringSubmatch(id); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// twoDigits zero-pads a month number
+func twoDigits(n int) string {
+	if n < 10 {
+		return "0" + strconv.Itoa(n)
+	}
+	return strconv.Itoa(n)
+}
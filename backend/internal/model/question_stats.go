@@ -0,0 +1,59 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// QuestionStats holds psychometric statistics of a question computed from the answer log.
+// Statistics always describe the currently published revision.
+type QuestionStats struct {
+	QuestionID        string    `json:"question_id" gorm:"primaryKey;type:text"`
+	RevisionID        string    `json:"revision_id" gorm:"type:text"`
+	LevelID           string    `json:"level_id" gorm:"not null;type:text;index"`
+	Responses         int       `json:"responses" gorm:"not null;default:0"` // Distinct learners who saw the question
+	Answered          int       `json:"answered" gorm:"not null;default:0"`  // Responses that were not skipped
+	PValue            float64   `json:"p_value" gorm:"default:0"`            // Proportion correct (difficulty)
+	PointBiserial     *float64  `json:"point_biserial"`                      // Item-rest correlation (discrimination), NULL if undefined
+	SkipRate          float64   `json:"skip_rate" gorm:"default:0"`
+	MedianDurationMS  int       `json:"median_duration_ms" gorm:"default:0"`
+	UpperPValue       float64   `json:"upper_p_value" gorm:"default:0"`     // Proportion correct among top performers
+	LowerPValue       float64   `json:"lower_p_value" gorm:"default:0"`     // Proportion correct among bottom performers
+	OptionRatesJSON   string    `json:"option_rates_json" gorm:"type:text"` // []OptionRate as JSON
+	SuspectedWrongKey bool      `json:"suspected_wrong_key" gorm:"not null;default:false;index"`
+	FlagReason        string    `json:"flag_reason,omitempty" gorm:"type:text"`
+	ComputedAt        time.Time `json:"computed_at" gorm:"not null"`
+
+	// Associations
+	Question *Question `json:"question,omitempty" gorm:"foreignKey:QuestionID;constraint:OnDelete:CASCADE"`
+}
+
+// OptionRate represents how often an option was selected
+type OptionRate struct {
+	Option    string  `json:"option"`
+	IsKey     bool    `json:"is_key"`
+	Count     int     `json:"count"`
+	Rate      float64 `json:"rate"`       // Share of answered responses selecting the option
+	UpperRate float64 `json:"upper_rate"` // Share among top performers
+	LowerRate float64 `json:"lower_rate"` // Share among bottom performers
+}
+
+// GetOptionRates parses the option selection rates
+func (qs *QuestionStats) GetOptionRates() ([]OptionRate, error) {
+	var rates []OptionRate
+	if qs.OptionRatesJSON == "" {
+		return rates, nil
+	}
+	err := json.Unmarshal([]byte(qs.OptionRatesJSON), &rates)
+	return rates, err
+}
+
+// SetOptionRates sets the option selection rates
+func (qs *QuestionStats) SetOptionRates(rates []OptionRate) error {
+	data, err := json.Marshal(rates)
+	if err != nil {
+		return err
+	}
+	qs.OptionRatesJSON = string(data)
+	return nil
+}
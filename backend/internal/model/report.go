@@ -0,0 +1,66 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// QuestionReport represents an issue with a question reported by a learner
+type QuestionReport struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:text"`
+	QuestionID  string     `json:"question_id" gorm:"not null;type:text;index"`
+	RevisionID  *string    `json:"revision_id" gorm:"type:text"` // Revision published when the report was filed
+	UserID      string     `json:"user_id" gorm:"not null;type:text;index"`
+	Category    string     `json:"category" gorm:"not null;type:text"`
+	Description string     `json:"description" gorm:"type:text"`
+	Status      string     `json:"status" gorm:"not null;type:text;default:'open';index"`
+	AdminNote   string     `json:"admin_note" gorm:"type:text"`
+	HandledBy   string     `json:"handled_by" gorm:"type:text"` // Admin who last triaged or closed the report
+	HandledAt   *time.Time `json:"handled_at"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"not null"`
+
+	// Associations
+	User     *User     `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Question *Question `json:"question,omitempty" gorm:"foreignKey:QuestionID;constraint:OnDelete:CASCADE"`
+}
+
+// Report category constants
+const (
+	ReportCategoryWrongAnswer = "wrong_answer"
+	ReportCategoryTypo        = "typo"
+	ReportCategoryAmbiguous   = "ambiguous"
+	ReportCategoryOffensive   = "offensive"
+)
+
+// Report status constants
+const (
+	ReportStatusOpen     = "open"     // Waiting for an admin
+	ReportStatusTriaged  = "triaged"  // Acknowledged, fix in progress
+	ReportStatusResolved = "resolved" // Issue fixed
+	ReportStatusRejected = "rejected" // Not an issue
+)
+
+// IsValidReportCategory checks if a report category is supported
+func IsValidReportCategory(category string) bool {
+	switch category {
+	case ReportCategoryWrongAnswer, ReportCategoryTypo, ReportCategoryAmbiguous, ReportCategoryOffensive:
+		return true
+	}
+	return false
+}
+
+// IsOpen reports whether the report still awaits a decision
+func (r *QuestionReport) IsOpen() bool {
+	return r.Status == ReportStatusOpen || r.Status == ReportStatusTriaged
+}
+
+// BeforeCreate generates UUID for new question report
+func (r *QuestionReport) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = uuid.New().String()
+	}
+	return nil
+}
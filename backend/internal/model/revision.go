@@ -0,0 +1,110 @@
+package model
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// QuestionRevision represents an immutable snapshot of a question's content.
+// Only drafts may be edited; once submitted for review a revision never changes.
+type QuestionRevision struct {
+	ID          string     `json:"id" gorm:"primaryKey;type:text"`
+	QuestionID  string     `json:"question_id" gorm:"not null;type:text;uniqueIndex:idx_question_revisions_number"`
+	Number      int        `json:"number" gorm:"not null;uniqueIndex:idx_question_revisions_number"` // 1, 2, 3... per question
+	Status      string     `json:"status" gorm:"not null;type:text;default:'draft';index"`
+	Subtitle    string     `json:"subtitle" gorm:"type:text"`
+	Stem        string     `json:"stem" gorm:"not null;type:text"`
+	ContentJSON string     `json:"content_json" gorm:"not null;type:text"`
+	AnswerJSON  string     `json:"answer_json" gorm:"not null;type:text"`
+	Score       int        `json:"score" gorm:"not null"`
+	ChangeNote  string     `json:"change_note" gorm:"type:text"` // Why this revision was made
+	CreatedBy   string     `json:"created_by" gorm:"type:text"`
+	ReviewedBy  string     `json:"reviewed_by" gorm:"type:text"`
+	ReviewNote  string     `json:"review_note" gorm:"type:text"`
+	SubmittedAt *time.Time `json:"submitted_at" gorm:"type:datetime"`
+	PublishedAt *time.Time `json:"published_at" gorm:"type:datetime"`
+	CreatedAt   time.Time  `json:"created_at" gorm:"not null"`
+	UpdatedAt   time.Time  `json:"updated_at" gorm:"not null"`
+
+	// Associations
+	Question *Question `json:"question,omitempty" gorm:"foreignKey:QuestionID;constraint:OnDelete:CASCADE"`
+}
+
+// BeforeCreate generates UUID for new question revision
+func (qr *QuestionRevision) BeforeCreate(tx *gorm.DB) error {
+	if qr.ID == "" {
+		qr.ID = uuid.New().String()
+	}
+	return nil
+}
+
+// Revision status constants
+const (
+	RevisionStatusDraft      = "draft"      // Editable by authors
+	RevisionStatusInReview   = "in_review"  // Frozen, waiting for a reviewer
+	RevisionStatusPublished  = "published"  // Currently shown to learners
+	RevisionStatusSuperseded = "superseded" // Was published, replaced by a newer revision
+)
+
+// IsEditable checks if the revision content may still be changed
+func (qr *QuestionRevision) IsEditable() bool {
+	return qr.Status == RevisionStatusDraft
+}
+
+// AsQuestion returns a question carrying this revision's content, so that
+// grading and parsing helpers work against the exact version a learner saw
+func (qr *QuestionRevision) AsQuestion() *Question {
+	return &Question{
+		ID:          qr.QuestionID,
+		Subtitle:    qr.Subtitle,
+		Stem:        qr.Stem,
+		ContentJSON: qr.ContentJSON,
+		AnswerJSON:  qr.AnswerJSON,
+		Score:       qr.Score,
+		CreatedBy:   qr.CreatedBy,
+	}
+}
+
+// NewRevisionFromQuestion snapshots the current content of a question
+func NewRevisionFromQuestion(q *Question, number int) *QuestionRevision {
+	return &QuestionRevision{
+		QuestionID:  q.ID,
+		Number:      number,
+		Subtitle:    q.Subtitle,
+		Stem:        q.Stem,
+		ContentJSON: q.ContentJSON,
+		AnswerJSON:  q.AnswerJSON,
+		Score:       q.Score,
+		CreatedBy:   q.CreatedBy,
+	}
+}
+
+// AnswerRecord represents a single answer submitted by a learner
+type AnswerRecord struct {
+	ID         string    `json:"id" gorm:"primaryKey;type:text"`
+	UserID     string    `json:"user_id" gorm:"not null;type:text;index"`
+	LevelID    string    `json:"level_id" gorm:"not null;type:text;index"`
+	QuestionID string    `json:"question_id" gorm:"not null;type:text;index"`
+	RevisionID string    `json:"revision_id" gorm:"not null;type:text;index"` // Revision the learner actually saw
+	AnswerJSON string    `json:"answer_json" gorm:"type:text"`
+	IsCorrect  bool      `json:"is_correct" gorm:"not null;default:false"`
+	Score      int       `json:"score" gorm:"not null;default:0"`
+	DurationMS int       `json:"duration_ms" gorm:"default:0"`
+	Skipped    bool      `json:"skipped" gorm:"not null;default:false"` // Learner moved on without answering
+	CreatedAt  time.Time `json:"created_at" gorm:"not null;index"`
+
+	// Associations
+	User     *User             `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Question *Question         `json:"question,omitempty" gorm:"foreignKey:QuestionID;constraint:OnDelete:CASCADE"`
+	Revision *QuestionRevision `json:"revision,omitempty" gorm:"foreignKey:RevisionID"`
+}
+
+// BeforeCreate generates UUID for new answer record
+func (ar *AnswerRecord) BeforeCreate(tx *gorm.DB) error {
+	if ar.ID == "" {
+		ar.ID = uuid.New().String()
+	}
+	return nil
+}
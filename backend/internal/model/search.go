@@ -0,0 +1,135 @@
+package model
+
+import (
+	"fmt"
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// Full-text search tables. They are derived from the content tables, kept in sync by
+// triggers and rebuilt on startup, so they are managed like indexes rather than migrated.
+//
+// FTS5 is only available when the binary is built with the sqlite_fts5 tag
+// (go build -tags sqlite_fts5); without it search falls back to LIKE queries.
+const (
+	SearchPapersTable    = "search_papers"
+	SearchQuestionsTable = "search_questions"
+	SearchSubjectsTable  = "search_subjects"
+)
+
+// questionContentTextSQL extracts the searchable text of a question's content JSON
+const questionContentTextSQL = `CASE WHEN json_valid(%[1]s.content_json) THEN trim(
+	coalesce(json_extract(%[1]s.content_json, '$.text'), '') || ' ' ||
+	coalesce(json_extract(%[1]s.content_json, '$.question'), '') || ' ' ||
+	coalesce((SELECT group_concat(value, ' ') FROM json_each(%[1]s.content_json, '$.options')), '') || ' ' ||
+	coalesce(json_extract(%[1]s.content_json, '$.code'), '')
+) ELSE %[1]s.content_json END`
+
+// searchTriggers lists the triggers keeping the search tables in sync
+var searchTriggers = []string{
+	"search_papers_ai", "search_papers_au", "search_papers_ad",
+	"search_questions_ai", "search_questions_au", "search_questions_ad",
+	"search_subjects_ai", "search_subjects_au", "search_subjects_ad",
+}
+
+// HasFTS5 reports whether the SQLite driver was compiled with FTS5 support
+func HasFTS5(db *gorm.DB) bool {
+	var enabled int
+	if err := db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error; err != nil {
+		return false
+	}
+	return enabled == 1
+}
+
+// SearchIndexReady reports whether the FTS5 search tables can be queried
+func SearchIndexReady(db *gorm.DB) bool {
+	return HasFTS5(db) && db.Migrator().HasTable(SearchPapersTable)
+}
+
+// SetupSearchIndex creates the FTS5 search tables and triggers and rebuilds their content.
+// Existing sync triggers are always dropped first, so they are recreated with the current
+// text extraction, or removed when FTS5 is not available so that writes keep working.
+func (d *Database) SetupSearchIndex() (bool, error) {
+	for _, trigger := range searchTriggers {
+		if err := d.DB.Exec("DROP TRIGGER IF EXISTS " + trigger).Error; err != nil {
+			return false, fmt.Errorf("failed to drop search trigger %s: %w", trigger, err)
+		}
+	}
+	if !HasFTS5(d.DB) {
+		log.Println("FTS5 not available, full-text search will use LIKE queries")
+		return false, nil
+	}
+
+	questionText := func(row string) string {
+		return fmt.Sprintf(questionContentTextSQL, row)
+	}
+
+	// The trigram tokenizer matches substrings, so CJK text without word boundaries is searchable
+	statements := []string{
+		`CREATE VIRTUAL TABLE IF NOT EXISTS search_papers USING fts5(
+			paper_id UNINDEXED, title, authors, tokenize = 'trigram')`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS search_questions USING fts5(
+			question_id UNINDEXED, stem, content, tokenize = 'trigram')`,
+		`CREATE VIRTUAL TABLE IF NOT EXISTS search_subjects USING fts5(
+			subject_id UNINDEXED, name, description, tokenize = 'trigram')`,
+
+		`CREATE TRIGGER IF NOT EXISTS search_papers_ai AFTER INSERT ON papers BEGIN
+			INSERT INTO search_papers(paper_id, title, authors) VALUES (new.id, new.title, new.paper_author);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_papers_au AFTER UPDATE ON papers BEGIN
+			DELETE FROM search_papers WHERE paper_id = old.id;
+			INSERT INTO search_papers(paper_id, title, authors) VALUES (new.id, new.title, new.paper_author);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_papers_ad AFTER DELETE ON papers BEGIN
+			DELETE FROM search_papers WHERE paper_id = old.id;
+		END`,
+
+		`CREATE TRIGGER IF NOT EXISTS search_questions_ai AFTER INSERT ON questions BEGIN
+			INSERT INTO search_questions(question_id, stem, content) VALUES (new.id, new.stem, ` + questionText("new") + `);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_questions_au AFTER UPDATE ON questions BEGIN
+			DELETE FROM search_questions WHERE question_id = old.id;
+			INSERT INTO search_questions(question_id, stem, content) VALUES (new.id, new.stem, ` + questionText("new") + `);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_questions_ad AFTER DELETE ON questions BEGIN
+			DELETE FROM search_questions WHERE question_id = old.id;
+		END`,
+
+		`CREATE TRIGGER IF NOT EXISTS search_subjects_ai AFTER INSERT ON subjects BEGIN
+			INSERT INTO search_subjects(subject_id, name, description) VALUES (new.id, new.name, new.description);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_subjects_au AFTER UPDATE ON subjects BEGIN
+			DELETE FROM search_subjects WHERE subject_id = old.id;
+			INSERT INTO search_subjects(subject_id, name, description) VALUES (new.id, new.name, new.description);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS search_subjects_ad AFTER DELETE ON subjects BEGIN
+			DELETE FROM search_subjects WHERE subject_id = old.id;
+		END`,
+	}
+
+	// Rebuild from scratch: rows may have changed while the triggers were missing
+	rebuild := []string{
+		`DELETE FROM search_papers`,
+		`INSERT INTO search_papers(paper_id, title, authors) SELECT id, title, paper_author FROM papers`,
+		`DELETE FROM search_questions`,
+		`INSERT INTO search_questions(question_id, stem, content) SELECT q.id, q.stem, ` + questionText("q") + ` FROM questions q`,
+		`DELETE FROM search_subjects`,
+		`INSERT INTO search_subjects(subject_id, name, description) SELECT id, name, description FROM subjects`,
+	}
+
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		for _, stmt := range append(statements, rebuild...) {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("failed to set up search index: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	log.Println("Full-text search index ready")
+	return true, nil
+}
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"strings"
 	"time"
 
 	"github.com/google/uuid"
@@ -10,15 +11,22 @@ import (
 
 // User represents a user in the system
 type User struct {
-	ID            string    `json:"id" gorm:"primaryKey;type:text"`
-	Email         string    `json:"email" gorm:"unique;not null;type:text"`
-	PasswordHash  string    `json:"-" gorm:"not null;type:text"` // Never expose password hash in JSON
-	DisplayName   string    `json:"display_name" gorm:"type:text"`
-	AvatarURL     string    `json:"avatar_url" gorm:"type:text"`
-	EthAddress    string    `json:"eth_address" gorm:"type:text"` // Ethereum wallet address
-	EthPrivateKey string    `json:"-" gorm:"type:text"`           // Never expose private key in JSON
-	CreatedAt     time.Time `json:"created_at" gorm:"not null"`
-	UpdatedAt     time.Time `json:"updated_at" gorm:"not null"`
+	ID                  string     `json:"id" gorm:"primaryKey;type:text"`
+	Email               string     `json:"email" gorm:"unique;not null;type:text"`
+	PasswordHash        string     `json:"-" gorm:"not null;type:text"` // Never expose password hash in JSON
+	DisplayName         string     `json:"display_name" gorm:"type:text"`
+	AvatarURL           string     `json:"avatar_url" gorm:"type:text"`
+	EthAddress          string     `json:"eth_address" gorm:"type:text"` // Ethereum wallet address
+	EthPrivateKey       string     `json:"-" gorm:"type:text"`           // Never expose private key in JSON
+	Role                string     `json:"role" gorm:"not null;type:text;default:'user'"`
+	EmailVerifiedAt     *time.Time `json:"email_verified_at"`           // Nil until the email address is confirmed
+	TokenVersion        int        `json:"-" gorm:"not null;default:0"` // Access tokens carrying an older version are rejected
+	DeletionRequestedAt *time.Time `json:"deletion_requested_at"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at" gorm:"index"` // The account is purged once this has passed
+	WalletDisposition   string     `json:"-" gorm:"type:text"`                 // Choice for the custodial wallet key on deletion
+	AnonymizedAt        *time.Time `json:"anonymized_at"`                      // Set when a deleted account was anonymized
+	CreatedAt           time.Time  `json:"created_at" gorm:"not null"`
+	UpdatedAt           time.Time  `json:"updated_at" gorm:"not null"`
 
 	// Associations
 	RefreshTokens []RefreshToken    `json:"-" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
@@ -36,6 +44,58 @@ func (u *User) BeforeCreate(tx *gorm.DB) error {
 	return nil
 }
 
+// User role constants
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+	RoleGuest = "guest" // Plays without registering until upgraded to a user
+)
+
+// IsAdmin checks if the user has administrator privileges
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+// IsGuest checks if the user is an unregistered guest
+func (u *User) IsGuest() bool {
+	return u.Role == RoleGuest
+}
+
+// IsEmailVerified checks if the user has confirmed their email address
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Custodial wallet dispositions chosen when deleting an account
+const (
+	WalletExport  = "export"  // The private key was handed to the user
+	WalletForfeit = "forfeit" // The user gave up the wallet and its NFTs
+)
+
+// IsDeletionPending checks if the user has asked for the account to be deleted
+func (u *User) IsDeletionPending() bool {
+	return u.DeletionScheduledAt != nil && u.AnonymizedAt == nil
+}
+
+// PlaceholderEmailDomain is used for the email of accounts created through an external
+// provider, which have no email login until one is attached
+const PlaceholderEmailDomain = "wechat.invalid"
+
+// GuestEmailDomain is used for the email of guest accounts
+const GuestEmailDomain = "guest.invalid"
+
+// IsReservedEmail checks if an address belongs to a domain used for placeholder emails,
+// which can never be registered
+func IsReservedEmail(email string) bool {
+	email = strings.ToLower(email)
+	return strings.HasSuffix(email, "@"+PlaceholderEmailDomain) || strings.HasSuffix(email, "@"+GuestEmailDomain)
+}
+
+// HasEmailLogin checks if the user can log in with an email address and password
+func (u *User) HasEmailLogin() bool {
+	return u.PasswordHash != "" && !strings.HasSuffix(u.Email, "@"+PlaceholderEmailDomain)
+}
+
 // SetPassword hashes and sets the password
 func (u *User) SetPassword(password string) error {
 	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
@@ -54,10 +114,16 @@ func (u *User) CheckPassword(password string) bool {
 
 // RefreshToken represents a JWT refresh token
 type RefreshToken struct {
-	Token     string    `json:"token" gorm:"primaryKey;type:text"`
-	UserID    string    `json:"user_id" gorm:"not null;type:text;index"`
-	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
-	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+	Token      string     `json:"token" gorm:"primaryKey;type:text"`
+	UserID     string     `json:"user_id" gorm:"not null;type:text;index"`
+	FamilyID   string     `json:"family_id" gorm:"not null;type:text;index"` // Shared by all tokens rotated from one login
+	RotatedAt  *time.Time `json:"rotated_at"`                                // Set once exchanged; presenting it again is reuse
+	DeviceName string     `json:"device_name" gorm:"type:text"`              // Client-supplied label, carried over on rotation
+	UserAgent  string     `json:"user_agent" gorm:"type:text"`
+	IPAddress  string     `json:"ip_address" gorm:"type:text"`
+	LastUsedAt *time.Time `json:"last_used_at"` // Time of the login or refresh that issued this token
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"not null"`
+	CreatedAt  time.Time  `json:"created_at" gorm:"not null"`
 
 	// Associations
 	User *User `json:"user,omitempty" gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
@@ -68,6 +134,9 @@ func (rt *RefreshToken) BeforeCreate(tx *gorm.DB) error {
 	if rt.Token == "" {
 		rt.Token = uuid.New().String()
 	}
+	if rt.FamilyID == "" {
+		rt.FamilyID = uuid.New().String()
+	}
 	return nil
 }
 
@@ -76,6 +145,30 @@ func (rt *RefreshToken) IsExpired() bool {
 	return time.Now().After(rt.ExpiresAt)
 }
 
+// IsRotated checks if the refresh token has already been exchanged for a new one
+func (rt *RefreshToken) IsRotated() bool {
+	return rt.RotatedAt != nil
+}
+
+// RevokedSession denies the access tokens already issued for a signed-out session until
+// the last of them expires. The session's refresh tokens are deleted on sign-out, so the
+// denial is stored on its own where every instance can see it.
+type RevokedSession struct {
+	SessionID string    `json:"session_id" gorm:"primaryKey;type:text"` // Family ID of the session's refresh tokens
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null;index"`       // When the last access token of the session expires
+	CreatedAt time.Time `json:"created_at" gorm:"not null"`
+}
+
+// RevokeAllTokens signs a user out everywhere: it deletes the refresh tokens and bumps
+// the token version, so access tokens already issued are rejected too
+func RevokeAllTokens(db *gorm.DB, userID string) error {
+	if err := db.Where("user_id = ?", userID).Delete(&RefreshToken{}).Error; err != nil {
+		return err
+	}
+	return db.Model(&User{}).Where("id = ?", userID).
+		UpdateColumn("token_version", gorm.Expr("token_version + 1")).Error
+}
+
 // UserProgress represents user progress on levels
 type UserProgress struct {
 	UserID        string     `json:"user_id" gorm:"primaryKey;type:text"`
@@ -0,0 +1,303 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"paperplay/config"
+	"paperplay/internal/mailer"
+	"paperplay/internal/model"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Account token purposes
+const (
+	TokenPurposeVerifyEmail   = "verify_email"
+	TokenPurposeResetPassword = "reset_password"
+)
+
+// Account errors
+var (
+	ErrInvalidAccountToken  = errors.New("invalid or already used token")
+	ErrAccountTokenExpired  = errors.New("token has expired")
+	ErrEmailAlreadyVerified = errors.New("email address is already verified")
+	ErrEmailNotVerified     = errors.New("email address is not verified")
+	ErrUserNotFound         = errors.New("user not found")
+)
+
+// accountTokenClaims are the claims of email verification and password reset tokens.
+// The stamp binds a token to the account state it was issued for: the email address for
+// verification and the password hash for reset, so a reset token works only once.
+type accountTokenClaims struct {
+	Purpose string `json:"purpose"`
+	Stamp   string `json:"stamp"`
+	jwt.RegisteredClaims
+}
+
+// AccountService handles email verification, password reset, account deletion and data export
+type AccountService struct {
+	db            *gorm.DB
+	mailer        mailer.Mailer
+	logger        *zap.Logger
+	signingKey    []byte
+	baseURL       string
+	verifyTTL     time.Duration
+	resetTTL      time.Duration
+	deletionGrace time.Duration
+	deletionMode  string
+}
+
+// NewAccountService creates a new account service
+func NewAccountService(db *gorm.DB, m mailer.Mailer, cfg *config.Config, logger *zap.Logger) *AccountService {
+	// Derived from the JWT secret so account tokens can never pass as access tokens
+	key := sha256.Sum256([]byte("paperplay-account-tokens:" + cfg.JWT.SecretKey))
+
+	deletionMode := cfg.Account.DeletionMode
+	if deletionMode == "" {
+		deletionMode = DeletionModeAnonymize
+	}
+
+	return &AccountService{
+		db:            db,
+		mailer:        m,
+		logger:        logger,
+		signingKey:    key[:],
+		baseURL:       strings.TrimRight(cfg.Mail.AppBaseURL, "/"),
+		verifyTTL:     time.Duration(cfg.Mail.VerifyTokenTTL) * time.Hour,
+		resetTTL:      time.Duration(cfg.Mail.ResetTokenTTL) * time.Minute,
+		deletionGrace: time.Duration(cfg.Account.DeletionGracePeriod) * 24 * time.Hour,
+		deletionMode:  deletionMode,
+	}
+}
+
+// tokenStamp returns the account state a token of the given purpose is bound to
+func tokenStamp(user *model.User, purpose string) string {
+	state := user.Email
+	if purpose == TokenPurposeResetPassword {
+		state = user.PasswordHash
+	}
+	sum := sha256.Sum256([]byte(purpose + ":" + state))
+	return hex.EncodeToString(sum[:8])
+}
+
+// issueToken creates a signed, expiring token for a user
+func (s *AccountService) issueToken(user *model.User, purpose string, ttl time.Duration) (string, error) {
+	now := time.Now()
+	claims := accountTokenClaims{
+		Purpose: purpose,
+		Stamp:   tokenStamp(user, purpose),
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   user.ID,
+			Issuer:    "paperplay",
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.signingKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return token, nil
+}
+
+// parseToken validates a token and returns the user it was issued for
+func (s *AccountService) parseToken(tx *gorm.DB, tokenString, purpose string) (*model.User, error) {
+	var claims accountTokenClaims
+	_, err := jwt.ParseWithClaims(tokenString, &claims, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return s.signingKey, nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrAccountTokenExpired
+		}
+		return nil, ErrInvalidAccountToken
+	}
+	if claims.Purpose != purpose {
+		return nil, ErrInvalidAccountToken
+	}
+
+	var user model.User
+	if err := tx.First(&user, "id = ?", claims.Subject).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrInvalidAccountToken
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if claims.Stamp != tokenStamp(&user, purpose) {
+		return nil, ErrInvalidAccountToken
+	}
+	return &user, nil
+}
+
+// link builds a frontend URL carrying a token
+func (s *AccountService) link(path, token string) string {
+	return s.baseURL + path + "?token=" + url.QueryEscape(token)
+}
+
+// SendVerificationEmail emails a verification link to the user
+func (s *AccountService) SendVerificationEmail(user *model.User) error {
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+
+	token, err := s.issueToken(user, TokenPurposeVerifyEmail, s.verifyTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"Please confirm your email address for PaperPlay by opening the link below:\n\n%s\n\n"+
+		"The link expires in %d hours. If you did not create an account, you can ignore this email.\n",
+		user.DisplayName, s.link("/verify-email", token), int(s.verifyTTL.Hours()))
+
+	if err := s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your PaperPlay email address",
+		Body:    body,
+	}); err != nil {
+		s.logger.Warn("Failed to send verification email", zap.String("user_id", user.ID), zap.Error(err))
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
+	return nil
+}
+
+// ResendVerification sends a new verification email to a user
+func (s *AccountService) ResendVerification(userID string) error {
+	var user model.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	return s.SendVerificationEmail(&user)
+}
+
+// VerifyEmail marks the email address of the token's user as verified
+func (s *AccountService) VerifyEmail(token string) (*model.User, error) {
+	user, err := s.parseToken(s.db, token, TokenPurposeVerifyEmail)
+	if err != nil {
+		return nil, err
+	}
+	if user.IsEmailVerified() {
+		return user, nil
+	}
+
+	now := time.Now()
+	if err := s.db.Model(user).Update("email_verified_at", now).Error; err != nil {
+		return nil, fmt.Errorf("failed to verify email: %w", err)
+	}
+	user.EmailVerifiedAt = &now
+
+	s.logger.Info("Email verified", zap.String("user_id", user.ID))
+	return user, nil
+}
+
+// RequestPasswordReset emails a reset link if an account exists for the address.
+// Unknown addresses are not reported, so the endpoint cannot be used to probe for accounts.
+func (s *AccountService) RequestPasswordReset(email string) error {
+	var user model.User
+	if err := s.db.Where("email = ?", strings.ToLower(email)).First(&user).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+
+	token, err := s.issueToken(&user, TokenPurposeResetPassword, s.resetTTL)
+	if err != nil {
+		return err
+	}
+
+	body := fmt.Sprintf("Hi %s,\n\n"+
+		"We received a request to reset your PaperPlay password. Open the link below to choose a new one:\n\n%s\n\n"+
+		"The link expires in %d minutes and can only be used once. "+
+		"If you did not request a reset, you can ignore this email.\n",
+		user.DisplayName, s.link("/reset-password", token), int(s.resetTTL.Minutes()))
+
+	if err := s.mailer.Send(&mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your PaperPlay password",
+		Body:    body,
+	}); err != nil {
+		// Failing only for existing accounts would reveal which addresses are registered
+		s.logger.Error("Failed to send password reset email", zap.String("user_id", user.ID), zap.Error(err))
+	}
+	return nil
+}
+
+// ResetPassword sets a new password and signs the user out everywhere
+func (s *AccountService) ResetPassword(token, newPassword string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		user, err := s.parseToken(tx, token, TokenPurposeResetPassword)
+		if err != nil {
+			return err
+		}
+
+		if err := user.SetPassword(newPassword); err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		updates := map[string]any{"password_hash": user.PasswordHash}
+		// Receiving the reset link proves ownership of the address
+		if !user.IsEmailVerified() {
+			updates["email_verified_at"] = time.Now()
+		}
+		if err := tx.Model(user).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+
+		if err := model.RevokeAllTokens(tx, user.ID); err != nil {
+			return fmt.Errorf("failed to revoke tokens: %w", err)
+		}
+
+		s.logger.Info("Password reset", zap.String("user_id", user.ID))
+		return nil
+	})
+}
+
+// ChangePassword replaces the password of a user who knows the current one and signs
+// the user out everywhere. It returns the user with the new token version.
+func (s *AccountService) ChangePassword(userID, currentPassword, newPassword string) (*model.User, error) {
+	var user model.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		if !user.HasEmailLogin() {
+			return ErrNoEmailLogin
+		}
+		if !user.CheckPassword(currentPassword) {
+			return ErrInvalidPassword
+		}
+
+		if err := user.SetPassword(newPassword); err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		if err := tx.Model(&user).Update("password_hash", user.PasswordHash).Error; err != nil {
+			return fmt.Errorf("failed to update password: %w", err)
+		}
+		if err := model.RevokeAllTokens(tx, user.ID); err != nil {
+			return fmt.Errorf("failed to revoke tokens: %w", err)
+		}
+		return tx.First(&user, "id = ?", userID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Password changed", zap.String("user_id", user.ID))
+	return &user, nil
+}
@@ -0,0 +1,333 @@
+package service
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"paperplay/internal/mailer"
+	"paperplay/internal/model"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Account deletion modes
+const (
+	DeletionModeAnonymize = "anonymize" // Scrub personal data, keep answers for question statistics
+	DeletionModeDelete    = "delete"    // Remove the user and everything linked to it
+)
+
+// anonymizedEmailDomain is used for the email of anonymized accounts
+const anonymizedEmailDomain = "deleted.invalid"
+
+// Account management errors
+var (
+	ErrInvalidPassword       = errors.New("password is incorrect")
+	ErrNoEmailLogin          = errors.New("account has no password")
+	ErrWalletActionRequired  = errors.New("choose whether to export or forfeit the custodial wallet")
+	ErrDeletionPending       = errors.New("account deletion is already scheduled")
+	ErrNoDeletionPending     = errors.New("no account deletion is scheduled")
+	ErrInvalidWalletDecision = errors.New("wallet decision must be export or forfeit")
+)
+
+// userDataModels are the tables holding a user's learning data, keyed by user_id
+var userDataModels = []any{
+	&model.UserProgress{}, &model.UserAttempts{}, &model.AnswerRecord{}, &model.Event{}, &model.OutboxEvent{},
+	&model.UserAchievement{}, &model.UserAchievementProgress{}, &model.AchievementAuditLog{}, &model.NFTAsset{},
+	&model.QuestionReport{},
+}
+
+// deleteUserData removes a user and all learning data linked to it
+func deleteUserData(tx *gorm.DB, userID string) error {
+	for _, m := range userDataModels {
+		if err := tx.Where("user_id = ?", userID).Delete(m).Error; err != nil {
+			return fmt.Errorf("failed to delete user data: %w", err)
+		}
+	}
+	// Entries about other users stay in their audit trail without naming the actor
+	if err := tx.Model(&model.AchievementAuditLog{}).Where("actor_id = ?", userID).
+		Update("actor_id", nil).Error; err != nil {
+		return fmt.Errorf("failed to anonymize achievement audit log: %w", err)
+	}
+	if err := tx.Delete(&model.User{}, "id = ?", userID).Error; err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	return nil
+}
+
+// UserDataExport is everything stored about a user, as handed out by the data export
+type UserDataExport struct {
+	ExportedAt   time.Time                   `json:"exported_at"`
+	Profile      *model.User                 `json:"profile"`
+	Identities   []model.ExternalIdentity    `json:"identities"`
+	Progress     []model.UserProgress        `json:"progress"`
+	Attempts     []model.UserAttempts        `json:"daily_attempts"`
+	Answers      []model.AnswerRecord        `json:"answers"`
+	Events       []model.Event               `json:"events"`
+	Achievements []model.UserAchievement     `json:"achievements"`
+	AuditLog     []model.AchievementAuditLog `json:"achievement_audit_log"`
+	NFTAssets    []model.NFTAsset            `json:"nft_assets"`
+	Reports      []model.QuestionReport      `json:"question_reports"`
+}
+
+// DeletionRequest is the outcome of scheduling an account deletion
+type DeletionRequest struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+	// Custodial wallet handed over when the user chose to export it; shown only once
+	WalletAddress    string `json:"wallet_address,omitempty"`
+	WalletPrivateKey string `json:"wallet_private_key,omitempty"`
+}
+
+// ExportUserData collects the data stored about a user
+func (s *AccountService) ExportUserData(userID string) (*UserDataExport, error) {
+	export := &UserDataExport{ExportedAt: time.Now().UTC()}
+
+	var user model.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	export.Profile = &user
+
+	queries := []struct {
+		name string
+		dest any
+		db   *gorm.DB
+	}{
+		{"identities", &export.Identities, s.db.Order("created_at")},
+		{"progress", &export.Progress, s.db.Order("level_id")},
+		{"daily attempts", &export.Attempts, s.db.Order("stat_date")},
+		{"answers", &export.Answers, s.db.Order("created_at")},
+		{"events", &export.Events, s.db.Order("created_at")},
+		{"achievements", &export.Achievements, s.db.Preload("Achievement").Order("earned_at")},
+		{"achievement audit log", &export.AuditLog, s.db.Order("created_at")},
+		{"NFT assets", &export.NFTAssets, s.db.Order("created_at")},
+		{"question reports", &export.Reports, s.db.Order("created_at")},
+	}
+	for _, q := range queries {
+		if err := q.db.Where("user_id = ?", userID).Find(q.dest).Error; err != nil {
+			return nil, fmt.Errorf("failed to export %s: %w", q.name, err)
+		}
+	}
+	return export, nil
+}
+
+// WriteExportZip writes a data export as a ZIP archive with one JSON file per section
+func WriteExportZip(w io.Writer, export *UserDataExport) error {
+	files := []struct {
+		name string
+		data any
+	}{
+		{"profile.json", export.Profile},
+		{"identities.json", export.Identities},
+		{"progress.json", export.Progress},
+		{"daily_attempts.json", export.Attempts},
+		{"answers.json", export.Answers},
+		{"events.json", export.Events},
+		{"achievements.json", export.Achievements},
+		{"achievement_audit_log.json", export.AuditLog},
+		{"nft_assets.json", export.NFTAssets},
+		{"question_reports.json", export.Reports},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", file.name, err)
+		}
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", file.name, err)
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return nil
+}
+
+// RequestDeletion schedules the user's account for deletion after the grace period and
+// signs it out everywhere. Users with a password must confirm it. A custodial wallet is
+// never dropped silently: the user has to either export its key, which is returned once,
+// or forfeit it together with its NFTs.
+func (s *AccountService) RequestDeletion(userID, password, walletDecision string) (*DeletionRequest, error) {
+	var user model.User
+	result := &DeletionRequest{}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		if user.IsDeletionPending() {
+			return ErrDeletionPending
+		}
+		if user.HasEmailLogin() && !user.CheckPassword(password) {
+			return ErrInvalidPassword
+		}
+
+		updates := map[string]any{}
+		if user.EthPrivateKey != "" {
+			switch walletDecision {
+			case model.WalletExport:
+				result.WalletAddress = user.EthAddress
+				result.WalletPrivateKey = user.EthPrivateKey
+			case model.WalletForfeit:
+			case "":
+				return ErrWalletActionRequired
+			default:
+				return ErrInvalidWalletDecision
+			}
+			updates["wallet_disposition"] = walletDecision
+		}
+
+		now := time.Now()
+		result.ScheduledAt = now.Add(s.deletionGrace)
+		updates["deletion_requested_at"] = now
+		updates["deletion_scheduled_at"] = result.ScheduledAt
+		if err := tx.Model(&user).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to schedule deletion: %w", err)
+		}
+
+		if err := model.RevokeAllTokens(tx, user.ID); err != nil {
+			return fmt.Errorf("failed to revoke tokens: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Account deletion scheduled",
+		zap.String("user_id", user.ID),
+		zap.Time("scheduled_at", result.ScheduledAt),
+		zap.String("wallet_disposition", user.WalletDisposition))
+	if result.WalletPrivateKey != "" {
+		s.logger.Warn("Custodial wallet key exported for account deletion",
+			zap.String("user_id", user.ID), zap.String("eth_address", user.EthAddress))
+	}
+
+	if user.HasEmailLogin() {
+		body := fmt.Sprintf("Hi %s,\n\n"+
+			"Your PaperPlay account is scheduled to be deleted on %s. "+
+			"Until then you can log in and cancel the deletion from your account settings.\n\n"+
+			"If you did not ask for this, log in and cancel the deletion, then change your password.\n",
+			user.DisplayName, result.ScheduledAt.UTC().Format("2 January 2006 15:04 MST"))
+		if err := s.mailer.Send(&mailer.Message{
+			To:      user.Email,
+			Subject: "Your PaperPlay account will be deleted",
+			Body:    body,
+		}); err != nil {
+			s.logger.Warn("Failed to send deletion notice", zap.String("user_id", user.ID), zap.Error(err))
+		}
+	}
+	return result, nil
+}
+
+// CancelDeletion cancels a scheduled account deletion
+func (s *AccountService) CancelDeletion(userID string) error {
+	result := s.db.Model(&model.User{}).
+		Where("id = ? AND deletion_scheduled_at IS NOT NULL AND anonymized_at IS NULL", userID).
+		Updates(map[string]any{
+			"deletion_requested_at": nil,
+			"deletion_scheduled_at": nil,
+			"wallet_disposition":    "",
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to cancel deletion: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNoDeletionPending
+	}
+
+	s.logger.Info("Account deletion cancelled", zap.String("user_id", userID))
+	return nil
+}
+
+// PurgeDueAccounts carries out the deletions whose grace period has passed and
+// returns how many accounts were purged
+func (s *AccountService) PurgeDueAccounts() (int, error) {
+	var users []model.User
+	if err := s.db.Where("deletion_scheduled_at <= ? AND anonymized_at IS NULL", time.Now()).
+		Find(&users).Error; err != nil {
+		return 0, fmt.Errorf("failed to get accounts due for deletion: %w", err)
+	}
+
+	purged := 0
+	for i := range users {
+		if err := s.purgeUser(&users[i]); err != nil {
+			s.logger.Error("Failed to purge account", zap.String("user_id", users[i].ID), zap.Error(err))
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// purgeUser anonymizes or deletes one account according to the deletion mode
+func (s *AccountService) purgeUser(user *model.User) error {
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Credentials and sign-in data go in either mode
+		if err := model.RevokeAllTokens(tx, user.ID); err != nil {
+			return fmt.Errorf("failed to revoke tokens: %w", err)
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.ExternalIdentity{}).Error; err != nil {
+			return fmt.Errorf("failed to delete sign-in data: %w", err)
+		}
+		if err := tx.Where("scope = ? AND identifier = ?", model.ThrottleScopeAccount, user.Email).
+			Delete(&model.LoginThrottle{}).Error; err != nil {
+			return fmt.Errorf("failed to delete login throttle: %w", err)
+		}
+		if err := tx.Where("created_by = ?", user.ID).Delete(&model.APIKey{}).Error; err != nil {
+			return fmt.Errorf("failed to delete API keys: %w", err)
+		}
+		// Undelivered events would otherwise keep evaluating achievements for the account
+		if err := tx.Where("user_id = ?", user.ID).Delete(&model.OutboxEvent{}).Error; err != nil {
+			return fmt.Errorf("failed to delete outbox events: %w", err)
+		}
+
+		if s.deletionMode == DeletionModeDelete {
+			return deleteUserData(tx, user.ID)
+		}
+
+		// Learning data stays, but nothing links it to a person any more
+		if err := tx.Model(&model.User{}).Where("id = ?", user.ID).Updates(map[string]any{
+			"email":             "deleted-" + user.ID + "@" + anonymizedEmailDomain,
+			"password_hash":     "",
+			"display_name":      "Deleted user",
+			"avatar_url":        "",
+			"eth_address":       "",
+			"eth_private_key":   "",
+			"email_verified_at": nil,
+			"anonymized_at":     time.Now(),
+		}).Error; err != nil {
+			return fmt.Errorf("failed to anonymize user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if user.EthPrivateKey != "" {
+		s.logger.Warn("Custodial wallet key destroyed",
+			zap.String("user_id", user.ID),
+			zap.String("eth_address", user.EthAddress),
+			zap.String("wallet_disposition", user.WalletDisposition))
+	}
+	s.logger.Info("Account purged", zap.String("user_id", user.ID), zap.String("mode", s.deletionMode))
+	return nil
+}
@@ -0,0 +1,341 @@
+package service
+
+import (
+	"archive/zip"
+	"bytes"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"paperplay/config"
+	"paperplay/internal/mailer"
+	"paperplay/internal/model"
+)
+
+var tokenLinkPattern = regexp.MustCompile(`token=(\S+)`)
+
+func setupAccountTest(t *testing.T) (*gorm.DB, *mailer.OutboxMailer, *AccountService) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.RefreshToken{}))
+
+	outbox, err := mailer.NewOutboxMailer(t.TempDir(), "PaperPlay <no-reply@example.com>")
+	require.NoError(t, err)
+
+	cfg := &config.Config{
+		JWT: config.JWTConfig{SecretKey: "test-secret"},
+		Mail: config.MailConfig{
+			AppBaseURL:     "https://paperplay.example.com/",
+			VerifyTokenTTL: 48,
+			ResetTokenTTL:  30,
+		},
+	}
+	accountService := NewAccountService(db, outbox, cfg, zap.NewNop())
+
+	user := &model.User{ID: "user-1", Email: "learner@example.com", DisplayName: "Learner"}
+	require.NoError(t, user.SetPassword("old-password"))
+	require.NoError(t, db.Create(user).Error)
+
+	return db, outbox, accountService
+}
+
+// failingMailer refuses every message
+type failingMailer struct{}
+
+func (failingMailer) Send(*mailer.Message) error { return errors.New("smtp unavailable") }
+
+// lastToken extracts the token from the newest email in the outbox
+func lastToken(t *testing.T, outbox *mailer.OutboxMailer) (mailer.Message, string) {
+	messages, err := outbox.Messages()
+	require.NoError(t, err)
+	require.NotEmpty(t, messages)
+
+	msg := messages[len(messages)-1]
+	m := tokenLinkPattern.FindStringSubmatch(msg.Body)
+	require.NotNil(t, m, msg.Body)
+	token, err := url.QueryUnescape(m[1])
+	require.NoError(t, err)
+	return msg, token
+}
+
+func TestAccountService_VerifyEmail(t *testing.T) {
+	db, outbox, accountService := setupAccountTest(t)
+
+	require.NoError(t, accountService.ResendVerification("user-1"))
+	msg, token := lastToken(t, outbox)
+	assert.Equal(t, "learner@example.com", msg.To)
+	assert.Equal(t, "Confirm your PaperPlay email address", msg.Subject)
+	assert.Contains(t, msg.Body, "https://paperplay.example.com/verify-email?token=")
+
+	user, err := accountService.VerifyEmail(token)
+	require.NoError(t, err)
+	assert.True(t, user.IsEmailVerified())
+
+	var stored model.User
+	require.NoError(t, db.First(&stored, "id = ?", "user-1").Error)
+	assert.NotNil(t, stored.EmailVerifiedAt)
+
+	assert.ErrorIs(t, accountService.ResendVerification("user-1"), ErrEmailAlreadyVerified)
+	assert.ErrorIs(t, accountService.ResendVerification("missing"), ErrUserNotFound)
+
+	// Tampered and wrong-purpose tokens are rejected
+	_, err = accountService.VerifyEmail(token + "x")
+	assert.ErrorIs(t, err, ErrInvalidAccountToken)
+	resetToken, err := accountService.issueToken(&stored, TokenPurposeResetPassword, time.Hour)
+	require.NoError(t, err)
+	_, err = accountService.VerifyEmail(resetToken)
+	assert.ErrorIs(t, err, ErrInvalidAccountToken)
+
+	expired, err := accountService.issueToken(&stored, TokenPurposeVerifyEmail, -time.Minute)
+	require.NoError(t, err)
+	_, err = accountService.VerifyEmail(expired)
+	assert.ErrorIs(t, err, ErrAccountTokenExpired)
+}
+
+func TestAccountService_ResetPassword(t *testing.T) {
+	db, outbox, accountService := setupAccountTest(t)
+	require.NoError(t, db.Create(&model.RefreshToken{UserID: "user-1", ExpiresAt: time.Now().Add(time.Hour)}).Error)
+
+	// Unknown addresses are silently ignored
+	require.NoError(t, accountService.RequestPasswordReset("nobody@example.com"))
+	messages, err := outbox.Messages()
+	require.NoError(t, err)
+	assert.Empty(t, messages)
+
+	// A failed send answers like an unknown address, so it does not reveal the account
+	accountService.mailer = failingMailer{}
+	require.NoError(t, accountService.RequestPasswordReset("learner@example.com"))
+	accountService.mailer = outbox
+
+	require.NoError(t, accountService.RequestPasswordReset("Learner@Example.com"))
+	msg, token := lastToken(t, outbox)
+	assert.Equal(t, "Reset your PaperPlay password", msg.Subject)
+
+	require.NoError(t, accountService.ResetPassword(token, "new-password"))
+
+	var user model.User
+	require.NoError(t, db.First(&user, "id = ?", "user-1").Error)
+	assert.True(t, user.CheckPassword("new-password"))
+	assert.False(t, user.CheckPassword("old-password"))
+	// Receiving the link proves ownership of the address
+	assert.True(t, user.IsEmailVerified())
+
+	var sessions int64
+	db.Model(&model.RefreshToken{}).Where("user_id = ?", "user-1").Count(&sessions)
+	assert.Zero(t, sessions)
+	// Access tokens already issued are revoked as well
+	assert.Equal(t, 1, user.TokenVersion)
+
+	// The token is bound to the old password hash, so it works only once
+	assert.ErrorIs(t, accountService.ResetPassword(token, "another-password"), ErrInvalidAccountToken)
+}
+
+func TestAccountService_ChangePassword(t *testing.T) {
+	db, _, accountService := setupAccountTest(t)
+	require.NoError(t, db.Create(&model.RefreshToken{UserID: "user-1", ExpiresAt: time.Now().Add(time.Hour)}).Error)
+
+	_, err := accountService.ChangePassword("user-1", "wrong-password", "new-password")
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+	_, err = accountService.ChangePassword("missing", "old-password", "new-password")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	user, err := accountService.ChangePassword("user-1", "old-password", "new-password")
+	require.NoError(t, err)
+	assert.True(t, user.CheckPassword("new-password"))
+	assert.Equal(t, 1, user.TokenVersion)
+
+	var sessions int64
+	db.Model(&model.RefreshToken{}).Where("user_id = ?", "user-1").Count(&sessions)
+	assert.Zero(t, sessions)
+
+	// Accounts created through WeChat have no password to change
+	wechatUser := &model.User{ID: "user-2", Email: "wechat-x@" + model.PlaceholderEmailDomain}
+	require.NoError(t, db.Create(wechatUser).Error)
+	_, err = accountService.ChangePassword("user-2", "", "new-password")
+	assert.ErrorIs(t, err, ErrNoEmailLogin)
+}
+
+// setupDeletionTest adds the tables touched by account deletion and some data for user-1
+func setupDeletionTest(t *testing.T, mode string) (*gorm.DB, *mailer.OutboxMailer, *AccountService) {
+	db, outbox, accountService := setupAccountTest(t)
+	require.NoError(t, db.AutoMigrate(
+		&model.UserProgress{}, &model.UserAttempts{}, &model.AnswerRecord{}, &model.Event{}, &model.OutboxEvent{},
+		&model.UserAchievement{}, &model.UserAchievementProgress{}, &model.AchievementAuditLog{}, &model.NFTAsset{},
+		&model.QuestionReport{}, &model.ExternalIdentity{}, &model.LoginThrottle{}, &model.APIKey{},
+	))
+	accountService.deletionGrace = 14 * 24 * time.Hour
+	accountService.deletionMode = mode
+
+	now := time.Now()
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", "user-1").Updates(map[string]any{
+		"eth_address":     "0xabc",
+		"eth_private_key": "deadbeef",
+	}).Error)
+	require.NoError(t, db.Create(&model.UserAttempts{StatDate: now.Format("2006-01-02"), UserID: "user-1", AttemptsTotal: 3}).Error)
+	require.NoError(t, db.Create(&model.AnswerRecord{ID: "answer-1", UserID: "user-1", LevelID: "level-1", QuestionID: "q-1", RevisionID: "rev-1", CreatedAt: now}).Error)
+	require.NoError(t, db.Create(&model.Event{ID: "event-1", UserID: "user-1", EventType: "question_answered", CreatedAt: now}).Error)
+	require.NoError(t, db.Create(&model.OutboxEvent{EventID: "event-1", UserID: "user-1", EventType: "question_answered", Status: model.OutboxPending, AvailableAt: now, CreatedAt: now}).Error)
+	actorID := "user-1"
+	require.NoError(t, db.Create(&model.AchievementAuditLog{UserID: "user-1", AchievementID: "first-pass", UserAchievementID: "ua-1",
+		Action: model.AuditActionAward, Source: model.AuditSourceRule, CreatedAt: now}).Error)
+	require.NoError(t, db.Create(&model.AchievementAuditLog{UserID: "user-2", AchievementID: "first-pass", UserAchievementID: "ua-2",
+		Action: model.AuditActionRevoke, Source: model.AuditSourceAdmin, ActorID: &actorID, CreatedAt: now}).Error)
+	require.NoError(t, db.Create(&model.ExternalIdentity{ID: "identity-1", UserID: "user-1", Provider: model.ProviderWeChat, Subject: "openid"}).Error)
+	require.NoError(t, db.Create(&model.RefreshToken{Token: "refresh-1", UserID: "user-1", FamilyID: "family-1", ExpiresAt: now.Add(time.Hour)}).Error)
+
+	return db, outbox, accountService
+}
+
+func TestAccountService_ExportUserData(t *testing.T) {
+	_, _, accountService := setupDeletionTest(t, DeletionModeAnonymize)
+
+	export, err := accountService.ExportUserData("user-1")
+	require.NoError(t, err)
+	assert.Equal(t, "learner@example.com", export.Profile.Email)
+	assert.Len(t, export.Attempts, 1)
+	assert.Len(t, export.Answers, 1)
+	assert.Len(t, export.Events, 1)
+	assert.Len(t, export.Identities, 1)
+	assert.Len(t, export.AuditLog, 1)
+	assert.Empty(t, export.NFTAssets)
+
+	var archive bytes.Buffer
+	require.NoError(t, WriteExportZip(&archive, export))
+	reader, err := zip.NewReader(bytes.NewReader(archive.Bytes()), int64(archive.Len()))
+	require.NoError(t, err)
+	names := make([]string, 0, len(reader.File))
+	for _, f := range reader.File {
+		names = append(names, f.Name)
+	}
+	assert.Contains(t, names, "profile.json")
+	assert.Contains(t, names, "daily_attempts.json")
+	assert.Contains(t, names, "nft_assets.json")
+	assert.Contains(t, names, "achievement_audit_log.json")
+
+	_, err = accountService.ExportUserData("missing")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
+
+func TestAccountService_RequestDeletion(t *testing.T) {
+	db, outbox, accountService := setupDeletionTest(t, DeletionModeAnonymize)
+
+	_, err := accountService.RequestDeletion("user-1", "wrong-password", model.WalletExport)
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+	_, err = accountService.RequestDeletion("user-1", "old-password", "")
+	assert.ErrorIs(t, err, ErrWalletActionRequired)
+
+	// Exporting the wallet hands out its key once
+	result, err := accountService.RequestDeletion("user-1", "old-password", model.WalletExport)
+	require.NoError(t, err)
+	assert.Equal(t, "0xabc", result.WalletAddress)
+	assert.Equal(t, "deadbeef", result.WalletPrivateKey)
+	assert.WithinDuration(t, time.Now().Add(14*24*time.Hour), result.ScheduledAt, time.Minute)
+
+	var user model.User
+	require.NoError(t, db.First(&user, "id = ?", "user-1").Error)
+	assert.True(t, user.IsDeletionPending())
+	assert.Equal(t, model.WalletExport, user.WalletDisposition)
+
+	var tokens int64
+	db.Model(&model.RefreshToken{}).Where("user_id = ?", "user-1").Count(&tokens)
+	assert.Zero(t, tokens)
+
+	messages, err := outbox.Messages()
+	require.NoError(t, err)
+	require.NotEmpty(t, messages)
+	assert.Equal(t, "Your PaperPlay account will be deleted", messages[len(messages)-1].Subject)
+
+	_, err = accountService.RequestDeletion("user-1", "old-password", model.WalletExport)
+	assert.ErrorIs(t, err, ErrDeletionPending)
+
+	// Nothing happens before the grace period is over
+	purged, err := accountService.PurgeDueAccounts()
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	require.NoError(t, accountService.CancelDeletion("user-1"))
+	assert.ErrorIs(t, accountService.CancelDeletion("user-1"), ErrNoDeletionPending)
+
+	var restored model.User
+	require.NoError(t, db.First(&restored, "id = ?", "user-1").Error)
+	assert.False(t, restored.IsDeletionPending())
+	assert.Empty(t, restored.WalletDisposition)
+}
+
+// expireDeletion moves a scheduled deletion into the past
+func expireDeletion(t *testing.T, db *gorm.DB, userID string) {
+	require.NoError(t, db.Model(&model.User{}).Where("id = ?", userID).
+		Update("deletion_scheduled_at", time.Now().Add(-time.Minute)).Error)
+}
+
+func TestAccountService_PurgeAnonymize(t *testing.T) {
+	db, _, accountService := setupDeletionTest(t, DeletionModeAnonymize)
+
+	_, err := accountService.RequestDeletion("user-1", "old-password", model.WalletForfeit)
+	require.NoError(t, err)
+	expireDeletion(t, db, "user-1")
+
+	purged, err := accountService.PurgeDueAccounts()
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	var user model.User
+	require.NoError(t, db.First(&user, "id = ?", "user-1").Error)
+	assert.Equal(t, "deleted-user-1@deleted.invalid", user.Email)
+	assert.Equal(t, "Deleted user", user.DisplayName)
+	assert.Empty(t, user.EthPrivateKey)
+	assert.Empty(t, user.EthAddress)
+	assert.False(t, user.HasEmailLogin())
+	assert.NotNil(t, user.AnonymizedAt)
+	assert.False(t, user.IsDeletionPending())
+
+	// Learning data is kept, sign-in data and undelivered events are gone
+	var answers, identities, outbox int64
+	db.Model(&model.AnswerRecord{}).Where("user_id = ?", "user-1").Count(&answers)
+	db.Model(&model.ExternalIdentity{}).Where("user_id = ?", "user-1").Count(&identities)
+	db.Model(&model.OutboxEvent{}).Where("user_id = ?", "user-1").Count(&outbox)
+	assert.Equal(t, int64(1), answers)
+	assert.Zero(t, identities)
+	assert.Zero(t, outbox)
+
+	// An anonymized account is not purged again
+	purged, err = accountService.PurgeDueAccounts()
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+}
+
+func TestAccountService_PurgeDelete(t *testing.T) {
+	db, _, accountService := setupDeletionTest(t, DeletionModeDelete)
+
+	_, err := accountService.RequestDeletion("user-1", "old-password", model.WalletForfeit)
+	require.NoError(t, err)
+	expireDeletion(t, db, "user-1")
+
+	purged, err := accountService.PurgeDueAccounts()
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	for _, m := range []any{
+		&model.User{}, &model.UserAttempts{}, &model.AnswerRecord{}, &model.Event{}, &model.OutboxEvent{},
+		&model.ExternalIdentity{},
+	} {
+		var count int64
+		db.Model(m).Count(&count)
+		assert.Zero(t, count, "%T", m)
+	}
+
+	// The user's own audit trail is gone; entries they caused for others no longer name them
+	var audit []model.AchievementAuditLog
+	require.NoError(t, db.Find(&audit).Error)
+	require.Len(t, audit, 1)
+	assert.Equal(t, "user-2", audit[0].UserID)
+	assert.Nil(t, audit[0].ActorID)
+}
@@ -1,14 +1,15 @@
 package service
 
 import (
+	"errors"
 	"fmt"
 	"paperplay/internal/model"
 	"paperplay/internal/websocket"
-	"strconv"
 	"time"
 
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // AchievementService handles achievement evaluation and management
@@ -31,30 +32,49 @@ func NewAchievementService(db *gorm.DB, logger *zap.Logger, wsHub *websocket.Hub
 
 // EvaluateUserAchievements checks and awards achievements for a user
 func (s *AchievementService) EvaluateUserAchievements(userID string) error {
-	// Get all active achievements
+	// Get all active achievements; lower tiers first so a series can be climbed in one pass
 	var achievements []model.Achievement
-	if err := s.db.Where("is_active = ?", true).Find(&achievements).Error; err != nil {
+	if err := s.db.Where("is_active = ?", true).Order("tier ASC").Find(&achievements).Error; err != nil {
 		return fmt.Errorf("failed to get achievements: %w", err)
 	}
 
-	// Get user's current achievements to avoid duplicates
+	previous, err := s.previousTiers()
+	if err != nil {
+		return err
+	}
+
+	// Get user's current achievements to avoid duplicates. Revoked achievements are
+	// not awarded again by rules, but do not unlock the tier above.
 	var userAchievements []model.UserAchievement
 	if err := s.db.Where("user_id = ?", userID).Find(&userAchievements).Error; err != nil {
 		return fmt.Errorf("failed to get user achievements: %w", err)
 	}
 
 	earnedAchievementIDs := make(map[string]bool)
+	revokedAchievementIDs := make(map[string]bool)
 	for _, ua := range userAchievements {
-		earnedAchievementIDs[ua.AchievementID] = true
+		if ua.IsRevoked() {
+			revokedAchievementIDs[ua.AchievementID] = true
+		} else {
+			earnedAchievementIDs[ua.AchievementID] = true
+		}
 	}
 
-	// Evaluate each achievement
+	// Evaluate each achievement; the evaluator shares metric values between rules
+	now := time.Now()
+	eval := newRuleEvaluator(s.db, userID, now)
 	for _, achievement := range achievements {
-		if earnedAchievementIDs[achievement.ID] {
-			continue // User already has this achievement
+		if earnedAchievementIDs[achievement.ID] || revokedAchievementIDs[achievement.ID] {
+			continue // User already has this achievement, or had it taken back
+		}
+		if !achievement.IsAvailableAt(now) {
+			continue // Its season has not started yet or has ended
+		}
+		if !tierUnlocked(achievement.ID, previous, earnedAchievementIDs) {
+			continue // The tier below has not been earned yet
 		}
 
-		earned, err := s.evaluateAchievement(userID, &achievement)
+		progress, err := s.evaluateAchievement(eval, &achievement)
 		if err != nil {
 			s.logger.Error("Failed to evaluate achievement",
 				zap.String("achievement_id", achievement.ID),
@@ -64,41 +84,185 @@ func (s *AchievementService) EvaluateUserAchievements(userID string) error {
 			continue
 		}
 
-		if earned {
-			if err := s.awardAchievement(userID, &achievement); err != nil {
+		if progress.Met {
+			if err := s.awardAchievement(userID, &achievement, ruleAward()); err != nil {
 				s.logger.Error("Failed to award achievement",
 					zap.String("achievement_id", achievement.ID),
 					zap.String("user_id", userID),
 					zap.Error(err),
 				)
+			} else {
+				earnedAchievementIDs[achievement.ID] = true
 			}
+		} else if err := s.notifyProgress(userID, &achievement, progress.Progress); err != nil {
+			s.logger.Warn("Failed to record achievement progress",
+				zap.String("achievement_id", achievement.ID),
+				zap.String("user_id", userID),
+				zap.Error(err),
+			)
 		}
 	}
 
 	return nil
 }
 
-// ForceAwardAchievement awards a specific achievement to a user without checking rules
+// AchievementProgress is a user's progress towards one active achievement
+type AchievementProgress struct {
+	Achievement model.Achievement
+	Earned      bool
+	EarnedAt    *time.Time
+	Locked      bool // A tier whose lower tier has not been earned; not evaluated
+	Revoked     bool // Taken back by an administrator; not evaluated
+	*RuleProgress
+}
+
+// GetAchievementProgress returns the user's progress towards every active
+// achievement. Earned achievements are complete and are not re-evaluated; ended
+// seasonal achievements the user did not earn are left out.
+func (s *AchievementService) GetAchievementProgress(userID string) ([]AchievementProgress, error) {
+	achievements, err := s.GetAllAchievements()
+	if err != nil {
+		return nil, err
+	}
+
+	var userAchievements []model.UserAchievement
+	if err := s.db.Where("user_id = ?", userID).Find(&userAchievements).Error; err != nil {
+		return nil, fmt.Errorf("failed to get user achievements: %w", err)
+	}
+	earnedAt := make(map[string]time.Time, len(userAchievements))
+	earned := make(map[string]bool, len(userAchievements))
+	revoked := make(map[string]bool)
+	for _, ua := range userAchievements {
+		if ua.IsRevoked() {
+			revoked[ua.AchievementID] = true
+			continue
+		}
+		earnedAt[ua.AchievementID] = ua.EarnedAt
+		earned[ua.AchievementID] = true
+	}
+
+	previous, err := s.previousTiers()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	eval := newRuleEvaluator(s.db, userID, now)
+	result := make([]AchievementProgress, 0, len(achievements))
+	for _, achievement := range achievements {
+		entry := AchievementProgress{Achievement: achievement}
+		if at, ok := earnedAt[achievement.ID]; ok {
+			entry.Earned = true
+			entry.EarnedAt = &at
+			entry.RuleProgress = &RuleProgress{Met: true, Progress: 1, Conditions: []ConditionProgress{}}
+			result = append(result, entry)
+			continue
+		}
+		if achievement.IsLimitedEdition(now) {
+			continue
+		}
+		if revoked[achievement.ID] {
+			entry.Revoked = true
+			entry.RuleProgress = &RuleProgress{Conditions: []ConditionProgress{}}
+			result = append(result, entry)
+			continue
+		}
+		if !tierUnlocked(achievement.ID, previous, earned) {
+			entry.Locked = true
+			entry.RuleProgress = &RuleProgress{Conditions: []ConditionProgress{}}
+			result = append(result, entry)
+			continue
+		}
+
+		progress, err := s.evaluateAchievement(eval, &achievement)
+		if err != nil {
+			// A broken rule hides one achievement's progress, not the whole page
+			s.logger.Error("Failed to evaluate achievement progress",
+				zap.String("achievement_id", achievement.ID),
+				zap.String("user_id", userID),
+				zap.Error(err),
+			)
+			continue
+		}
+		entry.RuleProgress = progress
+		result = append(result, entry)
+	}
+
+	return result, nil
+}
+
+// progressMilestones are the progress percentages users are notified about, highest first
+var progressMilestones = []int{90, 50}
+
+// notifyProgress tells the user once about each milestone crossed towards an achievement
+func (s *AchievementService) notifyProgress(userID string, achievement *model.Achievement, progress float64) error {
+	milestone := 0
+	for _, m := range progressMilestones {
+		if progress*100 >= float64(m) {
+			milestone = m
+			break
+		}
+	}
+	if milestone == 0 {
+		return nil
+	}
+
+	// Only the evaluation that raises the stored milestone sends the notice
+	mark := &model.UserAchievementProgress{UserID: userID, AchievementID: achievement.ID, Milestone: milestone}
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(mark)
+	if result.Error != nil {
+		return fmt.Errorf("failed to record milestone: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		result = s.db.Model(&model.UserAchievementProgress{}).
+			Where("user_id = ? AND achievement_id = ? AND milestone < ?", userID, achievement.ID, milestone).
+			Updates(map[string]any{"milestone": milestone, "updated_at": time.Now()})
+		if result.Error != nil {
+			return fmt.Errorf("failed to record milestone: %w", result.Error)
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+	}
+
+	if s.wsHub != nil {
+		s.wsHub.SendNotification(userID, &websocket.NotificationMessage{
+			ID:          achievement.ID,
+			Type:        "achievement_progress",
+			Title:       "成就进度",
+			Message:     fmt.Sprintf("距离成就「%s」已完成 %d%%", achievement.Name, milestone),
+			Achievement: notificationAchievement(achievement),
+		})
+	}
+	return nil
+}
+
+// ForceAwardAchievement awards a specific achievement to a user without checking rules.
+// A revoked achievement can be awarded again this way.
 func (s *AchievementService) ForceAwardAchievement(userID, achievementID string) error {
 	// Get the achievement
 	var achievement model.Achievement
 	if err := s.db.Where("id = ?", achievementID).First(&achievement).Error; err != nil {
 		if err == gorm.ErrRecordNotFound {
-			return fmt.Errorf("achievement not found")
+			return ErrAchievementNotFound
 		}
 		return fmt.Errorf("failed to get achievement: %w", err)
 	}
 
 	// Check if user already has this achievement
 	var userAchievement model.UserAchievement
-	if err := s.db.Where("user_id = ? AND achievement_id = ?", userID, achievementID).First(&userAchievement).Error; err == nil {
+	if err := s.db.Where("user_id = ? AND achievement_id = ? AND revoked_at IS NULL", userID, achievementID).
+		First(&userAchievement).Error; err == nil {
 		return fmt.Errorf("user already has this achievement")
 	} else if err != gorm.ErrRecordNotFound {
 		return fmt.Errorf("failed to check user achievement: %w", err)
 	}
 
 	// Award the achievement
-	if err := s.awardAchievement(userID, &achievement); err != nil {
+	opts := ruleAward()
+	opts.source = model.AuditSourceForce
+	opts.actorID = &userID
+	if err := s.awardAchievement(userID, &achievement, opts); err != nil {
 		return fmt.Errorf("failed to award achievement: %w", err)
 	}
 
@@ -112,248 +276,87 @@ func (s *AchievementService) ForceAwardAchievement(userID, achievementID string)
 }
 
 // evaluateAchievement checks if a user meets the criteria for an achievement
-func (s *AchievementService) evaluateAchievement(userID string, achievement *model.Achievement) (bool, error) {
+func (s *AchievementService) evaluateAchievement(eval *ruleEvaluator, achievement *model.Achievement) (*RuleProgress, error) {
 	rule, err := achievement.GetRule()
 	if err != nil {
-		return false, fmt.Errorf("failed to parse achievement rule: %w", err)
-	}
-
-	switch rule.Type {
-	case "first_try":
-		return s.evaluateFirstTryAchievement(userID, rule)
-	case "streak":
-		return s.evaluateStreakAchievement(userID, rule)
-	case "speed_accuracy":
-		return s.evaluateSpeedAccuracyAchievement(userID, rule)
-	case "endurance":
-		return s.evaluateEnduranceAchievement(userID, rule)
-	case "memory":
-		return s.evaluateMemoryAchievement(userID, rule)
-	default:
-		s.logger.Warn("Unknown achievement rule type", zap.String("type", rule.Type))
-		return false, nil
-	}
-}
-
-// evaluateFirstTryAchievement evaluates "首战告捷" achievement
-func (s *AchievementService) evaluateFirstTryAchievement(userID string, rule *model.AchievementRule) (bool, error) {
-	var todayStats model.UserAttempts
-	today := time.Now().Format("2006-01-02")
-
-	err := s.db.Where("user_id = ? AND stat_date = ?", userID, today).First(&todayStats).Error
-	if err == gorm.ErrRecordNotFound {
-		return false, nil
-	} else if err != nil {
-		return false, err
-	}
-
-	for _, condition := range rule.Conditions {
-		if !s.evaluateCondition(&todayStats, condition) {
-			return false, nil
-		}
+		return nil, fmt.Errorf("failed to parse achievement rule: %w", err)
 	}
 
-	return true, nil
+	return eval.inSeason(achievement).evaluate(rule)
 }
 
-// evaluateStreakAchievement evaluates "连击达人" achievement
-func (s *AchievementService) evaluateStreakAchievement(userID string, rule *model.AchievementRule) (bool, error) {
-	var latestStats model.UserAttempts
-	err := s.db.Where("user_id = ?", userID).
-		Order("stat_date DESC").
-		First(&latestStats).Error
-
-	if err == gorm.ErrRecordNotFound {
-		return false, nil
-	} else if err != nil {
-		return false, err
+// notificationAchievement summarizes an achievement for a WebSocket notification
+func notificationAchievement(achievement *model.Achievement) *websocket.NotificationAchievement {
+	return &websocket.NotificationAchievement{
+		ID:          achievement.ID,
+		Name:        achievement.Name,
+		Description: achievement.Description,
+		Level:       achievement.Level,
+		IconURL:     achievement.IconURL,
 	}
-
-	for _, condition := range rule.Conditions {
-		if !s.evaluateCondition(&latestStats, condition) {
-			return false, nil
-		}
-	}
-
-	return true, nil
 }
 
-// evaluateSpeedAccuracyAchievement evaluates "智速双全" achievement
-func (s *AchievementService) evaluateSpeedAccuracyAchievement(userID string, rule *model.AchievementRule) (bool, error) {
-	var todayStats model.UserAttempts
-	today := time.Now().Format("2006-01-02")
-
-	err := s.db.Where("user_id = ? AND stat_date = ?", userID, today).First(&todayStats).Error
-	if err == gorm.ErrRecordNotFound {
-		return false, nil
-	} else if err != nil {
-		return false, err
-	}
-
-	// Need at least some attempts to qualify
-	if todayStats.AttemptsTotal < 10 {
-		return false, nil
-	}
-
-	for _, condition := range rule.Conditions {
-		if !s.evaluateCondition(&todayStats, condition) {
-			return false, nil
-		}
-	}
-
-	return true, nil
+// awardOptions describes how an achievement is awarded
+type awardOptions struct {
+	earnedAt time.Time
+	notify   bool    // Send the user a real-time notification
+	source   string  // What caused the award, one of the model.AuditSource values
+	actorID  *string // User who caused the award, if not the system
 }
 
-// evaluateEnduranceAchievement evaluates "持久战士" achievement
-func (s *AchievementService) evaluateEnduranceAchievement(userID string, rule *model.AchievementRule) (bool, error) {
-	var todayStats model.UserAttempts
-	today := time.Now().Format("2006-01-02")
-
-	err := s.db.Where("user_id = ? AND stat_date = ?", userID, today).First(&todayStats).Error
-	if err == gorm.ErrRecordNotFound {
-		return false, nil
-	} else if err != nil {
-		return false, err
-	}
-
-	for _, condition := range rule.Conditions {
-		if !s.evaluateCondition(&todayStats, condition) {
-			return false, nil
-		}
-	}
-
-	return true, nil
+// ruleAward is an award earned now by meeting the achievement's rule
+func ruleAward() awardOptions {
+	return awardOptions{earnedAt: time.Now(), notify: true, source: model.AuditSourceRule}
 }
 
-// evaluateMemoryAchievement evaluates "记忆大师" achievement
-func (s *AchievementService) evaluateMemoryAchievement(userID string, rule *model.AchievementRule) (bool, error) {
-	var todayStats model.UserAttempts
-	today := time.Now().Format("2006-01-02")
-
-	err := s.db.Where("user_id = ? AND stat_date = ?", userID, today).First(&todayStats).Error
-	if err == gorm.ErrRecordNotFound {
-		return false, nil
-	} else if err != nil {
-		return false, err
-	}
-
-	for _, condition := range rule.Conditions {
-		if !s.evaluateCondition(&todayStats, condition) {
-			return false, nil
+// awardAchievement awards an achievement to a user and records it in the audit log.
+// The user is notified once the award is committed.
+func (s *AchievementService) awardAchievement(userID string, achievement *model.Achievement, opts awardOptions) error {
+	// Create user achievement record
+	userAchievement := &model.UserAchievement{
+		UserID:        userID,
+		AchievementID: achievement.ID,
+		EarnedAt:      opts.earnedAt,
+		Progress:      1.0,
+	}
+	// Silent awards are never announced, so they are not redelivered on connect either
+	if !opts.notify {
+		now := time.Now()
+		userAchievement.NotifiedAt = &now
+		userAchievement.ViewedAt = &now
+	}
+
+	var upgrade *tierUpgrade
+	var nftAsset *model.NFTAsset
+	var mintRequest *NFTMintRequest
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(userAchievement).Error; err != nil {
+			return fmt.Errorf("failed to create user achievement: %w", err)
 		}
-	}
-
-	return true, nil
-}
 
-// evaluateCondition evaluates a single condition against user stats
-func (s *AchievementService) evaluateCondition(stats *model.UserAttempts, condition model.ConditionRule) bool {
-	var value any
-
-	// Get the field value from stats
-	switch condition.Field {
-	case "attempts_total":
-		value = stats.AttemptsTotal
-	case "attempts_correct":
-		value = stats.AttemptsCorrect
-	case "attempts_first_try_correct":
-		value = stats.AttemptsFirstTryCorrect
-	case "correct_rate":
-		value = stats.CorrectRate
-	case "first_try_correct_rate":
-		value = stats.FirstTryCorrectRate
-	case "giveup_count":
-		value = stats.GiveupCount
-	case "skip_rate":
-		value = stats.SkipRate
-	case "avg_duration_ms":
-		value = stats.AvgDurationMs
-	case "total_time_ms":
-		value = stats.TotalTimeMs
-	case "sessions_count":
-		value = stats.SessionsCount
-	case "streak_days":
-		value = stats.StreakDays
-	case "review_due_count":
-		value = stats.ReviewDueCount
-	case "retention_score":
-		value = stats.RetentionScore
-	default:
-		s.logger.Warn("Unknown condition field", zap.String("field", condition.Field))
-		return false
-	}
-
-	return s.compareValues(value, condition.Operator, condition.Value)
-}
-
-// compareValues compares two values using the specified operator
-func (s *AchievementService) compareValues(actual any, operator string, expected any) bool {
-	switch operator {
-	case ">=":
-		return s.numericCompare(actual, expected) >= 0
-	case ">":
-		return s.numericCompare(actual, expected) > 0
-	case "<=":
-		return s.numericCompare(actual, expected) <= 0
-	case "<":
-		return s.numericCompare(actual, expected) < 0
-	case "=", "==":
-		return s.numericCompare(actual, expected) == 0
-	case "!=":
-		return s.numericCompare(actual, expected) != 0
-	default:
-		s.logger.Warn("Unknown operator", zap.String("operator", operator))
-		return false
-	}
-}
-
-// numericCompare compares two numeric values, returns -1, 0, or 1
-func (s *AchievementService) numericCompare(a, b any) int {
-	af := s.toFloat64(a)
-	bf := s.toFloat64(b)
-
-	if af < bf {
-		return -1
-	} else if af > bf {
-		return 1
-	}
-	return 0
-}
-
-// toFloat64 converts various numeric types to float64
-func (s *AchievementService) toFloat64(v any) float64 {
-	switch val := v.(type) {
-	case int:
-		return float64(val)
-	case int64:
-		return float64(val)
-	case float64:
-		return val
-	case float32:
-		return float64(val)
-	case string:
-		if f, err := strconv.ParseFloat(val, 64); err == nil {
-			return f
+		if err := tx.Create(&model.AchievementAuditLog{
+			UserID:            userID,
+			AchievementID:     achievement.ID,
+			UserAchievementID: userAchievement.ID,
+			Action:            model.AuditActionAward,
+			Source:            opts.source,
+			ActorID:           opts.actorID,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to create achievement audit log: %w", err)
 		}
-		return 0
-	default:
-		return 0
-	}
-}
 
-// awardAchievement awards an achievement to a user
-func (s *AchievementService) awardAchievement(userID string, achievement *model.Achievement) error {
-	return s.db.Transaction(func(tx *gorm.DB) error {
-		// Create user achievement record
-		userAchievement := &model.UserAchievement{
-			UserID:        userID,
-			AchievementID: achievement.ID,
-			EarnedAt:      time.Now(),
-			Progress:      1.0,
+		// A higher tier replaces the lower ones the user holds
+		if achievement.IsTiered() {
+			var err error
+			if upgrade, err = supersedeLowerTiers(tx, userID, achievement, userAchievement); err != nil {
+				return err
+			}
 		}
 
-		if err := tx.Create(userAchievement).Error; err != nil {
-			return fmt.Errorf("failed to create user achievement: %w", err)
+		// Milestones only matter until the achievement is earned
+		if err := tx.Where("user_id = ? AND achievement_id = ?", userID, achievement.ID).
+			Delete(&model.UserAchievementProgress{}).Error; err != nil {
+			return fmt.Errorf("failed to clear achievement progress: %w", err)
 		}
 
 		// Create event log
@@ -376,122 +379,157 @@ func (s *AchievementService) awardAchievement(userID string, achievement *model.
 			return fmt.Errorf("failed to create event: %w", err)
 		}
 
-		s.logger.Info("Achievement awarded",
-			zap.String("user_id", userID),
-			zap.String("achievement_id", achievement.ID),
-			zap.String("achievement_name", achievement.Name),
-		)
-
-		// Send real-time notification via WebSocket
-		if s.wsHub != nil {
-			notification := &websocket.NotificationMessage{
-				ID:      userAchievement.ID,
-				Type:    "achievement",
-				Title:   "成就解锁！",
-				Message: fmt.Sprintf("恭喜您获得成就：%s", achievement.Name),
-				Achievement: &struct {
-					ID          string `json:"id"`
-					Name        string `json:"name"`
-					Description string `json:"description"`
-					Level       int    `json:"level"`
-					IconURL     string `json:"icon_url"`
-				}{
-					ID:          achievement.ID,
-					Name:        achievement.Name,
-					Description: achievement.Description,
-					Level:       achievement.Level,
-					IconURL:     achievement.IconURL,
-				},
-			}
-			s.wsHub.SendNotification(userID, notification)
-		}
-
 		// Create NFT if enabled
 		if achievement.NFTEnabled && s.ethereumService != nil && s.ethereumService.IsEnabled() {
-			if err := s.createAchievementNFT(tx, userID, achievement); err != nil {
+			var err error
+			nftAsset, mintRequest, err = s.createAchievementNFT(tx, userID, achievement)
+			if errors.Is(err, ErrEmailNotVerified) {
+				s.logger.Info("Skipping NFT for unverified user",
+					zap.String("user_id", userID),
+					zap.String("achievement_id", achievement.ID),
+				)
+			} else if err != nil {
 				s.logger.Error("Failed to create NFT for achievement",
 					zap.String("user_id", userID),
 					zap.String("achievement_id", achievement.ID),
 					zap.Error(err),
 				)
 				// Don't fail the transaction for NFT creation errors
+			} else if upgrade != nil {
+				if err := linkUpgradedNFTs(tx, userID, upgrade, nftAsset); err != nil {
+					return err
+				}
 			}
 		}
 
 		return nil
 	})
+	if err != nil {
+		return err
+	}
+
+	// Mint only once the award is committed, so a rollback leaves no token behind
+	if mintRequest != nil {
+		go s.mintAchievementNFT(nftAsset.ID, *mintRequest)
+	}
+
+	s.logger.Info("Achievement awarded",
+		zap.String("user_id", userID),
+		zap.String("achievement_id", achievement.ID),
+		zap.String("achievement_name", achievement.Name),
+	)
+
+	// Send real-time notification via WebSocket. Undelivered notifications, and
+	// those whose delivery could not be recorded, are sent again when the user
+	// connects, until they are acknowledged.
+	if opts.notify && s.wsHub != nil {
+		notification := awardNotification(userAchievement.ID, achievement)
+		if upgrade != nil {
+			notification.Title = "成就升级！"
+			notification.Message = fmt.Sprintf("您的成就「%s」已升级为：%s", upgrade.from.Name, achievement.Name)
+		}
+		if s.wsHub.SendNotification(userID, notification) {
+			if err := s.MarkNotificationsDelivered(userID, []string{userAchievement.ID}); err != nil {
+				s.logger.Warn("Failed to record achievement notification delivery",
+					zap.String("user_achievement_id", userAchievement.ID),
+					zap.Error(err),
+				)
+			}
+		}
+	}
+
+	return nil
 }
 
-// createAchievementNFT creates an NFT for an achievement
-func (s *AchievementService) createAchievementNFT(tx *gorm.DB, userID string, achievement *model.Achievement) error {
+// createAchievementNFT records a pending NFT for an achievement and returns the request
+// to mint it with once the transaction commits
+func (s *AchievementService) createAchievementNFT(tx *gorm.DB, userID string, achievement *model.Achievement) (*model.NFTAsset, *NFTMintRequest, error) {
 	// Get user's ethereum address
 	var user model.User
 	if err := tx.First(&user, "id = ?", userID).Error; err != nil {
-		return fmt.Errorf("failed to get user: %w", err)
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Unverified accounts could be created in bulk to farm NFTs
+	if !user.IsEmailVerified() {
+		return nil, nil, ErrEmailNotVerified
 	}
 
 	if user.EthAddress == "" {
-		return fmt.Errorf("user has no ethereum address")
+		return nil, nil, fmt.Errorf("user has no ethereum address")
 	}
 
 	// Generate metadata URI
 	metadataURI, err := s.ethereumService.GenerateMetadataURI(achievement, userID)
 	if err != nil {
-		return fmt.Errorf("failed to generate metadata URI: %w", err)
+		return nil, nil, fmt.Errorf("failed to generate metadata URI: %w", err)
+	}
+
+	// Rarity is fixed at mint time
+	attributes, err := nftAttributes(tx, achievement)
+	if err != nil {
+		return nil, nil, err
 	}
 
 	// Create NFT asset record
 	nftAsset, err := s.ethereumService.CreateNFTAsset(userID, &achievement.ID, metadataURI)
 	if err != nil {
-		return fmt.Errorf("failed to create NFT asset: %w", err)
+		return nil, nil, fmt.Errorf("failed to create NFT asset: %w", err)
 	}
 
 	// Save NFT asset to database
 	if err := tx.Create(nftAsset).Error; err != nil {
-		return fmt.Errorf("failed to save NFT asset: %w", err)
+		return nil, nil, fmt.Errorf("failed to save NFT asset: %w", err)
 	}
 
-	// Queue NFT minting (in a real implementation, this would be done asynchronously)
-	go func() {
-		mintRequest := NFTMintRequest{
-			ToAddress: user.EthAddress,
-			TokenURI:  metadataURI,
-			Metadata: map[string]any{
-				"achievement_id": achievement.ID,
-				"user_id":        userID,
-			},
-		}
-
-		result, err := s.ethereumService.MintNFT(mintRequest)
-		if err != nil {
-			s.logger.Error("Failed to mint NFT",
-				zap.String("nft_asset_id", nftAsset.ID),
-				zap.Error(err),
-			)
-
-			// Mark NFT as failed
-			s.db.Model(nftAsset).Update("status", model.NFTStatusFailed)
-			return
-		}
-
-		// Update NFT asset with minting results
-		s.ethereumService.UpdateNFTAsset(nftAsset, result)
-		s.db.Save(nftAsset)
+	mintRequest := &NFTMintRequest{
+		ToAddress: user.EthAddress,
+		TokenURI:  metadataURI,
+		Metadata: map[string]any{
+			"achievement_id": achievement.ID,
+			"user_id":        userID,
+			"attributes":     attributes,
+		},
+	}
+	return nftAsset, mintRequest, nil
+}
 
-		s.logger.Info("NFT minted successfully",
-			zap.String("nft_asset_id", nftAsset.ID),
-			zap.String("token_id", result.TokenID),
-			zap.String("tx_hash", result.TransactionHash),
+// mintAchievementNFT mints a recorded NFT and stores the result on it
+func (s *AchievementService) mintAchievementNFT(nftAssetID string, mintRequest NFTMintRequest) {
+	result, err := s.ethereumService.MintNFT(mintRequest)
+	if err != nil {
+		s.logger.Error("Failed to mint NFT",
+			zap.String("nft_asset_id", nftAssetID),
+			zap.Error(err),
 		)
-	}()
 
-	return nil
+		// Mark NFT as failed, unless it was revoked in the meantime
+		s.db.Model(&model.NFTAsset{}).
+			Where("id = ? AND status = ?", nftAssetID, model.NFTStatusPending).
+			Update("status", model.NFTStatusFailed)
+		return
+	}
+
+	// Update NFT asset with minting results. A revocation while minting keeps
+	// its status; the token is recorded so it can still be burned by hand.
+	s.db.Model(&model.NFTAsset{}).
+		Where("id = ?", nftAssetID).
+		Updates(map[string]any{"token_id": result.TokenID, "mint_tx_hash": result.TransactionHash})
+	s.db.Model(&model.NFTAsset{}).
+		Where("id = ? AND status = ?", nftAssetID, model.NFTStatusPending).
+		Update("status", model.NFTStatusMinted)
+
+	s.logger.Info("NFT minted successfully",
+		zap.String("nft_asset_id", nftAssetID),
+		zap.String("token_id", result.TokenID),
+		zap.String("tx_hash", result.TransactionHash),
+	)
 }
 
 // EvaluateOnEvent evaluates achievements when specific events occur
 func (s *AchievementService) EvaluateOnEvent(userID string, eventType string, eventData map[string]any) error {
 	// Update user stats based on event
-	if err := s.updateUserStatsFromEvent(userID, eventType, eventData); err != nil {
+	if err := s.updateUserStatsFromEvent(s.db, userID, eventType, eventData); err != nil {
 		s.logger.Error("Failed to update user stats from event",
 			zap.String("user_id", userID),
 			zap.String("event_type", eventType),
@@ -503,40 +541,70 @@ func (s *AchievementService) EvaluateOnEvent(userID string, eventType string, ev
 	return s.EvaluateUserAchievements(userID)
 }
 
+// HandleEvent is the event bus handler. Like EvaluateOnEvent it updates the user's
+// statistics and evaluates their achievements, but an event is counted in the
+// statistics only once however often it is delivered.
+func (s *AchievementService) HandleEvent(event *model.OutboxEvent) error {
+	data, err := event.GetData()
+	if err != nil {
+		return fmt.Errorf("failed to parse event data: %w", err)
+	}
+
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		applied := tx.Model(&model.OutboxEvent{}).
+			Where("id = ? AND applied_at IS NULL", event.ID).
+			Update("applied_at", time.Now())
+		if applied.Error != nil {
+			return fmt.Errorf("failed to mark event applied: %w", applied.Error)
+		}
+		if applied.RowsAffected == 0 {
+			return nil // Counted by an earlier delivery
+		}
+		return s.updateUserStatsFromEvent(tx, event.UserID, event.EventType, data)
+	}); err != nil {
+		return err
+	}
+
+	return s.EvaluateUserAchievements(event.UserID)
+}
+
 // updateUserStatsFromEvent updates user statistics based on events
-func (s *AchievementService) updateUserStatsFromEvent(userID string, eventType string, eventData map[string]any) error {
-	stats, err := model.GetTodayStats(s.db, userID)
+func (s *AchievementService) updateUserStatsFromEvent(db *gorm.DB, userID string, eventType string, eventData map[string]any) error {
+	stats, err := model.GetTodayStats(db, userID)
 	if err != nil {
 		return fmt.Errorf("failed to get today's stats: %w", err)
 	}
 
 	switch eventType {
 	case model.EventQuestionAnswered:
+		if skipped, _ := eventData["skipped"].(bool); skipped {
+			return stats.AddGiveup(db)
+		}
 		if correct, ok := eventData["correct"].(bool); ok {
 			if firstTry, ok := eventData["first_try"].(bool); ok {
 				if duration, ok := eventData["duration_ms"].(float64); ok {
-					return stats.AddAttempt(s.db, correct, firstTry, int(duration))
+					return stats.AddAttempt(db, correct, firstTry, int(duration))
 				}
 			}
 		}
 
 	case model.EventLevelCompleted:
 		// Level completion might trigger streak updates
-		stats.StreakDays = s.calculateCurrentStreak(userID)
-		return s.db.Save(stats).Error
+		stats.StreakDays = s.calculateCurrentStreak(db, userID)
+		return db.Save(stats).Error
 
-	case "session_started":
+	case model.EventSessionStarted:
 		stats.SessionsCount++
-		return s.db.Save(stats).Error
+		return db.Save(stats).Error
 	}
 
 	return nil
 }
 
 // calculateCurrentStreak calculates the current learning streak for a user
-func (s *AchievementService) calculateCurrentStreak(userID string) int {
+func (s *AchievementService) calculateCurrentStreak(db *gorm.DB, userID string) int {
 	var attempts []model.UserAttempts
-	err := s.db.Where("user_id = ? AND attempts_total > 0", userID).
+	err := db.Where("user_id = ? AND attempts_total > 0", userID).
 		Order("stat_date DESC").
 		Limit(30). // Look at last 30 days
 		Find(&attempts).Error
@@ -567,10 +635,11 @@ func (s *AchievementService) calculateCurrentStreak(userID string) int {
 	return streak
 }
 
-// GetUserAchievements returns all achievements for a user
+// GetUserAchievements returns a user's achievements; revoked achievements and tiers
+// superseded by a higher tier of the same series are left out
 func (s *AchievementService) GetUserAchievements(userID string) ([]model.UserAchievement, error) {
 	var achievements []model.UserAchievement
-	err := s.db.Where("user_id = ?", userID).
+	err := s.db.Where("user_id = ? AND superseded_at IS NULL AND revoked_at IS NULL", userID).
 		Preload("Achievement").
 		Order("earned_at DESC").
 		Find(&achievements).Error
@@ -581,15 +650,26 @@ func (s *AchievementService) GetUserAchievements(userID string) ([]model.UserAch
 	return achievements, nil
 }
 
-// GetAllAchievements returns all available achievements
+// GetAllAchievements returns all available achievements. Seasonal achievements are
+// hidden until their season starts, and stay listed as limited editions after it ends.
 func (s *AchievementService) GetAllAchievements() ([]model.Achievement, error) {
 	var achievements []model.Achievement
 	err := s.db.Where("is_active = ?", true).
+		Preload("Series").
+		Preload("Stats.FirstEarner").
 		Order("level ASC, created_at ASC").
 		Find(&achievements).Error
 	if err != nil {
 		return nil, fmt.Errorf("failed to get achievements: %w", err)
 	}
 
-	return achievements, nil
+	now := time.Now()
+	visible := achievements[:0]
+	for _, achievement := range achievements {
+		if achievement.SeasonStateAt(now) != model.SeasonUpcoming {
+			visible = append(visible, achievement)
+		}
+	}
+
+	return visible, nil
 }
@@ -0,0 +1,336 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"paperplay/internal/model"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Achievement administration errors
+var (
+	ErrAchievementNotFound = errors.New("achievement not found")
+	ErrAchievementEarned   = errors.New("achievement has been earned; deactivate it instead")
+	ErrSeriesNotFound      = errors.New("achievement series not found")
+	ErrInvalidTier         = errors.New("a tier needs a series and a series needs a tier")
+	ErrDuplicateTier       = errors.New("the series already has this tier")
+	ErrInvalidSeason       = errors.New("a season must end after it starts")
+)
+
+// dryRunBatchSize is the number of users loaded at a time by a dry run
+const dryRunBatchSize = 500
+
+// AchievementInput describes an achievement created or replaced by an administrator
+type AchievementInput struct {
+	ID          string // Optional on create, generated if empty
+	Name        string
+	Description string
+	IconURL     string
+	Level       int
+	BadgeType   string
+	Rule        *model.AchievementRule
+	NFTEnabled  bool
+	NFTMetadata *model.NFTMetadataTemplate
+	IsActive    bool
+	SeriesID    *string
+	Tier        int
+	StartsAt    *time.Time // Seasonal achievements only
+	EndsAt      *time.Time
+	Backfill    BackfillOptions // Used if the change activates the achievement or changes its rule
+}
+
+// ListAchievements returns every achievement, including inactive ones
+func (s *AchievementService) ListAchievements() ([]model.Achievement, error) {
+	var achievements []model.Achievement
+	if err := s.db.Order("created_at ASC").Find(&achievements).Error; err != nil {
+		return nil, fmt.Errorf("failed to list achievements: %w", err)
+	}
+	return achievements, nil
+}
+
+// GetAchievement returns an achievement, active or not
+func (s *AchievementService) GetAchievement(id string) (*model.Achievement, error) {
+	var achievement model.Achievement
+	if err := s.db.First(&achievement, "id = ?", id).Error; err == gorm.ErrRecordNotFound {
+		return nil, ErrAchievementNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get achievement: %w", err)
+	}
+	return &achievement, nil
+}
+
+// CreateAchievement validates and stores a new achievement
+func (s *AchievementService) CreateAchievement(input *AchievementInput) (*model.Achievement, error) {
+	achievement := &model.Achievement{ID: input.ID}
+	if err := s.applyAchievementInput(achievement, input); err != nil {
+		return nil, err
+	}
+	active := achievement.IsActive
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(achievement).Error; err != nil {
+			return fmt.Errorf("failed to create achievement: %w", err)
+		}
+		// Create applies the is_active default to drafts
+		if !active {
+			if err := tx.Model(achievement).Update("is_active", false).Error; err != nil {
+				return fmt.Errorf("failed to create achievement: %w", err)
+			}
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Achievement created",
+		zap.String("achievement_id", achievement.ID),
+		zap.Bool("is_active", achievement.IsActive),
+	)
+	if achievement.IsActive {
+		s.backfillChanged(achievement.ID, input.Backfill)
+	}
+	return achievement, nil
+}
+
+// UpdateAchievement validates and replaces an achievement's definition. Users keep
+// achievements they already earned.
+func (s *AchievementService) UpdateAchievement(id string, input *AchievementInput) (*model.Achievement, error) {
+	achievement, err := s.GetAchievement(id)
+	if err != nil {
+		return nil, err
+	}
+	wasActive, oldRule := achievement.IsActive, achievement.RuleJSON
+	if err := s.applyAchievementInput(achievement, input); err != nil {
+		return nil, err
+	}
+	if err := s.db.Save(achievement).Error; err != nil {
+		return nil, fmt.Errorf("failed to update achievement: %w", err)
+	}
+
+	s.logger.Info("Achievement updated",
+		zap.String("achievement_id", achievement.ID),
+		zap.Bool("is_active", achievement.IsActive),
+	)
+	if achievement.IsActive && (!wasActive || achievement.RuleJSON != oldRule) {
+		s.backfillChanged(achievement.ID, input.Backfill)
+	}
+	return achievement, nil
+}
+
+// DeleteAchievement deletes an achievement nobody has earned
+func (s *AchievementService) DeleteAchievement(id string) error {
+	if _, err := s.GetAchievement(id); err != nil {
+		return err
+	}
+
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var earned int64
+		if err := tx.Model(&model.UserAchievement{}).Where("achievement_id = ?", id).Count(&earned).Error; err != nil {
+			return fmt.Errorf("failed to count earned achievements: %w", err)
+		}
+		if earned > 0 {
+			return ErrAchievementEarned
+		}
+
+		if err := tx.Where("achievement_id = ?", id).Delete(&model.UserAchievementProgress{}).Error; err != nil {
+			return fmt.Errorf("failed to delete achievement progress: %w", err)
+		}
+		if err := tx.Where("achievement_id = ?", id).Delete(&model.AchievementBackfill{}).Error; err != nil {
+			return fmt.Errorf("failed to delete achievement backfills: %w", err)
+		}
+		if err := tx.Delete(&model.Achievement{}, "id = ?", id).Error; err != nil {
+			return fmt.Errorf("failed to delete achievement: %w", err)
+		}
+		return nil
+	})
+}
+
+// backfillChanged starts a backfill of an achievement that was activated or whose
+// rule changed. The change is already saved, so a backfill that cannot start is
+// only logged; it can be started again by hand.
+func (s *AchievementService) backfillChanged(achievementID string, opts BackfillOptions) {
+	if _, err := s.StartBackfill(achievementID, opts); errors.Is(err, ErrBackfillRunning) {
+		s.logger.Warn("Achievement changed during its backfill", zap.String("achievement_id", achievementID))
+	} else if err != nil {
+		s.logger.Error("Failed to start achievement backfill", zap.String("achievement_id", achievementID), zap.Error(err))
+	}
+}
+
+// applyAchievementInput validates an input and copies it onto an achievement
+func (s *AchievementService) applyAchievementInput(achievement *model.Achievement, input *AchievementInput) error {
+	if input.Rule == nil {
+		return fmt.Errorf("%w: rule is required", model.ErrInvalidRule)
+	}
+	if err := input.Rule.Validate(); err != nil {
+		return err
+	}
+
+	if input.StartsAt != nil && input.EndsAt != nil && !input.EndsAt.After(*input.StartsAt) {
+		return ErrInvalidSeason
+	}
+
+	if (input.SeriesID == nil) != (input.Tier == 0) {
+		return ErrInvalidTier
+	}
+	if input.SeriesID != nil {
+		if err := s.db.First(&model.AchievementSeries{}, "id = ?", *input.SeriesID).Error; err == gorm.ErrRecordNotFound {
+			return ErrSeriesNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to get achievement series: %w", err)
+		}
+
+		var taken int64
+		if err := s.db.Model(&model.Achievement{}).
+			Where("series_id = ? AND tier = ? AND id <> ?", *input.SeriesID, input.Tier, achievement.ID).
+			Count(&taken).Error; err != nil {
+			return fmt.Errorf("failed to check achievement tier: %w", err)
+		}
+		if taken > 0 {
+			return ErrDuplicateTier
+		}
+	}
+
+	if err := achievement.SetRule(input.Rule); err != nil {
+		return fmt.Errorf("failed to encode rule: %w", err)
+	}
+	achievement.NFTMetadata = ""
+	if input.NFTMetadata != nil {
+		if err := achievement.SetNFTMetadata(input.NFTMetadata); err != nil {
+			return fmt.Errorf("failed to encode NFT metadata: %w", err)
+		}
+	}
+
+	achievement.Name = input.Name
+	achievement.Description = input.Description
+	achievement.IconURL = input.IconURL
+	achievement.Level = input.Level
+	achievement.BadgeType = input.BadgeType
+	achievement.NFTEnabled = input.NFTEnabled
+	achievement.IsActive = input.IsActive
+	achievement.SeriesID = input.SeriesID
+	achievement.Tier = input.Tier
+	achievement.StartsAt = input.StartsAt
+	achievement.EndsAt = input.EndsAt
+	if !achievement.IsSeasonal() {
+		achievement.SeasonState = ""
+	}
+	return nil
+}
+
+// DryRunUser is a user who would qualify for an achievement
+type DryRunUser struct {
+	UserID      string `json:"user_id"`
+	DisplayName string `json:"display_name"`
+}
+
+// DryRunResult is who would qualify for a rule if it were evaluated now
+type DryRunResult struct {
+	EvaluatedUsers  int          `json:"evaluated_users"`
+	QualifyingCount int          `json:"qualifying_count"` // Users who would be awarded the achievement
+	AlreadyEarned   int          `json:"already_earned"`   // Users who hold the achievement already
+	LockedUsers     int          `json:"locked_users"`     // Users who meet the rule but lack the tier below
+	Users           []DryRunUser `json:"users"`            // The first qualifying users, up to the limit
+	Truncated       bool         `json:"truncated"`        // More users qualify than are listed
+}
+
+// DryRunRule evaluates a rule against every user's current data without awarding
+// anything or sending notifications. With an achievement, its earners are counted
+// separately and its tier lock and season window apply; without a rule, the
+// achievement's own rule is used.
+func (s *AchievementService) DryRunRule(rule *model.AchievementRule, achievementID string, limit int) (*DryRunResult, error) {
+	var achievement *model.Achievement
+	if achievementID != "" {
+		var err error
+		if achievement, err = s.GetAchievement(achievementID); err != nil {
+			return nil, err
+		}
+		if rule == nil {
+			if rule, err = achievement.GetRule(); err != nil {
+				return nil, fmt.Errorf("%w: %v", model.ErrInvalidRule, err)
+			}
+		}
+	}
+	if rule == nil {
+		return nil, fmt.Errorf("%w: rule is required", model.ErrInvalidRule)
+	}
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Holders of the achievement, and of the tier it requires
+	earned := make(map[string]bool)
+	var required map[string]bool
+	if achievement != nil {
+		var holders []string
+		if err := s.db.Model(&model.UserAchievement{}).Where("achievement_id = ?", achievement.ID).
+			Pluck("user_id", &holders).Error; err != nil {
+			return nil, fmt.Errorf("failed to get achievement holders: %w", err)
+		}
+		for _, id := range holders {
+			earned[id] = true
+		}
+
+		previous, err := s.previousTiers()
+		if err != nil {
+			return nil, err
+		}
+		if below, ok := previous[achievement.ID]; ok {
+			var lowerHolders []string
+			if err := s.db.Model(&model.UserAchievement{}).Where("achievement_id = ? AND revoked_at IS NULL", below).
+				Pluck("user_id", &lowerHolders).Error; err != nil {
+				return nil, fmt.Errorf("failed to get lower tier holders: %w", err)
+			}
+			required = make(map[string]bool, len(lowerHolders))
+			for _, id := range lowerHolders {
+				required[id] = true
+			}
+		}
+	}
+
+	result := &DryRunResult{Users: []DryRunUser{}}
+	now := time.Now()
+	var users []model.User
+	err := s.db.Select("id", "display_name").
+		Where("anonymized_at IS NULL").
+		Order("id").
+		FindInBatches(&users, dryRunBatchSize, func(tx *gorm.DB, batch int) error {
+			for _, user := range users {
+				result.EvaluatedUsers++
+				if earned[user.ID] {
+					result.AlreadyEarned++
+					continue
+				}
+
+				eval := newRuleEvaluator(s.db, user.ID, now)
+				if achievement != nil {
+					eval = eval.inSeason(achievement)
+				}
+				progress, err := eval.evaluate(rule)
+				if err != nil {
+					return err
+				}
+				if !progress.Met {
+					continue
+				}
+				if required != nil && !required[user.ID] {
+					result.LockedUsers++
+					continue
+				}
+
+				result.QualifyingCount++
+				if len(result.Users) < limit {
+					result.Users = append(result.Users, DryRunUser{UserID: user.ID, DisplayName: user.DisplayName})
+				} else {
+					result.Truncated = true
+				}
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate users: %w", err)
+	}
+
+	return result, nil
+}
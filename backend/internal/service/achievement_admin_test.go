@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"paperplay/internal/model"
+)
+
+const completedLevelsRule = `{"match":{"source":"progress","agg":"count","filter":{"passed":true},"op":">=","value":2}}`
+
+func TestAchievementAdmin_ValidatesInput(t *testing.T) {
+	db := setupEventBusTest(t)
+	require.NoError(t, db.AutoMigrate(&model.AchievementSeries{}))
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	input := &AchievementInput{
+		ID: "two-levels", Name: "Two levels", Description: "Pass two levels", Level: 1, BadgeType: "learning",
+		Rule: parseRule(t, completedLevelsRule),
+	}
+	achievement, err := s.CreateAchievement(input)
+	require.NoError(t, err)
+	assert.False(t, achievement.IsActive)
+	stored, err := s.GetAchievement("two-levels")
+	require.NoError(t, err)
+	assert.False(t, stored.IsActive, "new achievements are drafts")
+
+	// Unknown fields, missing series and tiers without a series are rejected
+	bad := *input
+	bad.Rule = parseRule(t, `{"match":{"source":"progress","field":"nope","agg":"sum","op":">=","value":1}}`)
+	_, err = s.UpdateAchievement("two-levels", &bad)
+	assert.ErrorIs(t, err, model.ErrInvalidRule)
+
+	bad = *input
+	bad.Tier = 1
+	_, err = s.UpdateAchievement("two-levels", &bad)
+	assert.ErrorIs(t, err, ErrInvalidTier)
+
+	seriesID := "levels"
+	bad.SeriesID = &seriesID
+	_, err = s.UpdateAchievement("two-levels", &bad)
+	assert.ErrorIs(t, err, ErrSeriesNotFound)
+
+	require.NoError(t, db.Create(&model.AchievementSeries{ID: seriesID, Name: "Levels"}).Error)
+	_, err = s.UpdateAchievement("two-levels", &bad)
+	require.NoError(t, err)
+
+	other := bad
+	other.ID = "other"
+	_, err = s.CreateAchievement(&other)
+	assert.ErrorIs(t, err, ErrDuplicateTier)
+
+	_, err = s.UpdateAchievement("missing", input)
+	assert.ErrorIs(t, err, ErrAchievementNotFound)
+}
+
+func TestAchievementAdmin_DeleteRefusesEarned(t *testing.T) {
+	db := setupEventBusTest(t)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	for _, id := range []string{"earned", "unearned"} {
+		require.NoError(t, db.Create(&model.Achievement{ID: id, Name: id, Description: id, BadgeType: "learning", RuleJSON: completedLevelsRule}).Error)
+	}
+	require.NoError(t, db.Create(&model.UserAchievement{UserID: "u-1", AchievementID: "earned", EarnedAt: time.Now()}).Error)
+	require.NoError(t, db.Create(&model.UserAchievementProgress{UserID: "u-1", AchievementID: "unearned"}).Error)
+
+	assert.ErrorIs(t, s.DeleteAchievement("earned"), ErrAchievementEarned)
+	assert.ErrorIs(t, s.DeleteAchievement("missing"), ErrAchievementNotFound)
+	require.NoError(t, s.DeleteAchievement("unearned"))
+
+	var progress int64
+	db.Model(&model.UserAchievementProgress{}).Where("achievement_id = ?", "unearned").Count(&progress)
+	assert.Zero(t, progress)
+}
+
+func TestAchievementAdmin_DryRunRule(t *testing.T) {
+	db := setupEventBusTest(t)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	// u-0 passed no levels, u-1 one, u-2 and u-3 two, u-4 three
+	for i := 0; i < 5; i++ {
+		userID := fmt.Sprintf("u-%d", i)
+		require.NoError(t, db.Create(&model.User{ID: userID, Email: userID + "@example.com", DisplayName: userID}).Error)
+		for level := 0; level < i && level < 3; level++ {
+			require.NoError(t, db.Create(&model.UserProgress{
+				UserID: userID, LevelID: fmt.Sprintf("l-%d", level), Status: model.ProgressCompleted, Score: 80,
+			}).Error)
+		}
+	}
+	require.NoError(t, db.Create(&model.Achievement{
+		ID: "two-levels", Name: "Two levels", Description: "Pass two levels", BadgeType: "learning", RuleJSON: completedLevelsRule,
+	}).Error)
+	require.NoError(t, db.Create(&model.UserAchievement{UserID: "u-4", AchievementID: "two-levels", EarnedAt: time.Now()}).Error)
+
+	// A draft rule on its own
+	result, err := s.DryRunRule(parseRule(t, completedLevelsRule), "", 2)
+	require.NoError(t, err)
+	assert.Equal(t, 5, result.EvaluatedUsers)
+	assert.Equal(t, 3, result.QualifyingCount)
+	assert.Len(t, result.Users, 2)
+	assert.True(t, result.Truncated)
+
+	// The achievement's own rule; its holder is not counted again
+	result, err = s.DryRunRule(nil, "two-levels", 10)
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.QualifyingCount)
+	assert.Equal(t, 1, result.AlreadyEarned)
+	assert.Equal(t, []DryRunUser{{UserID: "u-2", DisplayName: "u-2"}, {UserID: "u-3", DisplayName: "u-3"}}, result.Users)
+	assert.False(t, result.Truncated)
+
+	// Nothing is awarded
+	var earned int64
+	db.Model(&model.UserAchievement{}).Count(&earned)
+	assert.Equal(t, int64(1), earned)
+
+	_, err = s.DryRunRule(parseRule(t, `{"match":{"source":"nope","agg":"count","op":">=","value":1}}`), "", 10)
+	assert.ErrorIs(t, err, model.ErrInvalidRule)
+}
@@ -0,0 +1,412 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"paperplay/internal/model"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Achievement backfill errors
+var (
+	ErrAchievementInactive = errors.New("achievement is not active")
+	ErrBackfillRunning     = errors.New("a backfill of this achievement has not finished yet")
+	ErrBackfillNotFound    = errors.New("achievement backfill not found")
+)
+
+const (
+	// backfillBatchSize is the number of users evaluated between checkpoints
+	backfillBatchSize = 200
+	// backfillLease is how long a runner holds a backfill without checkpointing
+	// before the cron job may take it over
+	backfillLease = 10 * time.Minute
+)
+
+// BackfillOptions controls how a backfill awards achievements
+type BackfillOptions struct {
+	Notify      bool   // Send real-time notifications; off by default for bulk awards
+	TriggeredBy string // Administrator who started the backfill
+}
+
+// StartBackfill queues a backfill of an active achievement and runs it in the
+// background. Only one unfinished backfill per achievement is allowed.
+func (s *AchievementService) StartBackfill(achievementID string, opts BackfillOptions) (*model.AchievementBackfill, error) {
+	achievement, err := s.GetAchievement(achievementID)
+	if err != nil {
+		return nil, err
+	}
+	if !achievement.IsActive {
+		return nil, ErrAchievementInactive
+	}
+
+	backfill := &model.AchievementBackfill{
+		AchievementID: achievementID,
+		Status:        model.BackfillPending,
+		Notify:        opts.Notify,
+		TriggeredBy:   opts.TriggeredBy,
+	}
+	if err := s.db.Transaction(func(tx *gorm.DB) error {
+		var unfinished int64
+		if err := tx.Model(&model.AchievementBackfill{}).
+			Where("achievement_id = ? AND status IN ?", achievementID, []string{model.BackfillPending, model.BackfillRunning}).
+			Count(&unfinished).Error; err != nil {
+			return fmt.Errorf("failed to check achievement backfills: %w", err)
+		}
+		if unfinished > 0 {
+			return ErrBackfillRunning
+		}
+		if err := tx.Create(backfill).Error; err != nil {
+			return fmt.Errorf("failed to create achievement backfill: %w", err)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Achievement backfill queued",
+		zap.String("backfill_id", backfill.ID),
+		zap.String("achievement_id", achievementID),
+		zap.Bool("notify", opts.Notify),
+	)
+
+	go func() {
+		if err := s.RunBackfill(backfill.ID); err != nil {
+			s.logger.Error("Achievement backfill failed",
+				zap.String("backfill_id", backfill.ID),
+				zap.Error(err),
+			)
+		}
+	}()
+
+	return backfill, nil
+}
+
+// GetBackfill returns a backfill of an achievement
+func (s *AchievementService) GetBackfill(achievementID, backfillID string) (*model.AchievementBackfill, error) {
+	var backfill model.AchievementBackfill
+	if err := s.db.First(&backfill, "id = ? AND achievement_id = ?", backfillID, achievementID).Error; err == gorm.ErrRecordNotFound {
+		return nil, ErrBackfillNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get achievement backfill: %w", err)
+	}
+	return &backfill, nil
+}
+
+// ListBackfills returns the backfills of an achievement, newest first
+func (s *AchievementService) ListBackfills(achievementID string) ([]model.AchievementBackfill, error) {
+	if _, err := s.GetAchievement(achievementID); err != nil {
+		return nil, err
+	}
+
+	var backfills []model.AchievementBackfill
+	if err := s.db.Where("achievement_id = ?", achievementID).
+		Order("created_at DESC").
+		Find(&backfills).Error; err != nil {
+		return nil, fmt.Errorf("failed to list achievement backfills: %w", err)
+	}
+	return backfills, nil
+}
+
+// ResumeBackfills runs the backfills that are queued or whose runner stopped, e.g.
+// in a restart, and returns how many were run
+func (s *AchievementService) ResumeBackfills() (int, error) {
+	var ids []string
+	if err := s.db.Model(&model.AchievementBackfill{}).
+		Where("status = ? OR (status = ? AND lease_until <= ?)", model.BackfillPending, model.BackfillRunning, time.Now()).
+		Order("created_at").
+		Pluck("id", &ids).Error; err != nil {
+		return 0, fmt.Errorf("failed to get unfinished backfills: %w", err)
+	}
+
+	for _, id := range ids {
+		if err := s.RunBackfill(id); err != nil {
+			s.logger.Error("Achievement backfill failed", zap.String("backfill_id", id), zap.Error(err))
+		}
+	}
+	return len(ids), nil
+}
+
+// RunBackfill claims a backfill and processes it from its checkpoint to the end. A
+// backfill held by another runner, or already finished, is left alone. After an
+// error the backfill is left to the cron job, unless its achievement was deleted or
+// deactivated.
+func (s *AchievementService) RunBackfill(id string) error {
+	now := time.Now()
+	claim := s.db.Model(&model.AchievementBackfill{}).
+		Where("id = ?", id).
+		Where("status = ? OR (status = ? AND lease_until <= ?)", model.BackfillPending, model.BackfillRunning, now).
+		Updates(map[string]any{"status": model.BackfillRunning, "lease_until": now.Add(backfillLease)})
+	if claim.Error != nil {
+		return fmt.Errorf("failed to claim achievement backfill: %w", claim.Error)
+	}
+	if claim.RowsAffected == 0 {
+		return nil
+	}
+
+	var backfill model.AchievementBackfill
+	if err := s.db.First(&backfill, "id = ?", id).Error; err != nil {
+		return fmt.Errorf("failed to get achievement backfill: %w", err)
+	}
+
+	if backfill.StartedAt == nil {
+		var total int64
+		if err := s.db.Model(&model.User{}).Where("anonymized_at IS NULL").Count(&total).Error; err != nil {
+			return s.interruptBackfill(&backfill, fmt.Errorf("failed to count users: %w", err))
+		}
+		if err := s.db.Model(&backfill).Updates(map[string]any{"started_at": now, "total_users": int(total)}).Error; err != nil {
+			return s.interruptBackfill(&backfill, fmt.Errorf("failed to start achievement backfill: %w", err))
+		}
+	}
+
+	for {
+		done, err := s.backfillBatch(&backfill)
+		if err != nil {
+			return s.interruptBackfill(&backfill, err)
+		}
+		if done {
+			break
+		}
+	}
+
+	if err := s.db.Model(&backfill).Updates(map[string]any{
+		"status":       model.BackfillCompleted,
+		"completed_at": time.Now(),
+		"lease_until":  nil,
+		"last_error":   "",
+	}).Error; err != nil {
+		return fmt.Errorf("failed to complete achievement backfill: %w", err)
+	}
+
+	s.logger.Info("Achievement backfill completed",
+		zap.String("backfill_id", backfill.ID),
+		zap.String("achievement_id", backfill.AchievementID),
+		zap.Int("processed_users", backfill.ProcessedUsers),
+		zap.Int("awarded_count", backfill.AwardedCount),
+	)
+	return nil
+}
+
+// interruptBackfill records why a backfill stopped. It fails for good if its
+// achievement is gone or inactive; otherwise the cron job resumes it.
+func (s *AchievementService) interruptBackfill(backfill *model.AchievementBackfill, cause error) error {
+	updates := map[string]any{"last_error": cause.Error(), "lease_until": time.Now()}
+	if errors.Is(cause, ErrAchievementNotFound) || errors.Is(cause, ErrAchievementInactive) {
+		updates["status"] = model.BackfillFailed
+		updates["completed_at"] = time.Now()
+		updates["lease_until"] = nil
+	}
+	if err := s.db.Model(backfill).Updates(updates).Error; err != nil {
+		s.logger.Error("Failed to record backfill error", zap.String("backfill_id", backfill.ID), zap.Error(err))
+	}
+	return cause
+}
+
+// backfillBatch evaluates the users after the checkpoint, awards those who qualify
+// and moves the checkpoint past them. It reports whether every user is done.
+func (s *AchievementService) backfillBatch(backfill *model.AchievementBackfill) (bool, error) {
+	// Reloaded every batch, so a deactivation stops the backfill
+	achievement, err := s.GetAchievement(backfill.AchievementID)
+	if err != nil {
+		return false, err
+	}
+	if !achievement.IsActive {
+		return false, ErrAchievementInactive
+	}
+	rule, err := achievement.GetRule()
+	if err != nil {
+		return false, fmt.Errorf("failed to parse achievement rule: %w", err)
+	}
+
+	var userIDs []string
+	if err := s.db.Model(&model.User{}).
+		Where("id > ? AND anonymized_at IS NULL", backfill.LastUserID).
+		Order("id").
+		Limit(backfillBatchSize).
+		Pluck("id", &userIDs).Error; err != nil {
+		return false, fmt.Errorf("failed to get users: %w", err)
+	}
+	if len(userIDs) == 0 {
+		return true, nil
+	}
+
+	// Users already holding the achievement or whose award was revoked, and holders
+	// of the tier below it if any
+	earned, err := s.holders(achievement.ID, userIDs, true)
+	if err != nil {
+		return false, err
+	}
+	previous, err := s.previousTiers()
+	if err != nil {
+		return false, err
+	}
+	var required map[string]bool
+	if below, ok := previous[achievement.ID]; ok {
+		if required, err = s.holders(below, userIDs, false); err != nil {
+			return false, err
+		}
+	}
+
+	now := time.Now()
+	awarded := 0
+	for _, userID := range userIDs {
+		if earned[userID] || (required != nil && !required[userID]) {
+			continue
+		}
+
+		progress, err := newRuleEvaluator(s.db, userID, now).inSeason(achievement).evaluate(rule)
+		if err != nil {
+			return false, fmt.Errorf("failed to evaluate user %s: %w", userID, err)
+		}
+		if !progress.Met {
+			continue
+		}
+
+		earnedAt, err := s.historicalEarnDate(userID, achievement, rule, now)
+		if err != nil {
+			return false, err
+		}
+		opts := awardOptions{earnedAt: earnedAt, notify: backfill.Notify, source: model.AuditSourceBackfill}
+		if backfill.TriggeredBy != "" {
+			opts.actorID = &backfill.TriggeredBy
+		}
+		if err := s.awardAchievement(userID, achievement, opts); err != nil {
+			s.logger.Error("Failed to award backfilled achievement",
+				zap.String("achievement_id", achievement.ID),
+				zap.String("user_id", userID),
+				zap.Error(err),
+			)
+			continue
+		}
+		awarded++
+	}
+
+	// Checkpoint; a batch interrupted before this is evaluated again, and users
+	// awarded in it are then skipped as holders
+	backfill.LastUserID = userIDs[len(userIDs)-1]
+	backfill.ProcessedUsers += len(userIDs)
+	backfill.AwardedCount += awarded
+	if err := s.db.Model(backfill).Updates(map[string]any{
+		"last_user_id":    backfill.LastUserID,
+		"processed_users": backfill.ProcessedUsers,
+		"awarded_count":   backfill.AwardedCount,
+		"lease_until":     time.Now().Add(backfillLease),
+	}).Error; err != nil {
+		return false, fmt.Errorf("failed to checkpoint achievement backfill: %w", err)
+	}
+
+	return len(userIDs) < backfillBatchSize, nil
+}
+
+// holders returns which of the given users hold an achievement, optionally counting
+// users whose award was revoked
+func (s *AchievementService) holders(achievementID string, userIDs []string, includeRevoked bool) (map[string]bool, error) {
+	query := s.db.Model(&model.UserAchievement{}).Where("achievement_id = ? AND user_id IN ?", achievementID, userIDs)
+	if !includeRevoked {
+		query = query.Where("revoked_at IS NULL")
+	}
+	var ids []string
+	if err := query.Pluck("user_id", &ids).Error; err != nil {
+		return nil, fmt.Errorf("failed to get achievement holders: %w", err)
+	}
+
+	held := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		held[id] = true
+	}
+	return held, nil
+}
+
+// historicalEarnDate returns the end of the first day on which a user met a rule,
+// replaying the dated history of their attempts and events. Level progress is only
+// known as it is now, so rules that use it, and rules first met today, are dated now.
+// Only activity within the achievement's season counts.
+func (s *AchievementService) historicalEarnDate(userID string, achievement *model.Achievement, rule *model.AchievementRule, now time.Time) (time.Time, error) {
+	root, err := rule.Root()
+	if err != nil {
+		return now, err
+	}
+	if !datedRule(root) {
+		return now, nil
+	}
+
+	days, err := s.activityDays(userID)
+	if err != nil {
+		return now, err
+	}
+	for _, day := range days {
+		end := day.AddDate(0, 0, 1).Add(-time.Nanosecond)
+		if !end.Before(now) {
+			break
+		}
+		progress, err := newHistoricalRuleEvaluator(s.db, userID, end).inSeason(achievement).evaluate(rule)
+		if err != nil {
+			return now, fmt.Errorf("failed to evaluate history of user %s: %w", userID, err)
+		}
+		if progress.Met {
+			return end, nil
+		}
+	}
+	return now, nil
+}
+
+// datedRule reports whether every metric of a rule reads dated history
+func datedRule(n *model.RuleNode) bool {
+	switch {
+	case n.All != nil, n.Any != nil:
+		children := n.All
+		if n.Any != nil {
+			children = n.Any
+		}
+		for _, child := range children {
+			if !datedRule(child) {
+				return false
+			}
+		}
+		return true
+	case n.Not != nil:
+		return datedRule(n.Not)
+	default:
+		return n.RuleMetric.Source != model.RuleSourceProgress
+	}
+}
+
+// activityDays returns the local days on which a user has statistics or events,
+// oldest first. Only these days can change a rule over dated history.
+func (s *AchievementService) activityDays(userID string) ([]time.Time, error) {
+	var statDates []string
+	if err := s.db.Model(&model.UserAttempts{}).Where("user_id = ?", userID).
+		Distinct().Pluck("stat_date", &statDates).Error; err != nil {
+		return nil, fmt.Errorf("failed to get activity days: %w", err)
+	}
+	var eventTimes []time.Time
+	if err := s.db.Model(&model.Event{}).Where("user_id = ?", userID).
+		Pluck("created_at", &eventTimes).Error; err != nil {
+		return nil, fmt.Errorf("failed to get event times: %w", err)
+	}
+
+	seen := make(map[string]bool)
+	var days []time.Time
+	add := func(day time.Time) {
+		key := day.Format("2006-01-02")
+		if !seen[key] {
+			seen[key] = true
+			days = append(days, day)
+		}
+	}
+	for _, date := range statDates {
+		if day, err := time.ParseInLocation("2006-01-02", date, time.Local); err == nil {
+			add(day)
+		}
+	}
+	for _, t := range eventTimes {
+		t = t.Local()
+		add(time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.Local))
+	}
+
+	sort.Slice(days, func(i, j int) bool { return days[i].Before(days[j]) })
+	return days, nil
+}
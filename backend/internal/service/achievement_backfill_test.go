@@ -0,0 +1,173 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"paperplay/internal/model"
+)
+
+const twelveAnswersRule = `{"match":{"source":"attempts","field":"attempts_total","agg":"sum","op":">=","value":12}}`
+
+// seedBackfillUsers creates users u-1 to u-n and an active achievement for twelve answers
+func seedBackfillUsers(t *testing.T, db *gorm.DB, n int) {
+	require.NoError(t, db.AutoMigrate(&model.AchievementSeries{}))
+	for i := 1; i <= n; i++ {
+		id := fmt.Sprintf("u-%d", i)
+		require.NoError(t, db.Create(&model.User{ID: id, Email: id + "@example.com", DisplayName: id}).Error)
+	}
+	require.NoError(t, db.Create(&model.Achievement{
+		ID: "twelve", Name: "Twelve", Description: "Answer twelve questions", BadgeType: "learning",
+		RuleJSON: twelveAnswersRule, IsActive: true,
+	}).Error)
+}
+
+func addAttempts(t *testing.T, db *gorm.DB, userID string, daysAgo, total int) {
+	date := time.Now().AddDate(0, 0, -daysAgo).Format("2006-01-02")
+	require.NoError(t, db.Create(&model.UserAttempts{UserID: userID, StatDate: date, AttemptsTotal: total}).Error)
+}
+
+func TestAchievementBackfill_AwardsWithHistoricalDate(t *testing.T) {
+	db := setupEventBusTest(t)
+	seedBackfillUsers(t, db, 4)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	// u-1 passed twelve answers three days ago, u-2 today, u-3 never; u-4 already holds it
+	addAttempts(t, db, "u-1", 5, 8)
+	addAttempts(t, db, "u-1", 3, 4)
+	addAttempts(t, db, "u-1", 1, 4)
+	addAttempts(t, db, "u-2", 0, 12)
+	addAttempts(t, db, "u-3", 2, 11)
+	addAttempts(t, db, "u-4", 2, 20)
+	heldSince := time.Now().AddDate(0, 0, -1)
+	require.NoError(t, db.Create(&model.UserAchievement{UserID: "u-4", AchievementID: "twelve", EarnedAt: heldSince}).Error)
+
+	backfill := &model.AchievementBackfill{AchievementID: "twelve", Status: model.BackfillPending}
+	require.NoError(t, db.Create(backfill).Error)
+	require.NoError(t, s.RunBackfill(backfill.ID))
+
+	require.NoError(t, db.First(backfill, "id = ?", backfill.ID).Error)
+	assert.Equal(t, model.BackfillCompleted, backfill.Status)
+	assert.Equal(t, 4, backfill.TotalUsers)
+	assert.Equal(t, 4, backfill.ProcessedUsers)
+	assert.Equal(t, 2, backfill.AwardedCount)
+	assert.Equal(t, "u-4", backfill.LastUserID)
+	assert.NotNil(t, backfill.CompletedAt)
+
+	earned := make(map[string]time.Time)
+	var awards []model.UserAchievement
+	require.NoError(t, db.Where("achievement_id = ?", "twelve").Find(&awards).Error)
+	for _, ua := range awards {
+		earned[ua.UserID] = ua.EarnedAt
+	}
+	assert.Len(t, earned, 3)
+	assert.Equal(t, time.Now().AddDate(0, 0, -3).Format("2006-01-02"), earned["u-1"].Local().Format("2006-01-02"))
+	assert.WithinDuration(t, time.Now(), earned["u-2"], time.Minute)
+	assert.WithinDuration(t, heldSince, earned["u-4"], time.Second)
+
+	// A finished backfill is not run again
+	require.NoError(t, db.Delete(&model.UserAchievement{}, "user_id = ?", "u-2").Error)
+	require.NoError(t, s.RunBackfill(backfill.ID))
+	var count int64
+	db.Model(&model.UserAchievement{}).Where("achievement_id = ?", "twelve").Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestAchievementBackfill_ResumesFromCheckpoint(t *testing.T) {
+	db := setupEventBusTest(t)
+	seedBackfillUsers(t, db, 3)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+	for _, id := range []string{"u-1", "u-2", "u-3"} {
+		addAttempts(t, db, id, 1, 12)
+	}
+
+	// A runner stopped after the batch ending at u-1
+	started := time.Now().Add(-time.Hour)
+	live := time.Now().Add(time.Minute)
+	backfill := &model.AchievementBackfill{
+		AchievementID: "twelve", Status: model.BackfillRunning, TotalUsers: 3, ProcessedUsers: 1,
+		LastUserID: "u-1", StartedAt: &started, LeaseUntil: &live,
+	}
+	require.NoError(t, db.Create(backfill).Error)
+
+	// Held by a live runner: left alone
+	require.NoError(t, s.RunBackfill(backfill.ID))
+	var awarded int64
+	db.Model(&model.UserAchievement{}).Count(&awarded)
+	assert.Zero(t, awarded)
+
+	require.NoError(t, db.Model(backfill).Update("lease_until", time.Now().Add(-time.Minute)).Error)
+	resumed, err := s.ResumeBackfills()
+	require.NoError(t, err)
+	assert.Equal(t, 1, resumed)
+
+	var holders []string
+	db.Model(&model.UserAchievement{}).Order("user_id").Pluck("user_id", &holders)
+	assert.Equal(t, []string{"u-2", "u-3"}, holders)
+
+	require.NoError(t, db.First(backfill, "id = ?", backfill.ID).Error)
+	assert.Equal(t, model.BackfillCompleted, backfill.Status)
+	assert.Equal(t, 3, backfill.ProcessedUsers)
+	assert.Equal(t, 2, backfill.AwardedCount)
+	assert.Equal(t, started.Unix(), backfill.StartedAt.Unix())
+}
+
+func TestAchievementBackfill_StartsOnActivation(t *testing.T) {
+	db := setupEventBusTest(t)
+	seedBackfillUsers(t, db, 1)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+	addAttempts(t, db, "u-1", 1, 12)
+
+	input := &AchievementInput{
+		ID: "draft", Name: "Draft", Description: "Answer twelve questions", Level: 1, BadgeType: "learning",
+		Rule: parseRule(t, twelveAnswersRule), Backfill: BackfillOptions{TriggeredBy: "admin-1"},
+	}
+	_, err := s.CreateAchievement(input)
+	require.NoError(t, err)
+	_, err = s.StartBackfill("draft", BackfillOptions{})
+	assert.ErrorIs(t, err, ErrAchievementInactive)
+
+	input.IsActive = true
+	_, err = s.UpdateAchievement("draft", input)
+	require.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		backfills, err := s.ListBackfills("draft")
+		return err == nil && len(backfills) == 1 && backfills[0].Status == model.BackfillCompleted
+	}, 2*time.Second, 10*time.Millisecond)
+
+	backfills, err := s.ListBackfills("draft")
+	require.NoError(t, err)
+	assert.Equal(t, "admin-1", backfills[0].TriggeredBy)
+	assert.False(t, backfills[0].Notify)
+	assert.Equal(t, 1, backfills[0].AwardedCount)
+
+	_, err = s.GetBackfill("twelve", backfills[0].ID)
+	assert.ErrorIs(t, err, ErrBackfillNotFound)
+}
+
+func TestAchievementBackfill_StopsWhenDeactivated(t *testing.T) {
+	db := setupEventBusTest(t)
+	seedBackfillUsers(t, db, 1)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	backfill := &model.AchievementBackfill{AchievementID: "twelve", Status: model.BackfillPending}
+	require.NoError(t, db.Create(backfill).Error)
+
+	// A second backfill of the same achievement is refused while one is unfinished
+	_, err := s.StartBackfill("twelve", BackfillOptions{})
+	assert.ErrorIs(t, err, ErrBackfillRunning)
+
+	require.NoError(t, db.Model(&model.Achievement{}).Where("id = ?", "twelve").Update("is_active", false).Error)
+	assert.ErrorIs(t, s.RunBackfill(backfill.ID), ErrAchievementInactive)
+
+	require.NoError(t, db.First(backfill, "id = ?", backfill.ID).Error)
+	assert.Equal(t, model.BackfillFailed, backfill.Status)
+	assert.Equal(t, ErrAchievementInactive.Error(), backfill.LastError)
+}
@@ -24,6 +24,8 @@ func TestForceAwardAchievement(t *testing.T) {
 		&model.User{},
 		&model.Achievement{},
 		&model.UserAchievement{},
+		&model.UserAchievementProgress{},
+		&model.AchievementAuditLog{},
 		&model.Event{},
 	)
 	require.NoError(t, err)
@@ -151,7 +153,7 @@ func TestWebSocketNotificationInForceAward(t *testing.T) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
 	require.NoError(t, err)
 
-	err = db.AutoMigrate(&model.User{}, &model.Achievement{}, &model.UserAchievement{}, &model.Event{})
+	err = db.AutoMigrate(&model.User{}, &model.Achievement{}, &model.UserAchievement{}, &model.UserAchievementProgress{}, &model.AchievementAuditLog{}, &model.Event{})
 	require.NoError(t, err)
 
 	// Setup WebSocket hub with notification tracking
@@ -206,6 +208,8 @@ func TestAchievementServiceEvaluationIntegration(t *testing.T) {
 		&model.User{},
 		&model.Achievement{},
 		&model.UserAchievement{},
+		&model.UserAchievementProgress{},
+		&model.AchievementAuditLog{},
 		&model.Event{},
 		&model.UserAttempts{},
 	)
@@ -0,0 +1,89 @@
+package service
+
+import (
+	"fmt"
+	"paperplay/internal/model"
+	"paperplay/internal/websocket"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// maxRedeliveredNotifications caps the unacknowledged achievements sent when a user connects
+const maxRedeliveredNotifications = 20
+
+// awardNotification is the notification of an earned achievement. Its ID is the user
+// achievement's, which clients send back to acknowledge it.
+func awardNotification(userAchievementID string, achievement *model.Achievement) *websocket.NotificationMessage {
+	return &websocket.NotificationMessage{
+		ID:          userAchievementID,
+		Type:        "achievement",
+		Title:       "成就解锁！",
+		Message:     fmt.Sprintf("恭喜您获得成就：%s", achievement.Name),
+		Achievement: notificationAchievement(achievement),
+	}
+}
+
+// unreadAchievements selects the user's achievements that have not been acknowledged.
+// Revoked achievements and tiers superseded by a higher tier are left out.
+func (s *AchievementService) unreadAchievements(userID string) *gorm.DB {
+	return s.db.Model(&model.UserAchievement{}).
+		Where("user_id = ? AND viewed_at IS NULL AND revoked_at IS NULL AND superseded_at IS NULL", userID)
+}
+
+// PendingNotifications returns notifications for the user's unacknowledged
+// achievements, oldest first
+func (s *AchievementService) PendingNotifications(userID string) ([]*websocket.NotificationMessage, error) {
+	var unread []model.UserAchievement
+	if err := s.unreadAchievements(userID).
+		Preload("Achievement").
+		Order("earned_at ASC").
+		Limit(maxRedeliveredNotifications).
+		Find(&unread).Error; err != nil {
+		return nil, fmt.Errorf("failed to get unread achievements: %w", err)
+	}
+
+	notifications := make([]*websocket.NotificationMessage, 0, len(unread))
+	for _, ua := range unread {
+		if ua.Achievement == nil {
+			continue
+		}
+		notifications = append(notifications, awardNotification(ua.ID, ua.Achievement))
+	}
+	return notifications, nil
+}
+
+// MarkNotificationsDelivered records when achievement notifications first reached the user
+func (s *AchievementService) MarkNotificationsDelivered(userID string, ids []string) error {
+	if err := s.db.Model(&model.UserAchievement{}).
+		Where("user_id = ? AND id IN ? AND notified_at IS NULL", userID, ids).
+		Update("notified_at", time.Now()).Error; err != nil {
+		return fmt.Errorf("failed to mark achievements notified: %w", err)
+	}
+	return nil
+}
+
+// AcknowledgeNotifications marks the user's achievements with the given IDs as viewed
+// and returns how many were unread. Unknown IDs and other users' achievements are ignored.
+func (s *AchievementService) AcknowledgeNotifications(userID string, ids []string) (int64, error) {
+	now := time.Now()
+	result := s.db.Model(&model.UserAchievement{}).
+		Where("user_id = ? AND id IN ? AND viewed_at IS NULL", userID, ids).
+		Updates(map[string]any{
+			"viewed_at":   now,
+			"notified_at": gorm.Expr("COALESCE(notified_at, ?)", now),
+		})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to acknowledge achievements: %w", result.Error)
+	}
+	return result.RowsAffected, nil
+}
+
+// UnreadNotificationCount returns the number of achievements the user has not acknowledged
+func (s *AchievementService) UnreadNotificationCount(userID string) (int64, error) {
+	var count int64
+	if err := s.unreadAchievements(userID).Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count unread achievements: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,102 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"paperplay/internal/model"
+)
+
+func TestAchievementNotifications_AcknowledgeAndCount(t *testing.T) {
+	db := setupRuleTest(t)
+	tiers := seedLevelSeries(t, db)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	for i := 0; i < 3; i++ {
+		require.NoError(t, db.Create(&model.Event{UserID: "u-1", EventType: model.EventLevelCompleted}).Error)
+	}
+	require.NoError(t, s.EvaluateUserAchievements("u-1"))
+
+	// Only the highest tier is unread; the lower one was superseded
+	unread, err := s.UnreadNotificationCount("u-1")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), unread)
+
+	pending, err := s.PendingNotifications("u-1")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "achievement", pending[0].Type)
+	assert.Equal(t, tiers[1].ID, pending[0].Achievement.ID)
+
+	var silver model.UserAchievement
+	require.NoError(t, db.First(&silver, "id = ?", pending[0].ID).Error)
+	assert.True(t, silver.IsNew(), "nobody was connected")
+
+	require.NoError(t, s.MarkNotificationsDelivered("u-1", []string{silver.ID}))
+	require.NoError(t, db.First(&silver, "id = ?", silver.ID).Error)
+	require.NotNil(t, silver.NotifiedAt)
+	delivered := *silver.NotifiedAt
+
+	// Other users cannot acknowledge it
+	acknowledged, err := s.AcknowledgeNotifications("u-2", []string{silver.ID})
+	require.NoError(t, err)
+	assert.Zero(t, acknowledged)
+
+	acknowledged, err = s.AcknowledgeNotifications("u-1", []string{silver.ID, "unknown"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), acknowledged)
+	acknowledged, err = s.AcknowledgeNotifications("u-1", []string{silver.ID})
+	require.NoError(t, err)
+	assert.Zero(t, acknowledged)
+
+	require.NoError(t, db.First(&silver, "id = ?", silver.ID).Error)
+	assert.NotNil(t, silver.ViewedAt)
+	assert.True(t, delivered.Equal(*silver.NotifiedAt), "first delivery is kept")
+
+	unread, err = s.UnreadNotificationCount("u-1")
+	require.NoError(t, err)
+	assert.Zero(t, unread)
+	pending, err = s.PendingNotifications("u-1")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	// Revoked achievements are not unread
+	require.NoError(t, s.ForceAwardAchievement("u-2", tiers[0].ID))
+	_, err = s.RevokeAchievement("u-2", tiers[0].ID, "admin-1", "shared account")
+	require.NoError(t, err)
+	unread, err = s.UnreadNotificationCount("u-2")
+	require.NoError(t, err)
+	assert.Zero(t, unread)
+}
+
+func TestAchievementNotifications_SilentBackfillNotRedelivered(t *testing.T) {
+	db := setupEventBusTest(t)
+	seedBackfillUsers(t, db, 2)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+	addAttempts(t, db, "u-1", 1, 12)
+	addAttempts(t, db, "u-2", 1, 12)
+
+	silent := &model.AchievementBackfill{AchievementID: "twelve", Status: model.BackfillPending}
+	require.NoError(t, db.Create(silent).Error)
+	require.NoError(t, s.RunBackfill(silent.ID))
+
+	pending, err := s.PendingNotifications("u-1")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+	unread, err := s.UnreadNotificationCount("u-1")
+	require.NoError(t, err)
+	assert.Zero(t, unread)
+
+	// A notifying backfill leaves its awards for redelivery
+	require.NoError(t, db.Delete(&model.UserAchievement{}, "achievement_id = ?", "twelve").Error)
+	notifying := &model.AchievementBackfill{AchievementID: "twelve", Status: model.BackfillPending, Notify: true}
+	require.NoError(t, db.Create(notifying).Error)
+	require.NoError(t, s.RunBackfill(notifying.ID))
+
+	pending, err = s.PendingNotifications("u-2")
+	require.NoError(t, err)
+	assert.Len(t, pending, 1)
+}
@@ -0,0 +1,225 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"paperplay/internal/model"
+	"paperplay/internal/websocket"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Achievement revocation errors
+var (
+	ErrUserAchievementNotFound = errors.New("user has not earned this achievement")
+	ErrAchievementRevoked      = errors.New("achievement has already been revoked")
+)
+
+// AchievementAuditFilter narrows the achievement audit log
+type AchievementAuditFilter struct {
+	UserID        string
+	AchievementID string
+	Action        string
+	Limit         int
+	Offset        int
+}
+
+// RevokeAchievement takes an achievement back from a user. The award is kept as a
+// tombstone so rules do not award it again, lower tiers it superseded are restored,
+// and its NFT is burned if the contract supports it or marked revoked otherwise.
+func (s *AchievementService) RevokeAchievement(userID, achievementID, actorID, reason string) (*model.UserAchievement, error) {
+	achievement, err := s.GetAchievement(achievementID)
+	if err != nil {
+		return nil, err
+	}
+
+	var userAchievement model.UserAchievement
+	var burnable []model.NFTAsset
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND achievement_id = ? AND revoked_at IS NULL", userID, achievementID).
+			First(&userAchievement).Error; err == gorm.ErrRecordNotFound {
+			var revoked int64
+			if err := tx.Model(&model.UserAchievement{}).
+				Where("user_id = ? AND achievement_id = ?", userID, achievementID).
+				Count(&revoked).Error; err != nil {
+				return fmt.Errorf("failed to check user achievement: %w", err)
+			}
+			if revoked > 0 {
+				return ErrAchievementRevoked
+			}
+			return ErrUserAchievementNotFound
+		} else if err != nil {
+			return fmt.Errorf("failed to get user achievement: %w", err)
+		}
+
+		now := time.Now()
+		userAchievement.RevokedAt = &now
+		userAchievement.RevokeReason = reason
+		if actorID != "" {
+			userAchievement.RevokedBy = &actorID
+		}
+		if err := tx.Model(&userAchievement).Updates(map[string]any{
+			"revoked_at":    userAchievement.RevokedAt,
+			"revoked_by":    userAchievement.RevokedBy,
+			"revoke_reason": reason,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to revoke user achievement: %w", err)
+		}
+
+		// A revoked tier no longer replaces the lower tiers it superseded
+		if err := tx.Model(&model.UserAchievement{}).
+			Where("superseded_by = ? AND revoked_at IS NULL", userAchievement.ID).
+			Updates(map[string]any{"superseded_at": nil, "superseded_by": nil}).Error; err != nil {
+			return fmt.Errorf("failed to restore superseded tiers: %w", err)
+		}
+
+		nfts, err := s.revokeAchievementNFTs(tx, userID, achievementID)
+		if err != nil {
+			return err
+		}
+		burnable = nfts
+
+		event := &model.Event{UserID: userID, EventType: model.EventAchievementRevoked}
+		if err := event.SetData(map[string]any{
+			"achievement_id":   achievement.ID,
+			"achievement_name": achievement.Name,
+			"reason":           reason,
+		}); err != nil {
+			return fmt.Errorf("failed to set event data: %w", err)
+		}
+		if err := tx.Create(event).Error; err != nil {
+			return fmt.Errorf("failed to create event: %w", err)
+		}
+
+		if err := tx.Create(&model.AchievementAuditLog{
+			UserID:            userID,
+			AchievementID:     achievementID,
+			UserAchievementID: userAchievement.ID,
+			Action:            model.AuditActionRevoke,
+			Source:            model.AuditSourceAdmin,
+			ActorID:           userAchievement.RevokedBy,
+			Reason:            reason,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to create achievement audit log: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Burning is not undone by a rollback, so it waits for the commit
+	s.burnRevokedNFTs(burnable)
+
+	s.logger.Info("Achievement revoked",
+		zap.String("user_id", userID),
+		zap.String("achievement_id", achievementID),
+		zap.String("revoked_by", actorID),
+		zap.String("reason", reason),
+	)
+
+	if s.wsHub != nil {
+		s.wsHub.SendNotification(userID, &websocket.NotificationMessage{
+			ID:          userAchievement.ID,
+			Type:        "achievement_revoked",
+			Title:       "成就已撤销",
+			Message:     fmt.Sprintf("您的成就「%s」已被撤销：%s", achievement.Name, reason),
+			Achievement: notificationAchievement(achievement),
+		})
+	}
+
+	return &userAchievement, nil
+}
+
+// revokeAchievementNFTs marks revoked the NFTs of a revoked achievement, unlinks NFTs of
+// lower tiers that were upgraded to them, and returns the minted ones to burn
+func (s *AchievementService) revokeAchievementNFTs(tx *gorm.DB, userID, achievementID string) ([]model.NFTAsset, error) {
+	var nfts []model.NFTAsset
+	if err := tx.Where("user_id = ? AND achievement_id = ? AND status IN ?", userID, achievementID,
+		[]string{model.NFTStatusPending, model.NFTStatusMinted}).Find(&nfts).Error; err != nil {
+		return nil, fmt.Errorf("failed to get achievement NFTs: %w", err)
+	}
+
+	var burnable []model.NFTAsset
+	for _, nft := range nfts {
+		if err := tx.Model(&model.NFTAsset{}).Where("id = ?", nft.ID).
+			Update("status", model.NFTStatusRevoked).Error; err != nil {
+			return nil, fmt.Errorf("failed to revoke NFT: %w", err)
+		}
+
+		if err := tx.Model(&model.NFTAsset{}).
+			Where("upgraded_to_id = ?", nft.ID).
+			Update("upgraded_to_id", nil).Error; err != nil {
+			return nil, fmt.Errorf("failed to unlink upgraded NFTs: %w", err)
+		}
+
+		if nft.IsMinted() {
+			burnable = append(burnable, nft)
+		}
+	}
+	return burnable, nil
+}
+
+// burnRevokedNFTs burns the minted NFTs of a committed revocation. A token that cannot
+// be burned stays on chain, revoked and no longer honored.
+func (s *AchievementService) burnRevokedNFTs(nfts []model.NFTAsset) {
+	if s.ethereumService == nil || !s.ethereumService.SupportsBurn() {
+		return
+	}
+	for i := range nfts {
+		nft := &nfts[i]
+		txHash, err := s.ethereumService.BurnNFT(nft)
+		if err != nil {
+			s.logger.Warn("Failed to burn revoked NFT",
+				zap.String("nft_asset_id", nft.ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		if err := s.db.Model(&model.NFTAsset{}).
+			Where("id = ? AND status = ?", nft.ID, model.NFTStatusRevoked).
+			Updates(map[string]any{"status": model.NFTStatusBurned, "burn_tx_hash": txHash}).Error; err != nil {
+			s.logger.Error("Failed to record burned NFT",
+				zap.String("nft_asset_id", nft.ID),
+				zap.String("tx_hash", txHash),
+				zap.Error(err),
+			)
+		}
+	}
+}
+
+// ListAchievementAuditLog returns audit log entries matching the filter, newest first,
+// and the total number of matching entries
+func (s *AchievementService) ListAchievementAuditLog(filter AchievementAuditFilter) ([]model.AchievementAuditLog, int64, error) {
+	query := s.db.Model(&model.AchievementAuditLog{})
+	if filter.UserID != "" {
+		query = query.Where("user_id = ?", filter.UserID)
+	}
+	if filter.AchievementID != "" {
+		query = query.Where("achievement_id = ?", filter.AchievementID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count achievement audit log: %w", err)
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+
+	var entries []model.AchievementAuditLog
+	if err := query.Order("created_at DESC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&entries).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list achievement audit log: %w", err)
+	}
+
+	return entries, total, nil
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"paperplay/config"
+	"paperplay/internal/model"
+)
+
+func TestAchievementRevoke_KeepsTombstone(t *testing.T) {
+	db := setupRuleTest(t)
+	tiers := seedLevelSeries(t, db)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.Create(&model.Event{UserID: "u-1", EventType: model.EventLevelCompleted}).Error)
+	}
+	require.NoError(t, s.EvaluateUserAchievements("u-1"))
+
+	revoked, err := s.RevokeAchievement("u-1", tiers[2].ID, "admin-1", "scripted answers")
+	require.NoError(t, err)
+	assert.True(t, revoked.IsRevoked())
+	assert.Equal(t, "admin-1", *revoked.RevokedBy)
+	assert.Equal(t, "scripted answers", revoked.RevokeReason)
+
+	// The tier below is restored and the revoked tier is not awarded again
+	require.NoError(t, s.EvaluateUserAchievements("u-1"))
+	held, err := s.GetUserAchievements("u-1")
+	require.NoError(t, err)
+	require.Len(t, held, 1)
+	assert.Equal(t, tiers[1].ID, held[0].AchievementID)
+
+	var rows int64
+	db.Model(&model.UserAchievement{}).Where("user_id = ?", "u-1").Count(&rows)
+	assert.Equal(t, int64(3), rows)
+
+	progress, err := s.GetAchievementProgress("u-1")
+	require.NoError(t, err)
+	for _, p := range progress {
+		assert.Equal(t, p.Achievement.ID == tiers[2].ID, p.Revoked, p.Achievement.ID)
+	}
+
+	_, err = s.RevokeAchievement("u-1", tiers[2].ID, "admin-1", "again")
+	assert.ErrorIs(t, err, ErrAchievementRevoked)
+	_, err = s.RevokeAchievement("u-2", tiers[2].ID, "admin-1", "never earned")
+	assert.ErrorIs(t, err, ErrUserAchievementNotFound)
+	_, err = s.RevokeAchievement("u-1", "missing", "admin-1", "no such achievement")
+	assert.ErrorIs(t, err, ErrAchievementNotFound)
+
+	// A force award grants it again beside the tombstone
+	require.NoError(t, s.ForceAwardAchievement("u-1", tiers[2].ID))
+
+	entries, total, err := s.ListAchievementAuditLog(AchievementAuditFilter{UserID: "u-1", AchievementID: tiers[2].ID})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	sources := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		sources = append(sources, entry.Action+"/"+entry.Source)
+	}
+	assert.ElementsMatch(t, []string{"award/rule", "revoke/admin", "award/force"}, sources)
+
+	entries, total, err = s.ListAchievementAuditLog(AchievementAuditFilter{Action: model.AuditActionRevoke})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	assert.Equal(t, "admin-1", *entries[0].ActorID)
+	assert.Equal(t, "scripted answers", entries[0].Reason)
+}
+
+func TestAchievementRevoke_BurnsOrRevokesNFT(t *testing.T) {
+	db := setupRuleTest(t)
+	tiers := seedLevelSeries(t, db)
+
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	eth := &EthereumService{config: &config.EthereumConfig{BurnEnabled: true}, privateKey: key, enabled: true}
+	s := NewAchievementService(db, zap.NewNop(), nil, eth)
+
+	// u-1 holds a minted NFT of the top tier, to which the NFT of the tier below was upgraded
+	award := func(userID, achievementID, status string) *model.NFTAsset {
+		require.NoError(t, db.Create(&model.UserAchievement{UserID: userID, AchievementID: achievementID}).Error)
+		nft := &model.NFTAsset{UserID: userID, AchievementID: &achievementID, MetadataURI: "uri", Status: status}
+		require.NoError(t, db.Create(nft).Error)
+		return nft
+	}
+	minted := award("u-1", tiers[1].ID, model.NFTStatusMinted)
+	lower := award("u-1", tiers[0].ID, model.NFTStatusMinted)
+	require.NoError(t, db.Model(lower).Update("upgraded_to_id", minted.ID).Error)
+	pending := award("u-2", tiers[1].ID, model.NFTStatusPending)
+
+	_, err = s.RevokeAchievement("u-1", tiers[1].ID, "admin-1", "shared account")
+	require.NoError(t, err)
+	require.NoError(t, db.First(minted, "id = ?", minted.ID).Error)
+	assert.Equal(t, model.NFTStatusBurned, minted.Status)
+	assert.NotEmpty(t, minted.BurnTxHash)
+	require.NoError(t, db.First(lower, "id = ?", lower.ID).Error)
+	assert.Nil(t, lower.UpgradedToID)
+
+	// NFTs not minted yet cannot be burned
+	_, err = s.RevokeAchievement("u-2", tiers[1].ID, "admin-1", "shared account")
+	require.NoError(t, err)
+	require.NoError(t, db.First(pending, "id = ?", pending.ID).Error)
+	assert.Equal(t, model.NFTStatusRevoked, pending.Status)
+	assert.Empty(t, pending.BurnTxHash)
+
+	// Without burn support minted NFTs are marked revoked
+	eth.config.BurnEnabled = false
+	other := award("u-3", tiers[1].ID, model.NFTStatusMinted)
+	_, err = s.RevokeAchievement("u-3", tiers[1].ID, "admin-1", "shared account")
+	require.NoError(t, err)
+	require.NoError(t, db.First(other, "id = ?", other.ID).Error)
+	assert.Equal(t, model.NFTStatusRevoked, other.Status)
+}
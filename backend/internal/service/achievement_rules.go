@@ -0,0 +1,318 @@
+package service
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"paperplay/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ruleEvaluator evaluates achievement rules for one user at one point in time.
+// Metric values are cached, so rules sharing a metric query it only once.
+type ruleEvaluator struct {
+	db         *gorm.DB
+	userID     string
+	now        time.Time
+	historical bool // Ignore rows dated after now
+	cache      map[string]float64
+
+	// Only activity within a seasonal achievement's window counts
+	seasonStart, seasonEnd *time.Time
+}
+
+// newRuleEvaluator creates an evaluator for a user as of now
+func newRuleEvaluator(db *gorm.DB, userID string, now time.Time) *ruleEvaluator {
+	return &ruleEvaluator{
+		db:     db,
+		userID: userID,
+		now:    now,
+		cache:  make(map[string]float64),
+	}
+}
+
+// newHistoricalRuleEvaluator creates an evaluator for a user as of a past time. Only
+// attempts and events are dated history; level progress is the current state.
+func newHistoricalRuleEvaluator(db *gorm.DB, userID string, asOf time.Time) *ruleEvaluator {
+	e := newRuleEvaluator(db, userID, asOf)
+	e.historical = true
+	return e
+}
+
+// inSeason returns an evaluator that only counts activity within the window of a
+// seasonal achievement. It shares the cache, whose keys include the window.
+func (e *ruleEvaluator) inSeason(achievement *model.Achievement) *ruleEvaluator {
+	if !achievement.IsSeasonal() {
+		return e
+	}
+	seasonal := *e
+	seasonal.seasonStart, seasonal.seasonEnd = achievement.StartsAt, achievement.EndsAt
+	return &seasonal
+}
+
+// unmetProgressCap keeps a rule that is not met from reporting full progress,
+// e.g. "> 5" with a current value of 5
+const unmetProgressCap = 0.99
+
+// RuleProgress is how far a user is from meeting a rule
+type RuleProgress struct {
+	Met        bool                `json:"met"`
+	Progress   float64             `json:"progress"` // 0.0-1.0; 1.0 only when met
+	Conditions []ConditionProgress `json:"conditions"`
+}
+
+// ConditionProgress is the current value of one metric versus its threshold
+type ConditionProgress struct {
+	Source   string  `json:"source"`
+	Field    string  `json:"field,omitempty"`
+	Agg      string  `json:"agg"`
+	Window   string  `json:"window"`
+	Days     int     `json:"days,omitempty"`
+	Operator string  `json:"op"`
+	Current  float64 `json:"current"`
+	Target   float64 `json:"target"`
+	Met      bool    `json:"met"`
+	Negated  bool    `json:"negated,omitempty"` // Inside a "not": the rule needs this condition to fail
+}
+
+// evaluate reports whether the user meets a rule and how close they are
+func (e *ruleEvaluator) evaluate(rule *model.AchievementRule) (*RuleProgress, error) {
+	if err := rule.Validate(); err != nil {
+		return nil, err
+	}
+	root, err := rule.Root()
+	if err != nil {
+		return nil, err
+	}
+
+	progress := &RuleProgress{Conditions: []ConditionProgress{}}
+	met, fraction, err := e.evalNode(root, false, progress)
+	if err != nil {
+		return nil, err
+	}
+	progress.Met = met
+	progress.Progress = fraction
+	return progress, nil
+}
+
+// evalNode evaluates a validated node and collects its conditions. The fraction of
+// an "all" group is the mean of its children, of an "any" group the best child,
+// and of a "not" either 0 or 1.
+func (e *ruleEvaluator) evalNode(n *model.RuleNode, negated bool, progress *RuleProgress) (bool, float64, error) {
+	switch {
+	case n.All != nil, n.Any != nil:
+		isAll, children := n.All != nil, n.All
+		if !isAll {
+			children = n.Any
+		}
+		met, total, best := isAll, 0.0, 0.0
+		for _, child := range children {
+			childMet, fraction, err := e.evalNode(child, negated, progress)
+			if err != nil {
+				return false, 0, err
+			}
+			if isAll {
+				met = met && childMet
+			} else {
+				met = met || childMet
+			}
+			total += fraction
+			best = math.Max(best, fraction)
+		}
+		if isAll {
+			return met, capProgress(met, total/float64(len(children))), nil
+		}
+		return met, capProgress(met, best), nil
+	case n.Not != nil:
+		childMet, _, err := e.evalNode(n.Not, !negated, progress)
+		if err != nil || childMet {
+			return false, 0, err
+		}
+		return true, 1, nil
+	default:
+		m := n.RuleMetric
+		value, err := e.metricValue(m)
+		if err != nil {
+			return false, 0, err
+		}
+		met := compareRuleValue(value, m.Operator, m.Value)
+		window := m.Window
+		if window == "" {
+			window = model.RuleWindowLifetime
+		}
+		progress.Conditions = append(progress.Conditions, ConditionProgress{
+			Source: m.Source, Field: m.Field, Agg: m.Agg, Window: window, Days: m.Days,
+			Operator: m.Operator, Current: value, Target: m.Value, Met: met, Negated: negated,
+		})
+		return met, metricProgress(met, value, m.Operator, m.Value), nil
+	}
+}
+
+// metricProgress estimates how far a value is towards a threshold. Only lower
+// bounds can be approached; other comparisons are either met or not.
+func metricProgress(met bool, value float64, operator string, target float64) float64 {
+	if met {
+		return 1
+	}
+	if (operator != ">=" && operator != ">") || target <= 0 {
+		return 0
+	}
+	return capProgress(false, math.Max(0, value/target))
+}
+
+// capProgress bounds a fraction to 0-1, below 1 while the rule is not met
+func capProgress(met bool, fraction float64) float64 {
+	if met {
+		return 1
+	}
+	return math.Min(fraction, unmetProgressCap)
+}
+
+// metricValue computes the aggregate a metric compares; aggregates over no rows are 0
+func (e *ruleEvaluator) metricValue(m *model.RuleMetric) (float64, error) {
+	// The threshold does not change the aggregate, so it is left out of the cache key
+	keyMetric := *m
+	keyMetric.Operator, keyMetric.Value = "", 0
+	keyJSON, _ := json.Marshal(keyMetric)
+	key := string(keyJSON)
+	if e.seasonStart != nil {
+		key += "|from " + e.seasonStart.String()
+	}
+	if e.seasonEnd != nil {
+		key += "|until " + e.seasonEnd.String()
+	}
+	if value, ok := e.cache[key]; ok {
+		return value, nil
+	}
+
+	var table, timeColumn string
+	switch m.Source {
+	case model.RuleSourceAttempts:
+		table = "user_attempts"
+	case model.RuleSourceEvents:
+		table, timeColumn = "events", "events.created_at"
+	case model.RuleSourceProgress:
+		table, timeColumn = "user_progresses", "user_progresses.updated_at"
+	default:
+		return 0, fmt.Errorf("%w: unknown source %q", model.ErrInvalidRule, m.Source)
+	}
+
+	query := e.db.Table(table).Where(table+".user_id = ?", e.userID)
+
+	if start, ok := e.windowStart(m); ok {
+		if m.Source == model.RuleSourceAttempts {
+			query = query.Where("user_attempts.stat_date >= ?", start.Format("2006-01-02"))
+		} else {
+			query = query.Where(timeColumn+" >= ?", start)
+		}
+	}
+
+	// Daily statistics count for every day the season covers
+	if e.seasonStart != nil {
+		if m.Source == model.RuleSourceAttempts {
+			query = query.Where("user_attempts.stat_date >= ?", e.seasonStart.In(e.now.Location()).Format("2006-01-02"))
+		} else {
+			query = query.Where(timeColumn+" >= ?", e.seasonStart.In(e.now.Location()))
+		}
+	}
+	if e.seasonEnd != nil {
+		if m.Source == model.RuleSourceAttempts {
+			lastDay := e.seasonEnd.Add(-time.Nanosecond).In(e.now.Location())
+			query = query.Where("user_attempts.stat_date <= ?", lastDay.Format("2006-01-02"))
+		} else {
+			query = query.Where(timeColumn+" < ?", e.seasonEnd.In(e.now.Location()))
+		}
+	}
+
+	// Level progress is only known as it is now; a level replayed since would
+	// otherwise drop out
+	if e.historical {
+		switch m.Source {
+		case model.RuleSourceAttempts:
+			query = query.Where("user_attempts.stat_date <= ?", e.now.Format("2006-01-02"))
+		case model.RuleSourceEvents:
+			query = query.Where(timeColumn+" <= ?", e.now)
+		}
+	}
+
+	if f := m.Filter; f != nil {
+		if f.EventType != "" {
+			query = query.Where("events.event_type = ?", f.EventType)
+		}
+		if f.LevelID != "" {
+			query = query.Where(table+".level_id = ?", f.LevelID)
+		}
+		if f.SubjectID != "" {
+			levels := e.db.Table("levels").Select("levels.id").
+				Joins("JOIN papers ON papers.id = levels.paper_id").
+				Where("papers.subject_id = ?", f.SubjectID)
+			query = query.Where(table+".level_id IN (?)", levels)
+		}
+		if f.Passed {
+			query = query.Where("user_progresses.status = ?", model.ProgressCompleted)
+		}
+		if f.MinStars > 0 {
+			query = query.Where("user_progresses.stars >= ?", f.MinStars)
+		}
+	}
+
+	// Field names are checked against a whitelist by Validate
+	column := table + "." + m.Field
+	var expr string
+	switch m.Agg {
+	case model.RuleAggCount:
+		expr = "COUNT(*)"
+	case model.RuleAggDistinct:
+		expr = "COUNT(DISTINCT " + column + ")"
+	case model.RuleAggSum:
+		expr = "SUM(" + column + ")"
+	case model.RuleAggMax:
+		expr = "MAX(" + column + ")"
+	default:
+		return 0, fmt.Errorf("%w: unknown aggregation %q", model.ErrInvalidRule, m.Agg)
+	}
+
+	var value sql.NullFloat64
+	if err := query.Select(expr).Row().Scan(&value); err != nil {
+		return 0, fmt.Errorf("failed to aggregate %s: %w", m.Source, err)
+	}
+
+	e.cache[key] = value.Float64
+	return value.Float64, nil
+}
+
+// windowStart returns the start of a metric's window; lifetime windows have none
+func (e *ruleEvaluator) windowStart(m *model.RuleMetric) (time.Time, bool) {
+	today := time.Date(e.now.Year(), e.now.Month(), e.now.Day(), 0, 0, 0, 0, e.now.Location())
+	switch m.Window {
+	case model.RuleWindowToday:
+		return today, true
+	case model.RuleWindowRolling:
+		return today.AddDate(0, 0, -(m.Days - 1)), true
+	default:
+		return time.Time{}, false
+	}
+}
+
+// compareRuleValue compares an aggregate with a threshold
+func compareRuleValue(actual float64, operator string, expected float64) bool {
+	switch operator {
+	case ">=":
+		return actual >= expected
+	case ">":
+		return actual > expected
+	case "<=":
+		return actual <= expected
+	case "<":
+		return actual < expected
+	case "=", "==":
+		return actual == expected
+	case "!=":
+		return actual != expected
+	default:
+		return false
+	}
+}
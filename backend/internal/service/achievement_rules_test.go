@@ -0,0 +1,235 @@
+package service
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"paperplay/internal/model"
+)
+
+func setupRuleTest(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&model.User{}, &model.Subject{}, &model.Paper{}, &model.Level{},
+		&model.UserProgress{}, &model.UserAttempts{}, &model.Event{},
+	))
+	return db
+}
+
+func parseRule(t *testing.T, ruleJSON string) *model.AchievementRule {
+	a := &model.Achievement{RuleJSON: ruleJSON}
+	rule, err := a.GetRule()
+	require.NoError(t, err)
+	return rule
+}
+
+func TestAchievementRule_Validate(t *testing.T) {
+	valid := []string{
+		`{"match":{"source":"attempts","field":"total_time_ms","agg":"sum","window":"rolling","days":7,"op":">=","value":3600000}}`,
+		`{"match":{"any":[{"source":"events","agg":"count","filter":{"event_type":"level_completed"},"op":">=","value":5},{"not":{"source":"progress","field":"level_id","agg":"distinct","op":"<","value":3}}]}}`,
+		`{"match":{"source":"progress","agg":"count","filter":{"subject_id":"s-1","passed":true,"min_stars":3},"op":">=","value":1}}`,
+		`{"type":"memory","conditions":[{"field":"retention_score","operator":">=","value":0.85}]}`,
+	}
+	for _, ruleJSON := range valid {
+		assert.NoError(t, parseRule(t, ruleJSON).Validate(), ruleJSON)
+	}
+
+	invalid := []string{
+		`{"match":{}}`,
+		`{"match":{"all":[]}}`,
+		`{"match":{"all":[{"source":"attempts","agg":"count","op":">=","value":1}],"source":"attempts","agg":"count","op":">=","value":1}}`,
+		`{"match":{"source":"answers","agg":"count","op":">=","value":1}}`,
+		`{"match":{"source":"attempts","field":"password_hash","agg":"max","op":">=","value":1}}`,
+		`{"match":{"source":"events","field":"event_type","agg":"sum","op":">=","value":1}}`,
+		`{"match":{"source":"attempts","agg":"avg","field":"correct_rate","op":">=","value":1}}`,
+		`{"match":{"source":"attempts","agg":"count","window":"rolling","op":">=","value":1}}`,
+		`{"match":{"source":"attempts","agg":"count","filter":{"subject_id":"s-1"},"op":">=","value":1}}`,
+		`{"match":{"source":"events","agg":"count","filter":{"passed":true},"op":">=","value":1}}`,
+		`{"match":{"source":"events","agg":"count","op":"~","value":1}}`,
+		`{"type":"unknown","conditions":[]}`,
+	}
+	for _, ruleJSON := range invalid {
+		assert.Error(t, parseRule(t, ruleJSON).Validate(), ruleJSON)
+	}
+}
+
+func TestRuleEvaluator_WindowsAndAggregations(t *testing.T) {
+	db := setupRuleTest(t)
+	now := time.Now()
+	day := func(offset int) string { return now.AddDate(0, 0, offset).Format("2006-01-02") }
+
+	for offset, minutes := range map[int]int{0: 20, -3: 30, -10: 50} {
+		require.NoError(t, db.Create(&model.UserAttempts{
+			UserID: "u-1", StatDate: day(offset), AttemptsTotal: 5, TotalTimeMs: minutes * 60000, StreakDays: -offset + 1,
+		}).Error)
+	}
+	for i := 0; i < 3; i++ {
+		require.NoError(t, db.Create(&model.Event{UserID: "u-1", EventType: model.EventLevelCompleted}).Error)
+	}
+	old := &model.Event{UserID: "u-1", EventType: model.EventLevelCompleted}
+	require.NoError(t, db.Create(old).Error)
+	require.NoError(t, db.Model(old).UpdateColumn("created_at", now.AddDate(0, 0, -30)).Error)
+	require.NoError(t, db.Create(&model.Event{UserID: "u-1", EventType: model.EventLevelFailed}).Error)
+
+	cases := []struct {
+		rule string
+		want bool
+	}{
+		{`{"source":"attempts","field":"total_time_ms","agg":"sum","window":"today","op":"=","value":1200000}`, true},
+		{`{"source":"attempts","field":"total_time_ms","agg":"sum","window":"rolling","days":7,"op":"=","value":3000000}`, true},
+		{`{"source":"attempts","field":"total_time_ms","agg":"sum","op":"=","value":6000000}`, true},
+		{`{"source":"attempts","field":"streak_days","agg":"max","op":">=","value":11}`, true},
+		{`{"source":"attempts","field":"stat_date","agg":"distinct","window":"rolling","days":4,"op":"=","value":2}`, true},
+		{`{"source":"events","agg":"count","filter":{"event_type":"level_completed"},"op":"=","value":4}`, true},
+		{`{"source":"events","agg":"count","filter":{"event_type":"level_completed"},"window":"rolling","days":7,"op":"=","value":3}`, true},
+		{`{"source":"events","field":"event_type","agg":"distinct","op":"=","value":2}`, true},
+		{`{"any":[{"source":"events","agg":"count","filter":{"event_type":"session_started"},"op":">=","value":1},{"source":"attempts","agg":"count","op":"=","value":3}]}`, true},
+		{`{"all":[{"source":"attempts","agg":"count","op":"=","value":3},{"not":{"source":"events","agg":"count","filter":{"event_type":"level_failed"},"window":"today","op":">","value":0}}]}`, false},
+		{`{"source":"attempts","field":"total_time_ms","agg":"max","window":"today","op":">","value":1200000}`, false},
+	}
+	for _, c := range cases {
+		rule := parseRule(t, `{"match":`+c.rule+`}`)
+		progress, err := newRuleEvaluator(db, "u-1", now).evaluate(rule)
+		require.NoError(t, err, c.rule)
+		assert.Equal(t, c.want, progress.Met, c.rule)
+	}
+
+	// Another user's rows are never counted
+	progress, err := newRuleEvaluator(db, "u-2", now).evaluate(parseRule(t, `{"match":{"source":"attempts","agg":"count","op":">=","value":1}}`))
+	require.NoError(t, err)
+	assert.False(t, progress.Met)
+}
+
+func TestRuleEvaluator_ProgressBySubject(t *testing.T) {
+	db := setupRuleTest(t)
+
+	for _, subject := range []string{"physics", "biology"} {
+		require.NoError(t, db.Create(&model.Subject{ID: subject, Name: subject}).Error)
+		for i, stars := range []int{3, 3, 1} {
+			paperID := subject + "-paper-" + string(rune('a'+i))
+			levelID := subject + "-level-" + string(rune('a'+i))
+			require.NoError(t, db.Create(&model.Paper{ID: paperID, SubjectID: subject, Title: paperID, PaperAuthor: "A", PaperPubYM: "2020-01", PaperCitationCount: "0"}).Error)
+			require.NoError(t, db.Create(&model.Level{ID: levelID, PaperID: paperID, Name: levelID, PassCondition: "{}"}).Error)
+			status := model.ProgressCompleted
+			if subject == "biology" {
+				status = model.ProgressInProgress
+			}
+			require.NoError(t, db.Create(&model.UserProgress{UserID: "u-1", LevelID: levelID, Status: status, Stars: stars}).Error)
+		}
+	}
+
+	rule := func(filter string, value int) *model.AchievementRule {
+		return parseRule(t, `{"match":{"source":"progress","agg":"count","filter":`+filter+`,"op":">=","value":`+jsonNumber(value)+`}}`)
+	}
+	eval := newRuleEvaluator(db, "u-1", time.Now())
+
+	progress, err := eval.evaluate(rule(`{"subject_id":"physics","passed":true}`, 3))
+	require.NoError(t, err)
+	assert.True(t, progress.Met)
+
+	progress, err = eval.evaluate(rule(`{"subject_id":"biology","passed":true}`, 1))
+	require.NoError(t, err)
+	assert.False(t, progress.Met)
+
+	progress, err = eval.evaluate(rule(`{"min_stars":3}`, 4))
+	require.NoError(t, err)
+	assert.True(t, progress.Met)
+
+	progress, err = eval.evaluate(rule(`{"passed":true,"min_stars":3}`, 3))
+	require.NoError(t, err)
+	assert.False(t, progress.Met)
+
+	// Progress is the current state, so levels replayed after a past time still count
+	progress, err = newHistoricalRuleEvaluator(db, "u-1", time.Now().AddDate(0, 0, -7)).
+		evaluate(rule(`{"subject_id":"physics","passed":true}`, 3))
+	require.NoError(t, err)
+	assert.True(t, progress.Met)
+}
+
+func TestRuleEvaluator_LegacyRules(t *testing.T) {
+	db := setupRuleTest(t)
+	now := time.Now()
+	memory := parseRule(t, `{"type":"memory","conditions":[{"field":"retention_score","operator":">=","value":0.85},{"field":"review_due_count","operator":"=","value":0}]}`)
+	speed := parseRule(t, `{"type":"speed_accuracy","conditions":[{"field":"avg_duration_ms","operator":"<=","value":30000},{"field":"correct_rate","operator":">=","value":0.9}]}`)
+
+	// Without a row for today, legacy rules fail even when zero would match
+	progress, err := newRuleEvaluator(db, "u-1", now).evaluate(memory)
+	require.NoError(t, err)
+	assert.False(t, progress.Met)
+
+	require.NoError(t, db.Create(&model.UserAttempts{
+		UserID: "u-1", StatDate: now.Format("2006-01-02"), AttemptsTotal: 5, CorrectRate: 1, AvgDurationMs: 1000, RetentionScore: 0.9,
+	}).Error)
+	progress, err = newRuleEvaluator(db, "u-1", now).evaluate(memory)
+	require.NoError(t, err)
+	assert.True(t, progress.Met)
+
+	// Speed & accuracy still needs ten attempts
+	progress, err = newRuleEvaluator(db, "u-1", now).evaluate(speed)
+	require.NoError(t, err)
+	assert.False(t, progress.Met)
+}
+
+func jsonNumber(n int) string {
+	data, _ := json.Marshal(n)
+	return string(data)
+}
+
+func TestEvaluateUserAchievements_ProgressMilestones(t *testing.T) {
+	db := setupRuleTest(t)
+	require.NoError(t, db.AutoMigrate(&model.Achievement{}, &model.UserAchievement{}, &model.UserAchievementProgress{}, &model.AchievementAuditLog{}, &model.AchievementStats{}))
+	require.NoError(t, db.Create(&model.Achievement{
+		ID: "ten-levels", Name: "Ten levels", Description: "Complete ten levels", BadgeType: "learning", IsActive: true,
+		RuleJSON: `{"match":{"source":"events","agg":"count","filter":{"event_type":"level_completed"},"op":">=","value":10}}`,
+	}).Error)
+	achievementService := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	complete := func(n int) {
+		for i := 0; i < n; i++ {
+			require.NoError(t, db.Create(&model.Event{UserID: "u-1", EventType: model.EventLevelCompleted}).Error)
+		}
+	}
+	milestone := func() int {
+		var mark model.UserAchievementProgress
+		if err := db.Where("user_id = ? AND achievement_id = ?", "u-1", "ten-levels").First(&mark).Error; err != nil {
+			return 0
+		}
+		return mark.Milestone
+	}
+
+	complete(4)
+	require.NoError(t, achievementService.EvaluateUserAchievements("u-1"))
+	assert.Equal(t, 0, milestone())
+
+	complete(2)
+	require.NoError(t, achievementService.EvaluateUserAchievements("u-1"))
+	assert.Equal(t, 50, milestone())
+
+	complete(3)
+	require.NoError(t, achievementService.EvaluateUserAchievements("u-1"))
+	assert.Equal(t, 90, milestone())
+
+	progress, err := achievementService.GetAchievementProgress("u-1")
+	require.NoError(t, err)
+	require.Len(t, progress, 1)
+	assert.False(t, progress[0].Earned)
+	assert.InDelta(t, 0.9, progress[0].Progress, 0.001)
+	assert.Equal(t, 9.0, progress[0].Conditions[0].Current)
+
+	// Earning the achievement clears its milestone
+	complete(1)
+	require.NoError(t, achievementService.EvaluateUserAchievements("u-1"))
+	assert.Equal(t, 0, milestone())
+	progress, err = achievementService.GetAchievementProgress("u-1")
+	require.NoError(t, err)
+	assert.True(t, progress[0].Earned)
+	assert.Equal(t, 1.0, progress[0].Progress)
+}
@@ -0,0 +1,98 @@
+package service
+
+import (
+	"fmt"
+	"paperplay/internal/model"
+	"paperplay/internal/websocket"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// SeasonTransition is a seasonal achievement whose season started or ended
+type SeasonTransition struct {
+	Achievement model.Achievement
+	From        string // Season state before, empty if never seen
+	To          string // model.SeasonOpen or model.SeasonEnded
+}
+
+// Notification is the announcement of the transition to every connected user
+func (t *SeasonTransition) Notification() *websocket.NotificationMessage {
+	notification := &websocket.NotificationMessage{
+		ID:          t.Achievement.ID,
+		Achievement: notificationAchievement(&t.Achievement),
+	}
+	if t.To == model.SeasonOpen {
+		notification.Type = "achievement_season_started"
+		notification.Title = "限时成就开启！"
+		notification.Message = fmt.Sprintf("限时成就「%s」现已开启", t.Achievement.Name)
+		if t.Achievement.EndsAt != nil {
+			notification.Message += fmt.Sprintf("，截止 %s", t.Achievement.EndsAt.Local().Format("2006-01-02 15:04"))
+		}
+	} else {
+		notification.Type = "achievement_season_ended"
+		notification.Title = "限时成就已结束"
+		notification.Message = fmt.Sprintf("限时成就「%s」已结束，获得者将永久保留此限定徽章", t.Achievement.Name)
+	}
+	return notification
+}
+
+// AdvanceSeasons records the season state of every active seasonal achievement as
+// of now, and returns the seasons that started or ended since it was last called.
+// Each transition is returned once, even if several instances run the cron job.
+// A season that ends without having been seen open is recorded silently. After an
+// error, the transitions recorded so far are returned with it.
+func (s *AchievementService) AdvanceSeasons(now time.Time) ([]SeasonTransition, error) {
+	var achievements []model.Achievement
+	if err := s.db.Where("is_active = ? AND (starts_at IS NOT NULL OR ends_at IS NOT NULL)", true).
+		Find(&achievements).Error; err != nil {
+		return nil, fmt.Errorf("failed to get seasonal achievements: %w", err)
+	}
+
+	var transitions []SeasonTransition
+	for _, achievement := range achievements {
+		from, to := achievement.SeasonState, achievement.SeasonStateAt(now)
+		if from == to {
+			continue
+		}
+
+		claimed := false
+		if err := s.db.Transaction(func(tx *gorm.DB) error {
+			result := tx.Model(&model.Achievement{}).
+				Where("id = ? AND COALESCE(season_state, '') = ?", achievement.ID, from).
+				Update("season_state", to)
+			if result.Error != nil {
+				return fmt.Errorf("failed to update season state: %w", result.Error)
+			}
+			claimed = result.RowsAffected > 0
+
+			// Milestones towards an achievement that can no longer be earned are moot
+			if claimed && to == model.SeasonEnded {
+				if err := tx.Where("achievement_id = ?", achievement.ID).
+					Delete(&model.UserAchievementProgress{}).Error; err != nil {
+					return fmt.Errorf("failed to clear achievement progress: %w", err)
+				}
+			}
+			return nil
+		}); err != nil {
+			return transitions, err
+		}
+		if !claimed {
+			continue // Another instance got there first
+		}
+
+		s.logger.Info("Achievement season changed",
+			zap.String("achievement_id", achievement.ID),
+			zap.String("from", from),
+			zap.String("to", to),
+		)
+
+		if to == model.SeasonOpen || (to == model.SeasonEnded && from == model.SeasonOpen) {
+			achievement.SeasonState = to
+			transitions = append(transitions, SeasonTransition{Achievement: achievement, From: from, To: to})
+		}
+	}
+
+	return transitions, nil
+}
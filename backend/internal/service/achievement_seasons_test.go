@@ -0,0 +1,120 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"paperplay/internal/model"
+)
+
+func TestAchievementSeasons_WindowedEvaluation(t *testing.T) {
+	db := setupEventBusTest(t)
+	seedBackfillUsers(t, db, 2)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	// "twelve" runs from three days ago to in two days; "graduation" starts tomorrow
+	// and "conference" ended yesterday, both with the same rule
+	now := time.Now()
+	season := func(id string, startDays, endDays int) {
+		start, end := now.AddDate(0, 0, startDays), now.AddDate(0, 0, endDays)
+		require.NoError(t, db.Model(&model.Achievement{}).Where("id = ?", id).
+			Updates(map[string]any{"starts_at": start, "ends_at": end}).Error)
+	}
+	for _, id := range []string{"graduation", "conference"} {
+		require.NoError(t, db.Create(&model.Achievement{
+			ID: id, Name: id, Description: "Seasonal", BadgeType: "learning", RuleJSON: twelveAnswersRule, IsActive: true,
+		}).Error)
+	}
+	season("twelve", -3, 2)
+	season("graduation", 1, 8)
+	season("conference", -10, -1)
+
+	// u-1 answered most questions before the season; u-2 within it
+	addAttempts(t, db, "u-1", 5, 8)
+	addAttempts(t, db, "u-1", 0, 6)
+	addAttempts(t, db, "u-2", 2, 12)
+
+	require.NoError(t, s.EvaluateUserAchievements("u-1"))
+	require.NoError(t, s.EvaluateUserAchievements("u-2"))
+
+	var earned []string
+	require.NoError(t, db.Model(&model.UserAchievement{}).Order("user_id").Pluck("user_id || ':' || achievement_id", &earned).Error)
+	assert.Equal(t, []string{"u-2:twelve"}, earned)
+
+	progress, err := s.GetAchievementProgress("u-1")
+	require.NoError(t, err)
+	require.Len(t, progress, 1, "upcoming and ended seasons are left out")
+	assert.Equal(t, "twelve", progress[0].Achievement.ID)
+	assert.Equal(t, float64(6), progress[0].Conditions[0].Current)
+
+	// Upcoming seasons are hidden; ended ones stay listed as limited editions
+	achievements, err := s.GetAllAchievements()
+	require.NoError(t, err)
+	listed := make(map[string]bool)
+	for _, achievement := range achievements {
+		listed[achievement.ID] = achievement.IsLimitedEdition(now)
+	}
+	assert.Equal(t, map[string]bool{"twelve": false, "conference": true}, listed)
+}
+
+func TestAchievementSeasons_AdvanceSeasons(t *testing.T) {
+	db := setupEventBusTest(t)
+	seedBackfillUsers(t, db, 1)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	now := time.Now()
+	start, end := now.Add(-time.Hour), now.Add(time.Hour)
+	require.NoError(t, db.Model(&model.Achievement{}).Where("id = ?", "twelve").
+		Updates(map[string]any{"starts_at": start, "ends_at": end}).Error)
+	require.NoError(t, db.Create(&model.Achievement{
+		ID: "upcoming", Name: "Upcoming", Description: "Seasonal", BadgeType: "learning", RuleJSON: twelveAnswersRule,
+		IsActive: true, StartsAt: &end,
+	}).Error)
+
+	transitions, err := s.AdvanceSeasons(now)
+	require.NoError(t, err)
+	require.Len(t, transitions, 1)
+	assert.Equal(t, "twelve", transitions[0].Achievement.ID)
+	assert.Equal(t, model.SeasonOpen, transitions[0].To)
+	assert.Equal(t, "achievement_season_started", transitions[0].Notification().Type)
+
+	// Each transition is announced once
+	transitions, err = s.AdvanceSeasons(now)
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+
+	// At the end of the season the upcoming one opens and milestones are cleared
+	require.NoError(t, db.Create(&model.UserAchievementProgress{UserID: "u-1", AchievementID: "twelve", Milestone: 50}).Error)
+	transitions, err = s.AdvanceSeasons(end)
+	require.NoError(t, err)
+	states := make(map[string]string)
+	for _, transition := range transitions {
+		states[transition.Achievement.ID] = transition.Notification().Type
+	}
+	assert.Equal(t, map[string]string{
+		"twelve":   "achievement_season_ended",
+		"upcoming": "achievement_season_started",
+	}, states)
+
+	var milestones int64
+	db.Model(&model.UserAchievementProgress{}).Count(&milestones)
+	assert.Zero(t, milestones)
+
+	// A season never seen open ends without an announcement
+	past := now.Add(-2 * time.Hour)
+	require.NoError(t, db.Create(&model.Achievement{
+		ID: "missed", Name: "Missed", Description: "Seasonal", BadgeType: "learning", RuleJSON: twelveAnswersRule,
+		IsActive: true, EndsAt: &past,
+	}).Error)
+	transitions, err = s.AdvanceSeasons(end)
+	require.NoError(t, err)
+	assert.Empty(t, transitions)
+
+	var missed model.Achievement
+	require.NoError(t, db.First(&missed, "id = ?", "missed").Error)
+	assert.Equal(t, model.SeasonEnded, missed.SeasonState)
+}
@@ -0,0 +1,130 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"paperplay/internal/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// nftRarityTrait is the NFT attribute holding an achievement's rarity
+const nftRarityTrait = "Rarity"
+
+// rarityActiveDays is the window in which a user must have practised to count as active
+const rarityActiveDays = 30
+
+// holderRow is one holder of an achievement, used during computation
+type holderRow struct {
+	AchievementID string
+	UserID        string
+	EarnedAt      time.Time
+	SignedUpAt    time.Time
+}
+
+// RefreshAchievementStats recomputes the statistics and rarity of every achievement
+// and returns the number of achievements updated. Rarity is the share of active users,
+// registered users who practised in the last 30 days, holding the achievement.
+func (s *AchievementService) RefreshAchievementStats() (int, error) {
+	var achievementIDs []string
+	if err := s.db.Model(&model.Achievement{}).Pluck("id", &achievementIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to get achievements: %w", err)
+	}
+
+	since := time.Now().AddDate(0, 0, -rarityActiveDays).Format("2006-01-02")
+	var activeIDs []string
+	if err := s.db.Model(&model.UserAttempts{}).
+		Distinct("user_attempts.user_id").
+		Joins("JOIN users ON users.id = user_attempts.user_id").
+		Where("user_attempts.stat_date >= ? AND users.role <> ? AND users.anonymized_at IS NULL", since, model.RoleGuest).
+		Pluck("user_attempts.user_id", &activeIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to get active users: %w", err)
+	}
+	active := make(map[string]bool, len(activeIDs))
+	for _, id := range activeIDs {
+		active[id] = true
+	}
+
+	var holders []holderRow
+	if err := s.db.Table("user_achievements").
+		Select("user_achievements.achievement_id, user_achievements.user_id, user_achievements.earned_at, users.created_at AS signed_up_at").
+		Joins("JOIN users ON users.id = user_achievements.user_id").
+		Where("user_achievements.revoked_at IS NULL").
+		Order("user_achievements.earned_at ASC").
+		Scan(&holders).Error; err != nil {
+		return 0, fmt.Errorf("failed to get achievement holders: %w", err)
+	}
+	byAchievement := make(map[string][]holderRow)
+	for _, h := range holders {
+		byAchievement[h.AchievementID] = append(byAchievement[h.AchievementID], h)
+	}
+
+	now := time.Now()
+	for _, id := range achievementIDs {
+		stats := computeAchievementStats(id, byAchievement[id], active)
+		stats.ComputedAt = now
+		if err := s.db.Save(stats).Error; err != nil {
+			return 0, fmt.Errorf("failed to save achievement stats: %w", err)
+		}
+	}
+
+	return len(achievementIDs), nil
+}
+
+// computeAchievementStats aggregates the holders of an achievement, earliest first
+func computeAchievementStats(achievementID string, holders []holderRow, active map[string]bool) *model.AchievementStats {
+	stats := &model.AchievementStats{
+		AchievementID: achievementID,
+		HolderCount:   len(holders),
+		ActiveUsers:   len(active),
+	}
+
+	days := make([]int, 0, len(holders))
+	for _, h := range holders {
+		if active[h.UserID] {
+			stats.ActiveHolders++
+		}
+		days = append(days, int(math.Max(0, h.EarnedAt.Sub(h.SignedUpAt).Hours()/24)))
+	}
+
+	if len(holders) > 0 {
+		first := holders[0]
+		stats.FirstEarnerID = &first.UserID
+		stats.FirstEarnedAt = &first.EarnedAt
+		medianDays := median(days)
+		stats.MedianDaysToEarn = &medianDays
+	}
+
+	if stats.ActiveUsers > 0 {
+		stats.HolderPercent = math.Round(float64(stats.ActiveHolders)/float64(stats.ActiveUsers)*10000) / 100
+		stats.Rarity = model.RarityFor(stats.HolderPercent)
+	}
+
+	return stats
+}
+
+// nftAttributes returns the NFT attributes of an achievement: those of its metadata
+// template, and its rarity when computed unless the template sets one itself
+func nftAttributes(db *gorm.DB, achievement *model.Achievement) ([]model.NFTAttribute, error) {
+	metadata, err := achievement.GetNFTMetadata()
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse NFT metadata: %w", err)
+	}
+	attributes := metadata.Attributes
+	for _, attribute := range attributes {
+		if strings.EqualFold(attribute.TraitType, nftRarityTrait) {
+			return attributes, nil
+		}
+	}
+
+	var stats model.AchievementStats
+	if err := db.Where("achievement_id = ?", achievement.ID).Limit(1).Find(&stats).Error; err != nil {
+		return nil, fmt.Errorf("failed to get achievement stats: %w", err)
+	}
+	if stats.Rarity != "" {
+		attributes = append(attributes, model.NFTAttribute{TraitType: nftRarityTrait, Value: stats.Rarity})
+	}
+	return attributes, nil
+}
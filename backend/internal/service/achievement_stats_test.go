@@ -0,0 +1,83 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"paperplay/internal/model"
+)
+
+func TestAchievementStats_Refresh(t *testing.T) {
+	db := setupEventBusTest(t)
+	seedBackfillUsers(t, db, 4)
+	s := NewAchievementService(db, zap.NewNop(), nil, nil)
+	require.NoError(t, db.Create(&model.Achievement{
+		ID: "nobody", Name: "Nobody", Description: "Never earned", BadgeType: "learning", RuleJSON: twelveAnswersRule,
+	}).Error)
+
+	// u-1 to u-3 practised recently, u-4 long ago; the guest does not count
+	require.NoError(t, db.Create(&model.User{ID: "guest", Email: "guest@example.com", DisplayName: "guest", Role: model.RoleGuest}).Error)
+	for _, id := range []string{"u-1", "u-2", "u-3", "guest"} {
+		addAttempts(t, db, id, 1, 3)
+	}
+	addAttempts(t, db, "u-4", 40, 3)
+
+	// u-4 earned it first, 10 days after signing up; u-1 8 days after. u-2's award was revoked.
+	now := time.Now()
+	earn := func(userID string, signedUp, earned int) {
+		require.NoError(t, db.Model(&model.User{}).Where("id = ?", userID).
+			Update("created_at", now.AddDate(0, 0, -signedUp)).Error)
+		require.NoError(t, db.Create(&model.UserAchievement{UserID: userID, AchievementID: "twelve", EarnedAt: now.AddDate(0, 0, -earned)}).Error)
+	}
+	earn("u-4", 30, 20)
+	earn("u-1", 10, 2)
+	earn("u-2", 5, 1)
+	require.NoError(t, db.Model(&model.UserAchievement{}).Where("user_id = ?", "u-2").Update("revoked_at", now).Error)
+
+	count, err := s.RefreshAchievementStats()
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+
+	var stats model.AchievementStats
+	require.NoError(t, db.First(&stats, "achievement_id = ?", "twelve").Error)
+	assert.Equal(t, 2, stats.HolderCount)
+	assert.Equal(t, 3, stats.ActiveUsers)
+	assert.Equal(t, 1, stats.ActiveHolders)
+	assert.Equal(t, 33.33, stats.HolderPercent)
+	assert.Equal(t, model.RarityUncommon, stats.Rarity)
+	assert.Equal(t, "u-4", *stats.FirstEarnerID)
+	assert.Equal(t, 9, *stats.MedianDaysToEarn)
+
+	var unearned model.AchievementStats
+	require.NoError(t, db.First(&unearned, "achievement_id = ?", "nobody").Error)
+	assert.Zero(t, unearned.HolderCount)
+	assert.Equal(t, model.RarityLegendary, unearned.Rarity)
+	assert.Nil(t, unearned.FirstEarnerID)
+	assert.Nil(t, unearned.MedianDaysToEarn)
+
+	// Rarity is added to NFT attributes unless the template sets it
+	var twelve model.Achievement
+	require.NoError(t, db.First(&twelve, "id = ?", "twelve").Error)
+	attributes, err := nftAttributes(db, &twelve)
+	require.NoError(t, err)
+	assert.Equal(t, []model.NFTAttribute{{TraitType: "Rarity", Value: model.RarityUncommon}}, attributes)
+
+	require.NoError(t, twelve.SetNFTMetadata(&model.NFTMetadataTemplate{
+		Attributes: []model.NFTAttribute{{TraitType: "rarity", Value: "mythic"}},
+	}))
+	attributes, err = nftAttributes(db, &twelve)
+	require.NoError(t, err)
+	assert.Equal(t, []model.NFTAttribute{{TraitType: "rarity", Value: "mythic"}}, attributes)
+}
+
+func TestRarityFor(t *testing.T) {
+	assert.Equal(t, model.RarityCommon, model.RarityFor(50))
+	assert.Equal(t, model.RarityUncommon, model.RarityFor(20))
+	assert.Equal(t, model.RarityRare, model.RarityFor(19.99))
+	assert.Equal(t, model.RarityEpic, model.RarityFor(1))
+	assert.Equal(t, model.RarityLegendary, model.RarityFor(0.5))
+}
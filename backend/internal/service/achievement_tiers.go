@@ -0,0 +1,162 @@
+package service
+
+import (
+	"fmt"
+	"paperplay/internal/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// tierUpgrade describes the lower tiers a newly earned tier superseded
+type tierUpgrade struct {
+	from           *model.Achievement // Highest tier held before
+	achievementIDs []string           // Every superseded tier
+}
+
+// previousTiers maps each tiered achievement to the tier below it in its series.
+// Inactive tiers are included: a retired tier still has to have been earned.
+func (s *AchievementService) previousTiers() (map[string]string, error) {
+	var tiered []model.Achievement
+	if err := s.db.Select("id", "series_id", "tier").
+		Where("series_id IS NOT NULL AND tier > 0").
+		Find(&tiered).Error; err != nil {
+		return nil, fmt.Errorf("failed to get achievement tiers: %w", err)
+	}
+
+	byTier := make(map[string]string, len(tiered))
+	for _, a := range tiered {
+		byTier[fmt.Sprintf("%s/%d", *a.SeriesID, a.Tier)] = a.ID
+	}
+
+	previous := make(map[string]string)
+	for _, a := range tiered {
+		if id, ok := byTier[fmt.Sprintf("%s/%d", *a.SeriesID, a.Tier-1)]; ok {
+			previous[a.ID] = id
+		}
+	}
+	return previous, nil
+}
+
+// tierUnlocked reports whether the tier below an achievement, if any, has been earned
+func tierUnlocked(achievementID string, previous map[string]string, earned map[string]bool) bool {
+	below, ok := previous[achievementID]
+	return !ok || earned[below]
+}
+
+// supersedeLowerTiers marks the user's lower tiers of the series as superseded by a
+// newly earned tier. It returns nil if the user held no lower tier.
+func supersedeLowerTiers(tx *gorm.DB, userID string, achievement *model.Achievement, earned *model.UserAchievement) (*tierUpgrade, error) {
+	var lower []model.Achievement
+	if err := tx.Where("series_id = ? AND tier < ?", *achievement.SeriesID, achievement.Tier).
+		Order("tier DESC").Find(&lower).Error; err != nil {
+		return nil, fmt.Errorf("failed to get lower tiers: %w", err)
+	}
+	if len(lower) == 0 {
+		return nil, nil
+	}
+
+	ids := make([]string, 0, len(lower))
+	for _, a := range lower {
+		ids = append(ids, a.ID)
+	}
+
+	var held []model.UserAchievement
+	if err := tx.Where("user_id = ? AND achievement_id IN ? AND superseded_at IS NULL AND revoked_at IS NULL", userID, ids).
+		Find(&held).Error; err != nil {
+		return nil, fmt.Errorf("failed to get earned tiers: %w", err)
+	}
+	if len(held) == 0 {
+		return nil, nil
+	}
+
+	upgrade := &tierUpgrade{}
+	heldIDs := make(map[string]bool, len(held))
+	for _, ua := range held {
+		heldIDs[ua.AchievementID] = true
+		upgrade.achievementIDs = append(upgrade.achievementIDs, ua.AchievementID)
+	}
+	for i := range lower {
+		if heldIDs[lower[i].ID] {
+			upgrade.from = &lower[i]
+			break
+		}
+	}
+
+	if err := tx.Model(&model.UserAchievement{}).
+		Where("user_id = ? AND achievement_id IN ? AND superseded_at IS NULL AND revoked_at IS NULL", userID, upgrade.achievementIDs).
+		Updates(map[string]any{"superseded_at": time.Now(), "superseded_by": earned.ID}).Error; err != nil {
+		return nil, fmt.Errorf("failed to supersede lower tiers: %w", err)
+	}
+
+	event := &model.Event{UserID: userID, EventType: model.EventAchievementUpgraded}
+	if err := event.SetData(map[string]any{
+		"series_id":           *achievement.SeriesID,
+		"from_achievement_id": upgrade.from.ID,
+		"from_tier":           upgrade.from.Tier,
+		"to_achievement_id":   achievement.ID,
+		"to_tier":             achievement.Tier,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to set event data: %w", err)
+	}
+	if err := tx.Create(event).Error; err != nil {
+		return nil, fmt.Errorf("failed to create event: %w", err)
+	}
+
+	return upgrade, nil
+}
+
+// linkUpgradedNFTs points the NFTs of superseded tiers at the NFT of the new tier
+func linkUpgradedNFTs(tx *gorm.DB, userID string, upgrade *tierUpgrade, nftAsset *model.NFTAsset) error {
+	if err := tx.Model(&model.NFTAsset{}).
+		Where("user_id = ? AND achievement_id IN ? AND upgraded_to_id IS NULL", userID, upgrade.achievementIDs).
+		Update("upgraded_to_id", nftAsset.ID).Error; err != nil {
+		return fmt.Errorf("failed to link upgraded NFTs: %w", err)
+	}
+	return nil
+}
+
+// supersedeMergedTiers restores the supersede chain after another account's achievements
+// were merged into a user's: in each series the highest tier held replaces the lower ones
+func supersedeMergedTiers(tx *gorm.DB, userID string) error {
+	var held []model.UserAchievement
+	if err := tx.Preload("Achievement").
+		Where("user_id = ? AND superseded_at IS NULL AND revoked_at IS NULL", userID).
+		Find(&held).Error; err != nil {
+		return fmt.Errorf("failed to get earned achievements: %w", err)
+	}
+
+	highest := make(map[string]*model.UserAchievement)
+	for i := range held {
+		achievement := held[i].Achievement
+		if achievement == nil || !achievement.IsTiered() {
+			continue
+		}
+		if top, ok := highest[*achievement.SeriesID]; !ok || achievement.Tier > top.Achievement.Tier {
+			highest[*achievement.SeriesID] = &held[i]
+		}
+	}
+
+	for _, top := range highest {
+		upgrade, err := supersedeLowerTiers(tx, userID, top.Achievement, top)
+		if err != nil {
+			return err
+		}
+		if upgrade == nil {
+			continue
+		}
+
+		var nftAsset model.NFTAsset
+		err = tx.Where("user_id = ? AND achievement_id = ? AND upgraded_to_id IS NULL", userID, top.AchievementID).
+			First(&nftAsset).Error
+		if err == gorm.ErrRecordNotFound {
+			continue
+		} else if err != nil {
+			return fmt.Errorf("failed to get tier NFT: %w", err)
+		}
+		if err := linkUpgradedNFTs(tx, userID, upgrade, &nftAsset); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"paperplay/internal/model"
+)
+
+// seedLevelSeries creates a three-tier series for 1, 3 and 5 completed levels
+func seedLevelSeries(t *testing.T, db *gorm.DB) []model.Achievement {
+	require.NoError(t, db.AutoMigrate(
+		&model.AchievementSeries{}, &model.Achievement{}, &model.UserAchievement{},
+		&model.UserAchievementProgress{}, &model.NFTAsset{}, &model.AchievementAuditLog{},
+		&model.AchievementStats{},
+	))
+	series := &model.AchievementSeries{ID: "levels", Name: "Level Climber"}
+	require.NoError(t, db.Create(series).Error)
+
+	var tiers []model.Achievement
+	for i, threshold := range []string{"1", "3", "5"} {
+		tier := model.Achievement{
+			ID: "levels-" + threshold, Name: "Climber " + threshold, Description: threshold + " levels",
+			Level: i + 1, BadgeType: "learning", IsActive: true, SeriesID: &series.ID, Tier: i + 1,
+			RuleJSON: `{"match":{"source":"events","agg":"count","filter":{"event_type":"level_completed"},"op":">=","value":` + threshold + `}}`,
+		}
+		require.NoError(t, db.Create(&tier).Error)
+		tiers = append(tiers, tier)
+	}
+	return tiers
+}
+
+func TestAchievementTiers_ClimbAndSupersede(t *testing.T) {
+	db := setupRuleTest(t)
+	tiers := seedLevelSeries(t, db)
+	achievementService := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.Create(&model.Event{UserID: "u-1", EventType: model.EventLevelCompleted}).Error)
+	}
+
+	// All tiers are reached in one evaluation, each one superseding the one below
+	require.NoError(t, achievementService.EvaluateUserAchievements("u-1"))
+
+	var earned []model.UserAchievement
+	require.NoError(t, db.Where("user_id = ?", "u-1").Find(&earned).Error)
+	require.Len(t, earned, 3)
+	byAchievement := make(map[string]model.UserAchievement)
+	for _, ua := range earned {
+		byAchievement[ua.AchievementID] = ua
+	}
+	gold := byAchievement[tiers[2].ID]
+	assert.False(t, gold.IsSuperseded())
+	silver := byAchievement[tiers[1].ID]
+	assert.True(t, silver.IsSuperseded())
+	assert.Equal(t, gold.ID, *silver.SupersededBy)
+	assert.Equal(t, silver.ID, *byAchievement[tiers[0].ID].SupersededBy)
+
+	var upgrades []model.Event
+	require.NoError(t, db.Where("user_id = ? AND event_type = ?", "u-1", model.EventAchievementUpgraded).
+		Order("created_at").Find(&upgrades).Error)
+	require.Len(t, upgrades, 2)
+	data, err := upgrades[1].GetData()
+	require.NoError(t, err)
+	assert.Equal(t, tiers[1].ID, data["from_achievement_id"])
+	assert.Equal(t, tiers[2].ID, data["to_achievement_id"])
+
+	// Only the highest tier is displayed
+	shown, err := achievementService.GetUserAchievements("u-1")
+	require.NoError(t, err)
+	require.Len(t, shown, 1)
+	assert.Equal(t, tiers[2].ID, shown[0].AchievementID)
+}
+
+func TestAchievementTiers_HigherTierNeedsLowerTier(t *testing.T) {
+	db := setupRuleTest(t)
+	tiers := seedLevelSeries(t, db)
+	achievementService := NewAchievementService(db, zap.NewNop(), nil, nil)
+
+	// Retiring the first tier keeps the series locked for users without it
+	require.NoError(t, db.Model(&tiers[0]).Update("is_active", false).Error)
+	for i := 0; i < 5; i++ {
+		require.NoError(t, db.Create(&model.Event{UserID: "u-1", EventType: model.EventLevelCompleted}).Error)
+	}
+	require.NoError(t, achievementService.EvaluateUserAchievements("u-1"))
+
+	var count int64
+	db.Model(&model.UserAchievement{}).Where("user_id = ?", "u-1").Count(&count)
+	assert.Zero(t, count)
+
+	progress, err := achievementService.GetAchievementProgress("u-1")
+	require.NoError(t, err)
+	require.Len(t, progress, 2)
+	for _, p := range progress {
+		assert.True(t, p.Locked, p.Achievement.ID)
+		assert.Zero(t, p.Progress)
+	}
+
+	// A force-awarded lower tier unlocks the rest
+	require.NoError(t, achievementService.ForceAwardAchievement("u-1", tiers[0].ID))
+	require.NoError(t, achievementService.EvaluateUserAchievements("u-1"))
+	db.Model(&model.UserAchievement{}).Where("user_id = ?", "u-1").Count(&count)
+	assert.Equal(t, int64(3), count)
+}
+
+func TestAchievementTiers_LinkUpgradedNFTs(t *testing.T) {
+	db := setupRuleTest(t)
+	tiers := seedLevelSeries(t, db)
+
+	bronzeNFT := &model.NFTAsset{UserID: "u-1", AchievementID: &tiers[0].ID, ContractAddress: "0x0", MetadataURI: "uri", Status: model.NFTStatusMinted}
+	require.NoError(t, db.Create(bronzeNFT).Error)
+	require.NoError(t, db.Create(&model.UserAchievement{UserID: "u-1", AchievementID: tiers[0].ID}).Error)
+
+	silver := &model.UserAchievement{UserID: "u-1", AchievementID: tiers[1].ID}
+	require.NoError(t, db.Create(silver).Error)
+	upgrade, err := supersedeLowerTiers(db, "u-1", &tiers[1], silver)
+	require.NoError(t, err)
+	require.NotNil(t, upgrade)
+	assert.Equal(t, tiers[0].ID, upgrade.from.ID)
+
+	silverNFT := &model.NFTAsset{UserID: "u-1", AchievementID: &tiers[1].ID, ContractAddress: "0x0", MetadataURI: "uri"}
+	require.NoError(t, db.Create(silverNFT).Error)
+	require.NoError(t, linkUpgradedNFTs(db, "u-1", upgrade, silverNFT))
+
+	require.NoError(t, db.First(bronzeNFT, "id = ?", bronzeNFT.ID).Error)
+	require.NotNil(t, bronzeNFT.UpgradedToID)
+	assert.Equal(t, silverNFT.ID, *bronzeNFT.UpgradedToID)
+}
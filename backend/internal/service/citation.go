@@ -0,0 +1,655 @@
+package service
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"paperplay/internal/model"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Citation formats
+const (
+	CitationFormatBibTeX = "bibtex"
+	CitationFormatRIS    = "ris"
+)
+
+// Citation errors
+var (
+	ErrUnsupportedCitationFormat = errors.New("unsupported citation format")
+	ErrCitationParse             = errors.New("failed to parse citation")
+)
+
+// IsValidCitationFormat checks if the citation format is supported
+func IsValidCitationFormat(format string) bool {
+	return format == CitationFormatBibTeX || format == CitationFormatRIS
+}
+
+// ParseCitations parses BibTeX or RIS content into unsaved papers
+func ParseCitations(format, content string) ([]*model.Paper, error) {
+	switch format {
+	case CitationFormatBibTeX:
+		return ParseBibTeX(content)
+	case CitationFormatRIS:
+		return ParseRIS(content)
+	default:
+		return nil, ErrUnsupportedCitationFormat
+	}
+}
+
+// FormatCitation renders a paper as BibTeX or RIS
+func FormatCitation(format string, paper *model.Paper) (string, error) {
+	switch format {
+	case CitationFormatBibTeX:
+		return FormatBibTeX(paper), nil
+	case CitationFormatRIS:
+		return FormatRIS(paper), nil
+	default:
+		return "", ErrUnsupportedCitationFormat
+	}
+}
+
+// bibtexMonths maps BibTeX month macros to month numbers
+var bibtexMonths = map[string]int{
+	"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+	"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+}
+
+// bibtexParser is a small recursive-descent reader for BibTeX databases
+type bibtexParser struct {
+	src     []rune
+	pos     int
+	strings map[string]string // @string macros
+}
+
+// ParseBibTeX parses a BibTeX database; @comment and @preamble entries are skipped
+func ParseBibTeX(content string) ([]*model.Paper, error) {
+	p := &bibtexParser{src: []rune(content), strings: map[string]string{}}
+	for k, v := range bibtexMonths {
+		p.strings[k] = strconv.Itoa(v)
+	}
+
+	var papers []*model.Paper
+	for {
+		// Anything outside an entry is a comment
+		for p.pos < len(p.src) && p.src[p.pos] != '@' {
+			p.pos++
+		}
+		if p.pos >= len(p.src) {
+			break
+		}
+		p.pos++
+
+		entryType := strings.ToLower(p.readIdentifier())
+		p.skipSpace()
+		if p.pos >= len(p.src) || (p.src[p.pos] != '{' && p.src[p.pos] != '(') {
+			return nil, fmt.Errorf("%w: expected '{' after @%s", ErrCitationParse, entryType)
+		}
+		open := p.src[p.pos]
+		p.pos++
+
+		switch entryType {
+		case "comment", "preamble":
+			closing := '}'
+			if open == '(' {
+				closing = ')'
+			}
+			if err := p.skipBalanced(open, closing); err != nil {
+				return nil, err
+			}
+			continue
+		case "string":
+			fields, err := p.readFields()
+			if err != nil {
+				return nil, err
+			}
+			for k, v := range fields {
+				p.strings[k] = v
+			}
+			continue
+		}
+
+		p.skipSpace()
+		key := strings.TrimSpace(p.readUntil(','))
+		if p.pos < len(p.src) {
+			p.pos++
+		}
+		fields, err := p.readFields()
+		if err != nil {
+			return nil, fmt.Errorf("%w (entry %q)", err, key)
+		}
+		papers = append(papers, paperFromBibTeX(entryType, fields))
+	}
+
+	if len(papers) == 0 {
+		return nil, fmt.Errorf("%w: no BibTeX entries found", ErrCitationParse)
+	}
+	return papers, nil
+}
+
+// readFields reads "name = value" pairs up to the closing brace of an entry
+func (p *bibtexParser) readFields() (map[string]string, error) {
+	fields := map[string]string{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return nil, fmt.Errorf("%w: unexpected end of input", ErrCitationParse)
+		}
+		switch p.src[p.pos] {
+		case '}', ')':
+			p.pos++
+			return fields, nil
+		case ',':
+			p.pos++
+			continue
+		}
+
+		name := strings.ToLower(p.readIdentifier())
+		if name == "" {
+			return nil, fmt.Errorf("%w: expected field name at offset %d", ErrCitationParse, p.pos)
+		}
+		p.skipSpace()
+		if p.pos >= len(p.src) || p.src[p.pos] != '=' {
+			return nil, fmt.Errorf("%w: expected '=' after %s", ErrCitationParse, name)
+		}
+		p.pos++
+
+		value, err := p.readValue()
+		if err != nil {
+			return nil, err
+		}
+		fields[name] = value
+	}
+}
+
+// readValue reads a field value, concatenating parts joined with '#'
+func (p *bibtexParser) readValue() (string, error) {
+	var sb strings.Builder
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.src) {
+			return "", fmt.Errorf("%w: unexpected end of input", ErrCitationParse)
+		}
+		switch c := p.src[p.pos]; {
+		case c == '{':
+			p.pos++
+			start := p.pos
+			if err := p.skipBalanced('{', '}'); err != nil {
+				return "", err
+			}
+			sb.WriteString(string(p.src[start : p.pos-1]))
+		case c == '"':
+			p.pos++
+			start, depth := p.pos, 0
+			for p.pos < len(p.src) && (p.src[p.pos] != '"' || depth > 0) {
+				if p.src[p.pos] == '{' {
+					depth++
+				} else if p.src[p.pos] == '}' {
+					depth--
+				}
+				p.pos++
+			}
+			if p.pos >= len(p.src) {
+				return "", fmt.Errorf("%w: unterminated quoted value", ErrCitationParse)
+			}
+			sb.WriteString(string(p.src[start:p.pos]))
+			p.pos++
+		default:
+			word := p.readIdentifier()
+			if word == "" {
+				return "", fmt.Errorf("%w: expected value at offset %d", ErrCitationParse, p.pos)
+			}
+			if macro, ok := p.strings[strings.ToLower(word)]; ok {
+				word = macro
+			}
+			sb.WriteString(word)
+		}
+
+		p.skipSpace()
+		if p.pos < len(p.src) && p.src[p.pos] == '#' {
+			p.pos++
+			continue
+		}
+		return sb.String(), nil
+	}
+}
+
+// skipBalanced advances past the delimiter closing the current group. Only the
+// group's own delimiters nest, so parentheses inside a braced value are text.
+func (p *bibtexParser) skipBalanced(open, closing rune) error {
+	depth := 1
+	for p.pos < len(p.src) {
+		switch p.src[p.pos] {
+		case open:
+			depth++
+		case closing:
+			depth--
+		}
+		p.pos++
+		if depth == 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unbalanced braces", ErrCitationParse)
+}
+
+// readIdentifier reads an entry type, field name, key or bare value
+func (p *bibtexParser) readIdentifier() string {
+	start := p.pos
+	for p.pos < len(p.src) {
+		c := p.src[p.pos]
+		if unicode.IsSpace(c) || strings.ContainsRune(`{}(),="#`, c) {
+			break
+		}
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// readUntil reads up to (not including) the given rune or the end of the entry
+func (p *bibtexParser) readUntil(r rune) string {
+	start := p.pos
+	for p.pos < len(p.src) && p.src[p.pos] != r && p.src[p.pos] != '}' {
+		p.pos++
+	}
+	return string(p.src[start:p.pos])
+}
+
+// skipSpace skips whitespace
+func (p *bibtexParser) skipSpace() {
+	for p.pos < len(p.src) && unicode.IsSpace(p.src[p.pos]) {
+		p.pos++
+	}
+}
+
+// paperFromBibTeX maps BibTeX fields onto a paper
+func paperFromBibTeX(entryType string, fields map[string]string) *model.Paper {
+	paper := &model.Paper{
+		EntryType: entryType,
+		Title:     cleanLaTeX(fields["title"]),
+		Abstract:  cleanLaTeX(fields["abstract"]),
+		DOI:       strings.TrimSpace(fields["doi"]),
+		URL:       strings.TrimSpace(fields["url"]),
+	}
+
+	for _, key := range []string{"journal", "booktitle", "publisher", "howpublished"} {
+		if v := cleanLaTeX(fields[key]); v != "" {
+			paper.Venue = v
+			break
+		}
+	}
+
+	paper.PubYear, _ = model.ParsePubYM(fields["year"])
+	if m := strings.ToLower(strings.TrimSpace(fields["month"])); m != "" {
+		if n, err := strconv.Atoi(m); err == nil && n >= 1 && n <= 12 {
+			paper.PubMonth = n
+		} else if len(m) >= 3 {
+			paper.PubMonth = bibtexMonths[m[:3]]
+		}
+	}
+
+	eprint := strings.TrimSpace(fields["eprint"])
+	if strings.EqualFold(fields["archiveprefix"], "arxiv") && eprint != "" {
+		paper.ArxivID = eprint
+	} else if m := arxivVenuePattern.FindStringSubmatch(paper.Venue + " " + paper.URL); m != nil {
+		paper.ArxivID = m[1]
+	}
+
+	if n, err := strconv.Atoi(strings.TrimSpace(fields["citations"])); err == nil {
+		paper.CitationCount = n
+	}
+
+	for _, name := range splitBibTeXAuthors(fields["author"]) {
+		author := model.ParseAuthorName(cleanLaTeX(name))
+		author.Position = len(paper.Authors) + 1
+		paper.Authors = append(paper.Authors, author)
+	}
+	return paper
+}
+
+// arxivVenuePattern finds arXiv IDs in journal fields such as "arXiv preprint arXiv:1706.03762"
+var arxivVenuePattern = regexp.MustCompile(`(?i)arxiv(?:\.org/abs/|:)\s*(\d{4}\.\d{4,5}(?:v\d+)?)`)
+
+// splitBibTeXAuthors splits an author list on top-level " and "
+func splitBibTeXAuthors(s string) []string {
+	var names []string
+	depth, start := 0, 0
+	runes := []rune(s)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+		}
+		if depth == 0 && i+5 <= len(runes) && strings.EqualFold(string(runes[i:i+5]), " and ") {
+			names = append(names, string(runes[start:i]))
+			start = i + 5
+			i += 4
+		}
+	}
+	names = append(names, string(runes[start:]))
+
+	var result []string
+	for _, n := range names {
+		if n = strings.TrimSpace(n); n != "" && !strings.EqualFold(n, "others") {
+			result = append(result, n)
+		}
+	}
+	return result
+}
+
+// latexAccents maps common LaTeX accent commands to precomposed characters
+var latexAccents = map[string]string{
+	`\"a`: "ä", `\"o`: "ö", `\"u`: "ü", `\"A`: "Ä", `\"O`: "Ö", `\"U`: "Ü", `\"e`: "ë", `\"i`: "ï",
+	`\'a`: "á", `\'e`: "é", `\'i`: "í", `\'o`: "ó", `\'u`: "ú", `\'E`: "É", `\'c`: "ć", `\'n`: "ń", `\'s`: "ś", `\'z`: "ź",
+	"\\`a": "à", "\\`e": "è", "\\`i": "ì", "\\`o": "ò", "\\`u": "ù",
+	`\^a`: "â", `\^e`: "ê", `\^i`: "î", `\^o`: "ô", `\^u`: "û",
+	`\~n`: "ñ", `\~a`: "ã", `\~o`: "õ", `\c c`: "ç", `\c{c}`: "ç",
+	`\ss`: "ß", `\o`: "ø", `\O`: "Ø", `\aa`: "å", `\AA`: "Å", `\ae`: "æ", `\l`: "ł", `\L`: "Ł",
+	`\v c`: "č", `\v s`: "š", `\v z`: "ž", `\v{c}`: "č", `\v{s}`: "š", `\v{z}`: "ž",
+}
+
+var (
+	latexBracedAccent = regexp.MustCompile(`\\(["'` + "`" + `^~])\{([A-Za-z])\}`)
+	latexToken        = regexp.MustCompile(`\\(?:["'` + "`" + `^~][A-Za-z]|[cv](?: [a-z]|\{[a-z]\})|[a-zA-Z]+|[&%$#_{}])`)
+
+	// Escaped braces are held in private use runes while the grouping braces are stripped
+	latexHideBraces    = strings.NewReplacer("{", "\uE000", "}", "\uE001")
+	latexRestoreBraces = strings.NewReplacer("\uE000", "{", "\uE001", "}")
+)
+
+// cleanLaTeX turns a BibTeX field into plain text
+func cleanLaTeX(s string) string {
+	s = latexBracedAccent.ReplaceAllString(s, `\$1$2`)
+	s = latexToken.ReplaceAllStringFunc(s, func(m string) string {
+		if r, ok := latexAccents[m]; ok {
+			return r
+		}
+		if strings.ContainsAny(m[1:], "&%$#_{}") {
+			return latexHideBraces.Replace(m[1:]) // Escaped literal
+		}
+		return "" // Unknown command such as \emph
+	})
+	s = strings.NewReplacer("{", "", "}", "", "~", " ", "---", "—", "--", "–").Replace(s)
+	s = latexRestoreBraces.Replace(s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// escapeBibTeX escapes characters with a special meaning in BibTeX values
+func escapeBibTeX(s string) string {
+	return strings.NewReplacer(`&`, `\&`, `%`, `\%`, `$`, `\$`, `#`, `\#`, `_`, `\_`, `{`, `\{`, `}`, `\}`).Replace(s)
+}
+
+// citationStopWords are skipped when picking the title word of a cite key
+var citationStopWords = map[string]bool{
+	"a": true, "an": true, "the": true, "on": true, "of": true, "in": true, "for": true, "to": true, "and": true, "with": true,
+}
+
+// CitationKey builds a BibTeX key such as vaswani2017attention
+func CitationKey(paper *model.Paper) string {
+	var sb strings.Builder
+	if len(paper.Authors) > 0 {
+		sb.WriteString(citeKeyPart(paper.Authors[0].FamilyName))
+	} else if authors := paper.GetAuthors(); len(authors) > 0 {
+		sb.WriteString(citeKeyPart(model.ParseAuthorName(authors[0]).FamilyName))
+	}
+	if paper.PubYear > 0 {
+		sb.WriteString(strconv.Itoa(paper.PubYear))
+	}
+	for _, word := range strings.Fields(paper.Title) {
+		if w := citeKeyPart(word); w != "" && !citationStopWords[w] {
+			sb.WriteString(w)
+			break
+		}
+	}
+	if sb.Len() == 0 {
+		return citeKeyPart(paper.ID)
+	}
+	return sb.String()
+}
+
+// citeKeyPart lowercases a word and keeps letters and digits only
+func citeKeyPart(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, s)
+}
+
+// citationAuthors returns the ordered authors, parsing the legacy string if needed
+func citationAuthors(paper *model.Paper) []model.Author {
+	if len(paper.Authors) > 0 {
+		return paper.Authors
+	}
+	return paper.BuildAuthors()
+}
+
+// FormatBibTeX renders a paper as a BibTeX entry
+func FormatBibTeX(paper *model.Paper) string {
+	entryType := paper.EntryType
+	if entryType == "" {
+		entryType = "misc"
+		if paper.Venue != "" {
+			entryType = "article"
+		}
+	}
+
+	var names []string
+	for _, a := range citationAuthors(paper) {
+		if a.GivenName != "" && a.FamilyName != "" {
+			names = append(names, escapeBibTeX(a.FamilyName+", "+a.GivenName))
+		} else {
+			names = append(names, escapeBibTeX(a.Name))
+		}
+	}
+
+	venueField := "journal"
+	if entryType == "inproceedings" || entryType == "incollection" {
+		venueField = "booktitle"
+	} else if entryType == "misc" {
+		venueField = "howpublished"
+	}
+
+	fields := []struct{ name, value string }{
+		{"title", "{" + escapeBibTeX(paper.Title) + "}"},
+		{"author", strings.Join(names, " and ")},
+		{venueField, escapeBibTeX(paper.Venue)},
+		{"year", yearString(paper.PubYear)},
+		{"month", monthMacro(paper.PubMonth)},
+		{"doi", paper.DOI},
+		{"eprint", paper.ArxivID},
+		{"archivePrefix", archivePrefix(paper.ArxivID)},
+		{"url", paper.URL},
+		{"abstract", escapeBibTeX(paper.Abstract)},
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "@%s{%s,\n", entryType, CitationKey(paper))
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		fmt.Fprintf(&sb, "  %s = {%s},\n", f.name, f.value)
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
+
+// archivePrefix marks eprint fields as arXiv IDs
+func archivePrefix(arxivID string) string {
+	if arxivID == "" {
+		return ""
+	}
+	return "arXiv"
+}
+
+// yearString formats a year, empty if unknown
+func yearString(year int) string {
+	if year == 0 {
+		return ""
+	}
+	return strconv.Itoa(year)
+}
+
+// monthMacro returns the three-letter month name, empty if unknown
+func monthMacro(month int) string {
+	for name, n := range bibtexMonths {
+		if n == month {
+			return name
+		}
+	}
+	return ""
+}
+
+// risTypes maps RIS reference types to BibTeX entry types
+var risTypes = map[string]string{
+	"JOUR": "article", "CONF": "inproceedings", "CPAPER": "inproceedings", "CHAP": "incollection",
+	"BOOK": "book", "THES": "phdthesis", "RPRT": "techreport", "GEN": "misc", "ELEC": "misc", "UNPB": "unpublished",
+}
+
+// risTagPattern matches an RIS line such as "TY  - JOUR"
+var risTagPattern = regexp.MustCompile(`^([A-Z][A-Z0-9])  -(?: (.*))?$`)
+
+// ParseRIS parses RIS records terminated by ER
+func ParseRIS(content string) ([]*model.Paper, error) {
+	var papers []*model.Paper
+	var current *model.Paper
+	var lastTag string
+
+	scanner := bufio.NewScanner(strings.NewReader(strings.TrimPrefix(content, "\uFEFF")))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r ")
+		m := risTagPattern.FindStringSubmatch(line)
+		if m == nil {
+			// Continuation of a long abstract or title
+			if current != nil && strings.TrimSpace(line) != "" {
+				switch lastTag {
+				case "AB", "N2":
+					current.Abstract += " " + strings.TrimSpace(line)
+				case "TI", "T1":
+					current.Title += " " + strings.TrimSpace(line)
+				}
+			}
+			continue
+		}
+
+		tag, value := m[1], strings.TrimSpace(m[2])
+		lastTag = tag
+		if tag == "TY" {
+			entryType := risTypes[value]
+			if entryType == "" {
+				entryType = "misc"
+			}
+			current = &model.Paper{EntryType: entryType}
+			continue
+		}
+		if current == nil {
+			return nil, fmt.Errorf("%w: %s tag outside of a record", ErrCitationParse, tag)
+		}
+
+		switch tag {
+		case "ER":
+			papers = append(papers, current)
+			current = nil
+		case "AU", "A1":
+			author := model.ParseAuthorName(value)
+			author.Position = len(current.Authors) + 1
+			current.Authors = append(current.Authors, author)
+		case "TI", "T1":
+			current.Title = value
+		case "AB", "N2":
+			current.Abstract = value
+		case "JO", "JF", "T2", "BT", "JA":
+			if current.Venue == "" {
+				current.Venue = value
+			}
+		case "PY", "Y1", "DA":
+			if year, month := model.ParsePubYM(value); year > 0 {
+				if current.PubYear == 0 {
+					current.PubYear = year
+				}
+				if current.PubMonth == 0 {
+					current.PubMonth = month
+				}
+			}
+		case "DO":
+			current.DOI = value
+		case "UR", "L1":
+			if current.URL == "" {
+				current.URL = value
+			}
+			if m := arxivVenuePattern.FindStringSubmatch(value); m != nil && current.ArxivID == "" {
+				current.ArxivID = m[1]
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCitationParse, err)
+	}
+	if current != nil {
+		return nil, fmt.Errorf("%w: record without ER tag", ErrCitationParse)
+	}
+	if len(papers) == 0 {
+		return nil, fmt.Errorf("%w: no RIS records found", ErrCitationParse)
+	}
+	return papers, nil
+}
+
+// FormatRIS renders a paper as an RIS record
+func FormatRIS(paper *model.Paper) string {
+	risType := "GEN"
+	switch paper.EntryType {
+	case "article":
+		risType = "JOUR"
+	case "inproceedings", "conference":
+		risType = "CONF"
+	case "":
+		if paper.Venue != "" {
+			risType = "JOUR"
+		}
+	default:
+		for ris, bib := range risTypes {
+			if bib == paper.EntryType && ris != "CPAPER" && ris != "ELEC" {
+				risType = ris
+			}
+		}
+	}
+
+	var sb strings.Builder
+	write := func(tag, value string) {
+		if value != "" {
+			fmt.Fprintf(&sb, "%s  - %s\r\n", tag, value)
+		}
+	}
+
+	write("TY", risType)
+	write("TI", paper.Title)
+	for _, a := range citationAuthors(paper) {
+		if a.GivenName != "" && a.FamilyName != "" {
+			write("AU", a.FamilyName+", "+a.GivenName)
+		} else {
+			write("AU", a.Name)
+		}
+	}
+	if risType == "CONF" {
+		write("T2", paper.Venue)
+	} else {
+		write("JO", paper.Venue)
+	}
+	write("PY", yearString(paper.PubYear))
+	if paper.PubYear > 0 && paper.PubMonth > 0 {
+		write("DA", fmt.Sprintf("%04d/%02d", paper.PubYear, paper.PubMonth))
+	}
+	write("DO", paper.DOI)
+	write("UR", paper.URL)
+	write("AB", paper.Abstract)
+	sb.WriteString("ER  - \r\n")
+	return sb.String()
+}
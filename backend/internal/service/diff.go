@@ -0,0 +1,131 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"paperplay/internal/model"
+	"strings"
+)
+
+// DiffLine represents one line of a line-based diff
+type DiffLine struct {
+	Op   string `json:"op"` // "=" unchanged, "-" removed, "+" added
+	Text string `json:"text"`
+}
+
+// FieldDiff represents the change of a single revision field
+type FieldDiff struct {
+	Field string     `json:"field"`
+	From  any        `json:"from"`
+	To    any        `json:"to"`
+	Lines []DiffLine `json:"lines,omitempty"`
+}
+
+// RevisionDiff represents the differences between two revisions of a question
+type RevisionDiff struct {
+	QuestionID   string      `json:"question_id"`
+	FromRevision int         `json:"from_revision"`
+	ToRevision   int         `json:"to_revision"`
+	Changes      []FieldDiff `json:"changes"`
+}
+
+// DiffRevisions computes a field-by-field diff between two revisions
+func DiffRevisions(from, to *model.QuestionRevision) *RevisionDiff {
+	diff := &RevisionDiff{
+		QuestionID:   to.QuestionID,
+		FromRevision: from.Number,
+		ToRevision:   to.Number,
+		Changes:      []FieldDiff{},
+	}
+
+	textFields := []struct {
+		name     string
+		from, to string
+		isJSON   bool
+	}{
+		{"subtitle", from.Subtitle, to.Subtitle, false},
+		{"stem", from.Stem, to.Stem, false},
+		{"content_json", from.ContentJSON, to.ContentJSON, true},
+		{"answer_json", from.AnswerJSON, to.AnswerJSON, true},
+	}
+
+	for _, f := range textFields {
+		if f.from == f.to {
+			continue
+		}
+		a, b := f.from, f.to
+		if f.isJSON {
+			a, b = prettyJSON(a), prettyJSON(b)
+		}
+		diff.Changes = append(diff.Changes, FieldDiff{
+			Field: f.name,
+			From:  f.from,
+			To:    f.to,
+			Lines: diffLines(strings.Split(a, "\n"), strings.Split(b, "\n")),
+		})
+	}
+
+	if from.Score != to.Score {
+		diff.Changes = append(diff.Changes, FieldDiff{
+			Field: "score",
+			From:  from.Score,
+			To:    to.Score,
+		})
+	}
+
+	return diff
+}
+
+// prettyJSON indents JSON so that diffs are line-oriented; invalid JSON is returned as is
+func prettyJSON(s string) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(s), "", "  "); err != nil {
+		return s
+	}
+	return buf.String()
+}
+
+// diffLines computes a minimal line diff using the longest common subsequence
+func diffLines(a, b []string) []DiffLine {
+	// lcs[i][j] holds the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var lines []DiffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, DiffLine{Op: "=", Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, DiffLine{Op: "-", Text: a[i]})
+			i++
+		default:
+			lines = append(lines, DiffLine{Op: "+", Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, DiffLine{Op: "-", Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, DiffLine{Op: "+", Text: b[j]})
+	}
+
+	return lines
+}
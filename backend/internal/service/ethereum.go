@@ -3,6 +3,7 @@ package service
 import (
 	"context"
 	"crypto/ecdsa"
+	"errors"
 	"fmt"
 	"math/big"
 	"paperplay/config"
@@ -15,6 +16,9 @@ import (
 	"github.com/ethereum/go-ethereum/ethclient"
 )
 
+// ErrBurnNotSupported is returned when burning NFTs is not available
+var ErrBurnNotSupported = errors.New("burning NFTs is not supported")
+
 // EthereumService handles Ethereum blockchain operations
 type EthereumService struct {
 	config     *config.EthereumConfig
@@ -175,6 +179,25 @@ func (s *EthereumService) MintNFT(request NFTMintRequest) (*NFTMintResult, error
 	}, nil
 }
 
+// SupportsBurn checks if minted NFTs can be burned
+func (s *EthereumService) SupportsBurn() bool {
+	return s.enabled && s.config.BurnEnabled && s.privateKey != nil
+}
+
+// BurnNFT burns a minted NFT and returns the transaction hash
+func (s *EthereumService) BurnNFT(nftAsset *model.NFTAsset) (string, error) {
+	if !s.SupportsBurn() {
+		return "", ErrBurnNotSupported
+	}
+	if !nftAsset.IsMinted() {
+		return "", fmt.Errorf("NFT %s is not minted", nftAsset.ID)
+	}
+
+	// As with minting, the contract call is simulated. In a real implementation
+	// you would call the contract's burn function with nftAsset.TokenID.
+	return generateMockTxHash(), nil
+}
+
 // CreateNFTAsset creates an NFT asset record in the database
 func (s *EthereumService) CreateNFTAsset(userID string, achievementID *string, metadataURI string) (*model.NFTAsset, error) {
 	if !s.enabled {
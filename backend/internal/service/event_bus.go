@@ -0,0 +1,269 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"paperplay/config"
+	"paperplay/internal/model"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// outboxBatchSize bounds the events redelivered per poll
+const outboxBatchSize = 100
+
+// maxRetryDelay caps the wait before a failed event is retried
+const maxRetryDelay = 5 * time.Minute
+
+// EventHandler processes an event delivered by the event bus. An event can be
+// delivered more than once, so handlers must be idempotent.
+type EventHandler func(event *model.OutboxEvent) error
+
+// EventBus delivers domain events to a bounded pool of workers. Published events are
+// logged and written to the outbox in one transaction, then queued for immediate
+// delivery. A user's events always go to the same worker, so they are handled in
+// order and never concurrently. Events that do not fit in a queue, fail, or were
+// not finished before a restart are redelivered from the outbox by a poller.
+type EventBus struct {
+	db      *gorm.DB
+	config  *config.EventBusConfig
+	handler EventHandler
+	logger  *zap.Logger
+
+	queues []chan string
+	stop   chan struct{}
+	wg     sync.WaitGroup
+}
+
+// NewEventBus creates an event bus; call Start to begin delivering events
+func NewEventBus(db *gorm.DB, cfg *config.EventBusConfig, handler EventHandler, logger *zap.Logger) *EventBus {
+	queues := make([]chan string, cfg.Workers)
+	for i := range queues {
+		queues[i] = make(chan string, cfg.QueueSize)
+	}
+	return &EventBus{
+		db:      db,
+		config:  cfg,
+		handler: handler,
+		logger:  logger,
+		queues:  queues,
+		stop:    make(chan struct{}),
+	}
+}
+
+// Start launches the workers and the outbox poller. Events left in the outbox by a
+// previous run are picked up by the first poll.
+func (b *EventBus) Start() {
+	for _, queue := range b.queues {
+		b.wg.Add(1)
+		go b.work(queue)
+	}
+	b.wg.Add(1)
+	go b.poll()
+	b.logger.Info("Event bus started", zap.Int("workers", len(b.queues)))
+}
+
+// Stop stops the workers after the events they are handling. Queued events stay in
+// the outbox and are delivered after the next start.
+func (b *EventBus) Stop() {
+	close(b.stop)
+	b.wg.Wait()
+	b.logger.Info("Event bus stopped")
+}
+
+// Publish logs an event for a user and queues it for delivery. A "level_id" or
+// "question_id" in the data is also stored on the logged event.
+func (b *EventBus) Publish(userID, eventType string, data model.EventData) error {
+	dataJSON, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode event data: %w", err)
+	}
+
+	event := &model.Event{UserID: userID, EventType: eventType, DataJSON: string(dataJSON)}
+	if levelID, ok := data["level_id"].(string); ok && levelID != "" {
+		event.LevelID = &levelID
+	}
+	if questionID, ok := data["question_id"].(string); ok && questionID != "" {
+		event.QuestionID = &questionID
+	}
+
+	outbox := &model.OutboxEvent{
+		UserID:    userID,
+		EventType: eventType,
+		DataJSON:  string(dataJSON),
+		Status:    model.OutboxPending,
+		// The queued delivery has a lease's time before the poller delivers it again
+		AvailableAt: time.Now().Add(b.lease()),
+	}
+
+	if err := b.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(event).Error; err != nil {
+			return fmt.Errorf("failed to create event: %w", err)
+		}
+		outbox.EventID = event.ID
+		if err := tx.Create(outbox).Error; err != nil {
+			return fmt.Errorf("failed to create outbox event: %w", err)
+		}
+		return nil
+	}); err != nil {
+		b.logger.Error("Failed to publish event",
+			zap.String("user_id", userID),
+			zap.String("event_type", eventType),
+			zap.Error(err),
+		)
+		return err
+	}
+
+	b.dispatch(outbox)
+	return nil
+}
+
+// dispatch queues an event on its user's worker. A full queue leaves the event to
+// the poller.
+func (b *EventBus) dispatch(event *model.OutboxEvent) bool {
+	select {
+	case b.queues[b.shard(event.UserID)] <- event.ID:
+		return true
+	default:
+		return false
+	}
+}
+
+// shard picks the worker for a user
+func (b *EventBus) shard(userID string) int {
+	h := fnv.New32a()
+	h.Write([]byte(userID))
+	return int(h.Sum32() % uint32(len(b.queues)))
+}
+
+// work delivers the events of one queue until the bus stops
+func (b *EventBus) work(queue chan string) {
+	defer b.wg.Done()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case id := <-queue:
+			if err := b.deliver(id); err != nil {
+				b.logger.Error("Failed to deliver event", zap.String("outbox_id", id), zap.Error(err))
+			}
+		}
+	}
+}
+
+// deliver claims an event, runs the handler and records the outcome. An event that
+// is already claimed, finished or waiting to be retried is skipped.
+func (b *EventBus) deliver(id string) error {
+	now := time.Now()
+	claim := b.db.Model(&model.OutboxEvent{}).
+		Where("id = ?", id).
+		Where("(status = ? AND (attempts = 0 OR available_at <= ?)) OR (status = ? AND available_at <= ?)",
+			model.OutboxPending, now, model.OutboxProcessing, now).
+		Updates(map[string]any{
+			"status":       model.OutboxProcessing,
+			"attempts":     gorm.Expr("attempts + 1"),
+			"available_at": now.Add(b.lease()),
+		})
+	if claim.Error != nil {
+		return fmt.Errorf("failed to claim outbox event: %w", claim.Error)
+	}
+	if claim.RowsAffected == 0 {
+		return nil
+	}
+
+	var event model.OutboxEvent
+	if err := b.db.First(&event, "id = ?", id).Error; err != nil {
+		return fmt.Errorf("failed to get outbox event: %w", err)
+	}
+
+	handlerErr := b.handler(&event)
+	if handlerErr == nil {
+		return b.db.Model(&event).Updates(map[string]any{
+			"status":       model.OutboxDone,
+			"processed_at": time.Now(),
+			"last_error":   "",
+		}).Error
+	}
+
+	updates := map[string]any{"status": model.OutboxPending, "last_error": handlerErr.Error()}
+	if event.Attempts >= b.config.MaxAttempts {
+		// The achievement check cron job still evaluates the user
+		updates["status"] = model.OutboxFailed
+	} else {
+		updates["available_at"] = time.Now().Add(retryDelay(event.Attempts))
+	}
+	if err := b.db.Model(&event).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to record delivery failure: %w", err)
+	}
+	return fmt.Errorf("handler failed on attempt %d: %w", event.Attempts, handlerErr)
+}
+
+// retryDelay doubles the wait after each failed attempt, starting at one second
+func retryDelay(attempts int) time.Duration {
+	if attempts > 9 {
+		return maxRetryDelay
+	}
+	return min(time.Second<<(attempts-1), maxRetryDelay)
+}
+
+// lease is how long a queued or claimed event is left alone before redelivery
+func (b *EventBus) lease() time.Duration {
+	return time.Duration(b.config.Lease) * time.Second
+}
+
+// poll redelivers due events and purges old delivered ones until the bus stops
+func (b *EventBus) poll() {
+	defer b.wg.Done()
+	ticker := time.NewTicker(time.Duration(b.config.PollInterval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		if _, err := b.Redeliver(); err != nil {
+			b.logger.Error("Failed to redeliver outbox events", zap.Error(err))
+		}
+		if err := b.purge(); err != nil {
+			b.logger.Error("Failed to purge outbox events", zap.Error(err))
+		}
+
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Redeliver queues the pending and stalled events that are due, oldest first, and
+// returns how many were queued
+func (b *EventBus) Redeliver() (int, error) {
+	var due []model.OutboxEvent
+	if err := b.db.Select("id", "user_id").
+		Where("status IN ? AND available_at <= ?", []string{model.OutboxPending, model.OutboxProcessing}, time.Now()).
+		Order("created_at").
+		Limit(outboxBatchSize).
+		Find(&due).Error; err != nil {
+		return 0, fmt.Errorf("failed to get due outbox events: %w", err)
+	}
+
+	queued := 0
+	for i := range due {
+		if b.dispatch(&due[i]) {
+			queued++
+		}
+	}
+	if queued > 0 {
+		b.logger.Info("Redelivering outbox events", zap.Int("count", queued))
+	}
+	return queued, nil
+}
+
+// purge deletes events delivered more than the retention period ago
+func (b *EventBus) purge() error {
+	cutoff := time.Now().AddDate(0, 0, -b.config.RetentionDays)
+	return b.db.Where("status = ? AND processed_at < ?", model.OutboxDone, cutoff).
+		Delete(&model.OutboxEvent{}).Error
+}
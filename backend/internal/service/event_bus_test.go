@@ -0,0 +1,171 @@
+package service
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"paperplay/config"
+	"paperplay/internal/model"
+)
+
+func setupEventBusTest(t *testing.T) *gorm.DB {
+	db := setupRuleTest(t)
+	require.NoError(t, db.AutoMigrate(
+		&model.OutboxEvent{}, &model.Achievement{}, &model.UserAchievement{}, &model.UserAchievementProgress{},
+		&model.AchievementBackfill{}, &model.AchievementAuditLog{}, &model.AchievementStats{},
+	))
+	// Workers share the in-memory database, which exists only on its first connection
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+func testEventBusConfig() *config.EventBusConfig {
+	return &config.EventBusConfig{Workers: 2, QueueSize: 8, PollInterval: 1, Lease: 30, MaxAttempts: 2, RetentionDays: 7}
+}
+
+func TestEventBus_PublishDelivers(t *testing.T) {
+	db := setupEventBusTest(t)
+
+	delivered := make(chan *model.OutboxEvent, 1)
+	bus := NewEventBus(db, testEventBusConfig(), func(event *model.OutboxEvent) error {
+		delivered <- event
+		return nil
+	}, zap.NewNop())
+	bus.Start()
+	defer bus.Stop()
+
+	require.NoError(t, bus.Publish("u-1", model.EventLevelCompleted, model.EventData{"level_id": "l-1", "stars": 3}))
+
+	select {
+	case event := <-delivered:
+		assert.Equal(t, "u-1", event.UserID)
+		assert.Equal(t, model.EventLevelCompleted, event.EventType)
+		assert.Equal(t, 1, event.Attempts)
+		data, err := event.GetData()
+		require.NoError(t, err)
+		assert.Equal(t, 3.0, data["stars"])
+	case <-time.After(time.Second):
+		t.Fatal("event was not delivered within a second")
+	}
+
+	// The event is logged for rules that count events
+	var logged model.Event
+	require.NoError(t, db.Where("user_id = ? AND event_type = ?", "u-1", model.EventLevelCompleted).First(&logged).Error)
+	require.NotNil(t, logged.LevelID)
+	assert.Equal(t, "l-1", *logged.LevelID)
+
+	assert.Eventually(t, func() bool {
+		var outbox model.OutboxEvent
+		return db.First(&outbox, "event_id = ?", logged.ID).Error == nil && outbox.Status == model.OutboxDone
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestEventBus_RetriesThenGivesUp(t *testing.T) {
+	db := setupEventBusTest(t)
+
+	calls := 0
+	bus := NewEventBus(db, testEventBusConfig(), func(event *model.OutboxEvent) error {
+		calls++
+		return errors.New("boom")
+	}, zap.NewNop())
+
+	// Without Start the event stays queued, so deliveries can be driven one by one
+	require.NoError(t, bus.Publish("u-1", model.EventSessionStarted, nil))
+	var outbox model.OutboxEvent
+	require.NoError(t, db.First(&outbox).Error)
+
+	assert.Error(t, bus.deliver(outbox.ID))
+	require.NoError(t, db.First(&outbox, "id = ?", outbox.ID).Error)
+	assert.Equal(t, model.OutboxPending, outbox.Status)
+	assert.Equal(t, "boom", outbox.LastError)
+	assert.True(t, outbox.AvailableAt.After(time.Now()))
+
+	// Not retried before its backoff has passed
+	assert.NoError(t, bus.deliver(outbox.ID))
+	assert.Equal(t, 1, calls)
+
+	require.NoError(t, db.Model(&outbox).Update("available_at", time.Now().Add(-time.Second)).Error)
+	assert.Error(t, bus.deliver(outbox.ID))
+	require.NoError(t, db.First(&outbox, "id = ?", outbox.ID).Error)
+	assert.Equal(t, model.OutboxFailed, outbox.Status)
+	assert.Equal(t, 2, calls)
+
+	// Failed events are left to the cron job
+	queued, err := bus.Redeliver()
+	require.NoError(t, err)
+	assert.Zero(t, queued)
+}
+
+func TestEventBus_RedeliversAfterRestart(t *testing.T) {
+	db := setupEventBusTest(t)
+
+	// An event claimed by a worker that stopped, and one never queued
+	expired := time.Now().Add(-time.Minute)
+	require.NoError(t, db.Create(&model.OutboxEvent{
+		EventID: "e-1", UserID: "u-1", EventType: model.EventSessionStarted,
+		Status: model.OutboxProcessing, Attempts: 1, AvailableAt: expired,
+	}).Error)
+	require.NoError(t, db.Create(&model.OutboxEvent{
+		EventID: "e-2", UserID: "u-2", EventType: model.EventSessionStarted,
+		Status: model.OutboxPending, AvailableAt: expired,
+	}).Error)
+
+	var mu sync.Mutex
+	delivered := make(map[string]int)
+	bus := NewEventBus(db, testEventBusConfig(), func(event *model.OutboxEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		delivered[event.EventID]++
+		return nil
+	}, zap.NewNop())
+	bus.Start()
+	defer bus.Stop()
+
+	assert.Eventually(t, func() bool {
+		var done int64
+		db.Model(&model.OutboxEvent{}).Where("status = ?", model.OutboxDone).Count(&done)
+		return done == 2
+	}, 2*time.Second, 10*time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	assert.Equal(t, map[string]int{"e-1": 1, "e-2": 1}, delivered)
+}
+
+func TestAchievementService_HandleEventCountsStatsOnce(t *testing.T) {
+	db := setupEventBusTest(t)
+	require.NoError(t, db.Create(&model.Achievement{
+		ID: "first-try", Name: "First try", Description: "Answer right on the first try", BadgeType: "learning", IsActive: true,
+		RuleJSON: `{"match":{"source":"attempts","field":"attempts_first_try_correct","agg":"sum","op":">=","value":1}}`,
+	}).Error)
+	achievementService := NewAchievementService(db, zap.NewNop(), nil, nil)
+	bus := NewEventBus(db, testEventBusConfig(), achievementService.HandleEvent, zap.NewNop())
+
+	require.NoError(t, bus.Publish("u-1", model.EventQuestionAnswered, model.EventData{
+		"question_id": "q-1", "correct": true, "first_try": true, "duration_ms": 1500,
+	}))
+	var outbox model.OutboxEvent
+	require.NoError(t, db.First(&outbox).Error)
+
+	// A redelivery evaluates again but does not count the answer twice
+	require.NoError(t, achievementService.HandleEvent(&outbox))
+	require.NoError(t, achievementService.HandleEvent(&outbox))
+
+	stats, err := model.GetTodayStats(db, "u-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, stats.AttemptsTotal)
+	assert.Equal(t, 1500, stats.TotalTimeMs)
+
+	var earned int64
+	db.Model(&model.UserAchievement{}).Where("user_id = ? AND achievement_id = ?", "u-1", "first-try").Count(&earned)
+	assert.Equal(t, int64(1), earned)
+}
@@ -0,0 +1,446 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"paperplay/config"
+	"paperplay/internal/model"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Guest errors
+var (
+	ErrGuestsDisabled     = errors.New("guest play is not enabled")
+	ErrNotGuest           = errors.New("account is already registered")
+	ErrInvalidCredentials = errors.New("invalid email or password")
+)
+
+// defaultGuestDisplayName is given to guests who do not choose a name
+const defaultGuestDisplayName = "Guest"
+
+// UpgradeResult is the account a guest continues with after upgrading
+type UpgradeResult struct {
+	User   *model.User
+	Merged bool // The guest's data was merged into an existing account
+}
+
+// GuestService handles guest accounts: creating them, upgrading them to registered
+// accounts and purging the abandoned ones
+type GuestService struct {
+	db            *gorm.DB
+	ethService    *EthereumService
+	wechatService *WeChatService
+	logger        *zap.Logger
+	enabled       bool
+	retention     time.Duration
+}
+
+// NewGuestService creates a new guest service; ethService and wechatService may be nil
+func NewGuestService(db *gorm.DB, cfg *config.Config, ethService *EthereumService, wechatService *WeChatService, logger *zap.Logger) *GuestService {
+	return &GuestService{
+		db:            db,
+		ethService:    ethService,
+		wechatService: wechatService,
+		logger:        logger,
+		enabled:       cfg.Guest.Enabled,
+		retention:     time.Duration(cfg.Guest.RetentionDays) * 24 * time.Hour,
+	}
+}
+
+// CreateGuest creates a guest account, which can play but has no way to log in
+// other than its tokens
+func (s *GuestService) CreateGuest(displayName string) (*model.User, error) {
+	if !s.enabled {
+		return nil, ErrGuestsDisabled
+	}
+
+	displayName = strings.TrimSpace(displayName)
+	if displayName == "" {
+		displayName = defaultGuestDisplayName
+	}
+	user := &model.User{
+		Email:       "guest-" + uuid.New().String() + "@" + model.GuestEmailDomain,
+		DisplayName: displayName,
+		Role:        model.RoleGuest,
+	}
+	if err := s.db.Create(user).Error; err != nil {
+		return nil, fmt.Errorf("failed to create guest: %w", err)
+	}
+
+	s.logger.Info("Guest created", zap.String("user_id", user.ID))
+	return user, nil
+}
+
+// getGuest loads a user that must still be a guest
+func getGuest(tx *gorm.DB, guestID string) (*model.User, error) {
+	var guest model.User
+	if err := tx.First(&guest, "id = ?", guestID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if !guest.IsGuest() {
+		return nil, ErrNotGuest
+	}
+	return &guest, nil
+}
+
+// UpgradeWithEmail turns a guest into a registered account. A new address is set up
+// on the guest account itself and starts out unverified; the address of an existing
+// account needs its password, and the guest's data is merged into that account.
+func (s *GuestService) UpgradeWithEmail(guestID, email, password, displayName string) (*UpgradeResult, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if model.IsReservedEmail(email) {
+		return nil, ErrEmailTaken
+	}
+
+	result := &UpgradeResult{}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		guest, err := getGuest(tx, guestID)
+		if err != nil {
+			return err
+		}
+
+		var existing model.User
+		err = tx.Where("email = ?", email).First(&existing).Error
+		if err == nil {
+			if !existing.CheckPassword(password) {
+				return ErrInvalidCredentials
+			}
+			if err := mergeGuest(tx, guest.ID, existing.ID); err != nil {
+				return err
+			}
+			result.User, result.Merged = &existing, true
+			return nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return fmt.Errorf("failed to check email: %w", err)
+		}
+
+		if err := guest.SetPassword(password); err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		guest.Email = email
+		if err := s.register(tx, guest, displayName, map[string]any{
+			"email":         guest.Email,
+			"password_hash": guest.PasswordHash,
+		}); err != nil {
+			return err
+		}
+		result.User = guest
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logUpgrade(guestID, result, "email")
+	return result, nil
+}
+
+// UpgradeWithWeChat turns a guest into a registered account through WeChat login. An
+// unlinked WeChat account is linked to the guest account; for one already linked, the
+// guest's data is merged into the account it belongs to.
+func (s *GuestService) UpgradeWithWeChat(ctx context.Context, guestID, code, displayName string) (*UpgradeResult, error) {
+	if s.wechatService == nil {
+		return nil, ErrWeChatDisabled
+	}
+	session, err := s.wechatService.Code2Session(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UpgradeResult{}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		guest, err := getGuest(tx, guestID)
+		if err != nil {
+			return err
+		}
+
+		identity, err := s.wechatService.resolveIdentity(tx, session)
+		if err != nil {
+			return err
+		}
+		if identity != nil {
+			var existing model.User
+			if err := tx.First(&existing, "id = ?", identity.UserID).Error; err != nil {
+				return fmt.Errorf("failed to get user: %w", err)
+			}
+			if err := mergeGuest(tx, guest.ID, existing.ID); err != nil {
+				return err
+			}
+			result.User, result.Merged = &existing, true
+			return nil
+		}
+
+		guest.Email = "wechat-" + uuid.New().String() + "@" + model.PlaceholderEmailDomain
+		if err := s.register(tx, guest, displayName, map[string]any{"email": guest.Email}); err != nil {
+			return err
+		}
+		identity = &model.ExternalIdentity{UserID: guest.ID, Provider: model.ProviderWeChat, Subject: session.OpenID, UnionID: session.UnionID}
+		if err := tx.Create(identity).Error; err != nil {
+			return fmt.Errorf("failed to create wechat identity: %w", err)
+		}
+		result.User = guest
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logUpgrade(guestID, result, model.ProviderWeChat)
+	return result, nil
+}
+
+// register makes a guest a regular user, giving it a wallet like any new account.
+// The guest's tokens are revoked, so only the tokens issued for the upgrade work.
+func (s *GuestService) register(tx *gorm.DB, guest *model.User, displayName string, updates map[string]any) error {
+	guest.Role = model.RoleUser
+	updates["role"] = guest.Role
+	if displayName = strings.TrimSpace(displayName); displayName != "" {
+		guest.DisplayName = displayName
+		updates["display_name"] = displayName
+	}
+
+	if s.ethService != nil && s.ethService.IsEnabled() {
+		address, privateKey, err := s.ethService.GenerateWallet()
+		if err != nil {
+			return fmt.Errorf("failed to generate wallet: %w", err)
+		}
+		guest.EthAddress, guest.EthPrivateKey = address, privateKey
+		updates["eth_address"] = address
+		updates["eth_private_key"] = privateKey
+	}
+
+	if err := tx.Model(guest).Updates(updates).Error; err != nil {
+		return fmt.Errorf("failed to upgrade guest: %w", err)
+	}
+	if err := model.RevokeAllTokens(tx, guest.ID); err != nil {
+		return fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+	if err := tx.First(guest, "id = ?", guest.ID).Error; err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	return nil
+}
+
+// logUpgrade records the outcome of an upgrade
+func (s *GuestService) logUpgrade(guestID string, result *UpgradeResult, method string) {
+	s.logger.Info("Guest upgraded",
+		zap.String("guest_id", guestID),
+		zap.String("user_id", result.User.ID),
+		zap.String("method", method),
+		zap.Bool("merged", result.Merged))
+}
+
+// mergeGuest moves a guest's learning data to another account and deletes the guest.
+// Where both have a row for the same level, day or achievement, the rows are combined.
+func mergeGuest(tx *gorm.DB, guestID, userID string) error {
+	if err := mergeProgress(tx, guestID, userID); err != nil {
+		return err
+	}
+	if err := mergeAttempts(tx, guestID, userID); err != nil {
+		return err
+	}
+	if err := mergeAchievements(tx, guestID, userID); err != nil {
+		return err
+	}
+
+	// Rows without a natural key simply change owner. Outbox events not yet counted in the
+	// guest's statistics are then counted for the account when they are delivered.
+	for _, m := range []any{
+		&model.AnswerRecord{}, &model.Event{}, &model.OutboxEvent{}, &model.AchievementAuditLog{},
+		&model.NFTAsset{}, &model.QuestionReport{},
+	} {
+		if err := tx.Model(m).Where("user_id = ?", guestID).Update("user_id", userID).Error; err != nil {
+			return fmt.Errorf("failed to move guest data: %w", err)
+		}
+	}
+	if err := tx.Model(&model.AchievementAuditLog{}).Where("actor_id = ?", guestID).
+		Update("actor_id", userID).Error; err != nil {
+		return fmt.Errorf("failed to move guest audit actor: %w", err)
+	}
+
+	// Progress milestones are recomputed for the account on its next evaluation
+	if err := tx.Where("user_id = ?", guestID).Delete(&model.UserAchievementProgress{}).Error; err != nil {
+		return fmt.Errorf("failed to delete guest achievement progress: %w", err)
+	}
+
+	if err := tx.Where("user_id = ?", guestID).Delete(&model.RefreshToken{}).Error; err != nil {
+		return fmt.Errorf("failed to delete guest sessions: %w", err)
+	}
+	if err := tx.Delete(&model.User{}, "id = ?", guestID).Error; err != nil {
+		return fmt.Errorf("failed to delete guest: %w", err)
+	}
+	return nil
+}
+
+// mergeProgress keeps the better result for levels both accounts have played
+func mergeProgress(tx *gorm.DB, guestID, userID string) error {
+	var rows []model.UserProgress
+	if err := tx.Where("user_id = ?", guestID).Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to get guest progress: %w", err)
+	}
+
+	for _, row := range rows {
+		var existing model.UserProgress
+		err := tx.Where("user_id = ? AND level_id = ?", userID, row.LevelID).First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			row.UserID = userID
+			if err := tx.Create(&row).Error; err != nil {
+				return fmt.Errorf("failed to move guest progress: %w", err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get progress: %w", err)
+		}
+
+		existing.Status = max(existing.Status, row.Status)
+		existing.Score = max(existing.Score, row.Score)
+		existing.Stars = max(existing.Stars, row.Stars)
+		if existing.LastAttemptAt == nil || (row.LastAttemptAt != nil && row.LastAttemptAt.After(*existing.LastAttemptAt)) {
+			existing.LastAttemptAt = row.LastAttemptAt
+		}
+		if err := tx.Save(&existing).Error; err != nil {
+			return fmt.Errorf("failed to merge progress: %w", err)
+		}
+	}
+
+	if err := tx.Where("user_id = ?", guestID).Delete(&model.UserProgress{}).Error; err != nil {
+		return fmt.Errorf("failed to delete guest progress: %w", err)
+	}
+	return nil
+}
+
+// mergeAttempts adds up the daily statistics of days both accounts played on
+func mergeAttempts(tx *gorm.DB, guestID, userID string) error {
+	var rows []model.UserAttempts
+	if err := tx.Where("user_id = ?", guestID).Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to get guest attempts: %w", err)
+	}
+
+	for _, row := range rows {
+		var existing model.UserAttempts
+		err := tx.Where("user_id = ? AND stat_date = ?", userID, row.StatDate).First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			row.UserID = userID
+			if err := tx.Create(&row).Error; err != nil {
+				return fmt.Errorf("failed to move guest attempts: %w", err)
+			}
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get attempts: %w", err)
+		}
+
+		existing.AttemptsTotal += row.AttemptsTotal
+		existing.AttemptsCorrect += row.AttemptsCorrect
+		existing.AttemptsFirstTryCorrect += row.AttemptsFirstTryCorrect
+		existing.GiveupCount += row.GiveupCount
+		existing.TotalTimeMs += row.TotalTimeMs
+		existing.SessionsCount += row.SessionsCount
+		existing.StreakDays = max(existing.StreakDays, row.StreakDays)
+		existing.ReviewDueCount = max(existing.ReviewDueCount, row.ReviewDueCount)
+		existing.RetentionScore = max(existing.RetentionScore, row.RetentionScore)
+		if existing.AttemptsTotal > 0 {
+			existing.AvgDurationMs = existing.TotalTimeMs / existing.AttemptsTotal
+		}
+		existing.UpdateRates()
+		if err := tx.Save(&existing).Error; err != nil {
+			return fmt.Errorf("failed to merge attempts: %w", err)
+		}
+	}
+
+	if err := tx.Where("user_id = ?", guestID).Delete(&model.UserAttempts{}).Error; err != nil {
+		return fmt.Errorf("failed to delete guest attempts: %w", err)
+	}
+	return nil
+}
+
+// mergeAchievements moves the guest's achievements the account does not have yet. For
+// achievements both have, the earlier earn date is kept. Tiers are then superseded again
+// so only the highest tier of each series stays current.
+func mergeAchievements(tx *gorm.DB, guestID, userID string) error {
+	var rows []model.UserAchievement
+	if err := tx.Where("user_id = ?", guestID).Find(&rows).Error; err != nil {
+		return fmt.Errorf("failed to get guest achievements: %w", err)
+	}
+
+	for _, row := range rows {
+		var existing model.UserAchievement
+		err := tx.Where("user_id = ? AND achievement_id = ?", userID, row.AchievementID).First(&existing).Error
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			if err := tx.Model(&row).Update("user_id", userID).Error; err != nil {
+				return fmt.Errorf("failed to move guest achievement: %w", err)
+			}
+		case err != nil:
+			return fmt.Errorf("failed to get achievement: %w", err)
+		default:
+			if row.EarnedAt.Before(existing.EarnedAt) {
+				if err := tx.Model(&existing).Update("earned_at", row.EarnedAt).Error; err != nil {
+					return fmt.Errorf("failed to merge achievement: %w", err)
+				}
+			}
+			if err := tx.Model(&model.AchievementAuditLog{}).Where("user_achievement_id = ?", row.ID).
+				Update("user_achievement_id", existing.ID).Error; err != nil {
+				return fmt.Errorf("failed to move guest achievement audit log: %w", err)
+			}
+			if err := tx.Model(&model.UserAchievement{}).Where("superseded_by = ?", row.ID).
+				Update("superseded_by", existing.ID).Error; err != nil {
+				return fmt.Errorf("failed to move guest tier supersede: %w", err)
+			}
+			if err := tx.Delete(&row).Error; err != nil {
+				return fmt.Errorf("failed to delete guest achievement: %w", err)
+			}
+		}
+	}
+	return supersedeMergedTiers(tx, userID)
+}
+
+// PurgeAbandonedGuests deletes guest accounts that were created and last signed in or
+// refreshed a token before the retention period, and returns how many were purged
+func (s *GuestService) PurgeAbandonedGuests() (int, error) {
+	cutoff := time.Now().Add(-s.retention)
+	active := s.db.Model(&model.RefreshToken{}).
+		Select("user_id").
+		Where("last_used_at >= ? OR created_at >= ?", cutoff, cutoff)
+
+	var guestIDs []string
+	if err := s.db.Model(&model.User{}).
+		Where("role = ? AND created_at < ? AND id NOT IN (?)", model.RoleGuest, cutoff, active).
+		Pluck("id", &guestIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to get abandoned guests: %w", err)
+	}
+
+	purged := 0
+	for _, guestID := range guestIDs {
+		err := s.db.Transaction(func(tx *gorm.DB) error {
+			// Skip guests upgraded since the query
+			if err := tx.Where("id = ? AND role = ?", guestID, model.RoleGuest).First(&model.User{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Where("user_id = ?", guestID).Delete(&model.RefreshToken{}).Error; err != nil {
+				return fmt.Errorf("failed to delete guest sessions: %w", err)
+			}
+			return deleteUserData(tx, guestID)
+		})
+		if err == gorm.ErrRecordNotFound {
+			continue
+		}
+		if err != nil {
+			s.logger.Error("Failed to purge guest", zap.String("user_id", guestID), zap.Error(err))
+			continue
+		}
+		purged++
+	}
+	return purged, nil
+}
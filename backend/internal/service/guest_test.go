@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"paperplay/config"
+	"paperplay/internal/model"
+)
+
+func setupGuestTest(t *testing.T, sessions map[string]WeChatSession) (*gorm.DB, *GuestService) {
+	db, wechatService := setupWeChatTest(t, sessions)
+	require.NoError(t, db.AutoMigrate(
+		&model.RefreshToken{}, &model.UserProgress{}, &model.UserAttempts{}, &model.AnswerRecord{},
+		&model.Event{}, &model.OutboxEvent{}, &model.UserAchievement{}, &model.AchievementAuditLog{}, &model.UserAchievementProgress{}, &model.NFTAsset{}, &model.QuestionReport{},
+	))
+
+	guestService := NewGuestService(db, &config.Config{
+		Guest: config.GuestConfig{Enabled: true, RetentionDays: 30},
+	}, nil, wechatService, zap.NewNop())
+	return db, guestService
+}
+
+// seedGuestPlay gives a guest progress, attempts, an answer with its pending event and achievements
+func seedGuestPlay(t *testing.T, db *gorm.DB, guestID string, earnedAt time.Time) {
+	require.NoError(t, db.Create(&model.UserProgress{UserID: guestID, LevelID: "level-1", Status: model.ProgressCompleted, Score: 90, Stars: 3}).Error)
+	require.NoError(t, db.Create(&model.UserProgress{UserID: guestID, LevelID: "level-2", Status: model.ProgressInProgress, Score: 40}).Error)
+	require.NoError(t, db.Create(&model.UserAttempts{UserID: guestID, StatDate: "2026-10-17", AttemptsTotal: 4, AttemptsCorrect: 3, TotalTimeMs: 4000}).Error)
+	require.NoError(t, db.Create(&model.AnswerRecord{UserID: guestID, LevelID: "level-1", QuestionID: "q-1", RevisionID: "r-1", IsCorrect: true}).Error)
+	event := &model.Event{UserID: guestID, EventType: "question_answered"}
+	require.NoError(t, db.Create(event).Error)
+	require.NoError(t, db.Create(&model.OutboxEvent{EventID: event.ID, UserID: guestID, EventType: event.EventType,
+		Status: model.OutboxPending, AvailableAt: time.Now(), CreatedAt: time.Now()}).Error)
+	for _, achievementID := range []string{"first-pass", "perfect"} {
+		earned := &model.UserAchievement{UserID: guestID, AchievementID: achievementID, EarnedAt: earnedAt}
+		require.NoError(t, db.Create(earned).Error)
+		require.NoError(t, db.Create(&model.AchievementAuditLog{UserID: guestID, AchievementID: achievementID,
+			UserAchievementID: earned.ID, Action: model.AuditActionAward, Source: model.AuditSourceRule, CreatedAt: earnedAt}).Error)
+	}
+}
+
+func TestGuestService_UpgradeWithNewEmail(t *testing.T) {
+	db, guestService := setupGuestTest(t, nil)
+
+	guest, err := guestService.CreateGuest("")
+	require.NoError(t, err)
+	assert.True(t, guest.IsGuest())
+	assert.Equal(t, "Guest", guest.DisplayName)
+	assert.False(t, guest.HasEmailLogin())
+	seedGuestPlay(t, db, guest.ID, time.Now())
+
+	// Placeholder addresses cannot be claimed
+	_, err = guestService.UpgradeWithEmail(guest.ID, "someone@"+model.GuestEmailDomain, "password123", "")
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	result, err := guestService.UpgradeWithEmail(guest.ID, "New@Example.com", "password123", "Alice")
+	require.NoError(t, err)
+	assert.False(t, result.Merged)
+	assert.Equal(t, guest.ID, result.User.ID)
+	assert.Equal(t, model.RoleUser, result.User.Role)
+	assert.Equal(t, "new@example.com", result.User.Email)
+	assert.Equal(t, "Alice", result.User.DisplayName)
+	assert.True(t, result.User.HasEmailLogin())
+	assert.Equal(t, 1, result.User.TokenVersion)
+
+	// The data stays where it was
+	var progress int64
+	db.Model(&model.UserProgress{}).Where("user_id = ?", guest.ID).Count(&progress)
+	assert.Equal(t, int64(2), progress)
+
+	_, err = guestService.UpgradeWithEmail(guest.ID, "other@example.com", "password123", "")
+	assert.ErrorIs(t, err, ErrNotGuest)
+}
+
+func TestGuestService_MergeIntoExistingAccount(t *testing.T) {
+	db, guestService := setupGuestTest(t, nil)
+
+	user := &model.User{Email: "alice@example.com", DisplayName: "Alice", Role: model.RoleUser}
+	require.NoError(t, user.SetPassword("password123"))
+	require.NoError(t, db.Create(user).Error)
+	earlier := time.Now().Add(-48 * time.Hour)
+	require.NoError(t, db.Create(&model.UserProgress{UserID: user.ID, LevelID: "level-1", Status: model.ProgressInProgress, Score: 50, Stars: 1}).Error)
+	require.NoError(t, db.Create(&model.UserAttempts{UserID: user.ID, StatDate: "2026-10-17", AttemptsTotal: 6, AttemptsCorrect: 3, TotalTimeMs: 6000}).Error)
+	require.NoError(t, db.Create(&model.UserAchievement{UserID: user.ID, AchievementID: "first-pass", EarnedAt: time.Now()}).Error)
+
+	guest, err := guestService.CreateGuest("Player")
+	require.NoError(t, err)
+	seedGuestPlay(t, db, guest.ID, earlier)
+
+	_, err = guestService.UpgradeWithEmail(guest.ID, "alice@example.com", "wrong-password", "")
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+
+	result, err := guestService.UpgradeWithEmail(guest.ID, "alice@example.com", "password123", "")
+	require.NoError(t, err)
+	assert.True(t, result.Merged)
+	assert.Equal(t, user.ID, result.User.ID)
+
+	// The guest is gone and nothing is left under its ID
+	assert.ErrorIs(t, db.First(&model.User{}, "id = ?", guest.ID).Error, gorm.ErrRecordNotFound)
+	for _, m := range userDataModels {
+		var count int64
+		db.Model(m).Where("user_id = ?", guest.ID).Count(&count)
+		assert.Zero(t, count, "%T", m)
+	}
+
+	// Levels played by both keep the better result, others move over
+	var progress []model.UserProgress
+	db.Where("user_id = ?", user.ID).Order("level_id").Find(&progress)
+	require.Len(t, progress, 2)
+	assert.Equal(t, model.ProgressCompleted, progress[0].Status)
+	assert.Equal(t, 90, progress[0].Score)
+	assert.Equal(t, 3, progress[0].Stars)
+	assert.Equal(t, "level-2", progress[1].LevelID)
+
+	// Daily statistics of the same day add up
+	var attempts model.UserAttempts
+	require.NoError(t, db.Where("user_id = ? AND stat_date = ?", user.ID, "2026-10-17").First(&attempts).Error)
+	assert.Equal(t, 10, attempts.AttemptsTotal)
+	assert.Equal(t, 6, attempts.AttemptsCorrect)
+	assert.InDelta(t, 0.6, attempts.CorrectRate, 0.001)
+	assert.Equal(t, 1000, attempts.AvgDurationMs)
+
+	// Achievements are not duplicated; the earlier earn date wins
+	var achievements []model.UserAchievement
+	db.Where("user_id = ?", user.ID).Order("achievement_id").Find(&achievements)
+	require.Len(t, achievements, 2)
+	assert.WithinDuration(t, earlier, achievements[0].EarnedAt, time.Second)
+
+	// The audit trail follows the achievements it describes
+	var audit []model.AchievementAuditLog
+	db.Where("user_id = ?", user.ID).Order("achievement_id").Find(&audit)
+	require.Len(t, audit, 2)
+	assert.Equal(t, achievements[0].ID, audit[0].UserAchievementID)
+	assert.Equal(t, achievements[1].ID, audit[1].UserAchievementID)
+
+	// Undelivered events are delivered for the account
+	var answers, events, outbox int64
+	db.Model(&model.AnswerRecord{}).Where("user_id = ?", user.ID).Count(&answers)
+	db.Model(&model.Event{}).Where("user_id = ?", user.ID).Count(&events)
+	db.Model(&model.OutboxEvent{}).Where("user_id = ? AND status = ?", user.ID, model.OutboxPending).Count(&outbox)
+	assert.Equal(t, int64(1), answers)
+	assert.Equal(t, int64(1), events)
+	assert.Equal(t, int64(1), outbox)
+}
+
+func TestGuestService_MergeSupersedesTiers(t *testing.T) {
+	db, guestService := setupGuestTest(t, nil)
+	tiers := seedLevelSeries(t, db)
+	bronze, silver, gold := tiers[0].ID, tiers[1].ID, tiers[2].ID
+
+	user := &model.User{Email: "alice@example.com", DisplayName: "Alice", Role: model.RoleUser}
+	require.NoError(t, user.SetPassword("password123"))
+	require.NoError(t, db.Create(user).Error)
+	userSilver := &model.UserAchievement{UserID: user.ID, AchievementID: silver, EarnedAt: time.Now()}
+	require.NoError(t, db.Create(userSilver).Error)
+
+	// The guest climbed further, its Bronze already superseded by its own Gold
+	guest, err := guestService.CreateGuest("")
+	require.NoError(t, err)
+	guestGold := &model.UserAchievement{UserID: guest.ID, AchievementID: gold, EarnedAt: time.Now()}
+	require.NoError(t, db.Create(guestGold).Error)
+	superseded := time.Now()
+	require.NoError(t, db.Create(&model.UserAchievement{UserID: guest.ID, AchievementID: bronze, EarnedAt: time.Now(),
+		SupersededAt: &superseded, SupersededBy: &guestGold.ID}).Error)
+
+	_, err = guestService.UpgradeWithEmail(guest.ID, "alice@example.com", "password123", "")
+	require.NoError(t, err)
+
+	var current []model.UserAchievement
+	require.NoError(t, db.Where("user_id = ? AND superseded_at IS NULL", user.ID).Find(&current).Error)
+	require.Len(t, current, 1)
+	assert.Equal(t, gold, current[0].AchievementID)
+
+	var merged model.UserAchievement
+	require.NoError(t, db.First(&merged, "id = ?", userSilver.ID).Error)
+	assert.True(t, merged.IsSuperseded())
+	assert.Equal(t, guestGold.ID, *merged.SupersededBy)
+}
+
+func TestGuestService_UpgradeWithWeChat(t *testing.T) {
+	db, guestService := setupGuestTest(t, map[string]WeChatSession{
+		"code-new":    {OpenID: "openid-new", SessionKey: "key"},
+		"code-linked": {OpenID: "openid-linked", SessionKey: "key"},
+	})
+	ctx := context.Background()
+
+	// An unlinked WeChat account is linked to the guest itself
+	guest, err := guestService.CreateGuest("")
+	require.NoError(t, err)
+	result, err := guestService.UpgradeWithWeChat(ctx, guest.ID, "code-new", "")
+	require.NoError(t, err)
+	assert.False(t, result.Merged)
+	assert.Equal(t, guest.ID, result.User.ID)
+	assert.Equal(t, model.RoleUser, result.User.Role)
+	assert.False(t, result.User.HasEmailLogin())
+
+	var identity model.ExternalIdentity
+	require.NoError(t, db.Where("subject = ?", "openid-new").First(&identity).Error)
+	assert.Equal(t, guest.ID, identity.UserID)
+
+	// A linked one takes the guest's data to the account it belongs to
+	owner, _, err := guestService.wechatService.Login(ctx, "code-linked", "")
+	require.NoError(t, err)
+	guest, err = guestService.CreateGuest("")
+	require.NoError(t, err)
+	seedGuestPlay(t, db, guest.ID, time.Now())
+
+	result, err = guestService.UpgradeWithWeChat(ctx, guest.ID, "code-linked", "")
+	require.NoError(t, err)
+	assert.True(t, result.Merged)
+	assert.Equal(t, owner.ID, result.User.ID)
+
+	var progress int64
+	db.Model(&model.UserProgress{}).Where("user_id = ?", owner.ID).Count(&progress)
+	assert.Equal(t, int64(2), progress)
+
+	_, err = guestService.UpgradeWithWeChat(ctx, guest.ID, "code-linked", "")
+	assert.ErrorIs(t, err, ErrUserNotFound)
+	_, err = guestService.UpgradeWithWeChat(ctx, owner.ID, "code-new", "")
+	assert.ErrorIs(t, err, ErrNotGuest)
+}
+
+func TestGuestService_PurgeAbandonedGuests(t *testing.T) {
+	db, guestService := setupGuestTest(t, nil)
+	old := time.Now().AddDate(0, 0, -40)
+
+	abandoned, err := guestService.CreateGuest("")
+	require.NoError(t, err)
+	seedGuestPlay(t, db, abandoned.ID, old)
+	staleUse := time.Now().AddDate(0, 0, -35)
+	require.NoError(t, db.Create(&model.RefreshToken{UserID: abandoned.ID, LastUsedAt: &staleUse, ExpiresAt: old, CreatedAt: old}).Error)
+
+	active, err := guestService.CreateGuest("")
+	require.NoError(t, err)
+	recentUse := time.Now().AddDate(0, 0, -1)
+	require.NoError(t, db.Create(&model.RefreshToken{UserID: active.ID, LastUsedAt: &recentUse, ExpiresAt: time.Now().Add(time.Hour), CreatedAt: old}).Error)
+
+	fresh, err := guestService.CreateGuest("")
+	require.NoError(t, err)
+
+	registered := &model.User{Email: "old@example.com", Role: model.RoleUser}
+	require.NoError(t, db.Create(registered).Error)
+
+	for _, u := range []*model.User{abandoned, active, registered} {
+		require.NoError(t, db.Model(u).UpdateColumn("created_at", old).Error)
+	}
+
+	purged, err := guestService.PurgeAbandonedGuests()
+	require.NoError(t, err)
+	assert.Equal(t, 1, purged)
+
+	assert.ErrorIs(t, db.First(&model.User{}, "id = ?", abandoned.ID).Error, gorm.ErrRecordNotFound)
+	var rows int64
+	db.Model(&model.UserProgress{}).Where("user_id = ?", abandoned.ID).Count(&rows)
+	assert.Zero(t, rows)
+	db.Model(&model.OutboxEvent{}).Where("user_id = ?", abandoned.ID).Count(&rows)
+	assert.Zero(t, rows)
+	db.Model(&model.RefreshToken{}).Where("user_id = ?", abandoned.ID).Count(&rows)
+	assert.Zero(t, rows)
+
+	for _, u := range []*model.User{active, fresh, registered} {
+		assert.NoError(t, db.First(&model.User{}, "id = ?", u.ID).Error)
+	}
+}
+
+func TestGuestService_Disabled(t *testing.T) {
+	db, _ := setupGuestTest(t, nil)
+	guestService := NewGuestService(db, &config.Config{Guest: config.GuestConfig{RetentionDays: 30}}, nil, nil, zap.NewNop())
+
+	_, err := guestService.CreateGuest("")
+	assert.ErrorIs(t, err, ErrGuestsDisabled)
+}
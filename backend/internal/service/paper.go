@@ -0,0 +1,288 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"paperplay/internal/model"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Paper errors
+var (
+	ErrPaperNotFound        = errors.New("paper not found")
+	ErrSubjectNotFound      = errors.New("subject not found")
+	ErrPaperSubjectConflict = errors.New("paper already belongs to another subject")
+)
+
+// PaperService handles paper metadata and citations
+type PaperService struct {
+	db *gorm.DB
+}
+
+// NewPaperService creates a new paper service
+func NewPaperService(db *gorm.DB) *PaperService {
+	return &PaperService{
+		db: db,
+	}
+}
+
+// PaperImportResult summarizes a citation import
+type PaperImportResult struct {
+	Created int           `json:"created"`
+	Updated int           `json:"updated"`
+	Skipped []string      `json:"skipped,omitempty"` // Reasons for entries that were not imported
+	Papers  []model.Paper `json:"papers"`
+}
+
+// orderedAuthors preloads authors in byline order
+func orderedAuthors(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
+// GetPaper returns a paper with its subject and ordered authors
+func (s *PaperService) GetPaper(paperID string) (*model.Paper, error) {
+	var paper model.Paper
+	if err := s.db.Preload("Subject").Preload("Authors", orderedAuthors).
+		First(&paper, "id = ?", paperID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrPaperNotFound
+		}
+		return nil, fmt.Errorf("failed to get paper: %w", err)
+	}
+	if err := s.EnsureAuthors(&paper); err != nil {
+		return nil, err
+	}
+	return &paper, nil
+}
+
+// EnsureAuthors persists structured authors for papers written directly to the
+// database (e.g. by the agent), which only carry the legacy author string
+func (s *PaperService) EnsureAuthors(paper *model.Paper) error {
+	if len(paper.Authors) > 0 || paper.PaperAuthor == "" {
+		return nil
+	}
+
+	authors := paper.BuildAuthors()
+	if len(authors) == 0 {
+		return nil
+	}
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Another request may have created them concurrently
+		var count int64
+		if err := tx.Model(&model.Author{}).Where("paper_id = ?", paper.ID).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to count authors: %w", err)
+		}
+		if count > 0 {
+			return tx.Where("paper_id = ?", paper.ID).Order("position ASC").Find(&authors).Error
+		}
+		if err := tx.Create(&authors).Error; err != nil {
+			return fmt.Errorf("failed to create authors: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	paper.Authors = authors
+	return nil
+}
+
+// Cite renders the citation of a paper in the given format
+func (s *PaperService) Cite(paperID, format string) (*model.Paper, string, error) {
+	if !IsValidCitationFormat(format) {
+		return nil, "", ErrUnsupportedCitationFormat
+	}
+	paper, err := s.GetPaper(paperID)
+	if err != nil {
+		return nil, "", err
+	}
+	citation, err := FormatCitation(format, paper)
+	if err != nil {
+		return nil, "", err
+	}
+	return paper, citation, nil
+}
+
+// Export renders all papers, optionally of one subject, as a BibTeX or RIS file
+func (s *PaperService) Export(format, subjectID string) (string, error) {
+	if !IsValidCitationFormat(format) {
+		return "", ErrUnsupportedCitationFormat
+	}
+
+	query := s.db.Preload("Authors", orderedAuthors).Order("pub_year DESC, title ASC")
+	if subjectID != "" {
+		query = query.Where("subject_id = ?", subjectID)
+	}
+	var papers []model.Paper
+	if err := query.Find(&papers).Error; err != nil {
+		return "", fmt.Errorf("failed to get papers: %w", err)
+	}
+
+	entries := make([]string, 0, len(papers))
+	for i := range papers {
+		citation, err := FormatCitation(format, &papers[i])
+		if err != nil {
+			return "", err
+		}
+		entries = append(entries, citation)
+	}
+	return strings.Join(entries, "\n"), nil
+}
+
+// Import creates or updates the papers of a subject from BibTeX or RIS content.
+// Existing papers are matched by DOI, then arXiv ID, then title within the subject.
+// An entry matching a paper of another subject fails the import with ErrPaperSubjectConflict.
+func (s *PaperService) Import(format, content, subjectID string) (*PaperImportResult, error) {
+	parsed, err := ParseCitations(format, content)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &PaperImportResult{Papers: []model.Paper{}}
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var subject model.Subject
+		if err := tx.First(&subject, "id = ?", subjectID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrSubjectNotFound
+			}
+			return fmt.Errorf("failed to get subject: %w", err)
+		}
+
+		for i, incoming := range parsed {
+			if strings.TrimSpace(incoming.Title) == "" {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("entry %d: missing title", i+1))
+				continue
+			}
+
+			existing, err := findMatchingPaper(tx, incoming, subjectID)
+			if err != nil {
+				return err
+			}
+
+			var paper *model.Paper
+			if existing != nil {
+				paper = existing
+				mergePaperMetadata(paper, incoming)
+				if err := tx.Omit("Authors").Save(paper).Error; err != nil {
+					return fmt.Errorf("failed to update paper: %w", err)
+				}
+				result.Updated++
+			} else {
+				paper = incoming
+				paper.SubjectID = subjectID
+				paper.ID = agentPaperID(paper.ArxivID)
+				paper.PaperPubYM = model.FormatPubYM(paper.PubYear, paper.PubMonth)
+				if err := tx.Omit("Authors").Create(paper).Error; err != nil {
+					return fmt.Errorf("failed to create paper: %w", err)
+				}
+				result.Created++
+			}
+
+			if err := replaceAuthors(tx, paper, incoming.Authors); err != nil {
+				return err
+			}
+			result.Papers = append(result.Papers, *paper)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// findMatchingPaper looks up an existing paper of the subject for an imported entry.
+// DOIs and arXiv IDs identify a paper across subjects, so a match elsewhere is a conflict.
+func findMatchingPaper(tx *gorm.DB, incoming *model.Paper, subjectID string) (*model.Paper, error) {
+	candidates := []struct {
+		query string
+		key   string
+		args  []interface{}
+	}{
+		{"doi = ?", model.NormalizeDOI(incoming.DOI), nil},
+		{"arxiv_id = ?", incoming.ArxivID, nil},
+		{"id = ?", agentPaperID(incoming.ArxivID), nil},
+		{"subject_id = ? AND lower(title) = ?", strings.ToLower(strings.TrimSpace(incoming.Title)), []interface{}{subjectID}},
+	}
+
+	for _, c := range candidates {
+		if c.key == "" {
+			continue
+		}
+		args := append(c.args, c.key)
+
+		var paper model.Paper
+		err := tx.Where(c.query, args...).First(&paper).Error
+		if err == nil {
+			if paper.SubjectID != subjectID {
+				return nil, fmt.Errorf("%w: %q is paper %s of subject %s",
+					ErrPaperSubjectConflict, incoming.Title, paper.ID, paper.SubjectID)
+			}
+			return &paper, nil
+		}
+		if err != gorm.ErrRecordNotFound {
+			return nil, fmt.Errorf("failed to match paper: %w", err)
+		}
+	}
+	return nil, nil
+}
+
+// agentPaperID returns the ID the agent uses for an arXiv paper, empty if there is no arXiv ID
+func agentPaperID(arxivID string) string {
+	if arxivID == "" {
+		return ""
+	}
+	return "paper_" + arxivID
+}
+
+// mergePaperMetadata copies the non-empty imported fields onto an existing paper
+func mergePaperMetadata(paper, incoming *model.Paper) {
+	paper.Title = incoming.Title
+	for dst, src := range map[*string]string{
+		&paper.Abstract:  incoming.Abstract,
+		&paper.DOI:       incoming.DOI,
+		&paper.ArxivID:   incoming.ArxivID,
+		&paper.Venue:     incoming.Venue,
+		&paper.URL:       incoming.URL,
+		&paper.EntryType: incoming.EntryType,
+	} {
+		if src != "" {
+			*dst = src
+		}
+	}
+	if incoming.PubYear > 0 {
+		paper.PubYear, paper.PubMonth = incoming.PubYear, incoming.PubMonth
+		paper.PaperPubYM = model.FormatPubYM(paper.PubYear, paper.PubMonth)
+	}
+	if incoming.CitationCount > 0 {
+		paper.CitationCount = incoming.CitationCount
+		paper.PaperCitationCount = ""
+	}
+	if len(incoming.Authors) > 0 {
+		// Rebuilt from the structured authors on save
+		paper.PaperAuthor = ""
+		paper.Authors = incoming.Authors
+	}
+}
+
+// replaceAuthors swaps the stored authors of a paper for the imported ones
+func replaceAuthors(tx *gorm.DB, paper *model.Paper, authors []model.Author) error {
+	if len(authors) == 0 {
+		return nil
+	}
+	if err := tx.Where("paper_id = ?", paper.ID).Delete(&model.Author{}).Error; err != nil {
+		return fmt.Errorf("failed to delete authors: %w", err)
+	}
+	for i := range authors {
+		authors[i].ID = ""
+		authors[i].PaperID = paper.ID
+		authors[i].Position = i + 1
+	}
+	if err := tx.Create(&authors).Error; err != nil {
+		return fmt.Errorf("failed to create authors: %w", err)
+	}
+	paper.Authors = authors
+	return nil
+}
@@ -0,0 +1,283 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"paperplay/internal/model"
+)
+
+const testBibTeX = `
+@string{nips = "Advances in Neural Information Processing Systems"}
+
+% Comments outside entries are ignored
+@inproceedings{vaswani2017attention,
+  title     = {Attention Is {All} You Need},
+  author    = {Vaswani, Ashish and Shazeer, Noam and Parmar, Niki and Kaiser, {\L}ukasz},
+  booktitle = nips # " 30",
+  year      = 2017,
+  month     = jun,
+  doi       = {https://doi.org/10.5555/3295222.3295349},
+  abstract  = "The dominant sequence transduction models use 50\% less {RNNs}."
+}
+
+@article{devlin2018bert,
+  title         = "{BERT}: Pre-training of Deep Bidirectional Transformers",
+  author        = "Jacob Devlin and Ming-Wei Chang and Kristina Toutanova and others",
+  journal       = {arXiv preprint},
+  year          = {2018},
+  eprint        = {1810.04805},
+  archivePrefix = {arXiv}
+}
+`
+
+const testRIS = "TY  - JOUR\r\n" +
+	"TI  - Deep Residual Learning for Image Recognition\r\n" +
+	"AU  - He, Kaiming\r\n" +
+	"AU  - 张, 祥雨\r\n" +
+	"JO  - CVPR\r\n" +
+	"PY  - 2016/06/27\r\n" +
+	"DO  - 10.1109/CVPR.2016.90\r\n" +
+	"AB  - Deeper neural networks are more difficult\r\n" +
+	"to train.\r\n" +
+	"ER  - \r\n"
+
+func TestParseBibTeX(t *testing.T) {
+	papers, err := ParseBibTeX(testBibTeX)
+	require.NoError(t, err)
+	require.Len(t, papers, 2)
+
+	attention := papers[0]
+	assert.Equal(t, "inproceedings", attention.EntryType)
+	assert.Equal(t, "Attention Is All You Need", attention.Title)
+	assert.Equal(t, "Advances in Neural Information Processing Systems 30", attention.Venue)
+	assert.Equal(t, 2017, attention.PubYear)
+	assert.Equal(t, 6, attention.PubMonth)
+	assert.Equal(t, "The dominant sequence transduction models use 50% less RNNs.", attention.Abstract)
+	require.Len(t, attention.Authors, 4)
+	assert.Equal(t, "Ashish", attention.Authors[0].GivenName)
+	assert.Equal(t, "Vaswani", attention.Authors[0].FamilyName)
+	assert.Equal(t, "Łukasz Kaiser", attention.Authors[3].Name)
+	assert.Equal(t, 4, attention.Authors[3].Position)
+
+	bert := papers[1]
+	assert.Equal(t, "BERT: Pre-training of Deep Bidirectional Transformers", bert.Title)
+	assert.Equal(t, "1810.04805", bert.ArxivID)
+	// "and others" is not an author
+	require.Len(t, bert.Authors, 3)
+	assert.Equal(t, "Chang", bert.Authors[1].FamilyName)
+
+	_, err = ParseBibTeX("@article{broken, title = {unbalanced")
+	assert.ErrorIs(t, err, ErrCitationParse)
+	_, err = ParseBibTeX("no entries here")
+	assert.ErrorIs(t, err, ErrCitationParse)
+}
+
+func TestParseBibTeX_ParenthesesInValues(t *testing.T) {
+	papers, err := ParseBibTeX(`@comment(a {note) here)
+@article{a, title = {Smile :)}, author = {Doe, Jane}, year = 2020}
+@article(b, title = {Counting (Again}, abstract = {We show 1) foo and 2) bar.}, year = {2021})`)
+	require.NoError(t, err)
+	require.Len(t, papers, 2)
+
+	assert.Equal(t, "Smile :)", papers[0].Title)
+	assert.Equal(t, 2020, papers[0].PubYear)
+	require.Len(t, papers[0].Authors, 1)
+	assert.Equal(t, "Doe", papers[0].Authors[0].FamilyName)
+
+	assert.Equal(t, "Counting (Again", papers[1].Title)
+	assert.Equal(t, "We show 1) foo and 2) bar.", papers[1].Abstract)
+	assert.Equal(t, 2021, papers[1].PubYear)
+}
+
+func TestParseRIS(t *testing.T) {
+	papers, err := ParseRIS(testRIS)
+	require.NoError(t, err)
+	require.Len(t, papers, 1)
+
+	paper := papers[0]
+	assert.Equal(t, "article", paper.EntryType)
+	assert.Equal(t, "Deep Residual Learning for Image Recognition", paper.Title)
+	assert.Equal(t, "CVPR", paper.Venue)
+	assert.Equal(t, 2016, paper.PubYear)
+	assert.Equal(t, 6, paper.PubMonth)
+	assert.Equal(t, "10.1109/CVPR.2016.90", paper.DOI)
+	assert.Equal(t, "Deeper neural networks are more difficult to train.", paper.Abstract)
+	require.Len(t, paper.Authors, 2)
+	assert.Equal(t, "张", paper.Authors[1].FamilyName)
+
+	_, err = ParseRIS("TY  - JOUR\r\nTI  - No end\r\n")
+	assert.ErrorIs(t, err, ErrCitationParse)
+}
+
+func TestCitationRoundTrip(t *testing.T) {
+	paper := &model.Paper{
+		Title:       "Attention Is All You Need",
+		PaperAuthor: "Ashish Vaswani; Noam Shazeer",
+		Venue:       "NeurIPS",
+		EntryType:   "inproceedings",
+		PubYear:     2017,
+		PubMonth:    6,
+		DOI:         "10.5555/3295222.3295349",
+		ArxivID:     "1706.03762",
+		Abstract:    "Transformers & attention",
+	}
+	assert.Equal(t, "vaswani2017attention", CitationKey(paper))
+
+	bib := FormatBibTeX(paper)
+	assert.Contains(t, bib, "@inproceedings{vaswani2017attention,")
+	assert.Contains(t, bib, "author = {Vaswani, Ashish and Shazeer, Noam}")
+	assert.Contains(t, bib, "booktitle = {NeurIPS}")
+
+	for _, format := range []string{CitationFormatBibTeX, CitationFormatRIS} {
+		text, err := FormatCitation(format, paper)
+		require.NoError(t, err)
+		parsed, err := ParseCitations(format, text)
+		require.NoError(t, err, format)
+		require.Len(t, parsed, 1)
+
+		got := parsed[0]
+		assert.Equal(t, paper.Title, got.Title, format)
+		assert.Equal(t, paper.Venue, got.Venue, format)
+		assert.Equal(t, paper.PubYear, got.PubYear, format)
+		assert.Equal(t, paper.PubMonth, got.PubMonth, format)
+		assert.Equal(t, paper.DOI, got.DOI, format)
+		assert.Equal(t, paper.Abstract, got.Abstract, format)
+		require.Len(t, got.Authors, 2, format)
+		assert.Equal(t, "Ashish Vaswani", got.Authors[0].Name, format)
+	}
+
+	// Escaped braces survive the grouping braces being stripped
+	paper.Title = "Sets {a, b} in {LaTeX}"
+	text, err := FormatCitation(CitationFormatBibTeX, paper)
+	require.NoError(t, err)
+	parsed, err := ParseCitations(CitationFormatBibTeX, text)
+	require.NoError(t, err)
+	require.Len(t, parsed, 1)
+	assert.Equal(t, paper.Title, parsed[0].Title)
+
+	_, err = FormatCitation("endnote", paper)
+	assert.ErrorIs(t, err, ErrUnsupportedCitationFormat)
+}
+
+func setupPaperTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&model.Subject{},
+		&model.Paper{},
+		&model.Author{},
+	)
+	require.NoError(t, err)
+
+	require.NoError(t, db.Create(&model.Subject{ID: "subject-1", Name: "Deep Learning"}).Error)
+
+	// Paper written directly by the agent, with legacy metadata strings only
+	require.NoError(t, db.Exec(`INSERT INTO papers (id, subject_id, title, paper_author, paper_pub_ym,
+		paper_citation_count, created_at, updated_at) VALUES
+		('paper_1810.04805', 'subject-1', 'BERT', 'Jacob Devlin; Ming-Wei Chang', '2018', '42', datetime('now'), datetime('now'))`).Error)
+
+	return db
+}
+
+func TestPaperService_EnsureAuthors(t *testing.T) {
+	db := setupPaperTestDB(t)
+	paperService := NewPaperService(db)
+
+	paper, err := paperService.GetPaper("paper_1810.04805")
+	require.NoError(t, err)
+	require.Len(t, paper.Authors, 2)
+	assert.Equal(t, "Devlin", paper.Authors[0].FamilyName)
+	assert.Equal(t, 2, paper.Authors[1].Position)
+
+	var count int64
+	db.Model(&model.Author{}).Where("paper_id = ?", paper.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	// Second read uses the stored rows
+	paper, err = paperService.GetPaper("paper_1810.04805")
+	require.NoError(t, err)
+	require.Len(t, paper.Authors, 2)
+	db.Model(&model.Author{}).Where("paper_id = ?", paper.ID).Count(&count)
+	assert.Equal(t, int64(2), count)
+
+	_, citation, err := paperService.Cite("paper_1810.04805", CitationFormatBibTeX)
+	require.NoError(t, err)
+	assert.Contains(t, citation, "@misc{devlin2018bert,")
+
+	_, err = paperService.GetPaper("missing")
+	assert.ErrorIs(t, err, ErrPaperNotFound)
+}
+
+func TestPaperService_Import(t *testing.T) {
+	db := setupPaperTestDB(t)
+	paperService := NewPaperService(db)
+
+	result, err := paperService.Import(CitationFormatBibTeX, testBibTeX, "subject-1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+	// BERT is matched to the agent paper by its arXiv ID
+	assert.Equal(t, 1, result.Updated)
+
+	var bert model.Paper
+	require.NoError(t, db.Preload("Authors", orderedAuthors).First(&bert, "id = ?", "paper_1810.04805").Error)
+	assert.Equal(t, "BERT: Pre-training of Deep Bidirectional Transformers", bert.Title)
+	assert.Equal(t, "arXiv preprint", bert.Venue)
+	assert.Equal(t, 42, bert.CitationCount)
+	require.Len(t, bert.Authors, 3)
+	assert.Equal(t, "Jacob Devlin; Ming-Wei Chang; Kristina Toutanova", bert.PaperAuthor)
+
+	var attention model.Paper
+	require.NoError(t, db.First(&attention, "doi = ?", "10.5555/3295222.3295349").Error)
+	assert.Equal(t, "2017-06", attention.PaperPubYM)
+	assert.Equal(t, "0", attention.PaperCitationCount)
+
+	// Re-importing is idempotent: matched by DOI and arXiv ID
+	result, err = paperService.Import(CitationFormatBibTeX, testBibTeX, "subject-1")
+	require.NoError(t, err)
+	assert.Equal(t, 0, result.Created)
+	assert.Equal(t, 2, result.Updated)
+
+	var papers int64
+	db.Model(&model.Paper{}).Count(&papers)
+	assert.Equal(t, int64(2), papers)
+	var authors int64
+	db.Model(&model.Author{}).Count(&authors)
+	assert.Equal(t, int64(7), authors)
+
+	exported, err := paperService.Export(CitationFormatRIS, "subject-1")
+	require.NoError(t, err)
+	parsed, err := ParseRIS(exported)
+	require.NoError(t, err)
+	assert.Len(t, parsed, 2)
+
+	_, err = paperService.Import(CitationFormatBibTeX, testBibTeX, "missing")
+	assert.ErrorIs(t, err, ErrSubjectNotFound)
+}
+
+func TestPaperService_ImportConflictingSubject(t *testing.T) {
+	db := setupPaperTestDB(t)
+	paperService := NewPaperService(db)
+	require.NoError(t, db.Create(&model.Subject{ID: "subject-2", Name: "Language Models"}).Error)
+
+	_, err := paperService.Import(CitationFormatBibTeX, testBibTeX, "subject-1")
+	require.NoError(t, err)
+
+	// The same papers under another subject are rejected, not merged into the first subject's rows
+	_, err = paperService.Import(CitationFormatBibTeX, testBibTeX, "subject-2")
+	assert.ErrorIs(t, err, ErrPaperSubjectConflict)
+
+	var moved int64
+	db.Model(&model.Paper{}).Where("subject_id = ?", "subject-2").Count(&moved)
+	assert.Zero(t, moved)
+
+	// Papers not held by another subject still import
+	result, err := paperService.Import(CitationFormatRIS, testRIS, "subject-2")
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Created)
+}
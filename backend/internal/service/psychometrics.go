@@ -0,0 +1,480 @@
+package service
+
+import (
+	"fmt"
+	"math"
+	"paperplay/internal/model"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Psychometric thresholds
+const (
+	// MinResponsesForFlag is the number of answered responses needed before a question can be flagged
+	MinResponsesForFlag = 20
+	// extremeGroupShare is the share of learners forming the upper and lower performance groups
+	extremeGroupShare = 0.27
+	// negativeDiscrimination is the point-biserial below which a question is flagged
+	negativeDiscrimination = -0.1
+)
+
+// QuestionStatsService computes per-question statistics from the answer log
+type QuestionStatsService struct {
+	db *gorm.DB
+}
+
+// NewQuestionStatsService creates a new question statistics service
+func NewQuestionStatsService(db *gorm.DB) *QuestionStatsService {
+	return &QuestionStatsService{
+		db: db,
+	}
+}
+
+// QuestionStatsFilter holds the filters for listing question statistics
+type QuestionStatsFilter struct {
+	LevelID      string
+	FlaggedOnly  bool
+	MinResponses int
+	Limit        int
+	Offset       int
+}
+
+// itemResponse is a learner's response to one question, used during computation
+type itemResponse struct {
+	userID    string
+	skipped   bool
+	correct   bool
+	duration  int
+	selection []string
+	restScore float64 // Proportion correct on the learner's other questions in the level
+	hasRest   bool
+}
+
+// RefreshAll recomputes statistics for every level that has answers and returns the number of questions updated
+func (s *QuestionStatsService) RefreshAll() (int, error) {
+	var levelIDs []string
+	if err := s.db.Model(&model.AnswerRecord{}).
+		Distinct("level_id").
+		Pluck("level_id", &levelIDs).Error; err != nil {
+		return 0, fmt.Errorf("failed to get answered levels: %w", err)
+	}
+
+	total := 0
+	for _, levelID := range levelIDs {
+		count, err := s.RefreshLevel(levelID)
+		if err != nil {
+			return total, err
+		}
+		total += count
+	}
+
+	return total, nil
+}
+
+// RefreshLevel recomputes statistics for all published questions of a level.
+// Discrimination is relative to the other questions of the same level, so levels are computed as a unit.
+func (s *QuestionStatsService) RefreshLevel(levelID string) (int, error) {
+	var questions []model.Question
+	if err := s.db.Scopes(model.PublishedQuestions).
+		Where("level_id = ? AND published_revision_id IS NOT NULL", levelID).
+		Find(&questions).Error; err != nil {
+		return 0, fmt.Errorf("failed to get level questions: %w", err)
+	}
+	if len(questions) == 0 {
+		return 0, nil
+	}
+
+	revisionIDs := make([]string, 0, len(questions))
+	for _, q := range questions {
+		revisionIDs = append(revisionIDs, *q.PublishedRevisionID)
+	}
+
+	// Only answers to the published revisions are comparable
+	var records []model.AnswerRecord
+	if err := s.db.Where("level_id = ? AND revision_id IN ?", levelID, revisionIDs).
+		Order("created_at ASC").
+		Find(&records).Error; err != nil {
+		return 0, fmt.Errorf("failed to get answer records: %w", err)
+	}
+
+	responses := firstResponses(records)
+	computeRestScores(responses)
+
+	now := time.Now()
+	for i := range questions {
+		stats := computeQuestionStats(&questions[i], responses[questions[i].ID])
+		stats.ComputedAt = now
+		if err := s.db.Save(stats).Error; err != nil {
+			return 0, fmt.Errorf("failed to save question stats: %w", err)
+		}
+	}
+
+	return len(questions), nil
+}
+
+// GetQuestionStats retrieves the stored statistics of a question
+func (s *QuestionStatsService) GetQuestionStats(questionID string) (*model.QuestionStats, error) {
+	var stats model.QuestionStats
+	if err := s.db.First(&stats, "question_id = ?", questionID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrQuestionNotFound
+		}
+		return nil, fmt.Errorf("failed to get question stats: %w", err)
+	}
+	return &stats, nil
+}
+
+// ListQuestionStats lists stored statistics, worst discriminating questions first
+func (s *QuestionStatsService) ListQuestionStats(filter QuestionStatsFilter) ([]model.QuestionStats, int64, error) {
+	query := s.db.Model(&model.QuestionStats{})
+	if filter.LevelID != "" {
+		query = query.Where("level_id = ?", filter.LevelID)
+	}
+	if filter.FlaggedOnly {
+		query = query.Where("suspected_wrong_key = ?", true)
+	}
+	if filter.MinResponses > 0 {
+		query = query.Where("answered >= ?", filter.MinResponses)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count question stats: %w", err)
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+
+	var stats []model.QuestionStats
+	if err := query.Preload("Question").
+		Order("suspected_wrong_key DESC, COALESCE(point_biserial, 1) ASC, question_id ASC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&stats).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list question stats: %w", err)
+	}
+
+	return stats, total, nil
+}
+
+// firstResponses reduces the answer log to one response per learner and question.
+// The first real answer is used; a learner who only ever skipped counts as a skip.
+func firstResponses(records []model.AnswerRecord) map[string][]*itemResponse {
+	byQuestion := make(map[string][]*itemResponse)
+	seen := make(map[string]*itemResponse)
+
+	for _, record := range records {
+		key := record.QuestionID + "|" + record.UserID
+		existing, ok := seen[key]
+		if ok && !existing.skipped {
+			continue
+		}
+
+		response := &itemResponse{
+			userID:   record.UserID,
+			skipped:  record.Skipped,
+			correct:  record.IsCorrect && !record.Skipped,
+			duration: record.DurationMS,
+		}
+		if !record.Skipped {
+			response.selection = splitSelection(AnswerString(record.AnswerJSON))
+		}
+
+		if ok {
+			*existing = *response
+			continue
+		}
+		seen[key] = response
+		byQuestion[record.QuestionID] = append(byQuestion[record.QuestionID], response)
+	}
+
+	return byQuestion
+}
+
+// computeRestScores sets each response's rest score: the learner's proportion correct on the other questions
+func computeRestScores(byQuestion map[string][]*itemResponse) {
+	answered := make(map[string]int)
+	correct := make(map[string]int)
+	for _, responses := range byQuestion {
+		for _, r := range responses {
+			if r.skipped {
+				continue
+			}
+			answered[r.userID]++
+			if r.correct {
+				correct[r.userID]++
+			}
+		}
+	}
+
+	for _, responses := range byQuestion {
+		for _, r := range responses {
+			if r.skipped {
+				continue
+			}
+			others := answered[r.userID] - 1
+			if others == 0 {
+				continue
+			}
+			otherCorrect := correct[r.userID]
+			if r.correct {
+				otherCorrect--
+			}
+			r.restScore = float64(otherCorrect) / float64(others)
+			r.hasRest = true
+		}
+	}
+}
+
+// computeQuestionStats computes the statistics of one question from its responses
+func computeQuestionStats(question *model.Question, responses []*itemResponse) *model.QuestionStats {
+	stats := &model.QuestionStats{
+		QuestionID: question.ID,
+		RevisionID: *question.PublishedRevisionID,
+		LevelID:    question.LevelID,
+		Responses:  len(responses),
+	}
+
+	var answered []*itemResponse
+	var durations []int
+	correctCount := 0
+	for _, r := range responses {
+		if r.skipped {
+			continue
+		}
+		answered = append(answered, r)
+		if r.correct {
+			correctCount++
+		}
+		if r.duration > 0 {
+			durations = append(durations, r.duration)
+		}
+	}
+	stats.Answered = len(answered)
+
+	if stats.Responses > 0 {
+		stats.SkipRate = float64(stats.Responses-stats.Answered) / float64(stats.Responses)
+	}
+	if stats.Answered > 0 {
+		stats.PValue = float64(correctCount) / float64(stats.Answered)
+	}
+	stats.MedianDurationMS = median(durations)
+
+	// Discrimination only uses learners who answered other questions of the level
+	var ranked []*itemResponse
+	for _, r := range answered {
+		if r.hasRest {
+			ranked = append(ranked, r)
+		}
+	}
+	stats.PointBiserial = pointBiserial(ranked)
+
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return ranked[i].restScore > ranked[j].restScore
+	})
+	groupSize := int(math.Ceil(float64(len(ranked)) * extremeGroupShare))
+	var upper, lower []*itemResponse
+	if groupSize > 0 && len(ranked) >= 2 {
+		upper = ranked[:groupSize]
+		lower = ranked[len(ranked)-groupSize:]
+	}
+	stats.UpperPValue = proportionCorrect(upper)
+	stats.LowerPValue = proportionCorrect(lower)
+
+	rates := optionRates(question, answered, upper, lower)
+	_ = stats.SetOptionRates(rates)
+
+	stats.SuspectedWrongKey, stats.FlagReason = wrongKeyFlag(stats, rates)
+
+	return stats
+}
+
+// optionRates computes how often each option was selected overall and by the extreme groups
+func optionRates(question *model.Question, answered, upper, lower []*itemResponse) []model.OptionRate {
+	content, err := question.GetContent()
+	if err != nil || len(content.Options) == 0 {
+		return []model.OptionRate{}
+	}
+
+	// Options read "B. text" while keys and selections hold the bare label, so all are compared by label
+	keys := make(map[string]bool)
+	if answer, err := question.GetAnswer(); err == nil {
+		for _, option := range answer.CorrectOptions {
+			keys[optionLabel(option)] = true
+		}
+		if answer.CorrectOption != "" {
+			keys[optionLabel(answer.CorrectOption)] = true
+		}
+	}
+
+	rates := make([]model.OptionRate, 0, len(content.Options))
+	for _, option := range content.Options {
+		label := optionLabel(option)
+		rate := model.OptionRate{
+			Option:    option,
+			IsKey:     keys[label],
+			Count:     countSelecting(answered, label),
+			UpperRate: selectionRate(upper, label),
+			LowerRate: selectionRate(lower, label),
+		}
+		if len(answered) > 0 {
+			rate.Rate = float64(rate.Count) / float64(len(answered))
+		}
+		rates = append(rates, rate)
+	}
+
+	return rates
+}
+
+// wrongKeyFlag decides whether the answer key is likely wrong: strong learners getting the question "wrong"
+func wrongKeyFlag(stats *model.QuestionStats, rates []model.OptionRate) (bool, string) {
+	if stats.Answered < MinResponsesForFlag {
+		return false, ""
+	}
+
+	var reasons []string
+	if stats.PointBiserial != nil && *stats.PointBiserial < negativeDiscrimination {
+		reasons = append(reasons, fmt.Sprintf("negative discrimination (%.2f)", *stats.PointBiserial))
+	}
+	if stats.UpperPValue < stats.LowerPValue {
+		reasons = append(reasons, fmt.Sprintf("top performers score lower than bottom performers (%.2f < %.2f)",
+			stats.UpperPValue, stats.LowerPValue))
+	}
+
+	// A distractor preferred by top performers over every keyed option
+	bestKey := -1.0
+	for _, rate := range rates {
+		if rate.IsKey && rate.UpperRate > bestKey {
+			bestKey = rate.UpperRate
+		}
+	}
+	if bestKey >= 0 {
+		for _, rate := range rates {
+			if !rate.IsKey && rate.UpperRate > bestKey {
+				reasons = append(reasons, fmt.Sprintf("top performers prefer %q over the key", rate.Option))
+			}
+		}
+	}
+
+	return len(reasons) > 0, strings.Join(reasons, "; ")
+}
+
+// pointBiserial computes the correlation between correctness and rest score; nil when undefined
+func pointBiserial(responses []*itemResponse) *float64 {
+	n := len(responses)
+	if n < 2 {
+		return nil
+	}
+
+	var sum, sumCorrect float64
+	correct := 0
+	for _, r := range responses {
+		sum += r.restScore
+		if r.correct {
+			sumCorrect += r.restScore
+			correct++
+		}
+	}
+	if correct == 0 || correct == n {
+		return nil
+	}
+
+	mean := sum / float64(n)
+	var variance float64
+	for _, r := range responses {
+		variance += (r.restScore - mean) * (r.restScore - mean)
+	}
+	sd := math.Sqrt(variance / float64(n))
+	if sd == 0 {
+		return nil
+	}
+
+	p := float64(correct) / float64(n)
+	meanCorrect := sumCorrect / float64(correct)
+	meanIncorrect := (sum - sumCorrect) / float64(n-correct)
+	r := (meanCorrect - meanIncorrect) / sd * math.Sqrt(p*(1-p))
+	return &r
+}
+
+// proportionCorrect returns the share of correct responses
+func proportionCorrect(responses []*itemResponse) float64 {
+	if len(responses) == 0 {
+		return 0
+	}
+	correct := 0
+	for _, r := range responses {
+		if r.correct {
+			correct++
+		}
+	}
+	return float64(correct) / float64(len(responses))
+}
+
+// countSelecting counts the responses that selected the option with a label
+func countSelecting(responses []*itemResponse, label string) int {
+	count := 0
+	for _, r := range responses {
+		for _, selected := range r.selection {
+			if optionLabel(selected) == label {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// selectionRate returns the share of responses that selected an option
+func selectionRate(responses []*itemResponse, label string) float64 {
+	if len(responses) == 0 {
+		return 0
+	}
+	return float64(countSelecting(responses, label)) / float64(len(responses))
+}
+
+// optionLabel reduces an option such as "B. text" or "b）text" to its letter label "B";
+// text without a leading label is returned trimmed
+func optionLabel(option string) string {
+	option = strings.TrimSpace(option)
+	if option == "" {
+		return option
+	}
+	letter := option[0]
+	if !(letter >= 'A' && letter <= 'Z' || letter >= 'a' && letter <= 'z') {
+		return option
+	}
+	rest := strings.TrimSpace(option[1:])
+	if rest == "" || strings.ContainsRune(".．、:：)）", []rune(rest)[0]) {
+		return strings.ToUpper(option[:1])
+	}
+	return option
+}
+
+// splitSelection splits a comma separated answer into the selected options
+func splitSelection(answer string) []string {
+	var selection []string
+	for _, part := range strings.Split(answer, ",") {
+		if part = strings.TrimSpace(part); part != "" {
+			selection = append(selection, part)
+		}
+	}
+	return selection
+}
+
+// median returns the median of the values, 0 when empty
+func median(values []int) int {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 0 {
+		return (sorted[mid-1] + sorted[mid]) / 2
+	}
+	return sorted[mid]
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"paperplay/internal/model"
+)
+
+func setupPsychometricsTestDB(t *testing.T) (*gorm.DB, map[string]*model.QuestionRevision) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&model.User{},
+		&model.Level{},
+		&model.Question{},
+		&model.QuestionRevision{},
+		&model.AnswerRecord{},
+		&model.QuestionStats{},
+	)
+	require.NoError(t, err)
+
+	questionService := NewQuestionService(db)
+	revisions := make(map[string]*model.QuestionRevision)
+	for _, id := range []string{"q-miskeyed", "q-good", "q-other", "q-extra"} {
+		question := &model.Question{
+			ID:          id,
+			LevelID:     "level-1",
+			Stem:        "Stem of " + id,
+			ContentJSON: `{"type":"mcq","options":["A","B","C"]}`,
+			AnswerJSON:  `{"type":"single","correct_options":["A"]}`,
+			Score:       10,
+		}
+		require.NoError(t, db.Create(question).Error)
+		revision, err := questionService.EnsureBaseRevision(question)
+		require.NoError(t, err)
+		revisions[id] = revision
+	}
+
+	return db, revisions
+}
+
+func TestQuestionStatsService_RefreshLevel(t *testing.T) {
+	db, revisions := setupPsychometricsTestDB(t)
+	statsService := NewQuestionStatsService(db)
+
+	answer := func(userID, questionID, value string, correct bool, durationMS int) {
+		record := &model.AnswerRecord{
+			UserID:     userID,
+			LevelID:    "level-1",
+			QuestionID: questionID,
+			RevisionID: revisions[questionID].ID,
+			AnswerJSON: fmt.Sprintf("%q", value),
+			IsCorrect:  correct,
+			DurationMS: durationMS,
+		}
+		require.NoError(t, db.Create(record).Error)
+	}
+
+	// Strong learners pick "B" on the miskeyed question, weak learners pick the key "A"
+	for i := 0; i < 30; i++ {
+		userID := fmt.Sprintf("user-%02d", i)
+		strong := i < 15
+		if strong {
+			answer(userID, "q-miskeyed", "B", false, 1000)
+			answer(userID, "q-good", "A", true, 2000)
+			answer(userID, "q-other", "A", true, 3000)
+			answer(userID, "q-extra", "A", true, 3000)
+		} else {
+			answer(userID, "q-miskeyed", "A", true, 1000)
+			answer(userID, "q-good", "C", false, 4000)
+			answer(userID, "q-other", "B", false, 5000)
+			answer(userID, "q-extra", "C", false, 5000)
+		}
+	}
+
+	// A retry never replaces the first answer
+	answer("user-20", "q-good", "A", true, 500)
+
+	// One learner skips a question and never answers it
+	skip := &model.AnswerRecord{
+		UserID:     "user-skipper",
+		LevelID:    "level-1",
+		QuestionID: "q-good",
+		RevisionID: revisions["q-good"].ID,
+		Skipped:    true,
+	}
+	require.NoError(t, db.Create(skip).Error)
+
+	count, err := statsService.RefreshLevel("level-1")
+	require.NoError(t, err)
+	assert.Equal(t, 4, count)
+
+	miskeyed, err := statsService.GetQuestionStats("q-miskeyed")
+	require.NoError(t, err)
+	assert.Equal(t, 30, miskeyed.Answered)
+	assert.InDelta(t, 0.5, miskeyed.PValue, 0.001)
+	require.NotNil(t, miskeyed.PointBiserial)
+	assert.Less(t, *miskeyed.PointBiserial, 0.0)
+	assert.True(t, miskeyed.SuspectedWrongKey)
+	assert.Contains(t, miskeyed.FlagReason, `"B"`)
+	assert.Equal(t, 1000, miskeyed.MedianDurationMS)
+
+	rates, err := miskeyed.GetOptionRates()
+	require.NoError(t, err)
+	require.Len(t, rates, 3)
+	assert.True(t, rates[0].IsKey)
+	assert.InDelta(t, 0.5, rates[1].Rate, 0.001)
+	assert.InDelta(t, 1.0, rates[1].UpperRate, 0.001)
+
+	good, err := statsService.GetQuestionStats("q-good")
+	require.NoError(t, err)
+	assert.Equal(t, 31, good.Responses)
+	assert.Equal(t, 30, good.Answered)
+	assert.InDelta(t, 1.0/31.0, good.SkipRate, 0.001)
+	assert.InDelta(t, 0.5, good.PValue, 0.001)
+	require.NotNil(t, good.PointBiserial)
+	assert.Greater(t, *good.PointBiserial, 0.5)
+	assert.False(t, good.SuspectedWrongKey)
+
+	flagged, total, err := statsService.ListQuestionStats(QuestionStatsFilter{FlaggedOnly: true})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, flagged, 1)
+	assert.Equal(t, "q-miskeyed", flagged[0].QuestionID)
+}
+
+func TestQuestionStatsService_SmallSamplesNotFlagged(t *testing.T) {
+	db, revisions := setupPsychometricsTestDB(t)
+	statsService := NewQuestionStatsService(db)
+
+	for i, value := range []string{"B", "B", "A"} {
+		userID := fmt.Sprintf("user-%d", i)
+		require.NoError(t, db.Create(&model.AnswerRecord{
+			UserID:     userID,
+			LevelID:    "level-1",
+			QuestionID: "q-miskeyed",
+			RevisionID: revisions["q-miskeyed"].ID,
+			AnswerJSON: fmt.Sprintf("%q", value),
+			IsCorrect:  value == "A",
+		}).Error)
+	}
+
+	_, err := statsService.RefreshAll()
+	require.NoError(t, err)
+
+	stats, err := statsService.GetQuestionStats("q-miskeyed")
+	require.NoError(t, err)
+	assert.Equal(t, 3, stats.Answered)
+	assert.Nil(t, stats.PointBiserial) // Nobody answered another question of the level
+	assert.False(t, stats.SuspectedWrongKey)
+
+	unanswered, err := statsService.GetQuestionStats("q-other")
+	require.NoError(t, err)
+	assert.Equal(t, 0, unanswered.Responses)
+}
+
+func TestQuestionStatsService_GeneratorOptionFormat(t *testing.T) {
+	db, _ := setupPsychometricsTestDB(t)
+	questionService := NewQuestionService(db)
+	statsService := NewQuestionStatsService(db)
+
+	// Options carry their text while the key and the selections are bare labels
+	question := &model.Question{
+		ID:          "q-generated",
+		LevelID:     "level-2",
+		Stem:        "概念题",
+		ContentJSON: `{"type":"conceptual_question","question":"哪种方法适合数据稀缺的场景？","options":["A. 大规模预训练","B. 小样本学习","C. 强化学习"]}`,
+		AnswerJSON:  `{"correct_option":"B","explanation":"小样本学习只需少量标注"}`,
+		Score:       10,
+	}
+	require.NoError(t, db.Create(question).Error)
+	revision, err := questionService.EnsureBaseRevision(question)
+	require.NoError(t, err)
+
+	for i, value := range []string{"B", "B", "b", "A", "C"} {
+		require.NoError(t, db.Create(&model.AnswerRecord{
+			UserID:     fmt.Sprintf("user-%d", i),
+			LevelID:    "level-2",
+			QuestionID: question.ID,
+			RevisionID: revision.ID,
+			AnswerJSON: fmt.Sprintf("%q", value),
+			IsCorrect:  value == "B",
+		}).Error)
+	}
+
+	_, err = statsService.RefreshLevel("level-2")
+	require.NoError(t, err)
+
+	stats, err := statsService.GetQuestionStats(question.ID)
+	require.NoError(t, err)
+	rates, err := stats.GetOptionRates()
+	require.NoError(t, err)
+	require.Len(t, rates, 3)
+
+	assert.Equal(t, "B. 小样本学习", rates[1].Option)
+	assert.True(t, rates[1].IsKey)
+	assert.Equal(t, 3, rates[1].Count)
+	assert.InDelta(t, 0.6, rates[1].Rate, 0.001)
+	assert.False(t, rates[0].IsKey)
+	assert.Equal(t, 1, rates[0].Count)
+	assert.Equal(t, 1, rates[2].Count)
+}
+
+func TestOptionLabel(t *testing.T) {
+	assert.Equal(t, "B", optionLabel("B. 小样本学习"))
+	assert.Equal(t, "C", optionLabel("c）强化学习"))
+	assert.Equal(t, "A", optionLabel(" a "))
+	assert.Equal(t, "Option A", optionLabel("Option A"))
+	assert.Equal(t, "注意力", optionLabel("注意力"))
+}
+
+func TestMedian(t *testing.T) {
+	assert.Equal(t, 0, median(nil))
+	assert.Equal(t, 2, median([]int{3, 1, 2}))
+	assert.Equal(t, 25, median([]int{40, 10, 20, 30}))
+}
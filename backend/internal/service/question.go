@@ -0,0 +1,527 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"paperplay/internal/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Question revision errors
+var (
+	ErrQuestionNotFound       = errors.New("question not found")
+	ErrRevisionNotFound       = errors.New("revision not found")
+	ErrRevisionNotEditable    = errors.New("revision is no longer editable")
+	ErrRevisionInvalidStatus  = errors.New("revision is not in a valid status for this operation")
+	ErrPendingRevisionExists  = errors.New("question already has a pending revision")
+	ErrAnswerRecordNotFound   = errors.New("answer record not found")
+	ErrRevisionContentInvalid = errors.New("revision content is invalid")
+)
+
+// QuestionService handles question revisioning and answer recording
+type QuestionService struct {
+	db *gorm.DB
+}
+
+// NewQuestionService creates a new question service
+func NewQuestionService(db *gorm.DB) *QuestionService {
+	return &QuestionService{
+		db: db,
+	}
+}
+
+// RevisionInput holds the editable fields of a revision; nil fields keep their previous value
+type RevisionInput struct {
+	Subtitle    *string `json:"subtitle"`
+	Stem        *string `json:"stem"`
+	ContentJSON *string `json:"content_json"`
+	AnswerJSON  *string `json:"answer_json"`
+	Score       *int    `json:"score"`
+	ChangeNote  string  `json:"change_note"`
+}
+
+// apply copies the non-nil input fields onto a revision
+func (in *RevisionInput) apply(rev *model.QuestionRevision) {
+	if in.Subtitle != nil {
+		rev.Subtitle = *in.Subtitle
+	}
+	if in.Stem != nil {
+		rev.Stem = *in.Stem
+	}
+	if in.ContentJSON != nil {
+		rev.ContentJSON = *in.ContentJSON
+	}
+	if in.AnswerJSON != nil {
+		rev.AnswerJSON = *in.AnswerJSON
+	}
+	if in.Score != nil {
+		rev.Score = *in.Score
+	}
+	if in.ChangeNote != "" {
+		rev.ChangeNote = in.ChangeNote
+	}
+}
+
+// validateRevision makes sure the revision content can be rendered and graded
+func validateRevision(rev *model.QuestionRevision) error {
+	if rev.Stem == "" {
+		return fmt.Errorf("%w: stem is required", ErrRevisionContentInvalid)
+	}
+	if rev.Score < 0 {
+		return fmt.Errorf("%w: score must not be negative", ErrRevisionContentInvalid)
+	}
+	q := rev.AsQuestion()
+	if _, err := q.GetContent(); err != nil {
+		return fmt.Errorf("%w: content_json: %v", ErrRevisionContentInvalid, err)
+	}
+	answer, err := q.GetAnswer()
+	if err != nil {
+		return fmt.Errorf("%w: answer_json: %v", ErrRevisionContentInvalid, err)
+	}
+	switch answer.Type {
+	case "single", "multiple", "text", "code":
+	case "":
+		// Questions written by the generator only carry the legacy single choice key
+		if answer.CorrectOption == "" {
+			return fmt.Errorf("%w: answer type or correct_option is required", ErrRevisionContentInvalid)
+		}
+	default:
+		return fmt.Errorf("%w: unsupported answer type %q", ErrRevisionContentInvalid, answer.Type)
+	}
+	return nil
+}
+
+// CreateDraftQuestion creates a new, unpublished question with its first draft revision
+func (s *QuestionService) CreateDraftQuestion(levelID string, input *RevisionInput, author string) (*model.Question, *model.QuestionRevision, error) {
+	revision := &model.QuestionRevision{
+		Number:    1,
+		Status:    model.RevisionStatusDraft,
+		CreatedBy: author,
+	}
+	input.apply(revision)
+	if err := validateRevision(revision); err != nil {
+		return nil, nil, err
+	}
+
+	question := &model.Question{
+		LevelID:     levelID,
+		Subtitle:    revision.Subtitle,
+		Stem:        revision.Stem,
+		ContentJSON: revision.ContentJSON,
+		AnswerJSON:  revision.AnswerJSON,
+		Score:       revision.Score,
+		CreatedBy:   author,
+		Status:      model.QuestionStatusDraft,
+	}
+
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(question).Error; err != nil {
+			return fmt.Errorf("failed to create question: %w", err)
+		}
+		revision.QuestionID = question.ID
+		if err := tx.Create(revision).Error; err != nil {
+			return fmt.Errorf("failed to create revision: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return question, revision, nil
+}
+
+// EnsureBaseRevision returns the published revision of a question, snapshotting
+// questions written directly to the database (e.g. by the agent) as revision 1.
+// Hidden questions were published before reports withdrew them, so they are
+// snapshotted too; drafts have nothing published.
+func (s *QuestionService) EnsureBaseRevision(question *model.Question) (*model.QuestionRevision, error) {
+	if question.PublishedRevisionID != nil {
+		var revision model.QuestionRevision
+		if err := s.db.First(&revision, "id = ?", *question.PublishedRevisionID).Error; err != nil {
+			return nil, fmt.Errorf("failed to get published revision: %w", err)
+		}
+		return &revision, nil
+	}
+
+	if question.Status == model.QuestionStatusDraft {
+		return nil, ErrRevisionNotFound
+	}
+
+	var revision *model.QuestionRevision
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Another request may have snapshotted the question concurrently
+		var current model.Question
+		if err := tx.First(&current, "id = ?", question.ID).Error; err != nil {
+			return fmt.Errorf("failed to reload question: %w", err)
+		}
+		if current.PublishedRevisionID != nil {
+			var existing model.QuestionRevision
+			if err := tx.First(&existing, "id = ?", *current.PublishedRevisionID).Error; err != nil {
+				return fmt.Errorf("failed to get published revision: %w", err)
+			}
+			revision = &existing
+			return nil
+		}
+
+		number, err := s.nextRevisionNumber(tx, question.ID)
+		if err != nil {
+			return err
+		}
+
+		now := time.Now()
+		revision = model.NewRevisionFromQuestion(&current, number)
+		revision.Status = model.RevisionStatusPublished
+		revision.ChangeNote = "Initial snapshot"
+		revision.PublishedAt = &now
+		if err := tx.Create(revision).Error; err != nil {
+			return fmt.Errorf("failed to create base revision: %w", err)
+		}
+
+		return tx.Model(&model.Question{}).
+			Where("id = ?", question.ID).
+			Update("published_revision_id", revision.ID).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	question.PublishedRevisionID = &revision.ID
+	return revision, nil
+}
+
+// GetRevisions returns all revisions of a question, newest first
+func (s *QuestionService) GetRevisions(questionID string) ([]model.QuestionRevision, error) {
+	question, err := s.getQuestion(s.db, questionID)
+	if err != nil {
+		return nil, err
+	}
+	if question.Status != model.QuestionStatusDraft {
+		if _, err := s.EnsureBaseRevision(question); err != nil {
+			return nil, err
+		}
+	}
+
+	var revisions []model.QuestionRevision
+	if err := s.db.Where("question_id = ?", questionID).
+		Order("number DESC").
+		Find(&revisions).Error; err != nil {
+		return nil, fmt.Errorf("failed to get revisions: %w", err)
+	}
+	return revisions, nil
+}
+
+// GetRevision returns a single revision of a question
+func (s *QuestionService) GetRevision(questionID, revisionID string) (*model.QuestionRevision, error) {
+	return s.getRevision(s.db, questionID, revisionID)
+}
+
+// CreateRevision starts a new draft based on the latest revision of a question
+func (s *QuestionService) CreateRevision(questionID string, input *RevisionInput, author string) (*model.QuestionRevision, error) {
+	question, err := s.getQuestion(s.db, questionID)
+	if err != nil {
+		return nil, err
+	}
+	if question.Status != model.QuestionStatusDraft {
+		if _, err := s.EnsureBaseRevision(question); err != nil {
+			return nil, err
+		}
+	}
+
+	var revision *model.QuestionRevision
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		var pending int64
+		if err := tx.Model(&model.QuestionRevision{}).
+			Where("question_id = ? AND status IN ?", questionID,
+				[]string{model.RevisionStatusDraft, model.RevisionStatusInReview}).
+			Count(&pending).Error; err != nil {
+			return fmt.Errorf("failed to check pending revisions: %w", err)
+		}
+		if pending > 0 {
+			return ErrPendingRevisionExists
+		}
+
+		var latest model.QuestionRevision
+		if err := tx.Where("question_id = ?", questionID).
+			Order("number DESC").
+			First(&latest).Error; err != nil {
+			return fmt.Errorf("failed to get latest revision: %w", err)
+		}
+
+		revision = model.NewRevisionFromQuestion(latest.AsQuestion(), latest.Number+1)
+		revision.Status = model.RevisionStatusDraft
+		revision.CreatedBy = author
+		input.apply(revision)
+		if err := validateRevision(revision); err != nil {
+			return err
+		}
+
+		if err := tx.Create(revision).Error; err != nil {
+			return fmt.Errorf("failed to create revision: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// UpdateDraft edits a draft revision in place
+func (s *QuestionService) UpdateDraft(questionID, revisionID string, input *RevisionInput) (*model.QuestionRevision, error) {
+	revision, err := s.getRevision(s.db, questionID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if !revision.IsEditable() {
+		return nil, ErrRevisionNotEditable
+	}
+
+	input.apply(revision)
+	if err := validateRevision(revision); err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Save(revision).Error; err != nil {
+		return nil, fmt.Errorf("failed to update revision: %w", err)
+	}
+	return revision, nil
+}
+
+// SubmitForReview freezes a draft revision and hands it to reviewers
+func (s *QuestionService) SubmitForReview(questionID, revisionID string) (*model.QuestionRevision, error) {
+	revision, err := s.getRevision(s.db, questionID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if revision.Status != model.RevisionStatusDraft {
+		return nil, ErrRevisionInvalidStatus
+	}
+
+	now := time.Now()
+	revision.Status = model.RevisionStatusInReview
+	revision.SubmittedAt = &now
+	if err := s.db.Save(revision).Error; err != nil {
+		return nil, fmt.Errorf("failed to submit revision: %w", err)
+	}
+	return revision, nil
+}
+
+// RejectRevision sends a revision under review back to draft with reviewer notes
+func (s *QuestionService) RejectRevision(questionID, revisionID, reviewer, note string) (*model.QuestionRevision, error) {
+	revision, err := s.getRevision(s.db, questionID, revisionID)
+	if err != nil {
+		return nil, err
+	}
+	if revision.Status != model.RevisionStatusInReview {
+		return nil, ErrRevisionInvalidStatus
+	}
+
+	revision.Status = model.RevisionStatusDraft
+	revision.ReviewedBy = reviewer
+	revision.ReviewNote = note
+	revision.SubmittedAt = nil
+	if err := s.db.Save(revision).Error; err != nil {
+		return nil, fmt.Errorf("failed to reject revision: %w", err)
+	}
+	return revision, nil
+}
+
+// PublishRevision makes a reviewed revision the one learners see
+func (s *QuestionService) PublishRevision(questionID, revisionID, reviewer, note string) (*model.QuestionRevision, error) {
+	var revision *model.QuestionRevision
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		var err error
+		revision, err = s.getRevision(tx, questionID, revisionID)
+		if err != nil {
+			return err
+		}
+		if revision.Status != model.RevisionStatusInReview {
+			return ErrRevisionInvalidStatus
+		}
+
+		// Retire the previously published revision
+		if err := tx.Model(&model.QuestionRevision{}).
+			Where("question_id = ? AND status = ?", questionID, model.RevisionStatusPublished).
+			Update("status", model.RevisionStatusSuperseded).Error; err != nil {
+			return fmt.Errorf("failed to supersede revision: %w", err)
+		}
+
+		now := time.Now()
+		revision.Status = model.RevisionStatusPublished
+		revision.ReviewedBy = reviewer
+		revision.ReviewNote = note
+		revision.PublishedAt = &now
+		if err := tx.Save(revision).Error; err != nil {
+			return fmt.Errorf("failed to publish revision: %w", err)
+		}
+
+		// Mirror the published content onto the question row
+		updates := map[string]any{
+			"subtitle":              revision.Subtitle,
+			"stem":                  revision.Stem,
+			"content_json":          revision.ContentJSON,
+			"answer_json":           revision.AnswerJSON,
+			"score":                 revision.Score,
+			"status":                model.QuestionStatusPublished,
+			"published_revision_id": revision.ID,
+		}
+		if err := tx.Model(&model.Question{}).Where("id = ?", questionID).Updates(updates).Error; err != nil {
+			return fmt.Errorf("failed to update question: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return revision, nil
+}
+
+// DiffRevisions compares two revisions of the same question
+func (s *QuestionService) DiffRevisions(questionID, fromID, toID string) (*RevisionDiff, error) {
+	from, err := s.getRevision(s.db, questionID, fromID)
+	if err != nil {
+		return nil, err
+	}
+	to, err := s.getRevision(s.db, questionID, toID)
+	if err != nil {
+		return nil, err
+	}
+	return DiffRevisions(from, to), nil
+}
+
+// RecordAnswer stores a learner's answer against the revision they saw
+func (s *QuestionService) RecordAnswer(record *model.AnswerRecord) error {
+	if err := s.db.Create(record).Error; err != nil {
+		return fmt.Errorf("failed to record answer: %w", err)
+	}
+	return nil
+}
+
+// GetAnswerRecord returns a learner's answer together with the revision they saw
+func (s *QuestionService) GetAnswerRecord(userID, recordID string) (*model.AnswerRecord, error) {
+	var record model.AnswerRecord
+	err := s.db.Preload("Revision").
+		Where("id = ? AND user_id = ?", recordID, userID).
+		First(&record).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrAnswerRecordNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get answer record: %w", err)
+	}
+	return &record, nil
+}
+
+// RegradeAnswer grades a recorded answer again against the revision the learner saw
+func (s *QuestionService) RegradeAnswer(recordID string) (*model.AnswerRecord, bool, error) {
+	var record model.AnswerRecord
+	err := s.db.Preload("Revision").First(&record, "id = ?", recordID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, false, ErrAnswerRecordNotFound
+	} else if err != nil {
+		return nil, false, fmt.Errorf("failed to get answer record: %w", err)
+	}
+	if record.Revision == nil {
+		return nil, false, ErrRevisionNotFound
+	}
+
+	question := record.Revision.AsQuestion()
+	correct, err := question.IsCorrectAnswer(AnswerString(record.AnswerJSON))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to grade answer: %w", err)
+	}
+
+	score := 0
+	if correct {
+		score = question.Score
+	}
+
+	changed := correct != record.IsCorrect || score != record.Score
+	if changed {
+		record.IsCorrect = correct
+		record.Score = score
+		if err := s.db.Model(&record).Updates(map[string]any{
+			"is_correct": correct,
+			"score":      score,
+		}).Error; err != nil {
+			return nil, false, fmt.Errorf("failed to update answer record: %w", err)
+		}
+	}
+
+	return &record, changed, nil
+}
+
+// getQuestion loads a question by ID
+func (s *QuestionService) getQuestion(tx *gorm.DB, questionID string) (*model.Question, error) {
+	var question model.Question
+	err := tx.First(&question, "id = ?", questionID).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrQuestionNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get question: %w", err)
+	}
+	return &question, nil
+}
+
+// getRevision loads a revision and checks it belongs to the question
+func (s *QuestionService) getRevision(tx *gorm.DB, questionID, revisionID string) (*model.QuestionRevision, error) {
+	var revision model.QuestionRevision
+	err := tx.Where("id = ? AND question_id = ?", revisionID, questionID).First(&revision).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, ErrRevisionNotFound
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to get revision: %w", err)
+	}
+	return &revision, nil
+}
+
+// nextRevisionNumber returns the next free revision number of a question
+func (s *QuestionService) nextRevisionNumber(tx *gorm.DB, questionID string) (int, error) {
+	var maxNumber int
+	if err := tx.Model(&model.QuestionRevision{}).
+		Where("question_id = ?", questionID).
+		Select("COALESCE(MAX(number), 0)").
+		Scan(&maxNumber).Error; err != nil {
+		return 0, fmt.Errorf("failed to get revision number: %w", err)
+	}
+	return maxNumber + 1, nil
+}
+
+// AnswerValue converts a submitted answer into the string form used for grading
+func AnswerValue(v any) string {
+	switch val := v.(type) {
+	case string:
+		return val
+	case float64:
+		return strconv.FormatFloat(val, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(val)
+	case []any:
+		// Multiple choice answers are graded as comma-separated options
+		options := make([]string, 0, len(val))
+		for _, opt := range val {
+			if str, ok := opt.(string); ok {
+				options = append(options, str)
+			}
+		}
+		return strings.Join(options, ",")
+	default:
+		return ""
+	}
+}
+
+// AnswerString decodes a stored answer JSON into the string form used for grading
+func AnswerString(answerJSON string) string {
+	var v any
+	if err := json.Unmarshal([]byte(answerJSON), &v); err != nil {
+		return answerJSON
+	}
+	return AnswerValue(v)
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"paperplay/internal/model"
+)
+
+func setupQuestionServiceTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&model.User{},
+		&model.Level{},
+		&model.Question{},
+		&model.QuestionRevision{},
+		&model.AnswerRecord{},
+	)
+	require.NoError(t, err)
+
+	// Question written directly by the agent, without any revision
+	question := &model.Question{
+		ID:          "question-1",
+		LevelID:     "level-1",
+		Stem:        "What is backpropagation?",
+		ContentJSON: `{"type":"mcq","options":["A","B"]}`,
+		AnswerJSON:  `{"type":"single","correct_options":["A"],"explanation":"Because A"}`,
+		Score:       10,
+	}
+	require.NoError(t, db.Create(question).Error)
+
+	return db
+}
+
+func TestQuestionService_RevisionLifecycle(t *testing.T) {
+	db := setupQuestionServiceTestDB(t)
+	questionService := NewQuestionService(db)
+
+	var question model.Question
+	require.NoError(t, db.First(&question, "id = ?", "question-1").Error)
+	assert.Equal(t, model.QuestionStatusPublished, question.Status)
+
+	// Legacy questions are snapshotted as revision 1
+	base, err := questionService.EnsureBaseRevision(&question)
+	require.NoError(t, err)
+	assert.Equal(t, 1, base.Number)
+	assert.Equal(t, model.RevisionStatusPublished, base.Status)
+
+	again, err := questionService.EnsureBaseRevision(&question)
+	require.NoError(t, err)
+	assert.Equal(t, base.ID, again.ID)
+
+	// Start a draft that fixes the answer key
+	newAnswer := `{"type":"single","correct_options":["B"],"explanation":"Because B"}`
+	draft, err := questionService.CreateRevision("question-1", &RevisionInput{
+		AnswerJSON: &newAnswer,
+		ChangeNote: "Fix answer key",
+	}, "admin-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, draft.Number)
+	assert.Equal(t, model.RevisionStatusDraft, draft.Status)
+
+	_, err = questionService.CreateRevision("question-1", &RevisionInput{}, "admin-1")
+	assert.ErrorIs(t, err, ErrPendingRevisionExists)
+
+	// Learners still see the published content while the draft is pending
+	require.NoError(t, db.First(&question, "id = ?", "question-1").Error)
+	assert.Contains(t, question.AnswerJSON, `"A"`)
+
+	_, err = questionService.PublishRevision("question-1", draft.ID, "reviewer-1", "")
+	assert.ErrorIs(t, err, ErrRevisionInvalidStatus)
+
+	_, err = questionService.SubmitForReview("question-1", draft.ID)
+	require.NoError(t, err)
+
+	// Submitted revisions are frozen
+	stem := "Changed after submission"
+	_, err = questionService.UpdateDraft("question-1", draft.ID, &RevisionInput{Stem: &stem})
+	assert.ErrorIs(t, err, ErrRevisionNotEditable)
+
+	published, err := questionService.PublishRevision("question-1", draft.ID, "reviewer-1", "LGTM")
+	require.NoError(t, err)
+	assert.Equal(t, model.RevisionStatusPublished, published.Status)
+
+	require.NoError(t, db.First(&question, "id = ?", "question-1").Error)
+	assert.Contains(t, question.AnswerJSON, `"B"`)
+	require.NotNil(t, question.PublishedRevisionID)
+	assert.Equal(t, draft.ID, *question.PublishedRevisionID)
+
+	var previous model.QuestionRevision
+	require.NoError(t, db.First(&previous, "id = ?", base.ID).Error)
+	assert.Equal(t, model.RevisionStatusSuperseded, previous.Status)
+
+	// Diff shows only the answer key change
+	diff, err := questionService.DiffRevisions("question-1", base.ID, draft.ID)
+	require.NoError(t, err)
+	require.Len(t, diff.Changes, 1)
+	assert.Equal(t, "answer_json", diff.Changes[0].Field)
+}
+
+func TestQuestionService_ReviseHiddenLegacyQuestion(t *testing.T) {
+	db := setupQuestionServiceTestDB(t)
+	questionService := NewQuestionService(db)
+
+	// Reports hid the question before it was ever revised
+	require.NoError(t, db.Model(&model.Question{}).Where("id = ?", "question-1").
+		Update("status", model.QuestionStatusHidden).Error)
+
+	newAnswer := `{"type":"single","correct_options":["B"],"explanation":"Because B"}`
+	draft, err := questionService.CreateRevision("question-1", &RevisionInput{
+		AnswerJSON: &newAnswer,
+		ChangeNote: "Fix answer key",
+	}, "admin-1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, draft.Number)
+
+	revisions, err := questionService.GetRevisions("question-1")
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	assert.Equal(t, 1, revisions[1].Number)
+	assert.Equal(t, model.RevisionStatusPublished, revisions[1].Status)
+	assert.Contains(t, revisions[1].AnswerJSON, `"A"`)
+}
+
+func TestQuestionService_RegradeAgainstSeenRevision(t *testing.T) {
+	db := setupQuestionServiceTestDB(t)
+	questionService := NewQuestionService(db)
+
+	var question model.Question
+	require.NoError(t, db.First(&question, "id = ?", "question-1").Error)
+	base, err := questionService.EnsureBaseRevision(&question)
+	require.NoError(t, err)
+
+	record := &model.AnswerRecord{
+		UserID:     "user-1",
+		LevelID:    "level-1",
+		QuestionID: "question-1",
+		RevisionID: base.ID,
+		AnswerJSON: `"A"`,
+		IsCorrect:  false, // graded incorrectly at the time
+	}
+	require.NoError(t, questionService.RecordAnswer(record))
+
+	// Publishing a newer revision must not affect grading of the old answer
+	newAnswer := `{"type":"single","correct_options":["B"]}`
+	draft, err := questionService.CreateRevision("question-1", &RevisionInput{AnswerJSON: &newAnswer}, "admin-1")
+	require.NoError(t, err)
+	_, err = questionService.SubmitForReview("question-1", draft.ID)
+	require.NoError(t, err)
+	_, err = questionService.PublishRevision("question-1", draft.ID, "reviewer-1", "")
+	require.NoError(t, err)
+
+	regraded, changed, err := questionService.RegradeAnswer(record.ID)
+	require.NoError(t, err)
+	assert.True(t, changed)
+	assert.True(t, regraded.IsCorrect)
+	assert.Equal(t, 10, regraded.Score)
+
+	fetched, err := questionService.GetAnswerRecord("user-1", record.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, fetched.Revision.Number)
+
+	_, err = questionService.GetAnswerRecord("user-2", record.ID)
+	assert.ErrorIs(t, err, ErrAnswerRecordNotFound)
+}
+
+func TestQuestionService_DraftQuestionHiddenFromLearners(t *testing.T) {
+	db := setupQuestionServiceTestDB(t)
+	questionService := NewQuestionService(db)
+
+	stem := "New question"
+	content := `{"type":"mcq","options":["A","B"]}`
+	answer := `{"type":"single","correct_options":["A"]}`
+	score := 5
+	question, revision, err := questionService.CreateDraftQuestion("level-1", &RevisionInput{
+		Stem:        &stem,
+		ContentJSON: &content,
+		AnswerJSON:  &answer,
+		Score:       &score,
+	}, "admin-1")
+	require.NoError(t, err)
+	assert.Equal(t, model.QuestionStatusDraft, question.Status)
+
+	var visible int64
+	db.Model(&model.Question{}).Scopes(model.PublishedQuestions).Where("id = ?", question.ID).Count(&visible)
+	assert.Equal(t, int64(0), visible)
+
+	_, err = questionService.SubmitForReview(question.ID, revision.ID)
+	require.NoError(t, err)
+	_, err = questionService.PublishRevision(question.ID, revision.ID, "reviewer-1", "")
+	require.NoError(t, err)
+
+	db.Model(&model.Question{}).Scopes(model.PublishedQuestions).Where("id = ?", question.ID).Count(&visible)
+	assert.Equal(t, int64(1), visible)
+
+	invalid := `{"type":"essay"}`
+	_, _, err = questionService.CreateDraftQuestion("level-1", &RevisionInput{
+		Stem:        &stem,
+		ContentJSON: &content,
+		AnswerJSON:  &invalid,
+	}, "admin-1")
+	assert.ErrorIs(t, err, ErrRevisionContentInvalid)
+}
+
+func TestQuestionService_ReviseLegacyAnswerShape(t *testing.T) {
+	db := setupQuestionServiceTestDB(t)
+	questionService := NewQuestionService(db)
+
+	// Answers written by the question generator have no type, only the legacy key
+	question := &model.Question{
+		ID:          "question-generated",
+		LevelID:     "level-1",
+		Stem:        "概念题",
+		ContentJSON: `{"type":"conceptual_question","question":"哪种方法适合数据稀缺的场景？","options":["A. 大规模预训练","B. 小样本学习"]}`,
+		AnswerJSON:  `{"correct_option":"B","explanation":"小样本学习只需少量标注"}`,
+		Score:       10,
+	}
+	require.NoError(t, db.Create(question).Error)
+
+	stem := "概念题（修订）"
+	draft, err := questionService.CreateRevision(question.ID, &RevisionInput{Stem: &stem}, "admin-1")
+	require.NoError(t, err)
+	assert.Equal(t, question.AnswerJSON, draft.AnswerJSON)
+
+	invalid := `{"explanation":"No key"}`
+	_, err = questionService.UpdateDraft(question.ID, draft.ID, &RevisionInput{AnswerJSON: &invalid})
+	assert.ErrorIs(t, err, ErrRevisionContentInvalid)
+}
+
+func TestAnswerValue(t *testing.T) {
+	assert.Equal(t, "B", AnswerValue("B"))
+	assert.Equal(t, "42", AnswerValue(float64(42)))
+	assert.Equal(t, "3.5", AnswerValue(3.5))
+	assert.Equal(t, "7", AnswerValue(7))
+	assert.Equal(t, "A,C", AnswerValue([]any{"A", "C"}))
+	assert.Equal(t, "", AnswerValue(nil))
+}
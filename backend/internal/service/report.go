@@ -0,0 +1,332 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"paperplay/internal/model"
+	"paperplay/internal/websocket"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// AutoHideReportThreshold is the number of learners with open reports that hides a question
+const AutoHideReportThreshold = 5
+
+// Question report errors
+var (
+	ErrReportNotFound        = errors.New("report not found")
+	ErrReportClosed          = errors.New("report has already been closed")
+	ErrDuplicateReport       = errors.New("an open report for this question already exists")
+	ErrInvalidReportCategory = errors.New("invalid report category")
+)
+
+// ReportService handles learner-reported question issues and their moderation
+type ReportService struct {
+	db     *gorm.DB
+	logger *zap.Logger
+	wsHub  *websocket.Hub
+}
+
+// NewReportService creates a new report service
+func NewReportService(db *gorm.DB, logger *zap.Logger, wsHub *websocket.Hub) *ReportService {
+	return &ReportService{
+		db:     db,
+		logger: logger,
+		wsHub:  wsHub,
+	}
+}
+
+// ReportFilter holds the filters for listing reports
+type ReportFilter struct {
+	Status     string
+	Category   string
+	QuestionID string
+	Limit      int
+	Offset     int
+}
+
+// CreateReport files a report and hides the question once enough learners reported it.
+// It returns the report and whether the question was hidden as a result.
+func (s *ReportService) CreateReport(userID, questionID, category, description string) (*model.QuestionReport, bool, error) {
+	if !model.IsValidReportCategory(category) {
+		return nil, false, ErrInvalidReportCategory
+	}
+
+	var report *model.QuestionReport
+	hidden := false
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		// Drafts are never shown to learners, so they cannot be reported
+		var question model.Question
+		if err := tx.Where("id = ? AND status <> ?", questionID, model.QuestionStatusDraft).
+			First(&question).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrQuestionNotFound
+			}
+			return fmt.Errorf("failed to get question: %w", err)
+		}
+
+		var existing int64
+		if err := tx.Model(&model.QuestionReport{}).
+			Where("question_id = ? AND user_id = ? AND status IN ?", questionID, userID, openReportStatuses()).
+			Count(&existing).Error; err != nil {
+			return fmt.Errorf("failed to check existing reports: %w", err)
+		}
+		if existing > 0 {
+			return ErrDuplicateReport
+		}
+
+		report = &model.QuestionReport{
+			QuestionID:  questionID,
+			RevisionID:  question.PublishedRevisionID,
+			UserID:      userID,
+			Category:    category,
+			Description: description,
+			Status:      model.ReportStatusOpen,
+		}
+		if err := tx.Create(report).Error; err != nil {
+			return fmt.Errorf("failed to create report: %w", err)
+		}
+
+		if question.Status != model.QuestionStatusPublished {
+			return nil
+		}
+
+		reporters, err := countOpenReporters(tx, questionID)
+		if err != nil {
+			return err
+		}
+		if reporters < AutoHideReportThreshold {
+			return nil
+		}
+
+		if err := tx.Model(&model.Question{}).
+			Where("id = ?", questionID).
+			Update("status", model.QuestionStatusHidden).Error; err != nil {
+			return fmt.Errorf("failed to hide question: %w", err)
+		}
+		hidden = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if hidden {
+		s.logger.Warn("Question hidden after learner reports",
+			zap.String("question_id", questionID),
+			zap.Int("threshold", AutoHideReportThreshold),
+		)
+	}
+
+	return report, hidden, nil
+}
+
+// ListReports lists reports, oldest first so the queue is worked in order
+func (s *ReportService) ListReports(filter ReportFilter) ([]model.QuestionReport, int64, error) {
+	query := s.db.Model(&model.QuestionReport{})
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.Category != "" {
+		query = query.Where("category = ?", filter.Category)
+	}
+	if filter.QuestionID != "" {
+		query = query.Where("question_id = ?", filter.QuestionID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to count reports: %w", err)
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = 50
+	}
+
+	var reports []model.QuestionReport
+	if err := query.Preload("Question").
+		Order("created_at ASC").
+		Limit(filter.Limit).
+		Offset(filter.Offset).
+		Find(&reports).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list reports: %w", err)
+	}
+
+	return reports, total, nil
+}
+
+// GetReport retrieves a report with its question
+func (s *ReportService) GetReport(reportID string) (*model.QuestionReport, error) {
+	var report model.QuestionReport
+	if err := s.db.Preload("Question").First(&report, "id = ?", reportID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrReportNotFound
+		}
+		return nil, fmt.Errorf("failed to get report: %w", err)
+	}
+	return &report, nil
+}
+
+// TriageReport acknowledges a report
+func (s *ReportService) TriageReport(reportID, adminID, note string) (*model.QuestionReport, error) {
+	report, err := s.GetReport(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if !report.IsOpen() {
+		return nil, ErrReportClosed
+	}
+
+	now := time.Now()
+	report.Status = model.ReportStatusTriaged
+	report.AdminNote = note
+	report.HandledBy = adminID
+	report.HandledAt = &now
+	if err := s.db.Save(report).Error; err != nil {
+		return nil, fmt.Errorf("failed to triage report: %w", err)
+	}
+
+	return report, nil
+}
+
+// ResolveReport resolves a report together with all open reports of the same
+// issue (same question and category) and notifies every reporter.
+func (s *ReportService) ResolveReport(reportID, adminID, note string) ([]model.QuestionReport, error) {
+	report, err := s.GetReport(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if !report.IsOpen() {
+		return nil, ErrReportClosed
+	}
+
+	var resolved []model.QuestionReport
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("question_id = ? AND category = ? AND status IN ?",
+			report.QuestionID, report.Category, openReportStatuses()).
+			Find(&resolved).Error; err != nil {
+			return fmt.Errorf("failed to get duplicate reports: %w", err)
+		}
+
+		ids := make([]string, 0, len(resolved))
+		for _, r := range resolved {
+			ids = append(ids, r.ID)
+		}
+		if err := s.closeReports(tx, ids, model.ReportStatusResolved, adminID, note); err != nil {
+			return err
+		}
+
+		return s.restoreIfCleared(tx, report.QuestionID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range resolved {
+		resolved[i].Status = model.ReportStatusResolved
+		resolved[i].AdminNote = note
+		resolved[i].HandledBy = adminID
+		s.notifyReporter(&resolved[i], report.Question)
+	}
+
+	return resolved, nil
+}
+
+// RejectReport closes a single report as not an issue
+func (s *ReportService) RejectReport(reportID, adminID, note string) (*model.QuestionReport, error) {
+	report, err := s.GetReport(reportID)
+	if err != nil {
+		return nil, err
+	}
+	if !report.IsOpen() {
+		return nil, ErrReportClosed
+	}
+
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		if err := s.closeReports(tx, []string{report.ID}, model.ReportStatusRejected, adminID, note); err != nil {
+			return err
+		}
+		return s.restoreIfCleared(tx, report.QuestionID)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return s.GetReport(reportID)
+}
+
+// closeReports sets the final status of the given reports
+func (s *ReportService) closeReports(tx *gorm.DB, ids []string, status, adminID, note string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	if err := tx.Model(&model.QuestionReport{}).
+		Where("id IN ?", ids).
+		Updates(map[string]any{
+			"status":     status,
+			"admin_note": note,
+			"handled_by": adminID,
+			"handled_at": time.Now(),
+		}).Error; err != nil {
+		return fmt.Errorf("failed to close reports: %w", err)
+	}
+	return nil
+}
+
+// restoreIfCleared shows an auto-hidden question again once it falls below the report threshold
+func (s *ReportService) restoreIfCleared(tx *gorm.DB, questionID string) error {
+	reporters, err := countOpenReporters(tx, questionID)
+	if err != nil {
+		return err
+	}
+	if reporters >= AutoHideReportThreshold {
+		return nil
+	}
+
+	if err := tx.Model(&model.Question{}).
+		Where("id = ? AND status = ?", questionID, model.QuestionStatusHidden).
+		Update("status", model.QuestionStatusPublished).Error; err != nil {
+		return fmt.Errorf("failed to restore question: %w", err)
+	}
+	return nil
+}
+
+// notifyReporter tells a learner that their report was resolved
+func (s *ReportService) notifyReporter(report *model.QuestionReport, question *model.Question) {
+	if s.wsHub == nil {
+		return
+	}
+
+	message := "感谢您的反馈！您报告的题目问题已修复。"
+	if question != nil && question.Subtitle != "" {
+		message = fmt.Sprintf("感谢您的反馈！您报告的题目「%s」问题已修复。", question.Subtitle)
+	}
+
+	s.wsHub.SendNotification(report.UserID, &websocket.NotificationMessage{
+		ID:      report.ID,
+		Type:    "report_resolved",
+		Title:   "题目反馈已处理",
+		Message: message,
+	})
+}
+
+// countOpenReporters counts the distinct registered learners with open reports on a question;
+// guests cost nothing to create, so they do not count toward hiding it
+func countOpenReporters(tx *gorm.DB, questionID string) (int, error) {
+	var count int64
+	if err := tx.Model(&model.QuestionReport{}).
+		Where("question_id = ? AND status IN ?", questionID, openReportStatuses()).
+		Where("user_id NOT IN (?)", tx.Model(&model.User{}).Select("id").Where("role = ?", model.RoleGuest)).
+		Distinct("user_id").
+		Count(&count).Error; err != nil {
+		return 0, fmt.Errorf("failed to count open reports: %w", err)
+	}
+	return int(count), nil
+}
+
+// openReportStatuses returns the statuses of reports still awaiting a decision
+func openReportStatuses() []string {
+	return []string{model.ReportStatusOpen, model.ReportStatusTriaged}
+}
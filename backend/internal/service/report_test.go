@@ -0,0 +1,150 @@
+package service
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"paperplay/internal/model"
+	"paperplay/internal/websocket"
+)
+
+func setupReportTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&model.User{},
+		&model.Level{},
+		&model.Question{},
+		&model.QuestionRevision{},
+		&model.QuestionReport{},
+	)
+	require.NoError(t, err)
+
+	questions := []*model.Question{
+		{ID: "question-1", LevelID: "level-1", Stem: "Published", ContentJSON: "{}", AnswerJSON: "{}", Score: 10},
+		{ID: "question-draft", LevelID: "level-1", Stem: "Draft", ContentJSON: "{}", AnswerJSON: "{}", Score: 10,
+			Status: model.QuestionStatusDraft},
+	}
+	for _, q := range questions {
+		require.NoError(t, db.Create(q).Error)
+	}
+
+	return db
+}
+
+func newTestReportService(db *gorm.DB) *ReportService {
+	logger := zap.NewNop()
+	return NewReportService(db, logger, websocket.NewHub(logger))
+}
+
+func TestReportService_CreateReport(t *testing.T) {
+	db := setupReportTestDB(t)
+	reportService := newTestReportService(db)
+
+	report, hidden, err := reportService.CreateReport("user-1", "question-1", model.ReportCategoryTypo, "Misspelled word")
+	require.NoError(t, err)
+	assert.False(t, hidden)
+	assert.Equal(t, model.ReportStatusOpen, report.Status)
+
+	_, _, err = reportService.CreateReport("user-1", "question-1", model.ReportCategoryAmbiguous, "")
+	assert.ErrorIs(t, err, ErrDuplicateReport)
+
+	_, _, err = reportService.CreateReport("user-1", "question-1", "spam", "")
+	assert.ErrorIs(t, err, ErrInvalidReportCategory)
+
+	_, _, err = reportService.CreateReport("user-1", "question-draft", model.ReportCategoryTypo, "")
+	assert.ErrorIs(t, err, ErrQuestionNotFound)
+
+	// Once closed, the learner may report the question again
+	_, err = reportService.RejectReport(report.ID, "admin-1", "Not a typo")
+	require.NoError(t, err)
+	_, _, err = reportService.CreateReport("user-1", "question-1", model.ReportCategoryTypo, "Still misspelled")
+	assert.NoError(t, err)
+}
+
+func TestReportService_AutoHideAndRestore(t *testing.T) {
+	db := setupReportTestDB(t)
+	reportService := newTestReportService(db)
+
+	var reports []*model.QuestionReport
+	for i := 0; i < AutoHideReportThreshold; i++ {
+		report, hidden, err := reportService.CreateReport(fmt.Sprintf("user-%d", i), "question-1",
+			model.ReportCategoryWrongAnswer, "The key is wrong")
+		require.NoError(t, err)
+		assert.Equal(t, i == AutoHideReportThreshold-1, hidden)
+		reports = append(reports, report)
+	}
+
+	var question model.Question
+	require.NoError(t, db.First(&question, "id = ?", "question-1").Error)
+	assert.Equal(t, model.QuestionStatusHidden, question.Status)
+
+	// Hidden questions are invisible to learners
+	var visible int64
+	db.Model(&model.Question{}).Scopes(model.PublishedQuestions).Where("id = ?", "question-1").Count(&visible)
+	assert.Equal(t, int64(0), visible)
+
+	// Triage keeps the report open
+	triaged, err := reportService.TriageReport(reports[0].ID, "admin-1", "Looking into it")
+	require.NoError(t, err)
+	assert.Equal(t, model.ReportStatusTriaged, triaged.Status)
+
+	// Resolving one report resolves every open report of the same issue
+	resolved, err := reportService.ResolveReport(reports[0].ID, "admin-1", "Answer key fixed")
+	require.NoError(t, err)
+	assert.Len(t, resolved, AutoHideReportThreshold)
+
+	require.NoError(t, db.First(&question, "id = ?", "question-1").Error)
+	assert.Equal(t, model.QuestionStatusPublished, question.Status)
+
+	_, err = reportService.ResolveReport(reports[1].ID, "admin-1", "")
+	assert.ErrorIs(t, err, ErrReportClosed)
+
+	list, total, err := reportService.ListReports(ReportFilter{Status: model.ReportStatusResolved})
+	require.NoError(t, err)
+	assert.Equal(t, int64(AutoHideReportThreshold), total)
+	assert.Equal(t, "Answer key fixed", list[0].AdminNote)
+}
+
+func TestReportService_GuestsDoNotHide(t *testing.T) {
+	db := setupReportTestDB(t)
+	reportService := newTestReportService(db)
+
+	for i := 0; i < AutoHideReportThreshold; i++ {
+		guestID := fmt.Sprintf("guest-%d", i)
+		require.NoError(t, db.Create(&model.User{ID: guestID, Email: guestID + "@" + model.GuestEmailDomain, DisplayName: "Guest", Role: model.RoleGuest}).Error)
+		_, hidden, err := reportService.CreateReport(guestID, "question-1", model.ReportCategoryWrongAnswer, "")
+		require.NoError(t, err)
+		assert.False(t, hidden)
+	}
+
+	var question model.Question
+	require.NoError(t, db.First(&question, "id = ?", "question-1").Error)
+	assert.Equal(t, model.QuestionStatusPublished, question.Status)
+}
+
+func TestReportService_ResolveOnlySameCategory(t *testing.T) {
+	db := setupReportTestDB(t)
+	reportService := newTestReportService(db)
+
+	typo, _, err := reportService.CreateReport("user-1", "question-1", model.ReportCategoryTypo, "")
+	require.NoError(t, err)
+	ambiguous, _, err := reportService.CreateReport("user-2", "question-1", model.ReportCategoryAmbiguous, "")
+	require.NoError(t, err)
+
+	resolved, err := reportService.ResolveReport(typo.ID, "admin-1", "Fixed typo")
+	require.NoError(t, err)
+	require.Len(t, resolved, 1)
+	assert.Equal(t, typo.ID, resolved[0].ID)
+
+	other, err := reportService.GetReport(ambiguous.ID)
+	require.NoError(t, err)
+	assert.Equal(t, model.ReportStatusOpen, other.Status)
+}
@@ -0,0 +1,508 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"html"
+	"paperplay/internal/model"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+// Search result types
+const (
+	SearchTypePaper    = "paper"
+	SearchTypeQuestion = "question"
+	SearchTypeSubject  = "subject"
+)
+
+// Search engines
+const (
+	SearchEngineFTS5 = "fts5"
+	SearchEngineLike = "like"
+)
+
+const (
+	highlightOpen  = "<mark>"
+	highlightClose = "</mark>"
+	// FTS5 wraps hits in these control characters, so the text can be escaped before the
+	// markers are turned into markup
+	ftsHighlightOpen  = "\x02"
+	ftsHighlightClose = "\x03"
+	// minTrigramTerm is the shortest term the trigram tokenizer can match
+	minTrigramTerm = 3
+	// snippetRadius is the number of characters kept around the first match in fallback snippets
+	snippetRadius = 32
+	// likeCandidateFactor bounds the rows a LIKE query loads for ranking, as a multiple of the limit
+	likeCandidateFactor = 10
+)
+
+// ErrEmptySearchQuery is returned when the search query has no terms
+var ErrEmptySearchQuery = errors.New("search query is empty")
+
+// SearchService handles full-text search over papers, questions and subjects
+type SearchService struct {
+	db      *gorm.DB
+	useFTS5 bool
+}
+
+// NewSearchService creates a new search service, using FTS5 when the search index is available
+func NewSearchService(db *gorm.DB) *SearchService {
+	return &SearchService{
+		db:      db,
+		useFTS5: model.SearchIndexReady(db),
+	}
+}
+
+// SearchHit represents a single search result
+type SearchHit struct {
+	ID         string            `json:"id"`
+	Type       string            `json:"type"`
+	Title      string            `json:"title"`
+	ParentID   string            `json:"parent_id,omitempty"` // Subject of a paper, level of a question
+	Highlights map[string]string `json:"highlights"`          // Matched fields with <mark> around the hits
+	Score      float64           `json:"score"`               // Higher is more relevant
+}
+
+// SearchResults represents search hits grouped by type
+type SearchResults struct {
+	Query     string      `json:"query"`
+	Engine    string      `json:"engine"`
+	Papers    []SearchHit `json:"papers"`
+	Questions []SearchHit `json:"questions"`
+	Subjects  []SearchHit `json:"subjects"`
+	Total     int         `json:"total"`
+}
+
+// Search runs a query against the requested types; an empty type list searches everything
+func (s *SearchService) Search(query string, types []string, limit int) (*SearchResults, error) {
+	terms := strings.Fields(query)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+	if limit <= 0 {
+		limit = 10
+	}
+
+	wanted := make(map[string]bool)
+	for _, t := range types {
+		wanted[t] = true
+	}
+	include := func(t string) bool {
+		return len(wanted) == 0 || wanted[t]
+	}
+
+	// Trigrams cannot match terms shorter than three characters, such as two-character CJK words
+	engine := SearchEngineLike
+	if s.useFTS5 && shortestTerm(terms) >= minTrigramTerm {
+		engine = SearchEngineFTS5
+	}
+
+	results := &SearchResults{
+		Query:     query,
+		Engine:    engine,
+		Papers:    []SearchHit{},
+		Questions: []SearchHit{},
+		Subjects:  []SearchHit{},
+	}
+
+	var err error
+	if include(SearchTypePaper) {
+		if engine == SearchEngineFTS5 {
+			results.Papers, err = s.matchPapers(terms, limit)
+		} else {
+			results.Papers, err = s.likePapers(terms, limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if include(SearchTypeQuestion) {
+		if engine == SearchEngineFTS5 {
+			results.Questions, err = s.matchQuestions(terms, limit)
+		} else {
+			results.Questions, err = s.likeQuestions(terms, limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if include(SearchTypeSubject) {
+		if engine == SearchEngineFTS5 {
+			results.Subjects, err = s.matchSubjects(terms, limit)
+		} else {
+			results.Subjects, err = s.likeSubjects(terms, limit)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	results.Total = len(results.Papers) + len(results.Questions) + len(results.Subjects)
+	return results, nil
+}
+
+// matchPapers searches papers through the FTS5 index
+func (s *SearchService) matchPapers(terms []string, limit int) ([]SearchHit, error) {
+	var rows []struct {
+		ID        string
+		SubjectID string
+		Title     string
+		TitleHL   string
+		AuthorsHL string
+		Rank      float64
+	}
+	if err := s.db.Raw(`
+		SELECT p.id, p.subject_id, p.title,
+			highlight(search_papers, 1, ?, ?) AS title_hl,
+			highlight(search_papers, 2, ?, ?) AS authors_hl,
+			bm25(search_papers, 0, 10.0, 5.0) AS rank
+		FROM search_papers
+		JOIN papers p ON p.id = search_papers.paper_id
+		WHERE search_papers MATCH ?
+		ORDER BY rank
+		LIMIT ?`,
+		ftsHighlightOpen, ftsHighlightClose, ftsHighlightOpen, ftsHighlightClose, matchExpression(terms), limit,
+	).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to search papers: %w", err)
+	}
+
+	hits := make([]SearchHit, 0, len(rows))
+	for _, r := range rows {
+		hits = append(hits, SearchHit{
+			ID:       r.ID,
+			Type:     SearchTypePaper,
+			Title:    r.Title,
+			ParentID: r.SubjectID,
+			Highlights: matchedFields(map[string]string{
+				"title":   ftsHighlight(r.TitleHL),
+				"authors": ftsHighlight(r.AuthorsHL),
+			}),
+			Score: -r.Rank,
+		})
+	}
+	return hits, nil
+}
+
+// matchQuestions searches published questions through the FTS5 index
+func (s *SearchService) matchQuestions(terms []string, limit int) ([]SearchHit, error) {
+	var rows []struct {
+		ID        string
+		LevelID   string
+		Subtitle  string
+		Stem      string
+		StemHL    string
+		ContentHL string
+		Rank      float64
+	}
+	if err := s.db.Raw(`
+		SELECT q.id, q.level_id, q.subtitle, q.stem,
+			snippet(search_questions, 1, ?, ?, '…', 64) AS stem_hl,
+			snippet(search_questions, 2, ?, ?, '…', 64) AS content_hl,
+			bm25(search_questions, 0, 5.0, 2.0) AS rank
+		FROM search_questions
+		JOIN questions q ON q.id = search_questions.question_id
+		WHERE search_questions MATCH ? AND q.status = ?
+		ORDER BY rank
+		LIMIT ?`,
+		ftsHighlightOpen, ftsHighlightClose, ftsHighlightOpen, ftsHighlightClose,
+		matchExpression(terms), model.QuestionStatusPublished, limit,
+	).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to search questions: %w", err)
+	}
+
+	hits := make([]SearchHit, 0, len(rows))
+	for _, r := range rows {
+		hits = append(hits, SearchHit{
+			ID:       r.ID,
+			Type:     SearchTypeQuestion,
+			Title:    questionTitle(r.Subtitle, r.Stem),
+			ParentID: r.LevelID,
+			Highlights: matchedFields(map[string]string{
+				"stem":    ftsHighlight(r.StemHL),
+				"content": ftsHighlight(r.ContentHL),
+			}),
+			Score: -r.Rank,
+		})
+	}
+	return hits, nil
+}
+
+// matchSubjects searches subjects through the FTS5 index
+func (s *SearchService) matchSubjects(terms []string, limit int) ([]SearchHit, error) {
+	var rows []struct {
+		ID            string
+		Name          string
+		NameHL        string
+		DescriptionHL string
+		Rank          float64
+	}
+	if err := s.db.Raw(`
+		SELECT sub.id, sub.name,
+			highlight(search_subjects, 1, ?, ?) AS name_hl,
+			snippet(search_subjects, 2, ?, ?, '…', 64) AS description_hl,
+			bm25(search_subjects, 0, 10.0, 1.0) AS rank
+		FROM search_subjects
+		JOIN subjects sub ON sub.id = search_subjects.subject_id
+		WHERE search_subjects MATCH ?
+		ORDER BY rank
+		LIMIT ?`,
+		ftsHighlightOpen, ftsHighlightClose, ftsHighlightOpen, ftsHighlightClose, matchExpression(terms), limit,
+	).Scan(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to search subjects: %w", err)
+	}
+
+	hits := make([]SearchHit, 0, len(rows))
+	for _, r := range rows {
+		hits = append(hits, SearchHit{
+			ID:    r.ID,
+			Type:  SearchTypeSubject,
+			Title: r.Name,
+			Highlights: matchedFields(map[string]string{
+				"name":        ftsHighlight(r.NameHL),
+				"description": ftsHighlight(r.DescriptionHL),
+			}),
+			Score: -r.Rank,
+		})
+	}
+	return hits, nil
+}
+
+// likePapers searches papers with LIKE queries
+func (s *SearchService) likePapers(terms []string, limit int) ([]SearchHit, error) {
+	var papers []model.Paper
+	if err := likeQuery(s.db, terms, "title", "paper_author").
+		Limit(limit * likeCandidateFactor).Find(&papers).Error; err != nil {
+		return nil, fmt.Errorf("failed to search papers: %w", err)
+	}
+
+	hits := make([]SearchHit, 0, len(papers))
+	for _, p := range papers {
+		hits = append(hits, SearchHit{
+			ID:       p.ID,
+			Type:     SearchTypePaper,
+			Title:    p.Title,
+			ParentID: p.SubjectID,
+			Highlights: matchedFields(map[string]string{
+				"title":   highlightTerms(p.Title, terms),
+				"authors": highlightTerms(p.PaperAuthor, terms),
+			}),
+			Score: 10*termFrequency(p.Title, terms) + 5*termFrequency(p.PaperAuthor, terms),
+		})
+	}
+	return rankHits(hits, limit), nil
+}
+
+// likeQuestions searches published questions with LIKE queries
+func (s *SearchService) likeQuestions(terms []string, limit int) ([]SearchHit, error) {
+	var questions []model.Question
+	if err := likeQuery(s.db.Scopes(model.PublishedQuestions), terms, "stem", "content_json").
+		Limit(limit * likeCandidateFactor).Find(&questions).Error; err != nil {
+		return nil, fmt.Errorf("failed to search questions: %w", err)
+	}
+
+	hits := make([]SearchHit, 0, len(questions))
+	for _, q := range questions {
+		content := questionContentText(&q)
+		score := 5*termFrequency(q.Stem, terms) + 2*termFrequency(content, terms)
+		if score == 0 {
+			continue // Matched JSON keys only
+		}
+		hits = append(hits, SearchHit{
+			ID:       q.ID,
+			Type:     SearchTypeQuestion,
+			Title:    questionTitle(q.Subtitle, q.Stem),
+			ParentID: q.LevelID,
+			Highlights: matchedFields(map[string]string{
+				"stem":    snippetAround(q.Stem, terms),
+				"content": snippetAround(content, terms),
+			}),
+			Score: score,
+		})
+	}
+	return rankHits(hits, limit), nil
+}
+
+// likeSubjects searches subjects with LIKE queries
+func (s *SearchService) likeSubjects(terms []string, limit int) ([]SearchHit, error) {
+	var subjects []model.Subject
+	if err := likeQuery(s.db, terms, "name", "description").
+		Limit(limit * likeCandidateFactor).Find(&subjects).Error; err != nil {
+		return nil, fmt.Errorf("failed to search subjects: %w", err)
+	}
+
+	hits := make([]SearchHit, 0, len(subjects))
+	for _, sub := range subjects {
+		hits = append(hits, SearchHit{
+			ID:    sub.ID,
+			Type:  SearchTypeSubject,
+			Title: sub.Name,
+			Highlights: matchedFields(map[string]string{
+				"name":        highlightTerms(sub.Name, terms),
+				"description": snippetAround(sub.Description, terms),
+			}),
+			Score: 10*termFrequency(sub.Name, terms) + termFrequency(sub.Description, terms),
+		})
+	}
+	return rankHits(hits, limit), nil
+}
+
+// matchExpression builds an FTS5 query requiring every term, each quoted as a literal string
+func matchExpression(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + strings.ReplaceAll(term, `"`, `""`) + `"`
+	}
+	return strings.Join(quoted, " ")
+}
+
+// likeQuery requires every term to appear in at least one of the columns
+func likeQuery(db *gorm.DB, terms []string, columns ...string) *gorm.DB {
+	query := db
+	for _, term := range terms {
+		pattern := "%" + escapeLike(term) + "%"
+		conditions := make([]string, len(columns))
+		args := make([]any, len(columns))
+		for i, column := range columns {
+			conditions[i] = column + ` LIKE ? ESCAPE '\'`
+			args[i] = pattern
+		}
+		query = query.Where(strings.Join(conditions, " OR "), args...)
+	}
+	return query
+}
+
+// escapeLike escapes LIKE wildcards in a search term
+func escapeLike(term string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(term)
+}
+
+// shortestTerm returns the length in characters of the shortest term
+func shortestTerm(terms []string) int {
+	shortest := -1
+	for _, term := range terms {
+		if n := utf8.RuneCountInString(term); shortest < 0 || n < shortest {
+			shortest = n
+		}
+	}
+	return shortest
+}
+
+// matchedFields drops the fields without a highlighted hit
+func matchedFields(fields map[string]string) map[string]string {
+	matched := make(map[string]string)
+	for name, value := range fields {
+		if strings.Contains(value, highlightOpen) {
+			matched[name] = value
+		}
+	}
+	return matched
+}
+
+// ftsHighlight escapes an FTS5 highlight or snippet and turns its hit markers into markup
+func ftsHighlight(text string) string {
+	return strings.NewReplacer(ftsHighlightOpen, highlightOpen, ftsHighlightClose, highlightClose).
+		Replace(html.EscapeString(text))
+}
+
+// highlightTerms escapes the text and wraps every case-insensitive occurrence of the terms
+// in highlight markers
+func highlightTerms(text string, terms []string) string {
+	spans := matchSpans(text, terms)
+	if len(spans) == 0 {
+		return html.EscapeString(text)
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, span := range spans {
+		b.WriteString(html.EscapeString(text[last:span[0]]))
+		b.WriteString(highlightOpen)
+		b.WriteString(html.EscapeString(text[span[0]:span[1]]))
+		b.WriteString(highlightClose)
+		last = span[1]
+	}
+	b.WriteString(html.EscapeString(text[last:]))
+	return b.String()
+}
+
+// snippetAround highlights the terms in a window around the first match
+func snippetAround(text string, terms []string) string {
+	spans := matchSpans(text, terms)
+	if len(spans) == 0 {
+		return ""
+	}
+
+	runes := []rune(text)
+	first := utf8.RuneCountInString(text[:spans[0][0]])
+	start, end := first-snippetRadius, first+snippetRadius*2
+	prefix, suffix := "…", "…"
+	if start <= 0 {
+		start, prefix = 0, ""
+	}
+	if end >= len(runes) {
+		end, suffix = len(runes), ""
+	}
+
+	return prefix + highlightTerms(string(runes[start:end]), terms) + suffix
+}
+
+// matchSpans returns the byte ranges where the terms occur, ignoring case
+func matchSpans(text string, terms []string) [][]int {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	pattern, err := regexp.Compile("(?i)" + strings.Join(quoted, "|"))
+	if err != nil {
+		return nil
+	}
+	return pattern.FindAllStringIndex(text, -1)
+}
+
+// termFrequency counts the occurrences of the terms in a text
+func termFrequency(text string, terms []string) float64 {
+	lower := strings.ToLower(text)
+	count := 0
+	for _, term := range terms {
+		count += strings.Count(lower, strings.ToLower(term))
+	}
+	return float64(count)
+}
+
+// rankHits sorts hits by descending score and keeps the best ones
+func rankHits(hits []SearchHit, limit int) []SearchHit {
+	sort.SliceStable(hits, func(i, j int) bool { return hits[i].Score > hits[j].Score })
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// questionContentText returns the searchable text of a question's content
+func questionContentText(q *model.Question) string {
+	content, err := q.GetContent()
+	if err != nil {
+		return q.ContentJSON
+	}
+	parts := []string{content.Text, content.Question}
+	parts = append(parts, content.Options...)
+	parts = append(parts, content.Code)
+	return strings.TrimSpace(strings.Join(parts, " "))
+}
+
+// questionTitle returns a short title for a question hit
+func questionTitle(subtitle, stem string) string {
+	if subtitle != "" {
+		return subtitle
+	}
+	runes := []rune(stem)
+	if len(runes) > 80 {
+		return string(runes[:80]) + "…"
+	}
+	return stem
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"paperplay/internal/model"
+)
+
+func setupSearchTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&model.Subject{},
+		&model.Paper{},
+		&model.Level{},
+		&model.Question{},
+	)
+	require.NoError(t, err)
+
+	// Set up the index before writing so the sync triggers are exercised
+	_, err = (&model.Database{DB: db}).SetupSearchIndex()
+	require.NoError(t, err)
+
+	subject := &model.Subject{ID: "subject-1", Name: "深度学习", Description: "Neural networks and representation learning"}
+	require.NoError(t, db.Create(subject).Error)
+
+	papers := []*model.Paper{
+		{ID: "paper-1", SubjectID: "subject-1", Title: "Attention Is All You Need",
+			PaperAuthor: "Ashish Vaswani; Noam Shazeer", PaperPubYM: "2017-06", PaperCitationCount: "100000"},
+		{ID: "paper-2", SubjectID: "subject-1", Title: "基于注意力机制的神经机器翻译",
+			PaperAuthor: "张三; 李四", PaperPubYM: "2018-01", PaperCitationCount: "10"},
+	}
+	for _, p := range papers {
+		require.NoError(t, db.Create(p).Error)
+	}
+
+	questions := []*model.Question{
+		{ID: "question-1", LevelID: "level-1", Stem: "What does the attention mechanism compute?",
+			ContentJSON: `{"type":"mcq","options":["Weighted sum of values","Convolution"]}`, AnswerJSON: "{}", Score: 10},
+		// Content as written by the question generator, with the stem under "question"
+		{ID: "question-agent", LevelID: "level-1", Stem: "概念题",
+			ContentJSON: `{"type":"conceptual_question","concept_name":"自注意力","question":"自注意力机制中 Query<Key> 的点积用于计算什么？","options":["A. 注意力权重","B. 位置编码"]}`,
+			AnswerJSON:  `{"correct_option":"A","explanation":"点积经过 softmax 得到权重"}`, Score: 10},
+		{ID: "question-draft", LevelID: "level-1", Stem: "Draft attention question",
+			ContentJSON: `{"type":"mcq"}`, AnswerJSON: "{}", Score: 10, Status: model.QuestionStatusDraft},
+	}
+	for _, q := range questions {
+		require.NoError(t, db.Create(q).Error)
+	}
+
+	return db
+}
+
+func TestSearchService_Like(t *testing.T) {
+	db := setupSearchTestDB(t)
+	searchService := &SearchService{db: db}
+
+	results, err := searchService.Search("attention", nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, SearchEngineLike, results.Engine)
+	require.Len(t, results.Papers, 1)
+	assert.Equal(t, "paper-1", results.Papers[0].ID)
+	assert.Equal(t, "<mark>Attention</mark> Is All You Need", results.Papers[0].Highlights["title"])
+
+	// Draft questions are never returned
+	require.Len(t, results.Questions, 1)
+	assert.Equal(t, "question-1", results.Questions[0].ID)
+	assert.Contains(t, results.Questions[0].Highlights["stem"], "<mark>attention</mark>")
+
+	// Content text is searched, JSON keys are not
+	results, err = searchService.Search("weighted", []string{SearchTypeQuestion}, 10)
+	require.NoError(t, err)
+	require.Len(t, results.Questions, 1)
+	assert.Contains(t, results.Questions[0].Highlights["content"], "<mark>Weighted</mark>")
+
+	results, err = searchService.Search("options", []string{SearchTypeQuestion}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, results.Questions)
+
+	// Two-character CJK terms
+	results, err = searchService.Search("学习", []string{SearchTypeSubject}, 10)
+	require.NoError(t, err)
+	require.Len(t, results.Subjects, 1)
+	assert.Equal(t, "深度<mark>学习</mark>", results.Subjects[0].Highlights["name"])
+	assert.Empty(t, results.Papers)
+
+	_, err = searchService.Search("   ", nil, 10)
+	assert.ErrorIs(t, err, ErrEmptySearchQuery)
+}
+
+func TestSearchService_FTS5(t *testing.T) {
+	db := setupSearchTestDB(t)
+	if !model.HasFTS5(db) {
+		t.Skip("SQLite built without FTS5, run with -tags sqlite_fts5")
+	}
+	searchService := NewSearchService(db)
+
+	results, err := searchService.Search("attention", nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, SearchEngineFTS5, results.Engine)
+	require.Len(t, results.Papers, 1)
+	assert.Equal(t, "<mark>Attention</mark> Is All You Need", results.Papers[0].Highlights["title"])
+	require.Len(t, results.Questions, 1)
+	assert.Equal(t, "question-1", results.Questions[0].ID)
+
+	// CJK substrings match through the trigram tokenizer
+	results, err = searchService.Search("注意力", []string{SearchTypePaper}, 10)
+	require.NoError(t, err)
+	require.Len(t, results.Papers, 1)
+	assert.Equal(t, "paper-2", results.Papers[0].ID)
+	assert.Contains(t, results.Papers[0].Highlights["title"], "<mark>注意力</mark>")
+
+	// Updates and deletes are kept in sync by triggers
+	require.NoError(t, db.Model(&model.Paper{}).Where("id = ?", "paper-1").
+		Update("title", "Transformers everywhere").Error)
+	results, err = searchService.Search("attention", []string{SearchTypePaper}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, results.Papers)
+
+	require.NoError(t, db.Delete(&model.Subject{}, "id = ?", "subject-1").Error)
+	results, err = searchService.Search("深度学", []string{SearchTypeSubject}, 10)
+	require.NoError(t, err)
+	assert.Empty(t, results.Subjects)
+
+	// Quotes in user input cannot break the query syntax
+	_, err = searchService.Search(`"attention OR`, nil, 10)
+	assert.NoError(t, err)
+}
+
+func TestSearchService_AgentQuestionContent(t *testing.T) {
+	db := setupSearchTestDB(t)
+
+	engines := map[string]*SearchService{SearchEngineLike: {db: db}}
+	if model.HasFTS5(db) {
+		engines[SearchEngineFTS5] = NewSearchService(db)
+	}
+
+	for engine, searchService := range engines {
+		t.Run(engine, func(t *testing.T) {
+			// The question wording is only stored under "question" in the content JSON
+			results, err := searchService.Search("点积用于", []string{SearchTypeQuestion}, 10)
+			require.NoError(t, err)
+			assert.Equal(t, engine, results.Engine)
+			require.Len(t, results.Questions, 1)
+			assert.Equal(t, "question-agent", results.Questions[0].ID)
+
+			// Highlighted text is escaped before the hit markers are added
+			content := results.Questions[0].Highlights["content"]
+			assert.Contains(t, content, "<mark>点积用于</mark>")
+			assert.Contains(t, content, "Query&lt;Key&gt;")
+			assert.NotContains(t, content, "<Key>")
+		})
+	}
+}
@@ -140,7 +140,7 @@ func (s *UserService) GetUserStats(userID string) (*UserStats, error) {
 	// Get achievements count
 	var achievementsCount int64
 	if err := s.db.Model(&model.UserAchievement{}).
-		Where("user_id = ?", userID).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
 		Count(&achievementsCount).Error; err != nil {
 		return nil, fmt.Errorf("failed to get achievements count: %w", err)
 	}
@@ -272,10 +272,11 @@ func (s *UserService) GetUserProgress(userID string) (*UserProgressResponse, err
 	return &response, nil
 }
 
-// GetUserAchievements returns user's achievements
+// GetUserAchievements returns user's achievements, leaving out revoked achievements
+// and superseded tiers
 func (s *UserService) GetUserAchievements(userID string) ([]model.UserAchievement, error) {
 	var achievements []model.UserAchievement
-	if err := s.db.Where("user_id = ?", userID).
+	if err := s.db.Where("user_id = ? AND superseded_at IS NULL AND revoked_at IS NULL", userID).
 		Preload("Achievement").
 		Order("earned_at DESC").
 		Find(&achievements).Error; err != nil {
@@ -23,6 +23,7 @@ func setupUserServiceTestDB() *gorm.DB {
 		&model.UserProgress{},
 		&model.UserAttempts{},
 		&model.UserAchievement{},
+		&model.UserAchievementProgress{},
 		&model.Achievement{},
 		&model.Event{},
 		&model.RoadmapNode{},
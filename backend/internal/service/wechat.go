@@ -0,0 +1,378 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"paperplay/config"
+	"paperplay/internal/model"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// WeChat errors
+var (
+	ErrWeChatDisabled        = errors.New("wechat login is not enabled")
+	ErrInvalidWeChatCode     = errors.New("invalid or expired wechat login code")
+	ErrWeChatUnavailable     = errors.New("wechat service is unavailable")
+	ErrIdentityAlreadyLinked = errors.New("this wechat account is linked to another user")
+	ErrProviderAlreadyLinked = errors.New("a wechat account is already linked to this user")
+	ErrIdentityNotLinked     = errors.New("no wechat account is linked to this user")
+	ErrLastLoginMethod       = errors.New("cannot remove the only way to log in")
+	ErrEmailLoginExists      = errors.New("an email login is already set up for this user")
+	ErrEmailTaken            = errors.New("email address is already registered")
+)
+
+// code2session error codes, see the mini-program login documentation
+const (
+	weChatErrInvalidCode = 40029
+	weChatErrCodeUsed    = 40163
+	weChatErrRateLimited = 45011
+)
+
+// defaultWeChatDisplayName is given to users created from a WeChat login
+const defaultWeChatDisplayName = "WeChat User"
+
+// WeChatSession is the result of exchanging a wx.login code
+type WeChatSession struct {
+	OpenID     string `json:"openid"`
+	UnionID    string `json:"unionid"`
+	SessionKey string `json:"session_key"`
+	ErrCode    int    `json:"errcode"`
+	ErrMsg     string `json:"errmsg"`
+}
+
+// WeChatService handles WeChat mini-program login and account linking
+type WeChatService struct {
+	db         *gorm.DB
+	config     *config.WeChatConfig
+	ethService *EthereumService
+	client     *http.Client
+	logger     *zap.Logger
+}
+
+// NewWeChatService creates a new WeChat service; ethService may be nil
+func NewWeChatService(db *gorm.DB, cfg *config.WeChatConfig, ethService *EthereumService, logger *zap.Logger) *WeChatService {
+	return &WeChatService{
+		db:         db,
+		config:     cfg,
+		ethService: ethService,
+		client:     &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+		logger:     logger,
+	}
+}
+
+// IsEnabled checks if WeChat login is enabled
+func (s *WeChatService) IsEnabled() bool {
+	return s.config.Enabled
+}
+
+// Code2Session exchanges a wx.login code for the user's openid and unionid
+func (s *WeChatService) Code2Session(ctx context.Context, code string) (*WeChatSession, error) {
+	if !s.IsEnabled() {
+		return nil, ErrWeChatDisabled
+	}
+
+	query := url.Values{}
+	query.Set("appid", s.config.AppID)
+	query.Set("secret", s.config.AppSecret)
+	query.Set("js_code", code)
+	query.Set("grant_type", "authorization_code")
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.config.Code2SessionURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create code2session request: %w", err)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		// The error contains the URL and with it the app secret
+		s.logger.Warn("code2session request failed", zap.String("error", strings.ReplaceAll(err.Error(), s.config.AppSecret, "***")))
+		return nil, ErrWeChatUnavailable
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		s.logger.Warn("code2session returned an error status", zap.Int("status", resp.StatusCode))
+		return nil, ErrWeChatUnavailable
+	}
+
+	var session WeChatSession
+	if err := json.NewDecoder(resp.Body).Decode(&session); err != nil {
+		s.logger.Warn("Failed to decode code2session response", zap.Error(err))
+		return nil, ErrWeChatUnavailable
+	}
+
+	switch session.ErrCode {
+	case 0:
+	case weChatErrInvalidCode, weChatErrCodeUsed:
+		return nil, ErrInvalidWeChatCode
+	default:
+		s.logger.Warn("code2session failed",
+			zap.Int("errcode", session.ErrCode),
+			zap.String("errmsg", session.ErrMsg),
+			zap.Bool("rate_limited", session.ErrCode == weChatErrRateLimited))
+		return nil, ErrWeChatUnavailable
+	}
+	if session.OpenID == "" {
+		return nil, ErrWeChatUnavailable
+	}
+	return &session, nil
+}
+
+// Login resolves a wx.login code to a user, creating one on first login.
+// A new openid with a known unionid is linked to the user of that unionid.
+// It reports whether the user was created.
+func (s *WeChatService) Login(ctx context.Context, code, displayName string) (*model.User, bool, error) {
+	session, err := s.Code2Session(ctx, code)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var user model.User
+	created := false
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		identity, err := s.resolveIdentity(tx, session)
+		if err != nil {
+			return err
+		}
+
+		if identity != nil {
+			if err := tx.First(&user, "id = ?", identity.UserID).Error; err != nil {
+				return fmt.Errorf("failed to get user: %w", err)
+			}
+			return nil
+		}
+
+		user, err = s.createUser(tx, session, displayName)
+		if err != nil {
+			return err
+		}
+		created = true
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if created {
+		s.logger.Info("User created from wechat login", zap.String("user_id", user.ID))
+	}
+	return &user, created, nil
+}
+
+// resolveIdentity finds the identity of a session for logging in. A new openid of a
+// known unionid is linked to the same user, and a missing unionid is filled in.
+// It returns nil when the WeChat account is not linked to anyone.
+func (s *WeChatService) resolveIdentity(tx *gorm.DB, session *WeChatSession) (*model.ExternalIdentity, error) {
+	identity, err := s.findIdentity(tx, session)
+	if err != nil || identity == nil {
+		return identity, err
+	}
+
+	if identity.Subject != session.OpenID {
+		// Same person through another app of ours
+		identity = &model.ExternalIdentity{UserID: identity.UserID, Provider: model.ProviderWeChat, Subject: session.OpenID, UnionID: session.UnionID}
+		if err := tx.Create(identity).Error; err != nil {
+			return nil, fmt.Errorf("failed to link wechat identity: %w", err)
+		}
+	} else if identity.UnionID == "" && session.UnionID != "" {
+		if err := tx.Model(identity).Update("union_id", session.UnionID).Error; err != nil {
+			return nil, fmt.Errorf("failed to update wechat identity: %w", err)
+		}
+	}
+	return identity, nil
+}
+
+// findIdentity looks up the identity of a session by openid, then by unionid
+func (s *WeChatService) findIdentity(tx *gorm.DB, session *WeChatSession) (*model.ExternalIdentity, error) {
+	var identity model.ExternalIdentity
+	err := tx.Where("provider = ? AND subject = ?", model.ProviderWeChat, session.OpenID).First(&identity).Error
+	if err == nil {
+		return &identity, nil
+	}
+	if err != gorm.ErrRecordNotFound {
+		return nil, fmt.Errorf("failed to get wechat identity: %w", err)
+	}
+	if session.UnionID == "" {
+		return nil, nil
+	}
+
+	err = tx.Where("provider = ? AND union_id = ?", model.ProviderWeChat, session.UnionID).First(&identity).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wechat identity: %w", err)
+	}
+	return &identity, nil
+}
+
+// createUser creates a user without email login for a WeChat session
+func (s *WeChatService) createUser(tx *gorm.DB, session *WeChatSession, displayName string) (model.User, error) {
+	displayName = strings.TrimSpace(displayName)
+	if displayName == "" {
+		displayName = defaultWeChatDisplayName
+	}
+	user := model.User{
+		Email:       "wechat-" + uuid.New().String() + "@" + model.PlaceholderEmailDomain,
+		DisplayName: displayName,
+		Role:        model.RoleUser,
+	}
+
+	if s.ethService != nil && s.ethService.IsEnabled() {
+		address, privateKey, err := s.ethService.GenerateWallet()
+		if err != nil {
+			return user, fmt.Errorf("failed to generate wallet: %w", err)
+		}
+		user.EthAddress = address
+		user.EthPrivateKey = privateKey
+	}
+
+	if err := tx.Create(&user).Error; err != nil {
+		return user, fmt.Errorf("failed to create user: %w", err)
+	}
+	identity := &model.ExternalIdentity{UserID: user.ID, Provider: model.ProviderWeChat, Subject: session.OpenID, UnionID: session.UnionID}
+	if err := tx.Create(identity).Error; err != nil {
+		return user, fmt.Errorf("failed to create wechat identity: %w", err)
+	}
+	return user, nil
+}
+
+// LinkWeChat links the WeChat account of a wx.login code to an existing user
+func (s *WeChatService) LinkWeChat(ctx context.Context, userID, code string) (*model.ExternalIdentity, error) {
+	session, err := s.Code2Session(ctx, code)
+	if err != nil {
+		return nil, err
+	}
+
+	var identity model.ExternalIdentity
+	err = s.db.Transaction(func(tx *gorm.DB) error {
+		existing, err := s.findIdentity(tx, session)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			if existing.UserID != userID {
+				return ErrIdentityAlreadyLinked
+			}
+			if existing.Subject == session.OpenID {
+				identity = *existing
+				return nil
+			}
+		}
+
+		var count int64
+		if err := tx.Model(&model.ExternalIdentity{}).
+			Where("user_id = ? AND provider = ?", userID, model.ProviderWeChat).
+			Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check wechat identities: %w", err)
+		}
+		// A second openid is only accepted from the same WeChat user, recognised by unionid
+		if count > 0 && existing == nil {
+			return ErrProviderAlreadyLinked
+		}
+
+		identity = model.ExternalIdentity{UserID: userID, Provider: model.ProviderWeChat, Subject: session.OpenID, UnionID: session.UnionID}
+		if err := tx.Create(&identity).Error; err != nil {
+			return fmt.Errorf("failed to link wechat identity: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("WeChat account linked", zap.String("user_id", userID))
+	return &identity, nil
+}
+
+// UnlinkWeChat removes the WeChat identities of a user that can also log in by email
+func (s *WeChatService) UnlinkWeChat(userID string) error {
+	var user model.User
+	if err := s.db.First(&user, "id = ?", userID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if !user.HasEmailLogin() {
+		return ErrLastLoginMethod
+	}
+
+	result := s.db.Where("user_id = ? AND provider = ?", userID, model.ProviderWeChat).Delete(&model.ExternalIdentity{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to unlink wechat identity: %w", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrIdentityNotLinked
+	}
+
+	s.logger.Info("WeChat account unlinked", zap.String("user_id", userID))
+	return nil
+}
+
+// AttachEmail sets up email and password login for a user created through WeChat.
+// The address starts out unverified.
+func (s *WeChatService) AttachEmail(userID, email, password string) (*model.User, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if model.IsReservedEmail(email) {
+		return nil, ErrEmailTaken
+	}
+
+	var user model.User
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&user, "id = ?", userID).Error; err != nil {
+			if err == gorm.ErrRecordNotFound {
+				return ErrUserNotFound
+			}
+			return fmt.Errorf("failed to get user: %w", err)
+		}
+		if user.HasEmailLogin() {
+			return ErrEmailLoginExists
+		}
+
+		var count int64
+		if err := tx.Model(&model.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+			return fmt.Errorf("failed to check email: %w", err)
+		}
+		if count > 0 {
+			return ErrEmailTaken
+		}
+
+		if err := user.SetPassword(password); err != nil {
+			return fmt.Errorf("failed to hash password: %w", err)
+		}
+		user.Email = email
+		user.EmailVerifiedAt = nil
+		if err := tx.Model(&user).Updates(map[string]any{
+			"email":             user.Email,
+			"password_hash":     user.PasswordHash,
+			"email_verified_at": nil,
+		}).Error; err != nil {
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Email login attached", zap.String("user_id", userID))
+	return &user, nil
+}
+
+// ListIdentities returns the external identities linked to a user
+func (s *WeChatService) ListIdentities(userID string) ([]model.ExternalIdentity, error) {
+	var identities []model.ExternalIdentity
+	if err := s.db.Where("user_id = ?", userID).Order("created_at").Find(&identities).Error; err != nil {
+		return nil, fmt.Errorf("failed to list identities: %w", err)
+	}
+	return identities, nil
+}
@@ -0,0 +1,157 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"paperplay/config"
+	"paperplay/internal/model"
+)
+
+// fakeWeChat serves code2session, answering each code with a fixed session
+func fakeWeChat(t *testing.T, sessions map[string]WeChatSession) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		assert.Equal(t, "wx-app", query.Get("appid"))
+		assert.Equal(t, "wx-secret", query.Get("secret"))
+		assert.Equal(t, "authorization_code", query.Get("grant_type"))
+
+		session, ok := sessions[query.Get("js_code")]
+		if !ok {
+			session = WeChatSession{ErrCode: 40029, ErrMsg: "invalid code"}
+		}
+		json.NewEncoder(w).Encode(session)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func setupWeChatTest(t *testing.T, sessions map[string]WeChatSession) (*gorm.DB, *WeChatService) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&model.User{}, &model.ExternalIdentity{}))
+
+	server := fakeWeChat(t, sessions)
+	wechatService := NewWeChatService(db, &config.WeChatConfig{
+		Enabled:         true,
+		AppID:           "wx-app",
+		AppSecret:       "wx-secret",
+		Code2SessionURL: server.URL + "/sns/jscode2session",
+		Timeout:         5,
+	}, nil, zap.NewNop())
+	return db, wechatService
+}
+
+func TestWeChatService_Login(t *testing.T) {
+	db, wechatService := setupWeChatTest(t, map[string]WeChatSession{
+		"code-a":     {OpenID: "openid-a", SessionKey: "key"},
+		"code-a2":    {OpenID: "openid-a", UnionID: "union-a", SessionKey: "key"},
+		"code-other": {OpenID: "openid-a-other-app", UnionID: "union-a", SessionKey: "key"},
+		"code-busy":  {ErrCode: 45011, ErrMsg: "rate limited"},
+	})
+	ctx := context.Background()
+
+	// First login creates a user without email login
+	user, created, err := wechatService.Login(ctx, "code-a", "小明")
+	require.NoError(t, err)
+	assert.True(t, created)
+	assert.Equal(t, "小明", user.DisplayName)
+	assert.False(t, user.HasEmailLogin())
+	assert.False(t, user.CheckPassword(""))
+
+	// Later logins find the same user and pick up the unionid
+	again, created, err := wechatService.Login(ctx, "code-a2", "")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, user.ID, again.ID)
+
+	// Another app of ours yields a new openid with the same unionid
+	other, created, err := wechatService.Login(ctx, "code-other", "")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, user.ID, other.ID)
+
+	identities, err := wechatService.ListIdentities(user.ID)
+	require.NoError(t, err)
+	assert.Len(t, identities, 2)
+
+	_, _, err = wechatService.Login(ctx, "bad-code", "")
+	assert.ErrorIs(t, err, ErrInvalidWeChatCode)
+	_, _, err = wechatService.Login(ctx, "code-busy", "")
+	assert.ErrorIs(t, err, ErrWeChatUnavailable)
+
+	var users int64
+	db.Model(&model.User{}).Count(&users)
+	assert.Equal(t, int64(1), users)
+}
+
+func TestWeChatService_Disabled(t *testing.T) {
+	_, wechatService := setupWeChatTest(t, nil)
+	wechatService.config.Enabled = false
+
+	_, _, err := wechatService.Login(context.Background(), "code", "")
+	assert.ErrorIs(t, err, ErrWeChatDisabled)
+}
+
+func TestWeChatService_Linking(t *testing.T) {
+	db, wechatService := setupWeChatTest(t, map[string]WeChatSession{
+		"code-email-user": {OpenID: "openid-email-user"},
+		"code-wechat":     {OpenID: "openid-wechat"},
+		"code-second":     {OpenID: "openid-second"},
+	})
+	ctx := context.Background()
+
+	emailUser := &model.User{Email: "learner@example.com", DisplayName: "Learner"}
+	require.NoError(t, emailUser.SetPassword("password123"))
+	require.NoError(t, db.Create(emailUser).Error)
+
+	// An email account links a WeChat account, which then logs into it
+	_, err := wechatService.LinkWeChat(ctx, emailUser.ID, "code-email-user")
+	require.NoError(t, err)
+	user, created, err := wechatService.Login(ctx, "code-email-user", "")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, emailUser.ID, user.ID)
+
+	_, err = wechatService.LinkWeChat(ctx, emailUser.ID, "code-second")
+	assert.ErrorIs(t, err, ErrProviderAlreadyLinked)
+
+	// A WeChat account already in use cannot be linked elsewhere
+	wechatUser, _, err := wechatService.Login(ctx, "code-wechat", "")
+	require.NoError(t, err)
+	_, err = wechatService.LinkWeChat(ctx, emailUser.ID, "code-wechat")
+	assert.ErrorIs(t, err, ErrIdentityAlreadyLinked)
+	_, err = wechatService.LinkWeChat(ctx, wechatUser.ID, "code-email-user")
+	assert.ErrorIs(t, err, ErrIdentityAlreadyLinked)
+
+	// A WeChat account sets up email login, but not with a taken address
+	assert.ErrorIs(t, wechatService.UnlinkWeChat(wechatUser.ID), ErrLastLoginMethod)
+	_, err = wechatService.AttachEmail(wechatUser.ID, "Learner@example.com", "secret123")
+	assert.ErrorIs(t, err, ErrEmailTaken)
+
+	attached, err := wechatService.AttachEmail(wechatUser.ID, "New@Example.com", "secret123")
+	require.NoError(t, err)
+	assert.Equal(t, "new@example.com", attached.Email)
+	assert.True(t, attached.HasEmailLogin())
+	assert.False(t, attached.IsEmailVerified())
+
+	var stored model.User
+	require.NoError(t, db.First(&stored, "id = ?", wechatUser.ID).Error)
+	assert.True(t, stored.CheckPassword("secret123"))
+
+	_, err = wechatService.AttachEmail(wechatUser.ID, "another@example.com", "secret123")
+	assert.ErrorIs(t, err, ErrEmailLoginExists)
+
+	// With email login in place WeChat can be unlinked
+	require.NoError(t, wechatService.UnlinkWeChat(wechatUser.ID))
+	assert.ErrorIs(t, wechatService.UnlinkWeChat(wechatUser.ID), ErrIdentityNotLinked)
+}
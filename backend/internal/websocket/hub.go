@@ -32,6 +32,9 @@ type Hub struct {
 	// Logger
 	logger *zap.Logger
 
+	// Tracks delivery and acknowledgement of notifications, if set
+	notifications NotificationStore
+
 	// Mutex for thread-safe operations
 	mu sync.RWMutex
 }
@@ -53,6 +56,26 @@ type Client struct {
 	clientID string
 }
 
+// NotificationStore keeps track of the notifications a user has not acknowledged yet.
+// Notifications are identified by their ID.
+type NotificationStore interface {
+	// PendingNotifications returns the notifications to deliver again when the user connects
+	PendingNotifications(userID string) ([]*NotificationMessage, error)
+	// MarkNotificationsDelivered records that notifications were delivered to a client
+	MarkNotificationsDelivered(userID string, ids []string) error
+	// AcknowledgeNotifications records that the user has seen notifications and
+	// returns how many of them were unread
+	AcknowledgeNotifications(userID string, ids []string) (int64, error)
+	// UnreadNotificationCount returns the number of unacknowledged notifications
+	UnreadNotificationCount(userID string) (int64, error)
+}
+
+// AckMessage is sent by clients to acknowledge notifications
+type AckMessage struct {
+	Type string   `json:"type"` // "ack"
+	IDs  []string `json:"ids"`
+}
+
 // Message represents a WebSocket message
 type Message struct {
 	Type      string    `json:"type"`
@@ -63,19 +86,22 @@ type Message struct {
 
 // NotificationMessage represents a notification message
 type NotificationMessage struct {
+	ID          string                   `json:"id"`
+	Type        string                   `json:"type"` // "achievement", "level_completed", "system"
+	Title       string                   `json:"title"`
+	Message     string                   `json:"message"`
+	Icon        string                   `json:"icon,omitempty"`
+	URL         string                   `json:"url,omitempty"`
+	Achievement *NotificationAchievement `json:"achievement,omitempty"`
+}
+
+// NotificationAchievement is the achievement a notification is about
+type NotificationAchievement struct {
 	ID          string `json:"id"`
-	Type        string `json:"type"` // "achievement", "level_completed", "system"
-	Title       string `json:"title"`
-	Message     string `json:"message"`
-	Icon        string `json:"icon,omitempty"`
-	URL         string `json:"url,omitempty"`
-	Achievement *struct {
-		ID          string `json:"id"`
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		Level       int    `json:"level"`
-		IconURL     string `json:"icon_url"`
-	} `json:"achievement,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Level       int    `json:"level"`
+	IconURL     string `json:"icon_url"`
 }
 
 const (
@@ -88,8 +114,8 @@ const (
 	// Send pings to peer with this period. Must be less than pongWait.
 	pingPeriod = (pongWait * 9) / 10
 
-	// Maximum message size allowed from peer.
-	maxMessageSize = 512
+	// Maximum message size allowed from peer; fits an ack of about 100 notifications.
+	maxMessageSize = 4096
 )
 
 var (
@@ -195,6 +221,11 @@ func (h *Hub) Run() {
 	}
 }
 
+// SetNotificationStore enables redelivery and acknowledgement of notifications
+func (h *Hub) SetNotificationStore(store NotificationStore) {
+	h.notifications = store
+}
+
 // BroadcastToAll sends a message to all connected clients
 func (h *Hub) BroadcastToAll(messageType string, data any) {
 	message := Message{
@@ -214,15 +245,16 @@ func (h *Hub) BroadcastToAll(messageType string, data any) {
 	}
 }
 
-// SendToUser sends a message to a specific user
-func (h *Hub) SendToUser(userID string, messageType string, data any) {
+// SendToUser sends a message to a specific user and reports whether it was queued
+// for at least one of the user's clients
+func (h *Hub) SendToUser(userID string, messageType string, data any) bool {
 	h.mu.RLock()
 	clients, exists := h.userClients[userID]
 	h.mu.RUnlock()
 
 	if !exists || len(clients) == 0 {
 		h.logger.Debug("No clients found for user", zap.String("user_id", userID))
-		return
+		return false
 	}
 
 	message := Message{
@@ -235,13 +267,15 @@ func (h *Hub) SendToUser(userID string, messageType string, data any) {
 	jsonData, err := json.Marshal(message)
 	if err != nil {
 		h.logger.Error("Failed to marshal user message", zap.Error(err))
-		return
+		return false
 	}
 
+	delivered := false
 	h.mu.RLock()
 	for _, client := range clients {
 		select {
 		case client.send <- jsonData:
+			delivered = true
 		default:
 			// Client's send channel is full, remove it
 			close(client.send)
@@ -249,16 +283,20 @@ func (h *Hub) SendToUser(userID string, messageType string, data any) {
 		}
 	}
 	h.mu.RUnlock()
+	return delivered
 }
 
-// SendNotification sends a notification to a specific user
-func (h *Hub) SendNotification(userID string, notification *NotificationMessage) {
-	h.SendToUser(userID, "notification", notification)
+// SendNotification sends a notification to a specific user and reports whether it
+// was delivered to a connected client
+func (h *Hub) SendNotification(userID string, notification *NotificationMessage) bool {
+	delivered := h.SendToUser(userID, "notification", notification)
 	h.logger.Info("Notification sent",
 		zap.String("user_id", userID),
 		zap.String("notification_type", notification.Type),
 		zap.String("title", notification.Title),
+		zap.Bool("delivered", delivered),
 	)
+	return delivered
 }
 
 // GetConnectedUsers returns a list of currently connected user IDs
@@ -313,6 +351,7 @@ func (h *Hub) ServeWS(c *gin.Context, jwtService *middleware.JWTService) {
 	}
 
 	client.hub.register <- client
+	client.redeliverNotifications()
 
 	// Allow collection of memory referenced by the caller by doing all work in
 	// new goroutines.
@@ -391,6 +430,94 @@ func (c *Client) writePump() {
 	}
 }
 
+// queue adds a message to the client's outbound messages, dropping it if the buffer is full
+func (c *Client) queue(messageType string, data any) bool {
+	message := Message{
+		Type:      messageType,
+		UserID:    c.userID,
+		Data:      data,
+		Timestamp: time.Now(),
+	}
+	jsonData, err := json.Marshal(message)
+	if err != nil {
+		c.hub.logger.Error("Failed to marshal client message", zap.Error(err))
+		return false
+	}
+
+	select {
+	case c.send <- jsonData:
+		return true
+	default:
+		return false
+	}
+}
+
+// redeliverNotifications sends a newly connected user the notifications they have
+// not acknowledged yet
+func (c *Client) redeliverNotifications() {
+	if c.userID == "" || c.hub.notifications == nil {
+		return
+	}
+
+	pending, err := c.hub.notifications.PendingNotifications(c.userID)
+	if err != nil {
+		c.hub.logger.Error("Failed to get pending notifications",
+			zap.String("user_id", c.userID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	delivered := make([]string, 0, len(pending))
+	for _, notification := range pending {
+		if c.queue("notification", notification) {
+			delivered = append(delivered, notification.ID)
+		}
+	}
+	if len(delivered) == 0 {
+		return
+	}
+	if err := c.hub.notifications.MarkNotificationsDelivered(c.userID, delivered); err != nil {
+		c.hub.logger.Error("Failed to mark notifications delivered",
+			zap.String("user_id", c.userID),
+			zap.Error(err),
+		)
+	}
+}
+
+// acknowledge records the notifications a client acknowledged and replies with the
+// number still unread
+func (c *Client) acknowledge(message []byte) {
+	if c.userID == "" || c.hub.notifications == nil {
+		return
+	}
+
+	var ack AckMessage
+	if err := json.Unmarshal(message, &ack); err != nil || len(ack.IDs) == 0 {
+		c.hub.logger.Warn("Invalid acknowledgement", zap.String("client_id", c.clientID))
+		return
+	}
+
+	acknowledged, err := c.hub.notifications.AcknowledgeNotifications(c.userID, ack.IDs)
+	if err != nil {
+		c.hub.logger.Error("Failed to acknowledge notifications",
+			zap.String("user_id", c.userID),
+			zap.Error(err),
+		)
+		return
+	}
+	unread, err := c.hub.notifications.UnreadNotificationCount(c.userID)
+	if err != nil {
+		c.hub.logger.Error("Failed to count unread notifications",
+			zap.String("user_id", c.userID),
+			zap.Error(err),
+		)
+		return
+	}
+
+	c.queue("ack", map[string]int64{"acknowledged": acknowledged, "unread": unread})
+}
+
 // handleMessage processes incoming messages from clients
 func (c *Client) handleMessage(message []byte) {
 	var msg map[string]any
@@ -420,6 +547,9 @@ func (c *Client) handleMessage(message []byte) {
 			}
 		}
 
+	case "ack":
+		c.acknowledge(message)
+
 	case "subscribe":
 		// Handle subscription to specific channels
 		if channel, ok := msg["channel"].(string); ok {
@@ -0,0 +1,62 @@
+-- +goose Up
+-- Administrator role (grant with: UPDATE users SET role = 'admin' WHERE email = '...')
+ALTER TABLE users ADD COLUMN role TEXT NOT NULL DEFAULT 'user';
+
+-- Question lifecycle; existing questions stay visible to learners
+ALTER TABLE questions ADD COLUMN status TEXT NOT NULL DEFAULT 'published';
+ALTER TABLE questions ADD COLUMN published_revision_id TEXT;
+CREATE INDEX IF NOT EXISTS idx_questions_status ON questions(status);
+
+-- Immutable question revisions
+CREATE TABLE IF NOT EXISTS question_revisions (
+  id           TEXT     PRIMARY KEY,
+  question_id  TEXT     NOT NULL,
+  number       INTEGER  NOT NULL,
+  status       TEXT     NOT NULL DEFAULT 'draft',
+  subtitle     TEXT,
+  stem         TEXT     NOT NULL,
+  content_json TEXT     NOT NULL,
+  answer_json  TEXT     NOT NULL,
+  score        INTEGER  NOT NULL,
+  change_note  TEXT,
+  created_by   TEXT,
+  reviewed_by  TEXT,
+  review_note  TEXT,
+  submitted_at DATETIME,
+  published_at DATETIME,
+  created_at   DATETIME NOT NULL,
+  updated_at   DATETIME NOT NULL,
+  FOREIGN KEY (question_id) REFERENCES questions(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_question_revisions_number ON question_revisions(question_id, number);
+CREATE INDEX IF NOT EXISTS idx_question_revisions_status ON question_revisions(status);
+
+-- Answer log, each answer references the revision the learner saw
+CREATE TABLE IF NOT EXISTS answer_records (
+  id          TEXT     PRIMARY KEY,
+  user_id     TEXT     NOT NULL,
+  level_id    TEXT     NOT NULL,
+  question_id TEXT     NOT NULL,
+  revision_id TEXT     NOT NULL,
+  answer_json TEXT,
+  is_correct  NUMERIC  NOT NULL DEFAULT false,
+  score       INTEGER  NOT NULL DEFAULT 0,
+  duration_ms INTEGER  DEFAULT 0,
+  created_at  DATETIME NOT NULL,
+  FOREIGN KEY (user_id)     REFERENCES users(id)              ON DELETE CASCADE,
+  FOREIGN KEY (question_id) REFERENCES questions(id)          ON DELETE CASCADE,
+  FOREIGN KEY (revision_id) REFERENCES question_revisions(id)
+);
+CREATE INDEX IF NOT EXISTS idx_answer_records_user_id     ON answer_records(user_id);
+CREATE INDEX IF NOT EXISTS idx_answer_records_level_id    ON answer_records(level_id);
+CREATE INDEX IF NOT EXISTS idx_answer_records_question_id ON answer_records(question_id);
+CREATE INDEX IF NOT EXISTS idx_answer_records_revision_id ON answer_records(revision_id);
+CREATE INDEX IF NOT EXISTS idx_answer_records_created_at  ON answer_records(created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS answer_records;
+DROP TABLE IF EXISTS question_revisions;
+DROP INDEX IF EXISTS idx_questions_status;
+ALTER TABLE questions DROP COLUMN published_revision_id;
+ALTER TABLE questions DROP COLUMN status;
+ALTER TABLE users DROP COLUMN role;
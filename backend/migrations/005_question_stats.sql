@@ -0,0 +1,29 @@
+-- +goose Up
+-- Skipped questions are logged so skip rates can be computed
+ALTER TABLE answer_records ADD COLUMN skipped NUMERIC NOT NULL DEFAULT false;
+
+-- Per-question psychometrics, refreshed by the daily stats job
+CREATE TABLE IF NOT EXISTS question_stats (
+  question_id         TEXT     PRIMARY KEY,
+  revision_id         TEXT,
+  level_id            TEXT     NOT NULL,
+  responses           INTEGER  NOT NULL DEFAULT 0,
+  answered            INTEGER  NOT NULL DEFAULT 0,
+  p_value             REAL     DEFAULT 0,
+  point_biserial      REAL,
+  skip_rate           REAL     DEFAULT 0,
+  median_duration_ms  INTEGER  DEFAULT 0,
+  upper_p_value       REAL     DEFAULT 0,
+  lower_p_value       REAL     DEFAULT 0,
+  option_rates_json   TEXT,
+  suspected_wrong_key NUMERIC  NOT NULL DEFAULT false,
+  flag_reason         TEXT,
+  computed_at         DATETIME NOT NULL,
+  FOREIGN KEY (question_id) REFERENCES questions(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_question_stats_level_id            ON question_stats(level_id);
+CREATE INDEX IF NOT EXISTS idx_question_stats_suspected_wrong_key ON question_stats(suspected_wrong_key);
+
+-- +goose Down
+DROP TABLE IF EXISTS question_stats;
+ALTER TABLE answer_records DROP COLUMN skipped;
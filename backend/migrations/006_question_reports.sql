@@ -0,0 +1,25 @@
+-- +goose Up
+-- Learner-reported question issues; questions may now also be 'hidden'
+CREATE TABLE IF NOT EXISTS question_reports (
+  id          TEXT     PRIMARY KEY,
+  question_id TEXT     NOT NULL,
+  revision_id TEXT,
+  user_id     TEXT     NOT NULL,
+  category    TEXT     NOT NULL,
+  description TEXT,
+  status      TEXT     NOT NULL DEFAULT 'open',
+  admin_note  TEXT,
+  handled_by  TEXT,
+  handled_at  DATETIME,
+  created_at  DATETIME NOT NULL,
+  updated_at  DATETIME NOT NULL,
+  FOREIGN KEY (user_id)     REFERENCES users(id)     ON DELETE CASCADE,
+  FOREIGN KEY (question_id) REFERENCES questions(id) ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS idx_question_reports_question_id ON question_reports(question_id);
+CREATE INDEX IF NOT EXISTS idx_question_reports_user_id     ON question_reports(user_id);
+CREATE INDEX IF NOT EXISTS idx_question_reports_status      ON question_reports(status);
+
+-- +goose Down
+UPDATE questions SET status = 'published' WHERE status = 'hidden';
+DROP TABLE IF EXISTS question_reports;
@@ -0,0 +1,54 @@
+-- +goose Up
+-- Structured paper metadata; the legacy string columns stay in sync for the agent
+ALTER TABLE papers ADD COLUMN abstract       TEXT;
+ALTER TABLE papers ADD COLUMN doi            TEXT;
+ALTER TABLE papers ADD COLUMN arxiv_id       TEXT;
+ALTER TABLE papers ADD COLUMN venue          TEXT;
+ALTER TABLE papers ADD COLUMN url            TEXT;
+ALTER TABLE papers ADD COLUMN entry_type     TEXT;
+ALTER TABLE papers ADD COLUMN pub_year       INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE papers ADD COLUMN pub_month      INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE papers ADD COLUMN citation_count INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_papers_doi      ON papers(doi);
+CREATE INDEX IF NOT EXISTS idx_papers_arxiv_id ON papers(arxiv_id);
+
+-- Backfill from the legacy strings ("2017", "2017-06", "None")
+UPDATE papers SET pub_year = CAST(substr(paper_pub_ym, 1, 4) AS INTEGER)
+  WHERE paper_pub_ym GLOB '[12][0-9][0-9][0-9]*';
+UPDATE papers SET pub_month = CAST(substr(paper_pub_ym, 6, 2) AS INTEGER)
+  WHERE paper_pub_ym GLOB '[12][0-9][0-9][0-9]-[0-9][0-9]*'
+    AND CAST(substr(paper_pub_ym, 6, 2) AS INTEGER) BETWEEN 1 AND 12;
+UPDATE papers SET citation_count = CAST(paper_citation_count AS INTEGER)
+  WHERE paper_citation_count GLOB '[0-9]*';
+
+-- Agent-generated papers are stored as paper_<arXiv ID>
+UPDATE papers SET arxiv_id = substr(id, 7), url = 'https://arxiv.org/abs/' || substr(id, 7)
+  WHERE id GLOB 'paper_[0-9][0-9][0-9][0-9].[0-9][0-9][0-9][0-9]*';
+
+-- Authors in byline order; populated lazily from paper_author on first read
+CREATE TABLE IF NOT EXISTS paper_authors (
+  id              TEXT    PRIMARY KEY,
+  paper_id        TEXT    NOT NULL,
+  position        INTEGER NOT NULL,
+  name            TEXT    NOT NULL,
+  given_name      TEXT,
+  family_name     TEXT,
+  normalized_name TEXT,
+  FOREIGN KEY (paper_id) REFERENCES papers(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_paper_authors_position        ON paper_authors(paper_id, position);
+CREATE INDEX IF NOT EXISTS        idx_paper_authors_normalized_name ON paper_authors(normalized_name);
+
+-- +goose Down
+DROP TABLE IF EXISTS paper_authors;
+DROP INDEX IF EXISTS idx_papers_doi;
+DROP INDEX IF EXISTS idx_papers_arxiv_id;
+ALTER TABLE papers DROP COLUMN citation_count;
+ALTER TABLE papers DROP COLUMN pub_month;
+ALTER TABLE papers DROP COLUMN pub_year;
+ALTER TABLE papers DROP COLUMN entry_type;
+ALTER TABLE papers DROP COLUMN url;
+ALTER TABLE papers DROP COLUMN venue;
+ALTER TABLE papers DROP COLUMN arxiv_id;
+ALTER TABLE papers DROP COLUMN doi;
+ALTER TABLE papers DROP COLUMN abstract;
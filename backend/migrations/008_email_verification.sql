@@ -0,0 +1,7 @@
+-- +goose Up
+-- Email verification; accounts created before verification existed are treated as verified
+ALTER TABLE users ADD COLUMN email_verified_at DATETIME;
+UPDATE users SET email_verified_at = created_at;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN email_verified_at;
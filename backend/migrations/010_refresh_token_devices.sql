@@ -0,0 +1,13 @@
+-- +goose Up
+-- Per-device sessions; existing tokens were last used when they were issued
+ALTER TABLE refresh_tokens ADD COLUMN device_name TEXT;
+ALTER TABLE refresh_tokens ADD COLUMN user_agent TEXT;
+ALTER TABLE refresh_tokens ADD COLUMN ip_address TEXT;
+ALTER TABLE refresh_tokens ADD COLUMN last_used_at DATETIME;
+UPDATE refresh_tokens SET last_used_at = created_at WHERE last_used_at IS NULL;
+
+-- +goose Down
+ALTER TABLE refresh_tokens DROP COLUMN last_used_at;
+ALTER TABLE refresh_tokens DROP COLUMN ip_address;
+ALTER TABLE refresh_tokens DROP COLUMN user_agent;
+ALTER TABLE refresh_tokens DROP COLUMN device_name;
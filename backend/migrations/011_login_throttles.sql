@@ -0,0 +1,16 @@
+-- +goose Up
+-- Failed login tracking per account and per client IP
+CREATE TABLE IF NOT EXISTS login_throttles (
+    scope TEXT NOT NULL,
+    identifier TEXT NOT NULL,
+    failures INTEGER NOT NULL DEFAULT 0,
+    last_failure_at DATETIME,
+    locked_until DATETIME,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (scope, identifier)
+);
+CREATE INDEX IF NOT EXISTS idx_login_throttles_locked_until ON login_throttles(locked_until);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_login_throttles_locked_until;
+DROP TABLE IF EXISTS login_throttles;
@@ -0,0 +1,21 @@
+-- +goose Up
+-- Logins through external providers such as WeChat mini-programs
+CREATE TABLE IF NOT EXISTS external_identities (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    provider TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    union_id TEXT,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_external_identities_provider_subject ON external_identities(provider, subject);
+CREATE INDEX IF NOT EXISTS idx_external_identities_user_id ON external_identities(user_id);
+CREATE INDEX IF NOT EXISTS idx_external_identities_union_id ON external_identities(union_id);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_external_identities_union_id;
+DROP INDEX IF EXISTS idx_external_identities_user_id;
+DROP INDEX IF EXISTS idx_external_identities_provider_subject;
+DROP TABLE IF EXISTS external_identities;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Self-service account deletion with a grace period
+ALTER TABLE users ADD COLUMN deletion_requested_at DATETIME;
+ALTER TABLE users ADD COLUMN deletion_scheduled_at DATETIME;
+ALTER TABLE users ADD COLUMN wallet_disposition TEXT;
+ALTER TABLE users ADD COLUMN anonymized_at DATETIME;
+CREATE INDEX IF NOT EXISTS idx_users_deletion_scheduled_at ON users(deletion_scheduled_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_users_deletion_scheduled_at;
+ALTER TABLE users DROP COLUMN anonymized_at;
+ALTER TABLE users DROP COLUMN wallet_disposition;
+ALTER TABLE users DROP COLUMN deletion_scheduled_at;
+ALTER TABLE users DROP COLUMN deletion_requested_at;
@@ -0,0 +1,6 @@
+-- +goose Up
+-- Access tokens carry the user's token version; bumping it revokes them all
+ALTER TABLE users ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+-- +goose Down
+ALTER TABLE users DROP COLUMN token_version;
@@ -0,0 +1,14 @@
+-- +goose Up
+-- Highest progress milestone users were notified about for unearned achievements
+CREATE TABLE IF NOT EXISTS user_achievement_progresses (
+    user_id TEXT NOT NULL,
+    achievement_id TEXT NOT NULL,
+    milestone INTEGER NOT NULL DEFAULT 0,
+    updated_at DATETIME NOT NULL,
+    PRIMARY KEY (user_id, achievement_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (achievement_id) REFERENCES achievements(id) ON DELETE CASCADE
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS user_achievement_progresses;
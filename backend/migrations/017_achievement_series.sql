@@ -0,0 +1,28 @@
+-- +goose Up
+-- Tiered achievements: tier N+1 of a series requires tier N and supersedes it
+CREATE TABLE IF NOT EXISTS achievement_series (
+    id TEXT PRIMARY KEY,
+    name TEXT NOT NULL,
+    description TEXT,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+ALTER TABLE achievements ADD COLUMN series_id TEXT REFERENCES achievement_series(id);
+ALTER TABLE achievements ADD COLUMN tier INTEGER NOT NULL DEFAULT 0;
+CREATE INDEX IF NOT EXISTS idx_achievements_series_id ON achievements(series_id);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_achievements_series_tier ON achievements(series_id, tier) WHERE series_id IS NOT NULL;
+
+ALTER TABLE user_achievements ADD COLUMN superseded_at DATETIME;
+ALTER TABLE user_achievements ADD COLUMN superseded_by TEXT;
+
+ALTER TABLE nft_assets ADD COLUMN upgraded_to_id TEXT;
+
+-- +goose Down
+ALTER TABLE nft_assets DROP COLUMN upgraded_to_id;
+ALTER TABLE user_achievements DROP COLUMN superseded_by;
+ALTER TABLE user_achievements DROP COLUMN superseded_at;
+DROP INDEX IF EXISTS idx_achievements_series_tier;
+DROP INDEX IF EXISTS idx_achievements_series_id;
+ALTER TABLE achievements DROP COLUMN tier;
+ALTER TABLE achievements DROP COLUMN series_id;
+DROP TABLE IF EXISTS achievement_series;
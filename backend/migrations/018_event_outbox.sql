@@ -0,0 +1,22 @@
+-- +goose Up
+-- Events waiting for delivery to the in-process event bus
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id TEXT PRIMARY KEY,
+    event_id TEXT NOT NULL,
+    user_id TEXT NOT NULL,
+    event_type TEXT NOT NULL,
+    data_json TEXT,
+    status TEXT NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    available_at DATETIME NOT NULL,
+    applied_at DATETIME,
+    last_error TEXT,
+    created_at DATETIME NOT NULL,
+    processed_at DATETIME
+);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_event_id ON outbox_events(event_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_user_id ON outbox_events(user_id);
+CREATE INDEX IF NOT EXISTS idx_outbox_events_status_available ON outbox_events(status, available_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS outbox_events;
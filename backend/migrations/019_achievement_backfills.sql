@@ -0,0 +1,24 @@
+-- +goose Up
+-- Retroactive awarding of achievements, checkpointed by user ID
+CREATE TABLE IF NOT EXISTS achievement_backfills (
+    id TEXT PRIMARY KEY,
+    achievement_id TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'pending',
+    notify BOOLEAN NOT NULL DEFAULT 0,
+    triggered_by TEXT,
+    total_users INTEGER NOT NULL DEFAULT 0,
+    processed_users INTEGER NOT NULL DEFAULT 0,
+    awarded_count INTEGER NOT NULL DEFAULT 0,
+    last_user_id TEXT,
+    lease_until DATETIME,
+    last_error TEXT,
+    started_at DATETIME,
+    completed_at DATETIME,
+    created_at DATETIME NOT NULL,
+    updated_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_achievement_backfills_achievement_id ON achievement_backfills(achievement_id);
+CREATE INDEX IF NOT EXISTS idx_achievement_backfills_status ON achievement_backfills(status, lease_until);
+
+-- +goose Down
+DROP TABLE IF EXISTS achievement_backfills;
@@ -0,0 +1,29 @@
+-- +goose Up
+-- Revoked achievements are kept as tombstones; awards and revocations are audited
+ALTER TABLE user_achievements ADD COLUMN revoked_at DATETIME;
+ALTER TABLE user_achievements ADD COLUMN revoked_by TEXT;
+ALTER TABLE user_achievements ADD COLUMN revoke_reason TEXT;
+
+ALTER TABLE nft_assets ADD COLUMN burn_tx_hash TEXT;
+
+CREATE TABLE IF NOT EXISTS achievement_audit_logs (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL,
+    achievement_id TEXT NOT NULL,
+    user_achievement_id TEXT NOT NULL,
+    action TEXT NOT NULL,
+    source TEXT NOT NULL,
+    actor_id TEXT,
+    reason TEXT,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_achievement_audit_logs_user_id ON achievement_audit_logs(user_id);
+CREATE INDEX IF NOT EXISTS idx_achievement_audit_logs_achievement_id ON achievement_audit_logs(achievement_id);
+CREATE INDEX IF NOT EXISTS idx_achievement_audit_logs_created_at ON achievement_audit_logs(created_at);
+
+-- +goose Down
+DROP TABLE IF EXISTS achievement_audit_logs;
+ALTER TABLE nft_assets DROP COLUMN burn_tx_hash;
+ALTER TABLE user_achievements DROP COLUMN revoke_reason;
+ALTER TABLE user_achievements DROP COLUMN revoked_by;
+ALTER TABLE user_achievements DROP COLUMN revoked_at;
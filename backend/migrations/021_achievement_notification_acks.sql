@@ -0,0 +1,10 @@
+-- +goose Up
+-- Unacknowledged achievements are counted as unread and sent again on connect
+CREATE INDEX IF NOT EXISTS idx_user_achievements_unread ON user_achievements(user_id, viewed_at);
+
+-- Achievements earned before acknowledgement existed count as seen
+UPDATE user_achievements SET viewed_at = earned_at, notified_at = COALESCE(notified_at, earned_at)
+WHERE viewed_at IS NULL;
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_user_achievements_unread;
@@ -0,0 +1,17 @@
+-- +goose Up
+-- Aggregate achievement statistics and rarity, recomputed by the daily stats job
+CREATE TABLE IF NOT EXISTS achievement_stats (
+    achievement_id TEXT PRIMARY KEY,
+    holder_count INTEGER NOT NULL DEFAULT 0,
+    active_users INTEGER NOT NULL DEFAULT 0,
+    active_holders INTEGER NOT NULL DEFAULT 0,
+    holder_percent REAL DEFAULT 0,
+    rarity TEXT,
+    first_earner_id TEXT,
+    first_earned_at DATETIME,
+    median_days_to_earn INTEGER,
+    computed_at DATETIME NOT NULL
+);
+
+-- +goose Down
+DROP TABLE IF EXISTS achievement_stats;
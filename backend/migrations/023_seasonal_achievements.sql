@@ -0,0 +1,10 @@
+-- +goose Up
+-- Seasonal achievements can only be earned within a time window
+ALTER TABLE achievements ADD COLUMN starts_at DATETIME;
+ALTER TABLE achievements ADD COLUMN ends_at DATETIME;
+ALTER TABLE achievements ADD COLUMN season_state TEXT;
+
+-- +goose Down
+ALTER TABLE achievements DROP COLUMN season_state;
+ALTER TABLE achievements DROP COLUMN ends_at;
+ALTER TABLE achievements DROP COLUMN starts_at;
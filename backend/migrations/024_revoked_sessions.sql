@@ -0,0 +1,12 @@
+-- +goose Up
+-- Sessions signed out while their access tokens may still be live
+CREATE TABLE IF NOT EXISTS revoked_sessions (
+    session_id TEXT PRIMARY KEY,
+    expires_at DATETIME NOT NULL,
+    created_at DATETIME NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_sessions_expires_at ON revoked_sessions(expires_at);
+
+-- +goose Down
+DROP INDEX IF EXISTS idx_revoked_sessions_expires_at;
+DROP TABLE IF EXISTS revoked_sessions;
@@ -49,7 +49,7 @@ run_unit_tests() {
     # Run unit tests with coverage
     print_info "Running Go unit tests with coverage..."
     
-    if go test -v -race -coverprofile=coverage.out ./...; then
+    if go test -v -race -tags sqlite_fts5 -coverprofile=coverage.out ./...; then
         print_success "Unit tests passed"
         
         # Generate coverage report
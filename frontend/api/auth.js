@@ -29,6 +29,27 @@ export function login(data) {
   })
 }
 
+/**
+ * 微信小程序登录
+ * @param {Object} data - 登录数据
+ * @param {string} data.code - wx.login 返回的 code
+ * @param {string} [data.display_name] - 新建账号时使用的昵称
+ * @returns {Promise<{
+ *   user: Object,
+ *   access_token: string,
+ *   refresh_token: string,
+ *   token_type: string,
+ *   expires_in: number
+ * }>}
+ */
+export function wechatLogin(data) {
+  return request({
+    url: '/api/v1/auth/wechat',
+    method: 'POST',
+    data
+  })
+}
+
 /**
  * 用户注册
  * @param {Object} data - 注册数据